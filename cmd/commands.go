@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"spotify/internal/config"
+	"spotify/internal/processor"
+	"spotify/internal/ratelimit"
+	"spotify/internal/registry"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	spotifyauth "github.com/zmb3/spotify/v2/auth"
+)
+
+// Scopes needed by the kinds of commands.
+var (
+	readLibrary     = []string{spotifyauth.ScopeUserLibraryRead}
+	readPlaylists   = append(slices.Clone(readLibrary), spotifyauth.ScopePlaylistReadPrivate)
+	writePlaylists  = append(slices.Clone(readPlaylists), spotifyauth.ScopePlaylistModifyPublic, spotifyauth.ScopePlaylistModifyPrivate, spotifyauth.ScopeImageUpload)
+	writeLibrary    = append(slices.Clone(writePlaylists), spotifyauth.ScopeUserLibraryModify)
+	writeEverything = append(slices.Clone(writeLibrary), spotifyauth.ScopeUserFollowRead, spotifyauth.ScopeUserFollowModify)
+	// playback is added for the runs that start playing a playlist.
+	playback = []string{spotifyauth.ScopeUserReadPlaybackState, spotifyauth.ScopeUserModifyPlaybackState}
+	// allScopes covers every command, for a refresh token used by any of them.
+	allScopes = append(slices.Clone(writeEverything), playback...)
+)
+
+// builtin registers a command built from the app's own state.
+func builtin(c registry.Command, build func(a *app, args []string) (processor.Processor, error)) registry.Command {
+	c.Build = func(env registry.Env, args []string) (processor.Processor, error) {
+		return build(env.(*app), args)
+	}
+	return c
+}
+
+// noArgs adapts a build method of a command without arguments.
+func noArgs(build func(a *app) (processor.Processor, error)) func(a *app, args []string) (processor.Processor, error) {
+	return func(a *app, args []string) (processor.Processor, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("unexpected arguments %q: the command takes none", args)
+		}
+		return build(a)
+	}
+}
+
+func init() {
+	for _, c := range []registry.Command{
+		builtin(registry.Command{Name: "sort", Description: "Sort liked songs into a playlist per year", Scopes: writePlaylists, ConfigSection: "sorter", Validate: validateSorter}, (*app).buildSort),
+		builtin(registry.Command{Name: "unarchive", Description: "Like the songs archived by sort --archive again", Scopes: writeLibrary, ConfigSection: "sorter"}, (*app).buildUnarchive),
+		builtin(registry.Command{Name: "import-history", Description: "Import a streaming history export"}, (*app).buildHistoryImport),
+		builtin(registry.Command{Name: "import-csv", Description: "Like or collect the tracks of a CSV export from another service", Scopes: writeLibrary, ConfigSection: "matching"}, (*app).buildCSVImport),
+		builtin(registry.Command{Name: "top-played", Description: "Build a playlist of each year's most played songs", Scopes: writePlaylists, ConfigSection: "top_played", Validate: validateTopPlayed}, noArgs((*app).buildTopPlayed)),
+		builtin(registry.Command{Name: "wrapped", Description: "Print a year-in-review report of the streaming history", Scopes: readLibrary, ConfigSection: "top_played"}, (*app).buildWrapped),
+		builtin(registry.Command{Name: "forgotten-gems", Description: "Build a playlist of loved songs not played in a long time", Scopes: writePlaylists, ConfigSection: "forgotten_gems", Validate: validateForgottenGems}, noArgs((*app).buildForgottenGems)),
+		builtin(registry.Command{Name: "lastfm", Description: "Log in to Last.fm, build top playlists from scrobbles, or sync loves", Scopes: writeLibrary, ConfigSection: "lastfm"}, (*app).buildLastfmTask),
+		builtin(registry.Command{Name: "setlist", Description: "Build a playlist from a concert setlist on setlist.fm", Scopes: writePlaylists, ConfigSection: "matching"}, (*app).buildSetlist),
+		builtin(registry.Command{Name: "archive-charts", Description: "Archive snapshots of chart playlists", Scopes: writePlaylists, ConfigSection: "charts", Validate: validateCharts}, noArgs((*app).buildChartArchiver)),
+		builtin(registry.Command{Name: "folders", Description: "Report how playlists match the folder manifest", Scopes: writePlaylists, ConfigSection: "folders"}, noArgs((*app).buildFolders)),
+		builtin(registry.Command{Name: "album-check", Description: "Compare saved albums with liked songs and optionally fix them", Scopes: writeLibrary}, (*app).buildAlbumCheck),
+		builtin(registry.Command{Name: "complete-albums", Description: "Save albums you've liked most of", Scopes: writeLibrary, ConfigSection: "completionist", Validate: validateCompletionist}, (*app).buildCompleteAlbums),
+		builtin(registry.Command{Name: "health", Description: "Report duplicates, unavailable tracks and stale playlists", Scopes: readPlaylists}, (*app).buildHealthCheck),
+		builtin(registry.Command{Name: "range", Description: "Build a playlist of songs liked between two dates", Scopes: writePlaylists, ConfigSection: "date_range"}, (*app).buildDateRange),
+		builtin(registry.Command{Name: "on-this-day", Description: "Build a playlist of songs liked on this day in past years", Scopes: writePlaylists, ConfigSection: "on_this_day", Validate: validateOnThisDay}, noArgs((*app).buildOnThisDay)),
+		builtin(registry.Command{Name: "rolling", Description: "Keep a playlist of the most recently liked songs", Scopes: writePlaylists, ConfigSection: "rolling", Validate: validateRolling}, noArgs((*app).buildRolling)),
+		builtin(registry.Command{Name: "smart", Description: "Sync the smart playlists defined by rules", Scopes: writePlaylists, ConfigSection: "smart_playlists", Validate: validateSmartPlaylists}, noArgs((*app).buildSmartPlaylists)),
+		builtin(registry.Command{Name: "split", Description: "Split a big playlist into parts, or join them back", Scopes: writePlaylists, ConfigSection: "split"}, (*app).buildSplit),
+		builtin(registry.Command{Name: "clone", Description: "Copy any playlist into your account and keep it in sync", Scopes: writePlaylists}, (*app).buildClone),
+		builtin(registry.Command{Name: "migrate", Description: "Copy your library to another account", Scopes: writeEverything}, (*app).buildMigrate),
+		builtin(registry.Command{Name: "blend", Description: "Compare your liked songs with a friend's", Scopes: writePlaylists, ConfigSection: "blend"}, (*app).buildBlend),
+		builtin(registry.Command{Name: "export", Description: "Write the liked songs matching a query as CSV", Scopes: readLibrary}, queryTask("export")),
+		builtin(registry.Command{Name: "build", Description: "Write the liked songs matching a query to a playlist", Scopes: writePlaylists}, queryTask("build")),
+		builtin(registry.Command{Name: "remove", Description: "Unlike the songs matching a query", Scopes: writeLibrary}, queryTask("remove")),
+		builtin(registry.Command{Name: "languages", Description: "Build a playlist per language of your liked songs", Scopes: writePlaylists, ConfigSection: "languages"}, (*app).buildLanguagesTask),
+		builtin(registry.Command{Name: "seasons", Description: "Build a playlist per season of your liked songs, across all years", Scopes: writePlaylists, ConfigSection: "seasons", Validate: validateSeasons}, noArgs((*app).buildSeasons)),
+		builtin(registry.Command{Name: "erase-artist", Description: "Remove artists from likes, playlists, saved albums and follows", Scopes: writeEverything}, (*app).buildEraseArtist),
+		builtin(registry.Command{Name: "length-filter", Description: "Unlike songs shorter or longer than given lengths", Scopes: writeLibrary}, (*app).buildLengthFilter),
+		builtin(registry.Command{Name: "popularity", Description: "Split liked songs or a playlist into hits and deep cuts", Scopes: writePlaylists, ConfigSection: "popularity", Validate: validatePopularity}, (*app).buildPopularity),
+		builtin(registry.Command{Name: "workout", Description: "Build a workout playlist that follows a BPM curve", Scopes: writePlaylists, ConfigSection: "workout", Validate: validateWorkout}, noArgs((*app).buildWorkout)),
+		builtin(registry.Command{Name: "prune", Description: "Remove tracks added to a playlist more than N days ago", Scopes: writePlaylists}, (*app).buildPrune),
+		builtin(registry.Command{Name: "unfollow-playlists", Description: "Unfollow or delete the playlists whose name matches a pattern", Scopes: writePlaylists}, (*app).buildUnfollowPlaylists),
+		builtin(registry.Command{Name: "playlist", Description: "Print statistics of any playlist or reorder one for harmonic mixing", Scopes: writePlaylists, Public: true}, (*app).buildPlaylistTask),
+		builtin(registry.Command{Name: "queue", Description: "Add a playlist's tracks or the songs matching a query to the playback queue", Scopes: append(slices.Clone(readPlaylists), playback...)}, (*app).buildQueue),
+		builtin(registry.Command{Name: "devices", Description: "List your Spotify Connect devices", Scopes: playback}, noArgs((*app).buildDevices)),
+		builtin(registry.Command{Name: "transfer", Description: "Move playback to a device, optionally starting a playlist", Scopes: append(slices.Clone(readPlaylists), playback...)}, (*app).buildTransfer),
+		builtin(registry.Command{Name: "search", Description: "Search the catalog, then add results to a playlist or like them", Scopes: writeEverything, Public: true}, (*app).buildSearch),
+		builtin(registry.Command{Name: "tui", Description: "Browse liked songs and playlists and act on selected tracks", Scopes: writeLibrary}, noArgs((*app).buildBrowser)),
+		builtin(registry.Command{Name: "tag", Description: "Add, remove, list, import or export local tags"}, (*app).buildTagTask),
+		builtin(registry.Command{Name: "cover", Description: "Preview generated covers", ConfigSection: "covers"}, (*app).buildCoverTask),
+		builtin(registry.Command{Name: "replay-transcript", Description: "Re-issue the calls of a recorded transcript", Scopes: writeEverything}, (*app).buildReplay),
+		builtin(registry.Command{Name: "pipeline", Description: "Run a configured pipeline of commands", ConfigSection: "pipelines", Validate: validatePipelines}, (*app).buildPipeline),
+		builtin(registry.Command{Name: "daemon", Description: "Run the configured jobs on their intervals", ConfigSection: "daemon", Validate: validateDaemon}, noArgs((*app).buildDaemon)),
+		builtin(registry.Command{Name: "login", Description: "Log in with every permission, optionally printing a refresh token for containers", Scopes: allScopes}, (*app).buildLogin),
+		builtin(registry.Command{Name: "list-processors", Description: "List the available commands"}, noArgs((*app).buildListProcessors)),
+		builtin(registry.Command{Name: "check-config", Description: "Check config.yaml for unknown keys and invalid settings"}, noArgs((*app).buildCheckConfig)),
+		builtin(registry.Command{Name: "completion", Description: "Print the shell completion script for bash, zsh or fish", Quiet: true}, (*app).buildCompletion),
+	} {
+		registry.Register(c)
+	}
+}
+
+// scopesFor returns the scopes command needs: its own and, for the pipeline and the
+// daemon, those of the commands they run.
+func (a *app) scopesFor(command string, args []string) []string {
+	c, ok := registry.Lookup(command)
+	if !ok {
+		return nil
+	}
+	scopes := slices.Clone(c.Scopes)
+	add := func(cmd string, args []string) {
+		// Nested pipelines and daemons are refused when built; don't recurse into them.
+		if cmd == "pipeline" && command == "pipeline" || cmd == "daemon" {
+			return
+		}
+		for _, s := range a.scopesFor(cmd, args) {
+			if !slices.Contains(scopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	switch command {
+	case "sort":
+		if hasFlag(args, "play") {
+			scopes = append(scopes, playback...)
+		}
+		if hasFlag(args, "archive") {
+			scopes = append(scopes, spotifyauth.ScopeUserLibraryModify)
+		}
+	case "pipeline":
+		if p := a.pipeline(firstArg(args)); p != nil {
+			for _, step := range p.Steps {
+				add(step.Command, step.Args)
+			}
+		}
+	case "daemon":
+		for _, job := range a.cfg.Daemon.Jobs {
+			add(job.Command, job.Args)
+		}
+	}
+	slices.Sort(scopes)
+	return scopes
+}
+
+// hasFlag reports whether args set the boolean flag name.
+func hasFlag(args []string, name string) bool {
+	return slices.ContainsFunc(args, func(arg string) bool {
+		arg = strings.TrimLeft(arg, "-")
+		return arg == name || arg == name+"=true"
+	})
+}
+
+// firstArg returns the first argument that isn't a flag.
+func firstArg(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// parseInterleaved parses args with flags before, between or after the positional
+// arguments, and returns the positional ones.
+func parseInterleaved(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, fs.Arg(0)), fs.Args()[1:]
+	}
+}
+
+// queryTask adapts buildQueryTask to one of the query commands.
+func queryTask(command string) func(a *app, args []string) (processor.Processor, error) {
+	return func(a *app, args []string) (processor.Processor, error) {
+		return a.buildQueryTask(command, args)
+	}
+}
+
+// buildListProcessors prints every command with what it needs.
+func (a *app) buildListProcessors() (processor.Processor, error) {
+	return processor.ProcessorFunc(func(context.Context) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "COMMAND\tDESCRIPTION\tCONFIG\tPUBLIC\tSCOPES")
+		for _, c := range registry.All() {
+			section := c.ConfigSection
+			if section == "" {
+				section = "-"
+			}
+			public := "-"
+			if c.Public {
+				public = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.Description, section, public, strings.Join(c.Scopes, " "))
+		}
+		return w.Flush()
+	}), nil
+}
+
+// buildCheckConfig checks the config file for unknown keys and every command's settings
+// for errors, without logging in.
+func (a *app) buildCheckConfig() (processor.Processor, error) {
+	return processor.ProcessorFunc(func(context.Context) error {
+		var failures []error
+		if err := config.Check(configPath()); err != nil {
+			a.logger.Printf("❌ %v", err)
+			failures = append(failures, err)
+		}
+		if a.cfg.RateLimit.RequestsPerSecond != 0 {
+			if _, err := ratelimit.New(a.cfg.RateLimit.RequestsPerSecond, a.cfg.RateLimit.Burst); err != nil {
+				a.logger.Printf("❌ rate_limit: %v", err)
+				failures = append(failures, fmt.Errorf("rate_limit: %w", err))
+			}
+		}
+		if _, err := ratelimit.NewBatches(nil, a.cfg.RateLimit.BatchSize, a.cfg.RateLimit.BatchDelay); err != nil {
+			a.logger.Printf("❌ rate_limit: %v", err)
+			failures = append(failures, fmt.Errorf("rate_limit: %w", err))
+		}
+		for _, c := range registry.All() {
+			if c.Validate == nil {
+				continue
+			}
+			if err := c.Validate(a.cfg); err != nil {
+				a.logger.Printf("❌ %s (%s): %v", c.Name, c.ConfigSection, err)
+				failures = append(failures, fmt.Errorf("%s: %w", c.Name, err))
+				continue
+			}
+			a.logger.Printf("✅ %s", c.Name)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%w: %d problems:\n%w", errInvalidConfig, len(failures), errors.Join(failures...))
+		}
+		a.logger.Println("✅ The configuration is valid.")
+		return nil
+	}), nil
+}
+
+// The validators build the command's processor without a client: constructors only
+// parse and check their settings.
+
+func validateSorter(cfg config.Config) error {
+	_, err := processor.NewPlaylistSorter(nil, nil, nil, nil, cfg.Sorter, cfg.Playlists, processor.SorterOptions{})
+	return err
+}
+
+func validateTopPlayed(cfg config.Config) error {
+	_, err := processor.NewTopPlayedBuilder(nil, nil, nil, nil, cfg.TopPlayed, cfg.Matching, cfg.Playlists)
+	return err
+}
+
+func validateForgottenGems(cfg config.Config) error {
+	_, err := processor.NewForgottenGemsBuilder(nil, nil, nil, nil, cfg.ForgottenGems, cfg.Playlists)
+	return err
+}
+
+func validateCharts(cfg config.Config) error {
+	_, err := processor.NewChartArchiver(nil, nil, cfg.Charts, cfg.Playlists)
+	return err
+}
+
+func validateCompletionist(cfg config.Config) error {
+	_, err := processor.NewAlbumCompletionist(nil, nil, nil, nil, cfg.Completionist, cfg.Playlists, false)
+	return err
+}
+
+func validateRolling(cfg config.Config) error {
+	_, err := processor.NewRollingPlaylist(nil, nil, nil, nil, cfg.Rolling, cfg.Playlists)
+	return err
+}
+
+func validateSeasons(cfg config.Config) error {
+	loc, err := location(cfg.Sorter)
+	if err != nil {
+		return err
+	}
+	_, err = processor.NewSeasonPlaylistBuilder(nil, nil, nil, nil, cfg.Seasons, cfg.Playlists, loc)
+	return err
+}
+
+func validateOnThisDay(cfg config.Config) error {
+	loc, err := location(cfg.Sorter)
+	if err != nil {
+		return err
+	}
+	_, err = processor.NewOnThisDayBuilder(nil, nil, nil, nil, cfg.OnThisDay, cfg.Playlists, loc)
+	return err
+}
+
+func validatePopularity(cfg config.Config) error {
+	_, err := processor.NewPopularitySplitter(nil, nil, nil, nil, cfg.Popularity, cfg.Playlists, "")
+	return err
+}
+
+func validateWorkout(cfg config.Config) error {
+	_, err := processor.NewWorkoutBuilder(nil, nil, nil, nil, cfg.Workout, cfg.Playlists)
+	return err
+}
+
+func validateSmartPlaylists(cfg config.Config) error {
+	loc, err := location(cfg.Sorter)
+	if err != nil {
+		return err
+	}
+	_, err = processor.NewSmartPlaylistSyncer(nil, nil, nil, nil, cfg.SmartPlaylists, cfg.Playlists, loc)
+	return err
+}
+
+// validatePipelines checks that every step of every pipeline runs a known command.
+func validatePipelines(cfg config.Config) error {
+	for _, p := range cfg.Pipelines {
+		if p.OnError != "" && p.OnError != "stop" && p.OnError != "continue" {
+			return fmt.Errorf("unknown on_error '%s' in pipeline '%s'", p.OnError, p.Name)
+		}
+		for _, step := range p.Steps {
+			if _, ok := registry.Lookup(step.Command); !ok || step.Command == "pipeline" || step.Command == "daemon" {
+				return fmt.Errorf("pipeline '%s' can't run the command '%s'", p.Name, step.Command)
+			}
+		}
+	}
+	return nil
+}
+
+// validateDaemon checks that every job runs a known command on a positive interval, and
+// that jobs sending emails have a server to send them through.
+func validateDaemon(cfg config.Config) error {
+	for _, job := range cfg.Daemon.Jobs {
+		if _, ok := registry.Lookup(job.Command); !ok || job.Command == "daemon" {
+			return fmt.Errorf("a job can't run the command '%s'", job.Command)
+		}
+		if job.Interval <= 0 {
+			return fmt.Errorf("job '%s' needs a positive interval", job.Command)
+		}
+		if job.Timeout < 0 {
+			return fmt.Errorf("job '%s' has a negative timeout", job.Command)
+		}
+		switch job.Notify {
+		case "", "always", "failure":
+		default:
+			return fmt.Errorf("job '%s' has an invalid notify '%s' (use always or failure)", job.Command, job.Notify)
+		}
+		if job.Notify != "" {
+			email := cfg.Daemon.Email
+			if email.Host == "" || email.From == "" || len(email.To) == 0 {
+				return fmt.Errorf("job '%s' sends emails, so daemon.email needs a host, from and to", job.Command)
+			}
+		}
+	}
+	return nil
+}
+
+// location returns the time zone dates are grouped in, configured for the sorter.
+func location(cfg config.Sorter) (*time.Location, error) {
+	if cfg.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %w", cfg.Timezone, err)
+	}
+	return loc, nil
+}
+
+// buildLogin handles "login [--print-refresh-token]". It always logs in interactively,
+// even when SPOTIFY_REFRESH_TOKEN is set, to get a fresh token.
+func (a *app) buildLogin(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("login")
+	printToken := fs.Bool("print-refresh-token", false, "print a refresh token to set as SPOTIFY_REFRESH_TOKEN")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return processor.ProcessorFunc(func(context.Context) error {
+		client := a.authenticate("Spotify", false)
+		if !*printToken {
+			return nil
+		}
+		token, err := client.Token()
+		if err != nil {
+			return fmt.Errorf("could not read the token: %w", err)
+		}
+		if token.RefreshToken == "" {
+			return errors.New("spotify returned no refresh token")
+		}
+		fmt.Println("Keep this secret: it gives access to your account with every permission.")
+		fmt.Printf("SPOTIFY_REFRESH_TOKEN=%s\n", token.RefreshToken)
+		return nil
+	}), nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"spotify/internal/cache"
+	"spotify/internal/processor"
+	"spotify/internal/registry"
+	"strings"
+	"text/template"
+)
+
+// completionWords are the words completed as the first argument of a command.
+var completionWords = map[string][]string{
+	"completion": {"bash", "zsh", "fish"},
+	"cover":      {"preview"},
+	"languages":  {"set"},
+	"lastfm":     {"login", "top", "import-loves", "push-likes"},
+	"playlist":   {"stats", "mix"},
+	"tag":        {"add", "remove", "list", "export", "import"},
+}
+
+// playlistCommands take the name of one of the user's playlists as an argument, and
+// playlistFlags as a value.
+var (
+	playlistCommands = []string{"cover", "playlist", "prune", "queue", "split"}
+	playlistFlags    = []string{"playlist", "quarantine", "add-to"}
+)
+
+// errProbing stops a command built by commandFlags before it logs in.
+var errProbing = errors.New("probing the flags of a command")
+
+type completionSpec struct {
+	Prog string
+	// Func is Prog as a shell function name.
+	Func             string
+	Global           []completionFlag
+	Commands         []completionCommand
+	PlaylistCommands []string
+	PlaylistFlags    []string
+}
+
+type completionCommand struct {
+	Name, Description string
+	Flags             []completionFlag
+	Words             []string
+}
+
+type completionFlag struct {
+	Name, Usage string
+	// Value is set for flags that take a value, as opposed to switches.
+	Value bool
+}
+
+// buildCompletion handles "completion bash|zsh|fish [--name spotify-manager]", and the
+// "completion playlists|pipelines" the scripts call for names known at the time.
+func (a *app) buildCompletion(args []string) (processor.Processor, error) {
+	const usage = "usage: completion bash|zsh|fish [--name spotify-manager]"
+	fs := a.newFlagSet("completion")
+	name := fs.String("name", "spotify-manager", "the name the binary is installed as")
+	positional, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(positional) != 1 {
+		return nil, errors.New(usage)
+	}
+	output := func(write func(w io.Writer) error) processor.Processor {
+		return processor.ProcessorFunc(func(context.Context) error { return write(os.Stdout) })
+	}
+	switch shell := positional[0]; shell {
+	case "playlists":
+		// Only playlists saved by the response cache are known without logging in.
+		return output(func(w io.Writer) error {
+			return printLines(w, cache.PlaylistNames(a.store))
+		}), nil
+	case "pipelines":
+		return output(func(w io.Writer) error {
+			var names []string
+			for _, p := range a.cfg.Pipelines {
+				names = append(names, p.Name)
+			}
+			return printLines(w, names)
+		}), nil
+	case "bash", "zsh", "fish":
+		spec := a.completionSpec(*name)
+		return output(func(w io.Writer) error {
+			return completionScripts.ExecuteTemplate(w, shell, spec)
+		}), nil
+	default:
+		return nil, errors.New(usage)
+	}
+}
+
+// completionSpec collects the commands and flags to complete.
+func (a *app) completionSpec(prog string) completionSpec {
+	spec := completionSpec{
+		Prog:             prog,
+		Func:             regexp.MustCompile(`\W`).ReplaceAllString(prog, "_"),
+		PlaylistCommands: playlistCommands,
+		PlaylistFlags:    playlistFlags,
+	}
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		spec.Global = append(spec.Global, newCompletionFlag(f))
+	})
+	for _, c := range registry.All() {
+		cmd := completionCommand{Name: c.Name, Description: c.Description, Words: completionWords[c.Name]}
+		for _, f := range a.commandFlags(c) {
+			cmd.Flags = append(cmd.Flags, newCompletionFlag(f))
+		}
+		spec.Commands = append(spec.Commands, cmd)
+	}
+	return spec
+}
+
+func newCompletionFlag(f *flag.Flag) completionFlag {
+	value := true
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		value = false
+	}
+	return completionFlag{Name: f.Name, Usage: f.Usage, Value: value}
+}
+
+// commandFlags returns the flags of c, read by building it with -h: the build stops when
+// it parses its arguments, or when it asks for a client, before doing anything.
+// Subcommands with flags of their own, like "lastfm top", aren't reached.
+func (a *app) commandFlags(c registry.Command) (flags []*flag.Flag) {
+	out := a.logger.Writer()
+	a.logger.SetOutput(io.Discard)
+	a.probing, a.probed = true, nil
+	defer func() {
+		a.logger.SetOutput(out)
+		a.probing = false
+		if r := recover(); r != nil && r != errProbing {
+			panic(r)
+		}
+		// The first flag set is the command's own; later ones belong to what it builds.
+		if len(a.probed) > 0 {
+			a.probed[0].VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+		}
+	}()
+	c.Build(a, []string{"-h"})
+	return nil
+}
+
+// printLines writes one line per value.
+func printLines(w io.Writer, values []string) error {
+	for _, v := range values {
+		if _, err := fmt.Fprintln(w, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dashed returns the flag as typed: -o for single letters, --name otherwise.
+func dashed(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+var completionScripts = template.Must(template.New("").Funcs(template.FuncMap{
+	"dashed": dashed,
+	// flags lists the flags as typed, separated by spaces.
+	"flags": func(flags []completionFlag) string {
+		var s []string
+		for _, f := range flags {
+			s = append(s, dashed(f.Name))
+		}
+		return strings.Join(s, " ")
+	},
+	// patterns is a bash case pattern matching the flags, with one or two dashes.
+	"patterns": func(names []string) string {
+		var s []string
+		for _, n := range names {
+			s = append(s, "-"+n, "--"+n)
+		}
+		return strings.Join(s, "|")
+	},
+	// valueFlags returns the names of the flags in flags or among the commands' that take
+	// a value other than a playlist.
+	"valueFlags": func(spec completionSpec) []string {
+		var names []string
+		add := func(flags []completionFlag) {
+			for _, f := range flags {
+				if f.Value && !slices.Contains(names, f.Name) && !slices.Contains(playlistFlags, f.Name) {
+					names = append(names, f.Name)
+				}
+			}
+		}
+		add(spec.Global)
+		for _, c := range spec.Commands {
+			add(c.Flags)
+		}
+		return names
+	},
+	"globalValueFlags": func(spec completionSpec) []string {
+		var names []string
+		for _, f := range spec.Global {
+			if f.Value {
+				names = append(names, f.Name)
+			}
+		}
+		return names
+	},
+	"names": func(commands []completionCommand) string {
+		var s []string
+		for _, c := range commands {
+			s = append(s, c.Name)
+		}
+		return strings.Join(s, " ")
+	},
+	"join": strings.Join,
+	// fish quotes s for fish.
+	"fish": func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	},
+	"isPlaylistFlag": func(name string) bool { return slices.Contains(playlistFlags, name) },
+}).Parse(`
+{{- define "bash-body" -}}
+_{{.Func}}_values() {
+    local IFS=$'\n'
+    compopt -o filenames 2>/dev/null
+    COMPREPLY=($(compgen -W "$({{.Prog}} completion "$1" 2>/dev/null)" -- "$cur"))
+}
+
+_{{.Func}}() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmd="" npos=0 i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        local word="${COMP_WORDS[i]}"
+        if [[ -z $cmd ]]; then
+            case "$word" in
+                {{patterns (globalValueFlags .)}}) ((i++)) ;;
+                -*) ;;
+                *) cmd="$word" ;;
+            esac
+        elif [[ $word != -* ]]; then
+            case "${COMP_WORDS[i-1]}" in
+                {{patterns (valueFlags .)}}|{{patterns .PlaylistFlags}}) ;;
+                *) ((npos++)) ;;
+            esac
+        fi
+    done
+
+    case "$prev" in
+        {{patterns .PlaylistFlags}}) _{{.Func}}_values playlists; return ;;
+        {{patterns (valueFlags .)}}) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+    esac
+    if [[ -z $cmd ]]; then
+        if [[ $cur == -* ]]; then
+            COMPREPLY=($(compgen -W "{{flags .Global}}" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "{{names .Commands}}" -- "$cur"))
+        fi
+        return
+    fi
+    if [[ $cur == -* ]]; then
+        case "$cmd" in
+{{- range .Commands}}{{if .Flags}}
+            {{.Name}}) COMPREPLY=($(compgen -W "{{flags .Flags}}" -- "$cur")) ;;
+{{- end}}{{end}}
+        esac
+        return
+    fi
+    case "$cmd" in
+{{- range .Commands}}{{if .Words}}
+        {{.Name}}) if ((npos == 0)); then COMPREPLY=($(compgen -W "{{join .Words " "}}" -- "$cur")); return; fi ;;
+{{- end}}{{end}}
+    esac
+    case "$cmd" in
+        pipeline) _{{.Func}}_values pipelines ;;
+        {{join .PlaylistCommands "|"}}) _{{.Func}}_values playlists ;;
+    esac
+}
+
+complete -o default -F _{{.Func}} {{.Prog}}
+{{end -}}
+
+{{- define "bash" -}}
+# bash completion for {{.Prog}}. Load it with:
+#   source <({{.Prog}} completion bash)
+
+{{template "bash-body" .}}
+{{- end -}}
+
+{{- define "zsh" -}}
+# zsh completion for {{.Prog}}. Load it with:
+#   source <({{.Prog}} completion zsh)
+
+(( $+functions[compdef] )) || { autoload -U +X compinit && compinit }
+autoload -U +X bashcompinit && bashcompinit
+
+{{template "bash-body" .}}
+{{- end -}}
+
+{{- define "fish" -}}
+# fish completion for {{.Prog}}. Load it with:
+#   {{.Prog}} completion fish | source
+
+complete -c {{.Prog}} -f
+{{- $prog := .Prog}}
+{{- range .Global}}
+complete -c {{$prog}} -n __fish_use_subcommand -l {{.Name}}{{if .Value}} -r -F{{end}} -d {{fish .Usage}}
+{{- end}}
+{{- range .Commands}}
+complete -c {{$prog}} -n __fish_use_subcommand -a {{.Name}} -d {{fish .Description}}
+{{- $cmd := .Name}}
+{{- if .Words}}
+complete -c {{$prog}} -n '__fish_seen_subcommand_from {{$cmd}}' -a {{fish (join .Words " ")}}
+{{- end}}
+{{- range .Flags}}
+complete -c {{$prog}} -n '__fish_seen_subcommand_from {{$cmd}}' {{if eq (len .Name) 1}}-s{{else}}-l{{end}} {{.Name}}
+{{- if .Value}} -r{{if isPlaylistFlag .Name}} -a '({{$prog}} completion playlists)'{{else}} -F{{end}}{{end}} -d {{fish .Usage}}
+{{- end}}
+{{- end}}
+complete -c {{$prog}} -n '__fish_seen_subcommand_from pipeline' -a '({{$prog}} completion pipelines)'
+complete -c {{$prog}} -n '__fish_seen_subcommand_from {{join .PlaylistCommands " "}}' -a '({{$prog}} completion playlists)'
+{{end -}}
+`))
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"spotify/internal/processor"
+
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+)
+
+// Exit codes, so scripts and systemd units can tell outcomes apart without parsing the
+// log. A bad flag exits with 2 too, as the flag package does.
+const (
+	exitFailure     = 1   // the run failed
+	exitConfig      = 2   // invalid config, flags or arguments
+	exitAuth        = 3   // logging in failed, or Spotify refused the credentials
+	exitRateLimited = 4   // Spotify's rate limit stopped the run; retry later
+	exitPartial     = 5   // some playlists or pipeline steps failed, the rest succeeded
+	exitNothingToDo = 6   // the run found nothing to change
+	exitInterrupted = 130 // stopped with Ctrl-C or a signal
+)
+
+// errInvalidConfig marks run errors caused by the configuration.
+var errInvalidConfig = errors.New("invalid configuration")
+
+// exitCode returns the exit code for the error of a failed run. Auth and rate-limit
+// failures take precedence, as they explain any partial failure they caused.
+func exitCode(err error) int {
+	var apiErr spotify.Error
+	var retrieveErr *oauth2.RetrieveError
+	var partial *processor.PartialError
+	switch {
+	case errors.Is(err, errInvalidConfig):
+		return exitConfig
+	case errors.As(err, &retrieveErr),
+		errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized:
+		return exitAuth
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusTooManyRequests:
+		return exitRateLimited
+	case errors.As(err, &partial) && partial.Failed < partial.Total:
+		return exitPartial
+	}
+	return exitFailure
+}
+
+// fatalf logs like log.Fatalf, exiting with code.
+func fatalf(code int, format string, v ...any) {
+	log.Printf(format, v...)
+	os.Exit(code)
+}
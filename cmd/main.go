@@ -2,16 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"spotify/internal/auth"
-	"spotify/internal/generator"
+	"spotify/internal/config"
+	"spotify/internal/httplog"
+	"spotify/internal/interrupt"
+	"spotify/internal/notify"
 	"spotify/internal/processor"
+	"spotify/internal/ratelimit"
+	"spotify/internal/registry"
+	"spotify/internal/store"
+	"spotify/internal/vcr"
 	"time"
 
 	"github.com/joho/godotenv"
-	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"github.com/zmb3/spotify/v2"
+)
+
+const (
+	defaultStorePath  = "store.json"
+	defaultConfigPath = "config.yaml"
 )
 
 func main() {
@@ -20,27 +35,207 @@ func main() {
 		log.Println("Warning: Could not load .env file")
 	}
 
+	transcriptPath := flag.String("transcript", "", "record every mutating API call to this file")
+	simulate := flag.String("simulate", "", "run against the library in this JSON fixture instead of your account")
+	record := flag.String("record", "", "record the Spotify API's responses to this cassette file")
+	replay := flag.String("replay", "", "answer Spotify API requests from this cassette file instead of the network")
+	debugHTTP := flag.Bool("debug-http", false, "log every Spotify API request and its status")
+	yes := flag.Bool("yes", false, "don't ask before clearing playlists or removing songs, for scripted runs")
+	public := flag.Bool("public", false, "don't log in; read public data with an app token, for commands that support it")
+	desktopNotify := flag.Bool("notify", false, "show a desktop notification when the run finishes or fails")
+	flag.Parse()
+
+	logger := log.New(os.Stdout, " ", log.LstdFlags)
+	a := &app{
+		cfg:            loadConfig(),
+		store:          openStore(),
+		logger:         logger,
+		transcriptPath: *transcriptPath,
+		simulate:       *simulate,
+		replay:         *replay,
+		debugHTTP:      *debugHTTP,
+		yes:            *yes,
+		public:         *public,
+	}
+	if a.simulate != "" || a.replay != "" {
+		// Checkpoints and history of a made-up or replayed library mustn't end up in the
+		// real store.
+		a.store.ReadOnly()
+	}
+	// Replays don't touch the network, so they aren't limited.
+	if a.cfg.RateLimit.RequestsPerSecond > 0 && a.replay == "" {
+		limiter, err := ratelimit.New(a.cfg.RateLimit.RequestsPerSecond, a.cfg.RateLimit.Burst)
+		if err != nil {
+			fatalf(exitConfig, "🚨 Invalid rate_limit: %v", err)
+		}
+		a.limiter = limiter
+	}
+	a.backoff = ratelimit.NewBackoff(a.logger)
+	if *record != "" {
+		recorder, err := vcr.Create(*record)
+		if err != nil {
+			log.Fatalf("🚨 %v", err)
+		}
+		defer recorder.Close()
+		a.recorder = recorder
+	}
+
+	command, args := "sort", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	a.scopes = a.scopesFor(command, args)
+	c, ok := registry.Lookup(command)
+	if ok && a.public && !c.Public {
+		fatalf(exitConfig, "🚨 '%s' needs your account; run it without --public.", command)
+	}
+	quiet := ok && c.Quiet
+	task, err := a.buildTask(command, args)
+	if err != nil {
+		fatalf(exitConfig, "🚨 %v", err)
+	}
+
+	// Ctrl-C stops the run at the next safe point instead of killing it mid-write.
+	runCtx, stopRun := interrupt.Context(context.Background(), logger)
+	defer stopRun()
+	// The daemon runs until interrupted and applies its own per-job timeouts.
+	taskCtx, cancelTask := context.WithCancel(runCtx)
+	if command != "daemon" {
+		taskCtx, cancelTask = context.WithTimeout(runCtx, 30*time.Minute)
+	}
+	defer cancelTask()
+
+	if !quiet {
+		fmt.Println("🚀 Starting processor...")
+	}
+	started := time.Now()
+	runErr := task.Run(taskCtx)
+	if a.simulated != nil {
+		a.simulated.WriteSummary(os.Stdout)
+	}
+	if err := a.store.Save(); err != nil {
+		log.Printf("⚠️  Could not save local store: %v", err)
+	}
+	// The daemon never finishes by itself, and an interrupted run has someone at the
+	// terminal.
+	if *desktopNotify && command != "daemon" && !interrupt.Interrupted(runCtx) {
+		notifyDesktop(command, started, runErr)
+	}
+	if runErr != nil && interrupt.Interrupted(runCtx) {
+		fatalf(exitInterrupted, "🛑 Processor stopped before finishing: %v", runErr)
+	}
+	if errors.Is(runErr, processor.ErrNothingToDo) {
+		fmt.Println("\n✅ Processor finished with nothing to do.")
+		os.Exit(exitNothingToDo)
+	}
+	if runErr != nil {
+		fatalf(exitCode(runErr), "❌ Processor run failed: %v", runErr)
+	}
+
+	if !quiet {
+		fmt.Println("\n🎉 Processor finished successfully!")
+	}
+}
+
+// notifyDesktop tells the user a run is over, for when they switched away from the
+// terminal.
+func notifyDesktop(command string, started time.Time, runErr error) {
+	took := time.Since(started).Round(time.Second)
+	title, message := "✅ spotify-manager", fmt.Sprintf("'%s' finished in %s.", command, took)
+	if runErr != nil && !errors.Is(runErr, processor.ErrNothingToDo) {
+		title, message = "❌ spotify-manager", fmt.Sprintf("'%s' failed after %s: %v", command, took, runErr)
+	}
+	if err := notify.Desktop(title, message); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// login returns the client of the account, or with --public an app client that needs no
+// login.
+func (a *app) login() *spotify.Client {
+	if a.public {
+		return a.authenticateApp()
+	}
+	if token := os.Getenv("SPOTIFY_REFRESH_TOKEN"); token != "" {
+		return a.authenticateRefresh(token)
+	}
+	return a.authenticate("Spotify", false)
+}
+
+// authenticateRefresh returns a client authorized by a refresh token printed by "login
+// --print-refresh-token", for containers and other places without a browser.
+func (a *app) authenticateRefresh(refreshToken string) *spotify.Client {
+	if a.simulate != "" || a.replay != "" {
+		fatalf(exitConfig, "🚨 Logging in to Spotify isn't possible offline; run this command without --simulate or --replay.")
+	}
+	authConfig := auth.Config{
+		ClientID:     os.Getenv("SPOTIFY_CLIENT_ID"),
+		ClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"),
+		Transport:    a.transport,
+	}
+	if authConfig.ClientID == "" || authConfig.ClientSecret == "" {
+		fatalf(exitConfig, "🚨 SPOTIFY_CLIENT_ID and SPOTIFY_CLIENT_SECRET must be set.")
+	}
+	client := auth.New(authConfig).RefreshClient(refreshToken)
+	user, err := client.CurrentUser(context.Background())
+	if err != nil {
+		fatalf(exitAuth, "❌ SPOTIFY_REFRESH_TOKEN was refused: %v. Get a new one with 'login --print-refresh-token'.", err)
+	}
+	fmt.Printf("✅ Logged in as %s with SPOTIFY_REFRESH_TOKEN\n\n", user.DisplayName)
+	return client
+}
+
+// authenticateApp returns a client authorized as the app alone, for public data.
+func (a *app) authenticateApp() *spotify.Client {
+	if a.simulate != "" || a.replay != "" {
+		fatalf(exitConfig, "🚨 --public can't be combined with --simulate or --replay.")
+	}
+	authConfig := auth.Config{
+		ClientID:     os.Getenv("SPOTIFY_CLIENT_ID"),
+		ClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"),
+		Transport:    a.transport,
+	}
+	if authConfig.ClientID == "" || authConfig.ClientSecret == "" {
+		fatalf(exitConfig, "🚨 SPOTIFY_CLIENT_ID and SPOTIFY_CLIENT_SECRET must be set.")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client, err := auth.AppClient(ctx, authConfig)
+	if err != nil {
+		fatalf(exitAuth, "❌ Authentication failed: %v", err)
+	}
+	fmt.Println("✅ Using an app token; only public data can be read.")
+	return client
+}
+
+// authenticate runs the interactive login flow and returns a ready Spotify client. The
+// login asks only for the scopes of the command being run. account names the account to
+// log in to in the prompt, and switchAccount makes Spotify offer to log in as someone
+// else, for a second account.
+func (a *app) authenticate(account string, switchAccount bool) *spotify.Client {
+	if a.probing {
+		panic(errProbing)
+	}
+	if a.simulate != "" || a.replay != "" {
+		fatalf(exitConfig, "🚨 Logging in to %s isn't possible offline; run this command without --simulate or --replay.", account)
+	}
 	authConfig := auth.Config{
 		RedirectURL:  "http://127.0.0.1:8000/callback",
 		ClientID:     os.Getenv("SPOTIFY_CLIENT_ID"),
 		ClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"),
 		Port:         "8000",
-		// Scopes updated to allow for playlist creation and modification
-		Scopes: []string{
-			spotifyauth.ScopeUserLibraryRead,
-			spotifyauth.ScopePlaylistModifyPublic,
-			spotifyauth.ScopePlaylistModifyPrivate,
-			spotifyauth.ScopeImageUpload,
-			spotifyauth.ScopeUserLibraryModify,
-		},
+		Scopes:       a.scopes,
+		ShowDialog:   switchAccount,
 	}
+	authConfig.Transport = a.transport
 
 	if authConfig.ClientID == "" || authConfig.ClientSecret == "" {
-		log.Fatal("🚨 SPOTIFY_CLIENT_ID and SPOTIFY_CLIENT_SECRET must be set.")
+		fatalf(exitConfig, "🚨 SPOTIFY_CLIENT_ID and SPOTIFY_CLIENT_SECRET must be set.")
 	}
 
 	authenticator := auth.New(authConfig)
-	fmt.Println("👉 Please log in to Spotify by visiting this URL in your browser:")
+	fmt.Printf("👉 Please log in to %s by visiting this URL in your browser:\n", account)
 	fmt.Println(authenticator.AuthURL())
 
 	authCtx, cancelAuth := context.WithTimeout(context.Background(), 3*time.Minute)
@@ -48,27 +243,69 @@ func main() {
 
 	client, err := authenticator.GetClient(authCtx)
 	if err != nil {
-		log.Fatalf("❌ Authentication failed: %v", err)
+		fatalf(exitAuth, "❌ Authentication failed: %v", err)
 	}
 
 	user, err := client.CurrentUser(context.Background())
 	if err != nil {
-		log.Fatalf("❌ Couldn't get current user: %v", err)
+		fatalf(exitAuth, "❌ Couldn't get current user: %v", err)
 	}
 	fmt.Printf("\n✅ Logged in as: %s\n\n", user.DisplayName)
 
-	logger := log.New(os.Stdout, " ", log.LstdFlags)
+	return client
+}
 
-	imageGenerator := generator.NewImageGenerator()
-	sorterTask := processor.NewPlaylistSorter(client, logger, imageGenerator)
+// transport wraps the transport of a Spotify client in the recorder and tracer requested
+// by global flags, in the configured rate limit, in the backoff from Spotify's rate
+// limit and in the interrupt handling that lets requests in flight finish. The limit and
+// backoff apply outermost, so traced durations don't include the waits, and retries
+// count against the limit.
+func (a *app) transport(next http.RoundTripper) http.RoundTripper {
+	if a.recorder != nil {
+		next = a.recorder.Wrap(next)
+	}
+	if a.debugHTTP {
+		next = httplog.NewTransport(next, a.logger)
+	}
+	next = interrupt.Transport(next)
+	if a.limiter != nil {
+		next = a.limiter.Wrap(next)
+	}
+	return a.backoff.Wrap(next)
+}
 
-	taskCtx, cancelTask := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer cancelTask()
+// openStore opens the local store, whose location can be overridden with SPOTIFY_MANAGER_STORE.
+func openStore() *store.Store {
+	path := os.Getenv("SPOTIFY_MANAGER_STORE")
+	if path == "" {
+		path = defaultStorePath
+	}
+	// The store holds the streaming history and cached responses of the library, so it
+	// can be encrypted at rest.
+	st, err := store.OpenEncrypted(path, os.Getenv("SPOTIFY_MANAGER_PASSPHRASE"))
+	if errors.Is(err, store.ErrEncrypted) {
+		fatalf(exitConfig, "❌ Couldn't open local store: %v. Set SPOTIFY_MANAGER_PASSPHRASE.", err)
+	}
+	if err != nil {
+		log.Fatalf("❌ Couldn't open local store: %v", err)
+	}
+	return st
+}
 
-	fmt.Println("🚀 Starting processor...")
-	if err := sorterTask.Run(taskCtx); err != nil {
-		log.Fatalf("❌ Processor run failed: %v", err)
+// configPath returns the location of the YAML config, which can be overridden with
+// SPOTIFY_MANAGER_CONFIG.
+func configPath() string {
+	if path := os.Getenv("SPOTIFY_MANAGER_CONFIG"); path != "" {
+		return path
 	}
+	return defaultConfigPath
+}
 
-	fmt.Println("\n🎉 Processor finished successfully!")
+// loadConfig reads the YAML config.
+func loadConfig() config.Config {
+	cfg, err := config.Load(configPath())
+	if err != nil {
+		fatalf(exitConfig, "🚨 %v", err)
+	}
+	return cfg
 }
@@ -0,0 +1,1186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"spotify/internal/assets"
+	"spotify/internal/cache"
+	"spotify/internal/config"
+	"spotify/internal/confirm"
+	"spotify/internal/daemon"
+	"spotify/internal/folders"
+	"spotify/internal/generator"
+	"spotify/internal/history"
+	"spotify/internal/lastfm"
+	"spotify/internal/lists"
+	"spotify/internal/notify"
+	"spotify/internal/pipeline"
+	"spotify/internal/processor"
+	"spotify/internal/ratelimit"
+	"spotify/internal/registry"
+	"spotify/internal/setlistfm"
+	"spotify/internal/spotifytest"
+	"spotify/internal/store"
+	"spotify/internal/transcript"
+	"spotify/internal/vcr"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// app holds the state shared by every command: configuration, the local store and a
+// lazily authenticated Spotify client.
+type app struct {
+	cfg            config.Config
+	store          *store.Store
+	logger         *log.Logger
+	transcriptPath string
+	// simulate is the fixture to run against instead of the account; simulated is the
+	// client over it once loaded.
+	simulate  string
+	simulated *spotifytest.Client
+	// replay is the cassette to answer API requests from; recorder, if set, records the
+	// responses of the logged-in client to one.
+	replay   string
+	recorder *vcr.Recorder
+	// debugHTTP logs every API request of the logged-in client.
+	debugHTTP bool
+	// limiter, if set, caps the rate of API requests of every logged-in client.
+	limiter *ratelimit.Limiter
+	// backoff waits out Spotify's rate limit for every logged-in client.
+	backoff *ratelimit.Backoff
+	// yes skips the confirmations asked before destructive changes.
+	yes bool
+	// scopes are the permissions the login asks for: those of the command being run.
+	scopes []string
+	// public uses an app token instead of logging in.
+	public bool
+
+	client processor.SpotifyClient
+	assets *assets.Cache
+	// dryRun makes the client record writes instead of sending them; dryRunClient is
+	// that client once logged in.
+	dryRun       bool
+	dryRunClient *pipeline.DryRun
+	// probing builds commands only to read their flags, into probed; see commandFlags.
+	probing bool
+	probed  []*flag.FlagSet
+}
+
+// Client logs in on first use and wraps the client in the decorators requested
+// by global flags. Later calls reuse the same client.
+func (a *app) Client() processor.SpotifyClient {
+	if a.probing {
+		panic(errProbing)
+	}
+	if a.client != nil {
+		return a.client
+	}
+	var client processor.SpotifyClient
+	switch {
+	case a.simulate != "":
+		simulated, err := spotifytest.Load(a.simulate)
+		if err != nil {
+			log.Fatalf("🚨 %v", err)
+		}
+		a.simulated, client = simulated, simulated
+	case a.replay != "":
+		player, err := vcr.Load(a.replay)
+		if err != nil {
+			log.Fatalf("🚨 %v", err)
+		}
+		httpClient := player.Client()
+		httpClient.Transport = a.transport(httpClient.Transport)
+		client = spotify.New(httpClient)
+	case a.recorder != nil:
+		// Responses served from the cache would be missing from the cassette.
+		client = a.login()
+	case a.public:
+		// The cache keys library reads by user, and an app token has none.
+		client = a.login()
+	default:
+		client = cache.NewClient(a.login(), a.store, a.cfg.Cache.CatalogTTL, a.cfg.Cache.LibraryTTL)
+	}
+	// Simulated and replayed runs don't reach Spotify, so their writes aren't paced.
+	if rl := a.cfg.RateLimit; (rl.BatchSize > 0 || rl.BatchDelay > 0) && a.simulate == "" && a.replay == "" {
+		batches, err := ratelimit.NewBatches(client, rl.BatchSize, rl.BatchDelay)
+		if err != nil {
+			fatalf(exitConfig, "🚨 Invalid rate_limit: %v", err)
+		}
+		client = batches
+	}
+	// Simulated, replayed and dry runs don't change the account, so there's nothing to
+	// confirm.
+	if !a.yes && !a.dryRun && a.simulate == "" && a.replay == "" {
+		client = confirm.NewClient(client, os.Stdin, os.Stdout)
+	}
+	if a.dryRun {
+		a.dryRunClient = pipeline.NewDryRun(client)
+		client = a.dryRunClient
+	}
+	if a.transcriptPath != "" {
+		f, err := os.Create(a.transcriptPath)
+		if err != nil {
+			log.Fatalf("🚨 Couldn't create transcript file: %v", err)
+		}
+		client = transcript.NewRecorder(client, f)
+	}
+	// The lists apply on top of the recorder, so transcripts show what was really written.
+	l, err := lists.Load(a.cfg.Lists.Blocklist, a.cfg.Lists.Allowlist)
+	if err != nil {
+		fatalf(exitConfig, "🚨 %v", err)
+	}
+	if !l.Empty() {
+		client = lists.NewClient(client, l, a.logger)
+	}
+	a.client = client
+	return client
+}
+
+// confirmed runs task with its changes already confirmed, for commands that ask about
+// their whole plan themselves or take their own --yes. The client still asks for the
+// other tasks of the run, such as the next steps of a pipeline.
+func confirmed(task processor.Processor, err error) (processor.Processor, error) {
+	if err != nil {
+		return nil, err
+	}
+	return processor.ProcessorFunc(func(ctx context.Context) error {
+		return task.Run(confirm.WithConfirmed(ctx))
+	}), nil
+}
+
+// parseYears parses a comma-separated list of years such as "2024,2025".
+func parseYears(list string) ([]int, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var years []int
+	for _, field := range strings.Split(list, ",") {
+		year, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid year '%s' in --years", field)
+		}
+		years = append(years, year)
+	}
+	return years, nil
+}
+
+// Store returns the local store.
+func (a *app) Store() *store.Store {
+	return a.store
+}
+
+// Logger returns the logger every command writes progress to.
+func (a *app) Logger() *log.Logger {
+	return a.logger
+}
+
+// Config returns the configuration.
+func (a *app) Config() config.Config {
+	return a.cfg
+}
+
+// AssetCache returns the image cache shared by every feature that downloads artwork.
+func (a *app) AssetCache() *assets.Cache {
+	if a.assets == nil {
+		a.assets = assets.New(a.cfg.Cache.Dir, a.cfg.Cache.MaxSizeMB<<20)
+	}
+	return a.assets
+}
+
+// ImageGenerator returns a cover generator configured by the covers section.
+func (a *app) ImageGenerator() (processor.ImageGenerator, error) {
+	return generator.NewImageGenerator(a.cfg.Covers, a.store, a.AssetCache())
+}
+
+// newFlagSet returns the flag set of a command's arguments.
+func (a *app) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if a.probing {
+		fs.SetOutput(io.Discard)
+		a.probed = append(a.probed, fs)
+	}
+	return fs
+}
+
+// buildTask returns the processor implementing command.
+func (a *app) buildTask(command string, args []string) (processor.Processor, error) {
+	c, ok := registry.Lookup(command)
+	if !ok {
+		return nil, fmt.Errorf("unknown command '%s'. Available commands: %s", command, strings.Join(registry.Names(), ", "))
+	}
+	return c.Build(a, args)
+}
+
+// buildSort handles "sort [--resume] [--yes-huge] [--years 2024,2025 | --since 2024]
+// [--archive] [--prune-empty] [--full]".
+func (a *app) buildSort(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("sort")
+	resume := fs.Bool("resume", false, "continue an interrupted run from its checkpoint")
+	yesHuge := fs.Bool("yes-huge", false, "confirm a first run on a very large library")
+	yearList := fs.String("years", "", "only update these years' playlists, e.g. 2024,2025")
+	since := fs.Int("since", 0, "only update playlists from this year on")
+	play := fs.Bool("play", false, "start the newest playlist afterwards (Spotify Premium only)")
+	device := fs.String("device", "", "with --play, play on the device whose name contains this; default the active one")
+	archive := fs.Bool("archive", false, "unlike the songs of past years once their playlist is written; undo with unarchive")
+	pruneEmpty := fs.Bool("prune-empty", false, "delete the playlists of years without liked songs anymore, after confirmation")
+	full := fs.Bool("full", false, "run even if liked songs haven't changed since the last run")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	years, err := parseYears(*yearList)
+	if err != nil {
+		return nil, err
+	}
+	if len(years) > 0 && *since != 0 {
+		return nil, errors.New("use either --years or --since, not both")
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	opts := processor.SorterOptions{Resume: *resume, YesHuge: *yesHuge, Years: years, Since: *since, Play: *play, Device: *device, Ask: askTerminal(), Archive: *archive, PruneEmpty: *pruneEmpty, Full: *full}
+	sorter, err := processor.NewPlaylistSorter(a.Client(), a.store, a.logger, imageGenerator, a.cfg.Sorter, a.cfg.Playlists, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sorter configuration: %w", err)
+	}
+	return sorter, nil
+}
+
+// buildUnarchive handles "unarchive [--years 2022,2023]".
+func (a *app) buildUnarchive(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("unarchive")
+	yearList := fs.String("years", "", "only like again the songs liked in these years, e.g. 2022,2023")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errors.New("usage: unarchive [--years 2022,2023]")
+	}
+	years, err := parseYears(*yearList)
+	if err != nil {
+		return nil, err
+	}
+	unarchiver, err := processor.NewUnarchiver(a.Client(), a.store, a.logger, a.cfg.Sorter, years)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sorter configuration: %w", err)
+	}
+	return unarchiver, nil
+}
+
+// askTerminal returns a function asking y/N questions on the terminal, one at a time
+// when playlists are written in parallel. Without an answer it's a no.
+func askTerminal() func(question string) bool {
+	var mu sync.Mutex
+	in := bufio.NewReader(os.Stdin)
+	return func(question string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Printf("⚠️  %s [y/N] ", question)
+		answer, _ := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// buildHistoryImport handles "import-history <dir>".
+func (a *app) buildHistoryImport(args []string) (processor.Processor, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: import-history <path to unpacked export directory>")
+	}
+	return processor.NewHistoryImporter(args[0], a.store, a.logger), nil
+}
+
+// buildTopPlayed returns the most-played playlists builder.
+func (a *app) buildTopPlayed() (processor.Processor, error) {
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	builder, err := processor.NewTopPlayedBuilder(a.Client(), a.store, a.logger, imageGenerator, a.cfg.TopPlayed, a.cfg.Matching, a.cfg.Playlists)
+	if err != nil {
+		return nil, fmt.Errorf("invalid top_played configuration: %w", err)
+	}
+	return builder, nil
+}
+
+// buildForgottenGems returns the forgotten gems playlist builder.
+func (a *app) buildForgottenGems() (processor.Processor, error) {
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	builder, err := processor.NewForgottenGemsBuilder(a.Client(), a.store, a.logger, imageGenerator, a.cfg.ForgottenGems, a.cfg.Playlists)
+	if err != nil {
+		return nil, fmt.Errorf("invalid forgotten_gems configuration: %w", err)
+	}
+	return builder, nil
+}
+
+// buildChartArchiver returns the chart archiver.
+func (a *app) buildChartArchiver() (processor.Processor, error) {
+	archiver, err := processor.NewChartArchiver(a.Client(), a.logger, a.cfg.Charts, a.cfg.Playlists)
+	if err != nil {
+		return nil, fmt.Errorf("invalid charts configuration: %w", err)
+	}
+	return archiver, nil
+}
+
+// buildAlbumCheck handles "album-check [--fix-unliked unsave|like-tracks] [--save-complete]".
+func (a *app) buildAlbumCheck(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("album-check")
+	fixUnliked := fs.String("fix-unliked", "", `fix saved albums with no liked tracks: "unsave" or "like-tracks"`)
+	saveComplete := fs.Bool("save-complete", false, "save albums whose every track is liked")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	opts := processor.AlbumConsistencyOptions{UnlikedAlbums: *fixUnliked, SaveCompleteAlbums: *saveComplete}
+	return processor.NewAlbumConsistencyChecker(a.Client(), opts, os.Stdout, a.logger)
+}
+
+// buildHealthCheck handles "health [--stale-days N]".
+func (a *app) buildHealthCheck(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("health")
+	staleDays := fs.Int("stale-days", 90, "report generated playlists not updated for this many days")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return processor.NewHealthCheck(a.Client(), os.Stdout, a.logger, time.Duration(*staleDays)*24*time.Hour), nil
+}
+
+// buildCompleteAlbums handles "complete-albums [--playlist]".
+func (a *app) buildCompleteAlbums(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("complete-albums")
+	playlist := fs.Bool("playlist", false, "also write the tracks you haven't liked from those albums to a playlist")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	completionist, err := processor.NewAlbumCompletionist(a.Client(), a.store, a.logger, imageGenerator, a.cfg.Completionist, a.cfg.Playlists, *playlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid completionist configuration: %w", err)
+	}
+	return completionist, nil
+}
+
+// buildFolders returns the folder reconciler for the configured manifest.
+func (a *app) buildFolders() (processor.Processor, error) {
+	manifest, err := folders.Load(a.cfg.Folders.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	return processor.NewFolderReconciler(a.Client(), manifest, a.cfg.Folders.PrefixNames, os.Stdout, a.logger), nil
+}
+
+// buildReplay handles "replay-transcript <file>".
+func (a *app) buildReplay(args []string) (processor.Processor, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: replay-transcript <transcript file>")
+	}
+	return transcript.NewReplayer(a.Client(), args[0], a.logger), nil
+}
+
+// buildWrapped parses the flags of the year-in-review report.
+func (a *app) buildWrapped(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("wrapped")
+	year := fs.Int("year", 0, "year to review; defaults to the latest year in the history")
+	html := fs.String("o", "", `write a shareable HTML page to this file; "" skips it`)
+	top := fs.Int("top", 10, "length of each ranking")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *top <= 0 {
+		return nil, errors.New("--top must be positive")
+	}
+	opts := processor.WrappedOptions{
+		Year:    *year,
+		HTML:    *html,
+		Top:     *top,
+		MinPlay: time.Duration(a.cfg.TopPlayed.MinPlaySeconds) * time.Second,
+	}
+	return processor.NewWrappedReport(a.Client(), a.store, os.Stdout, a.logger, opts), nil
+}
+
+// buildLastfmTask handles "lastfm login", "lastfm top [--year N]", "lastfm import-loves"
+// and "lastfm push-likes".
+func (a *app) buildLastfmTask(args []string) (processor.Processor, error) {
+	const usage = "usage: lastfm login | lastfm top [--year 2024] | lastfm import-loves | lastfm push-likes"
+	if len(args) == 0 {
+		return nil, errors.New(usage)
+	}
+	lf := lastfm.New(os.Getenv("LASTFM_API_KEY"), os.Getenv("LASTFM_API_SECRET"), os.Getenv("LASTFM_SESSION_KEY"), a.cfg.LastFM.User)
+	if args[0] == "login" {
+		return processor.NewLastfmLogin(lf, a.logger, func() error {
+			_, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			return err
+		}), nil
+	}
+	if a.cfg.LastFM.User == "" {
+		return nil, errors.New("set lastfm.user in config.yaml")
+	}
+	switch args[0] {
+	case "top":
+		fs := a.newFlagSet("lastfm top")
+		year := fs.Int("year", time.Now().Year(), "year to rank scrobbles of")
+		if err := fs.Parse(args[1:]); err != nil {
+			return nil, err
+		}
+		imageGenerator, err := a.ImageGenerator()
+		if err != nil {
+			return nil, err
+		}
+		builder, err := processor.NewLastfmTopBuilder(a.Client(), lf, a.store, a.logger, imageGenerator, a.cfg.LastFM, a.cfg.Matching, a.cfg.Playlists, *year)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lastfm configuration: %w", err)
+		}
+		return builder, nil
+	case "import-loves":
+		return processor.NewLastfmLoveImporter(a.Client(), lf, a.logger, a.cfg.Matching), nil
+	case "push-likes":
+		return processor.NewLastfmLovePusher(a.Client(), lf, a.logger), nil
+	default:
+		return nil, errors.New(usage)
+	}
+}
+
+// buildSetlist parses "setlist <setlist.fm URL>" or "setlist --artist X --date Y".
+func (a *app) buildSetlist(args []string) (processor.Processor, error) {
+	const usage = `usage: setlist <setlist.fm URL> | setlist --artist "Radiohead" --date 2017-06-23 [--name "..."]`
+	fs := a.newFlagSet("setlist")
+	artist := fs.String("artist", "", "artist who played the concert")
+	date := fs.String("date", "", "day of the concert, e.g. 2017-06-23")
+	name := fs.String("name", "", "name of the playlist; defaults to artist, venue and date")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	var opts processor.SetlistOptions
+	if fs.NArg() > 0 {
+		id, err := setlistfm.IDFromURL(fs.Arg(0))
+		if err != nil {
+			return nil, err
+		}
+		opts.ID = id
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return nil, err
+		}
+	}
+	opts.Artist, opts.Name = *artist, *name
+	if *date != "" {
+		day, err := time.Parse(time.DateOnly, *date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --date '%s', expected YYYY-MM-DD", *date)
+		}
+		opts.Date = day
+	}
+	if opts.ID == "" && (opts.Artist == "" || opts.Date.IsZero()) {
+		return nil, errors.New(usage)
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	setlists := setlistfm.New(os.Getenv("SETLISTFM_API_KEY"))
+	return processor.NewSetlistPlaylistBuilder(a.Client(), setlists, a.store, a.logger, imageGenerator, a.cfg.Matching, a.cfg.Playlists, opts)
+}
+
+// location returns the time zone dates are grouped in, configured for the sorter.
+func (a *app) location() (*time.Location, error) {
+	return location(a.cfg.Sorter)
+}
+
+// buildRolling returns the rolling playlist maintainer.
+func (a *app) buildRolling() (processor.Processor, error) {
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	rolling, err := processor.NewRollingPlaylist(a.Client(), a.store, a.logger, imageGenerator, a.cfg.Rolling, a.cfg.Playlists)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rolling configuration: %w", err)
+	}
+	return rolling, nil
+}
+
+// buildSplit handles "split <playlist> [--size N]" and "split --join <playlist>".
+func (a *app) buildSplit(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("split")
+	size := fs.Int("size", a.cfg.Split.PartSize, "tracks per part")
+	join := fs.Bool("join", false, "join the parts of the playlist back together")
+	const usage = `usage: split "<playlist name>" [--size 1000] | split --join "<playlist name>"`
+	// Flags may come before or after the playlist name.
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, errors.New(usage)
+	}
+	name := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 {
+		return nil, errors.New(usage)
+	}
+	return processor.NewPlaylistSplitter(a.Client(), a.logger, processor.SplitOptions{Name: name, PartSize: *size, Join: *join})
+}
+
+// buildClone handles "clone <playlist URL> [--name X] [--description] [--cover]".
+func (a *app) buildClone(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("clone")
+	name := fs.String("name", "", "name of the copy; defaults to the source's")
+	description := fs.Bool("description", false, "copy the source's description")
+	cover := fs.Bool("cover", false, "copy the source's cover image")
+	const usage = `usage: clone <playlist URL, URI or ID> [--name "..."] [--description] [--cover]`
+	// Flags may come before or after the playlist.
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, errors.New(usage)
+	}
+	source, err := processor.ParsePlaylistRef(fs.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 {
+		return nil, errors.New(usage)
+	}
+	opts := processor.CloneOptions{Source: source, Name: *name, Description: *description, Cover: *cover}
+	return processor.NewPlaylistCloner(a.Client(), a.store, a.AssetCache(), a.logger, opts), nil
+}
+
+// buildMigrate handles "migrate [--only liked,albums,artists,playlists] [--resume]". It
+// logs in twice: first to the account to copy from, then to the one to copy to.
+func (a *app) buildMigrate(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("migrate")
+	only := fs.String("only", "liked,albums,artists,playlists", "parts of the library to copy")
+	resume := fs.Bool("resume", false, "skip what an interrupted migration already copied")
+	report := fs.String("report", "migration-report.csv", "where to list what couldn't be transferred")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	opts := processor.MigrateOptions{Resume: *resume, Report: *report}
+	for _, part := range strings.Split(*only, ",") {
+		switch strings.TrimSpace(part) {
+		case "liked":
+			opts.Liked = true
+		case "albums":
+			opts.Albums = true
+		case "artists":
+			opts.Artists = true
+		case "playlists":
+			opts.Playlists = true
+		default:
+			return nil, fmt.Errorf("unknown part '%s' in --only (available: liked, albums, artists, playlists)", part)
+		}
+	}
+	// A migration copies the library as it is, so the blocklist and allowlist don't apply.
+	source := a.authenticate("the account to migrate FROM", false)
+	target := a.authenticate("the account to migrate TO", true)
+	return processor.NewMigrator(source, target, a.store, a.AssetCache(), a.logger, opts), nil
+}
+
+// buildBlend handles "blend [--friend-csv file.csv [-o picks.csv]] [--friend-name X]".
+// Without a CSV it logs in a second time, as the friend.
+func (a *app) buildBlend(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("blend")
+	friendCSV := fs.String("friend-csv", "", "the friend's exported library, instead of logging in as them")
+	friendName := fs.String("friend-name", "", "the friend's name in playlist names")
+	out := fs.String("o", "", "with --friend-csv, write your picks for the friend to this CSV")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 {
+		return nil, errors.New("usage: blend [--friend-csv friend.csv [-o picks.csv]] [--friend-name Ann]")
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	mine := a.Client()
+	var friend processor.SpotifyClient
+	if *friendCSV == "" {
+		friend = a.authenticate("your friend's account", true)
+	}
+	opts := processor.BlendOptions{FriendCSV: *friendCSV, FriendName: *friendName, Out: *out}
+	return processor.NewBlendBuilder(mine, friend, a.store, a.logger, imageGenerator, a.cfg.Blend, a.cfg.Matching, a.cfg.Playlists, opts)
+}
+
+// buildCSVImport handles "import-csv <file.csv> --playlist <name> [--report file]".
+func (a *app) buildCSVImport(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("import-csv")
+	playlist := fs.String("playlist", "", "playlist to write the matched tracks to")
+	report := fs.String("report", "", "match report to write; defaults to <file>.report.csv")
+	const usage = `usage: import-csv <file.csv> --playlist "<name>" [--report report.csv]`
+	// Flags may come before or after the file.
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, errors.New(usage)
+	}
+	file := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 || *playlist == "" {
+		return nil, errors.New(usage)
+	}
+	if *report == "" {
+		*report = strings.TrimSuffix(file, ".csv") + ".report.csv"
+	}
+	return processor.NewCSVImporter(a.Client(), a.logger, a.cfg.Matching, processor.CSVImportOptions{File: file, Playlist: *playlist, Report: *report})
+}
+
+// buildSmartPlaylists returns the syncer of the playlists declared under smart_playlists.
+func (a *app) buildSmartPlaylists() (processor.Processor, error) {
+	loc, err := a.location()
+	if err != nil {
+		return nil, err
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	syncer, err := processor.NewSmartPlaylistSyncer(a.Client(), a.store, a.logger, imageGenerator, a.cfg.SmartPlaylists, a.cfg.Playlists, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smart_playlists configuration: %w", err)
+	}
+	return syncer, nil
+}
+
+// buildQueryTask handles the commands that act on the liked songs matching --query:
+// "export [-o file.csv]", "build --name <name>" and "remove [--yes]".
+func (a *app) buildQueryTask(command string, args []string) (processor.Processor, error) {
+	fs := a.newFlagSet(command)
+	expr := fs.String("query", "", `filter expression, e.g. 'artist:"Radiohead" AND year<2000'`)
+	output := fs.String("o", "", "export: CSV file to write; standard output by default")
+	name := fs.String("name", "", "build: name of the playlist")
+	confirm := fs.Bool("yes", false, "remove: actually remove the songs instead of listing them")
+	format := fs.String("format", "default", "export: column layout, or soundiiz or tunemymusic to import elsewhere")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	// Exports default to the whole library; the others are too consequential for that.
+	if *expr == "" && command != "export" {
+		return nil, fmt.Errorf("usage: %s --query <expression>", command)
+	}
+	loc, err := a.location()
+	if err != nil {
+		return nil, err
+	}
+	switch command {
+	case "export":
+		return processor.NewQueryExporter(a.Client(), a.store, a.logger, *expr, loc, *output, *format)
+	case "build":
+		imageGenerator, err := a.ImageGenerator()
+		if err != nil {
+			return nil, err
+		}
+		return processor.NewQueryPlaylistBuilder(a.Client(), a.store, a.logger, imageGenerator, *expr, loc, *name, a.cfg.Playlists)
+	default:
+		remover, err := processor.NewQueryRemover(a.Client(), a.store, a.logger, *expr, loc, *confirm)
+		if *confirm {
+			// remove --yes is the confirmation; don't ask again for each batch.
+			return confirmed(remover, err)
+		}
+		return remover, err
+	}
+}
+
+// buildOnThisDay returns the "On This Day" builder.
+func (a *app) buildOnThisDay() (processor.Processor, error) {
+	loc, err := a.location()
+	if err != nil {
+		return nil, err
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	builder, err := processor.NewOnThisDayBuilder(a.Client(), a.store, a.logger, imageGenerator, a.cfg.OnThisDay, a.cfg.Playlists, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid on_this_day configuration: %w", err)
+	}
+	return builder, nil
+}
+
+// buildDateRange handles "range --from 2023-06-01 --to 2023-08-31 --name <name>". Dates
+// are read in the sorter's time zone.
+func (a *app) buildDateRange(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("range")
+	from := fs.String("from", "", "first day, e.g. 2023-06-01")
+	to := fs.String("to", "", "last day, included, e.g. 2023-08-31")
+	name := fs.String("name", "", "name of the playlist")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *from == "" || *to == "" || *name == "" {
+		return nil, errors.New(`usage: range --from 2023-06-01 --to 2023-08-31 --name "Summer 2023"`)
+	}
+	loc, err := a.location()
+	if err != nil {
+		return nil, err
+	}
+	fromDay, err := time.ParseInLocation(time.DateOnly, *from, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --from date '%s', expected YYYY-MM-DD", *from)
+	}
+	toDay, err := time.ParseInLocation(time.DateOnly, *to, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --to date '%s', expected YYYY-MM-DD", *to)
+	}
+	opts := processor.DateRangeOptions{Name: *name, From: fromDay, To: toDay}
+
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	return processor.NewDateRangeBuilder(a.Client(), a.store, a.logger, imageGenerator, opts, a.cfg.DateRange, a.cfg.Playlists)
+}
+
+// buildLanguagesTask handles "languages", which builds the language playlists, and
+// "languages set <track> <code>", which overrides the detected language of a track.
+func (a *app) buildLanguagesTask(args []string) (processor.Processor, error) {
+	if len(args) == 0 {
+		imageGenerator, err := a.ImageGenerator()
+		if err != nil {
+			return nil, err
+		}
+		builder, err := processor.NewLanguagePlaylistBuilder(a.Client(), a.store, a.logger, imageGenerator, a.cfg.Languages, a.cfg.Playlists)
+		if err != nil {
+			return nil, fmt.Errorf("invalid languages configuration: %w", err)
+		}
+		return builder, nil
+	}
+	if args[0] != "set" || len(args) != 3 {
+		return nil, errors.New(`usage: languages [set <track ID or URI> <language code, "und" for unknown, or "" to clear>]`)
+	}
+	return processor.NewLanguageOverride(a.store, history.IDFromURI(args[1]), args[2], a.logger), nil
+}
+
+// buildEraseArtist handles "erase-artist <name or URL>... [--dry-run]". The eraser
+// confirms the whole plan once itself, so the client doesn't ask again for each step.
+func (a *app) buildEraseArtist(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("erase-artist")
+	dryRun := fs.Bool("dry-run", false, "list what would be removed without changing anything")
+	artists, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	opts := processor.ArtistEraseOptions{Artists: artists, DryRun: *dryRun, Yes: a.yes}
+	return confirmed(processor.NewArtistEraser(a.Client(), os.Stdin, os.Stdout, a.logger, opts))
+}
+
+// buildLengthFilter handles "length-filter [--min 1m] [--max 20m] [--quarantine
+// <playlist>] [--yes]".
+func (a *app) buildLengthFilter(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("length-filter")
+	minLength := fs.Duration("min", 0, "remove songs shorter than this, e.g. 60s")
+	maxLength := fs.Duration("max", 0, "remove songs longer than this, e.g. 20m")
+	quarantine := fs.String("quarantine", "", "move the songs to this playlist for review before unliking them")
+	confirm := fs.Bool("yes", false, "actually remove the songs instead of listing them")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	opts := processor.LengthFilterOptions{Min: *minLength, Max: *maxLength, Quarantine: *quarantine, Confirm: *confirm}
+	filter, err := processor.NewLengthFilter(a.Client(), a.logger, opts)
+	if *confirm {
+		// --yes is the confirmation; don't ask again for each batch.
+		return confirmed(filter, err)
+	}
+	return filter, err
+}
+
+// buildPrune handles "prune <playlist name, URL or URI> --days N".
+func (a *app) buildPrune(args []string) (processor.Processor, error) {
+	const usage = "usage: prune <playlist name, URL or URI> --days N"
+	fs := a.newFlagSet("prune")
+	days := fs.Int("days", 0, "remove tracks added more than this many days ago")
+	refs, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) != 1 || *days <= 0 {
+		return nil, errors.New(usage)
+	}
+	return processor.NewPlaylistPruner(a.Client(), a.logger, refs[0], time.Duration(*days)*24*time.Hour)
+}
+
+// buildUnfollowPlaylists handles "unfollow-playlists <pattern> [--regex] [--generated]
+// [--dry-run]". Like erase-artist, it confirms the whole list once itself.
+func (a *app) buildUnfollowPlaylists(args []string) (processor.Processor, error) {
+	const usage = "usage: unfollow-playlists <name pattern> [--regex] [--generated] [--dry-run]"
+	fs := a.newFlagSet("unfollow-playlists")
+	regex := fs.Bool("regex", false, "the pattern is a regular expression instead of a glob")
+	generated := fs.Bool("generated", false, "only playlists generated by this tool, recognized by their description")
+	dryRun := fs.Bool("dry-run", false, "list the playlists without unfollowing them")
+	patterns, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) != 1 {
+		return nil, errors.New(usage)
+	}
+	opts := processor.PlaylistUnfollowOptions{Pattern: patterns[0], Regex: *regex, Generated: *generated, DryRun: *dryRun, Yes: a.yes}
+	return confirmed(processor.NewPlaylistUnfollower(a.Client(), os.Stdin, os.Stdout, a.logger, opts))
+}
+
+// buildPlaylistTask handles "playlist stats <playlist URL, URI or ID>" and "playlist mix
+// <playlist name, URL or URI>".
+func (a *app) buildPlaylistTask(args []string) (processor.Processor, error) {
+	const usage = "usage: playlist stats <playlist URL, URI or ID> | playlist mix <playlist name, URL or URI>"
+	if len(args) != 2 {
+		return nil, errors.New(usage)
+	}
+	switch args[0] {
+	case "stats":
+		playlistID, err := processor.ParsePlaylistRef(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return processor.NewPlaylistStats(a.Client(), os.Stdout, a.logger, playlistID), nil
+	case "mix":
+		if a.public {
+			return nil, errors.New("playlist mix changes your playlist; run it without --public")
+		}
+		return processor.NewPlaylistMixer(a.Client(), a.logger, args[1])
+	default:
+		return nil, errors.New(usage)
+	}
+}
+
+// buildSearch handles "search <query> [--type track,artist,album] [--limit n] [--pick
+// rows] [--add-to <playlist>] [--like]". Flags may come before or after the query.
+func (a *app) buildSearch(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("search")
+	types := fs.String("type", "track", "comma-separated result types: track, artist, album")
+	limit := fs.Int("limit", 10, "results of each type to show, up to 50")
+	pick := fs.String("pick", "1", `results to act on, e.g. "1,3" or "all"`)
+	addTo := fs.String("add-to", "", "add the picked tracks to this playlist, creating it if needed")
+	like := fs.Bool("like", false, "like the picked tracks, save the picked albums and follow the picked artists")
+	terms, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if a.public && (*addTo != "" || *like) {
+		return nil, errors.New("--add-to and --like change your library; run them without --public")
+	}
+	opts := processor.SearchOptions{Types: strings.Split(*types, ","), Limit: *limit, Pick: *pick, AddTo: *addTo, Like: *like}
+	return processor.NewSearcher(a.Client(), os.Stdout, a.logger, strings.Join(terms, " "), opts)
+}
+
+// buildBrowser returns the terminal UI. It asks before removals itself, so the client
+// doesn't ask again and compete for standard input.
+func (a *app) buildBrowser() (processor.Processor, error) {
+	cached := func(userID string) processor.CachedLibrary {
+		// The local cache holds the real library, not the simulated or replayed one.
+		if a.simulate != "" || a.replay != "" {
+			return processor.CachedLibrary{}
+		}
+		return cache.LoadLibrary(a.store, userID)
+	}
+	return confirmed(processor.NewBrowser(a.Client(), a.store, cached, os.Stdin, os.Stdout, a.logger), nil)
+}
+
+// buildTagTask handles "tag add|remove <track> <tag>...", "tag list", "tag export [-o
+// file]" and "tag import <file>". Tags live in the local store; no login is needed.
+func (a *app) buildTagTask(args []string) (processor.Processor, error) {
+	const usage = "usage: tag add|remove <track ID or URI> <tag>... | tag list | tag export [-o tags.json] | tag import <tags.json>"
+	if len(args) == 0 {
+		return nil, errors.New(usage)
+	}
+	switch args[0] {
+	case "add", "remove":
+		if len(args) < 3 {
+			return nil, errors.New(usage)
+		}
+		trackID := history.IDFromURI(args[1])
+		if args[0] == "add" {
+			return processor.NewTagEditor(a.store, trackID, args[2:], nil, a.logger), nil
+		}
+		return processor.NewTagEditor(a.store, trackID, nil, args[2:], a.logger), nil
+	case "list":
+		return processor.NewTagLister(a.store, a.logger), nil
+	case "export":
+		fs := a.newFlagSet("tag export")
+		out := fs.String("o", "", "file to write; standard output by default")
+		if err := fs.Parse(args[1:]); err != nil {
+			return nil, err
+		}
+		return processor.NewTagExporter(a.store, *out, a.logger), nil
+	case "import":
+		if len(args) != 2 {
+			return nil, errors.New(usage)
+		}
+		return processor.NewTagImporter(a.store, args[1], a.logger), nil
+	}
+	return nil, errors.New(usage)
+}
+
+// buildCoverTask handles "cover preview <playlist name> [-o out.jpg]", which renders a
+// cover locally without logging in.
+func (a *app) buildCoverTask(args []string) (processor.Processor, error) {
+	const usage = "usage: cover preview <playlist name> [-o out.jpg] [-label text] [-subtitle text] [-style name]"
+	if len(args) == 0 || args[0] != "preview" {
+		return nil, errors.New(usage)
+	}
+	fs := a.newFlagSet("cover preview")
+	out := fs.String("o", "cover.jpg", "file to write the cover to")
+	label := fs.String("label", "", "large text drawn on the cover (defaults to the playlist name)")
+	subtitle := fs.String("subtitle", "", "smaller line drawn under the label")
+	style := fs.String("style", a.cfg.Covers.Style, "art style, overriding the config")
+	// Flags may come before or after the playlist name.
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, errors.New(usage)
+	}
+	name := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 0 {
+		return nil, errors.New(usage)
+	}
+
+	covers := a.cfg.Covers
+	covers.Style = *style
+	// Without a hash registry the preview doesn't claim a cover slot in the store. A real
+	// run may still re-seed the artwork if it collides with another playlist's cover.
+	imageGenerator, err := generator.NewImageGenerator(covers, nil, a.AssetCache())
+	if err != nil {
+		return nil, err
+	}
+	spec := processor.CoverSpec{Name: name, Label: *label, Subtitle: *subtitle}
+	return processor.NewCoverPreview(imageGenerator, spec, *out, a.logger), nil
+}
+
+// buildPipeline builds the configured pipeline named in args, e.g. "pipeline nightly
+// --dry-run".
+func (a *app) buildPipeline(args []string) (processor.Processor, error) {
+	const usage = "usage: pipeline <name> [--dry-run]"
+	fs := a.newFlagSet("pipeline")
+	dryRun := fs.Bool("dry-run", false, "report what the pipeline would change without changing anything")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, errors.New(usage)
+	}
+	name := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errors.New(usage)
+	}
+
+	cfg := a.pipeline(name)
+	if cfg == nil {
+		return nil, fmt.Errorf("no pipeline named '%s' in the config", name)
+	}
+	if len(cfg.Steps) == 0 {
+		return nil, fmt.Errorf("pipeline '%s' has no steps", name)
+	}
+	var continueOnError bool
+	switch cfg.OnError {
+	case "", "stop":
+	case "continue":
+		continueOnError = true
+	default:
+		return nil, fmt.Errorf("unknown on_error '%s' in pipeline '%s' (available: stop, continue)", cfg.OnError, name)
+	}
+	if *dryRun {
+		if a.client != nil {
+			return nil, errors.New("a dry run must be set up before logging in; run the pipeline on its own")
+		}
+		// Nothing of the run may outlive it, including checkpoints and imported data.
+		a.dryRun = true
+		a.store.ReadOnly()
+	}
+
+	steps := make([]pipeline.Step, 0, len(cfg.Steps))
+	for _, step := range cfg.Steps {
+		switch {
+		case step.Command == "pipeline" || step.Command == "daemon":
+			return nil, fmt.Errorf("a pipeline step can't run the %s command", step.Command)
+		case *dryRun && step.Command == "lastfm" && len(step.Args) > 0 && step.Args[0] == "push-likes":
+			return nil, errors.New("lastfm push-likes writes to Last.fm, so it can't be part of a dry run")
+		}
+		task, err := a.buildTask(step.Command, step.Args)
+		if err != nil {
+			return nil, fmt.Errorf("step '%s': %w", step.Command, err)
+		}
+		steps = append(steps, pipeline.Step{Name: strings.Join(append([]string{step.Command}, step.Args...), " "), Task: task})
+	}
+	if *dryRun {
+		// The report needs the recording client even if no step logged in.
+		a.Client()
+	}
+	return pipeline.New(cfg.Name, steps, continueOnError, a.dryRunClient, os.Stdout, a.logger), nil
+}
+
+// pipeline returns the configured pipeline called name, or nil.
+func (a *app) pipeline(name string) *config.Pipeline {
+	for i := range a.cfg.Pipelines {
+		if a.cfg.Pipelines[i].Name == name {
+			return &a.cfg.Pipelines[i]
+		}
+	}
+	return nil
+}
+
+// buildDaemon turns the configured jobs into a scheduler.
+func (a *app) buildDaemon() (processor.Processor, error) {
+	if len(a.cfg.Daemon.Jobs) == 0 {
+		return nil, errors.New("no daemon jobs configured")
+	}
+	// Jobs run unattended, with nobody to answer confirmations.
+	a.yes = true
+	jobs := make([]daemon.Job, 0, len(a.cfg.Daemon.Jobs))
+	var notifier daemon.Notifier
+	for _, jobCfg := range a.cfg.Daemon.Jobs {
+		if jobCfg.Command == "daemon" {
+			return nil, errors.New("a daemon job can't run the daemon command")
+		}
+		if jobCfg.Interval <= 0 {
+			return nil, fmt.Errorf("job '%s' needs a positive interval", jobCfg.Command)
+		}
+		task, err := a.buildTask(jobCfg.Command, jobCfg.Args)
+		if err != nil {
+			return nil, fmt.Errorf("job '%s': %w", jobCfg.Command, err)
+		}
+		jobs = append(jobs, daemon.Job{
+			Name:     jobCfg.Command,
+			Interval: jobCfg.Interval,
+			Timeout:  jobCfg.Timeout,
+			Task:     task,
+			Notify:   jobCfg.Notify,
+		})
+		if jobCfg.Notify != "" && notifier == nil {
+			mailer, err := notify.NewMailer(a.cfg.Daemon.Email, os.Getenv("SPOTIFY_MANAGER_SMTP_PASSWORD"))
+			if err != nil {
+				return nil, err
+			}
+			notifier = mailer
+		}
+	}
+	return daemon.New(jobs, a.store, a.logger, notifier), nil
+}
+
+// buildPopularity handles "popularity [--playlist <name, URL or URI>] [--threshold N]".
+func (a *app) buildPopularity(args []string) (processor.Processor, error) {
+	fs := a.newFlagSet("popularity")
+	playlist := fs.String("playlist", "", "split this playlist instead of the liked songs")
+	threshold := fs.Int("threshold", a.cfg.Popularity.Threshold, "lowest popularity (1-100) of a hit")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	cfg := a.cfg.Popularity
+	cfg.Threshold = *threshold
+	splitter, err := processor.NewPopularitySplitter(a.Client(), a.store, a.logger, imageGenerator, cfg, a.cfg.Playlists, *playlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid popularity configuration: %w", err)
+	}
+	return splitter, nil
+}
+
+// buildWorkout returns the workout playlist builder.
+func (a *app) buildWorkout() (processor.Processor, error) {
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	workout, err := processor.NewWorkoutBuilder(a.Client(), a.store, a.logger, imageGenerator, a.cfg.Workout, a.cfg.Playlists)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workout configuration: %w", err)
+	}
+	return workout, nil
+}
+
+// buildQueue handles "queue <playlist name, URL or URI> | --query <expression>
+// [--shuffle] [--limit N] [--device name]".
+func (a *app) buildQueue(args []string) (processor.Processor, error) {
+	const usage = "usage: queue <playlist name, URL or URI> | --query <expression> [--shuffle] [--limit N] [--device name]"
+	fs := a.newFlagSet("queue")
+	expr := fs.String("query", "", `queue the liked songs matching this filter expression instead of a playlist`)
+	shuffle := fs.Bool("shuffle", false, "queue the tracks in random order")
+	limit := fs.Int("limit", 0, "queue at most this many tracks; 0 queues them all")
+	device := fs.String("device", "", "queue on the device whose name contains this; default the active one")
+	refs, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 1 {
+		return nil, errors.New(usage)
+	}
+	loc, err := a.location()
+	if err != nil {
+		return nil, err
+	}
+	opts := processor.QueueOptions{Query: *expr, Shuffle: *shuffle, Limit: *limit, Device: *device}
+	if len(refs) == 1 {
+		opts.Playlist = refs[0]
+	}
+	return processor.NewQueuer(a.Client(), a.store, a.logger, loc, opts)
+}
+
+// buildDevices returns the device list.
+func (a *app) buildDevices() (processor.Processor, error) {
+	return processor.NewDeviceLister(a.Client(), os.Stdout), nil
+}
+
+// buildTransfer handles "transfer <device> [--playlist <name, URL or URI>] [--play]".
+func (a *app) buildTransfer(args []string) (processor.Processor, error) {
+	const usage = "usage: transfer <device name> [--playlist <playlist name, URL or URI>] [--play]"
+	fs := a.newFlagSet("transfer")
+	playlist := fs.String("playlist", "", "start this playlist on the device")
+	play := fs.Bool("play", false, "resume playback on the device, even if it was paused")
+	names, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, errors.New(usage)
+	}
+	return processor.NewPlaybackTransfer(a.Client(), a.logger, strings.Join(names, " "), *playlist, *play)
+}
+
+// buildSeasons returns the season playlists builder. Like dates are read in the sorter's
+// time zone.
+func (a *app) buildSeasons() (processor.Processor, error) {
+	loc, err := a.location()
+	if err != nil {
+		return nil, err
+	}
+	imageGenerator, err := a.ImageGenerator()
+	if err != nil {
+		return nil, err
+	}
+	builder, err := processor.NewSeasonPlaylistBuilder(a.Client(), a.store, a.logger, imageGenerator, a.cfg.Seasons, a.cfg.Playlists, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seasons configuration: %w", err)
+	}
+	return builder, nil
+}
@@ -0,0 +1,172 @@
+// Package assets downloads remote images such as album art and keeps them in an on-disk
+// cache shared by every feature that needs them, so they aren't fetched again across
+// features and runs.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // album art is served as JPEG
+	_ "image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// httpClient downloads assets. Images are optional decoration, so a slow CDN must not
+// stall a run.
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// Cache stores downloaded files under a directory, evicting the least recently used
+// ones once the directory grows past a size limit.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	scanned bool
+	size    int64
+}
+
+// New returns a cache rooted at dir holding at most maxBytes. An empty dir disables the
+// cache: every request is downloaded.
+func New(dir string, maxBytes int64) *Cache {
+	return &Cache{dir: dir, maxBytes: maxBytes}
+}
+
+// Get returns the contents at url, downloading it on a cache miss.
+func (c *Cache) Get(url string) ([]byte, error) {
+	if c.dir == "" {
+		return download(url)
+	}
+	path := c.pathFor(url)
+	if raw, err := os.ReadFile(path); err == nil {
+		// Touch the file so eviction sees it as recently used.
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return raw, nil
+	}
+
+	raw, err := download(url)
+	if err != nil {
+		return nil, err
+	}
+	// A cache that can't be written only costs a re-download next time.
+	_ = c.put(path, raw)
+	return raw, nil
+}
+
+// Image returns the decoded image at url.
+func (c *Cache) Image(url string) (image.Image, error) {
+	raw, err := c.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image: %w", err)
+	}
+	return img, nil
+}
+
+// pathFor names cached files after a hash of their URL.
+func (c *Cache) pathFor(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// put writes raw to path and evicts old entries if the cache is over its limit.
+func (c *Cache) put(path string, raw []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	if !c.scanned {
+		entries, err := c.entries()
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			c.size += e.Size()
+		}
+		c.scanned = true
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	c.size += int64(len(raw))
+	if c.maxBytes > 0 && c.size > c.maxBytes {
+		return c.evict()
+	}
+	return nil
+}
+
+// evict removes the least recently used files until the cache fits its limit.
+func (c *Cache) evict() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ModTime().Before(entries[j].ModTime()) })
+	for _, e := range entries {
+		if c.size <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		c.size -= e.Size()
+	}
+	return nil
+}
+
+// entries lists the cached files.
+func (c *Cache) entries() ([]fs.FileInfo, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]fs.FileInfo, 0, len(dirEntries))
+	for _, d := range dirEntries {
+		if d.IsDir() || filepath.Ext(d.Name()) == ".tmp" {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not download '%s': %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download '%s': %s", url, resp.Status)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not download '%s': %w", url, err)
+	}
+	return raw, nil
+}
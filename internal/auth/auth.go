@@ -11,6 +11,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/zmb3/spotify/v2"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
 // Config holds the necessary configuration for the authenticator.
@@ -20,6 +22,12 @@ type Config struct {
 	ClientSecret string
 	Port         string
 	Scopes       []string
+	// ShowDialog makes Spotify ask which account to use even if the browser is already
+	// logged in, for logging in to a second account.
+	ShowDialog bool
+	// Transport, if set, wraps the transport of the authenticated client, e.g. to record
+	// its requests.
+	Transport func(http.RoundTripper) http.RoundTripper
 }
 
 // Authenticator handles the OAuth2 flow for a CLI application.
@@ -45,6 +53,9 @@ func New(config Config) *Authenticator {
 
 // AuthURL returns the URL the user must visit to grant permissions.
 func (a *Authenticator) AuthURL() string {
+	if a.config.ShowDialog {
+		return a.auth.AuthURL(a.state, spotifyauth.ShowDialog)
+	}
 	return a.auth.AuthURL(a.state)
 }
 
@@ -90,7 +101,7 @@ func (a *Authenticator) startServer(clientChan chan *spotify.Client, errChan cha
 			return
 		}
 
-		client := spotify.New(a.auth.Client(r.Context(), token))
+		client := a.client(token)
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		_, err = fmt.Fprintln(w, "<html><body><h1>Login Completed!</h1><p>You can close this window now.</p></body></html>")
 		if err != nil {
@@ -108,3 +119,43 @@ func (a *Authenticator) startServer(clientChan chan *spotify.Client, errChan cha
 
 	return server
 }
+
+// client returns a client authorized by token. The login's request context ends with
+// its handler, but the client must keep refreshing its token for as long as the program
+// runs (e.g. in daemon mode). Rate-limited requests are left to config.Transport, e.g.
+// a ratelimit.Backoff.
+func (a *Authenticator) client(token *oauth2.Token) *spotify.Client {
+	httpClient := a.auth.Client(context.Background(), token)
+	if a.config.Transport != nil {
+		httpClient.Transport = a.config.Transport(httpClient.Transport)
+	}
+	return spotify.New(httpClient)
+}
+
+// RefreshClient returns a client authorized by the refresh token of an earlier login,
+// without the interactive flow. The token is only exchanged on the first request, so a
+// revoked one shows up as that request failing.
+func (a *Authenticator) RefreshClient(refreshToken string) *spotify.Client {
+	return a.client(&oauth2.Token{RefreshToken: refreshToken})
+}
+
+// AppClient returns a client authorized as the app alone, with the client credentials
+// flow: no user logs in, so it can only read public data such as the catalog and public
+// playlists. Only ClientID, ClientSecret and Transport of config are used.
+func AppClient(ctx context.Context, config Config) (*spotify.Client, error) {
+	credentials := &clientcredentials.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		TokenURL:     spotifyauth.TokenURL,
+	}
+	// Fetching the first token up front reports wrong credentials before any request.
+	token, err := credentials.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get an app token: %w", err)
+	}
+	httpClient := oauth2.NewClient(context.Background(), oauth2.ReuseTokenSource(token, credentials.TokenSource(context.Background())))
+	if config.Transport != nil {
+		httpClient.Transport = config.Transport(httpClient.Transport)
+	}
+	return spotify.New(httpClient), nil
+}
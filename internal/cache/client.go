@@ -0,0 +1,366 @@
+// Package cache memoizes read-only Spotify calls, so processors that run one after
+// another don't fetch the same user, playlists and tracks again.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"spotify/internal/processor"
+	"spotify/internal/store"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// runLifetime is how long a response is reused without being persisted: long enough for
+// the processors of one run to share it, short enough that daemon jobs hours apart
+// don't.
+const runLifetime = 10 * time.Minute
+
+// Client is a SpotifyClient that reuses the results of read calls. Every result is kept
+// in memory for a run; catalog lookups (artists, tracks, albums, audio features) and, if configured, the
+// user's library are also saved in the store for later runs. A write drops the cached
+// reads it affects.
+type Client struct {
+	processor.SpotifyClient
+	store      *store.Store
+	catalogTTL time.Duration
+	libraryTTL time.Duration
+
+	mu   sync.Mutex
+	user *spotify.PrivateUser
+	memo map[string]memoEntry
+}
+
+type memoEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// NewClient wraps client. catalogTTL and libraryTTL are how long catalog and library
+// responses are kept in st; 0 keeps them for the current run only.
+func NewClient(client processor.SpotifyClient, st *store.Store, catalogTTL, libraryTTL time.Duration) *Client {
+	st.PruneCachedResponses()
+	return &Client{
+		SpotifyClient: client,
+		store:         st,
+		catalogTTL:    catalogTTL,
+		libraryTTL:    libraryTTL,
+		memo:          make(map[string]memoEntry),
+	}
+}
+
+// CurrentUser is fetched once: a client is logged in to a single account.
+func (c *Client) CurrentUser(ctx context.Context) (*spotify.PrivateUser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.user == nil {
+		user, err := c.SpotifyClient.CurrentUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.user = user
+	}
+	user := *c.user
+	return &user, nil
+}
+
+func (c *Client) CurrentUsersTracks(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedTrackPage, error) {
+	return library(ctx, c, withOptions("liked", opts), func() (*spotify.SavedTrackPage, error) {
+		return c.SpotifyClient.CurrentUsersTracks(ctx, opts...)
+	})
+}
+
+func (c *Client) CurrentUsersAlbums(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedAlbumPage, error) {
+	return library(ctx, c, withOptions("albums", opts), func() (*spotify.SavedAlbumPage, error) {
+		return c.SpotifyClient.CurrentUsersAlbums(ctx, opts...)
+	})
+}
+
+func (c *Client) GetPlaylistsForUser(ctx context.Context, userID string, opts ...spotify.RequestOption) (*spotify.SimplePlaylistPage, error) {
+	return library(ctx, c, withOptions("playlists/"+userID, opts), func() (*spotify.SimplePlaylistPage, error) {
+		return c.SpotifyClient.GetPlaylistsForUser(ctx, userID, opts...)
+	})
+}
+
+func (c *Client) GetPlaylistTracks(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.PlaylistTrackPage, error) {
+	return library(ctx, c, withOptions(playlistTracksKey(playlistID), opts), func() (*spotify.PlaylistTrackPage, error) {
+		return c.SpotifyClient.GetPlaylistTracks(ctx, playlistID, opts...)
+	})
+}
+
+func (c *Client) GetArtists(ctx context.Context, ids ...spotify.ID) ([]*spotify.FullArtist, error) {
+	return cached(c, "catalog/artists/"+joinIDs(ids), c.catalogTTL, func() ([]*spotify.FullArtist, error) {
+		return c.SpotifyClient.GetArtists(ctx, ids...)
+	})
+}
+
+func (c *Client) GetTracks(ctx context.Context, ids []spotify.ID, opts ...spotify.RequestOption) ([]*spotify.FullTrack, error) {
+	return cached(c, withOptions("catalog/tracks/"+joinIDs(ids), opts), c.catalogTTL, func() ([]*spotify.FullTrack, error) {
+		return c.SpotifyClient.GetTracks(ctx, ids, opts...)
+	})
+}
+
+func (c *Client) GetAudioFeatures(ctx context.Context, ids ...spotify.ID) ([]*spotify.AudioFeatures, error) {
+	return cached(c, "catalog/audio-features/"+joinIDs(ids), c.catalogTTL, func() ([]*spotify.AudioFeatures, error) {
+		return c.SpotifyClient.GetAudioFeatures(ctx, ids...)
+	})
+}
+
+func (c *Client) GetAlbumTracks(ctx context.Context, id spotify.ID, opts ...spotify.RequestOption) (*spotify.SimpleTrackPage, error) {
+	return cached(c, withOptions("catalog/album-tracks/"+string(id), opts), c.catalogTTL, func() (*spotify.SimpleTrackPage, error) {
+		return c.SpotifyClient.GetAlbumTracks(ctx, id, opts...)
+	})
+}
+
+func (c *Client) AddTracksToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	defer c.drop(ctx, "liked")
+	return c.SpotifyClient.AddTracksToLibrary(ctx, ids...)
+}
+
+func (c *Client) RemoveTracksFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	defer c.drop(ctx, "liked")
+	return c.SpotifyClient.RemoveTracksFromLibrary(ctx, ids...)
+}
+
+func (c *Client) AddAlbumsToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	defer c.drop(ctx, "albums")
+	return c.SpotifyClient.AddAlbumsToLibrary(ctx, ids...)
+}
+
+func (c *Client) RemoveAlbumsFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	defer c.drop(ctx, "albums")
+	return c.SpotifyClient.RemoveAlbumsFromLibrary(ctx, ids...)
+}
+
+func (c *Client) CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error) {
+	defer c.drop(ctx, "playlists/")
+	return c.SpotifyClient.CreatePlaylistForUser(ctx, userID, playlistName, description, public, collaborative)
+}
+
+func (c *Client) UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error {
+	defer c.dropPlaylist(ctx, playlistID)
+	return c.SpotifyClient.UnfollowPlaylist(ctx, playlistID)
+}
+
+func (c *Client) ChangePlaylistName(ctx context.Context, playlistID spotify.ID, newName string) error {
+	defer c.drop(ctx, "playlists/")
+	return c.SpotifyClient.ChangePlaylistName(ctx, playlistID, newName)
+}
+
+func (c *Client) ChangePlaylistDescription(ctx context.Context, playlistID spotify.ID, newDescription string) error {
+	defer c.drop(ctx, "playlists/")
+	return c.SpotifyClient.ChangePlaylistDescription(ctx, playlistID, newDescription)
+}
+
+func (c *Client) ChangePlaylistAccess(ctx context.Context, playlistID spotify.ID, public bool) error {
+	defer c.drop(ctx, "playlists/")
+	return c.SpotifyClient.ChangePlaylistAccess(ctx, playlistID, public)
+}
+
+func (c *Client) SetPlaylistImage(ctx context.Context, playlistID spotify.ID, img io.Reader) error {
+	defer c.drop(ctx, "playlists/")
+	return c.SpotifyClient.SetPlaylistImage(ctx, playlistID, img)
+}
+
+func (c *Client) AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	defer c.dropPlaylist(ctx, playlistID)
+	return c.SpotifyClient.AddTracksToPlaylist(ctx, playlistID, trackIDs...)
+}
+
+func (c *Client) ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error) {
+	defer c.dropPlaylist(ctx, playlistID)
+	return c.SpotifyClient.ReplacePlaylistItems(ctx, playlistID, items...)
+}
+
+func (c *Client) RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	defer c.dropPlaylist(ctx, playlistID)
+	return c.SpotifyClient.RemoveTracksFromPlaylist(ctx, playlistID, trackIDs...)
+}
+
+// library caches a read of the user's own data under key, scoped to the logged-in
+// account so two accounts sharing a store never see each other's library.
+func library[T any](ctx context.Context, c *Client, key string, fetch func() (T, error)) (T, error) {
+	if key == "" {
+		return fetch()
+	}
+	scope, err := c.scope(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if processor.FreshReads(ctx) {
+		return refreshed(ctx, c, scope, key, fetch)
+	}
+	return cached(c, scope+key, c.libraryTTL, fetch)
+}
+
+// refreshed calls fetch without looking in the cache and saves its result like cached.
+// A result that differs from the saved one means the library changed, so the other
+// pages of the same list are dropped as well.
+func refreshed[T any](ctx context.Context, c *Client, scope, key string, fetch func() (T, error)) (T, error) {
+	v, err := fetch()
+	if err != nil {
+		return v, err
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return v, nil
+	}
+	if old, ok := c.lookup(scope + key); ok && !bytes.Equal(old, body) {
+		path, _, _ := strings.Cut(key, "?")
+		c.drop(ctx, path)
+	}
+	c.save(scope+key, body, c.libraryTTL)
+	return v, nil
+}
+
+// cached returns the response saved under key, or calls fetch and saves its result for
+// the run and, if ttl is positive, in the store. Hits are decoded afresh, so callers
+// can't change each other's results. Errors, and calls without a key, are never cached.
+func cached[T any](c *Client, key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	if key == "" {
+		return fetch()
+	}
+	var v T
+	if body, ok := c.lookup(key); ok && json.Unmarshal(body, &v) == nil {
+		return v, nil
+	}
+	v, err := fetch()
+	if err != nil {
+		return v, err
+	}
+	if body, err := json.Marshal(v); err == nil {
+		c.save(key, body, ttl)
+	}
+	return v, nil
+}
+
+// save keeps body under key for the run and, if ttl is positive, in the store.
+func (c *Client) save(key string, body []byte, ttl time.Duration) {
+	c.mu.Lock()
+	c.memo[key] = memoEntry{body: body, expires: time.Now().Add(runLifetime)}
+	c.mu.Unlock()
+	if ttl > 0 {
+		c.store.SetCachedResponse(key, body, ttl)
+	}
+}
+
+// lookup finds a response saved for this run, or failing that for later runs.
+func (c *Client) lookup(key string) ([]byte, bool) {
+	c.mu.Lock()
+	entry, ok := c.memo[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.body, true
+	}
+	return c.store.CachedResponse(key)
+}
+
+// drop forgets the library responses whose key starts with prefix.
+func (c *Client) drop(ctx context.Context, prefix string) {
+	scope, err := c.scope(ctx)
+	if err != nil {
+		// Without the account nothing of its library can have been cached.
+		return
+	}
+	prefix = scope + prefix
+	c.mu.Lock()
+	for key := range c.memo {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.memo, key)
+		}
+	}
+	c.mu.Unlock()
+	c.store.DropCachedResponses(prefix)
+}
+
+// dropPlaylist forgets a playlist's tracks and the playlist lists that show its size.
+func (c *Client) dropPlaylist(ctx context.Context, playlistID spotify.ID) {
+	c.drop(ctx, playlistTracksKey(playlistID))
+	c.drop(ctx, "playlists/")
+}
+
+// scope returns the key prefix of the logged-in account's library.
+func (c *Client) scope(ctx context.Context) (string, error) {
+	user, err := c.CurrentUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "user/" + user.ID + "/", nil
+}
+
+func playlistTracksKey(playlistID spotify.ID) string {
+	return "playlist-tracks/" + string(playlistID)
+}
+
+func joinIDs(ids []spotify.ID) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = string(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// withOptions appends to key the query string opts add to a request, e.g.
+// "?limit=50&offset=100". It returns "" if opts can't be read, so the call isn't cached.
+func withOptions(key string, opts []spotify.RequestOption) string {
+	if len(opts) == 0 {
+		return key
+	}
+	query, ok := Query(opts)
+	if !ok {
+		return ""
+	}
+	return key + "?" + query.Encode()
+}
+
+// errProbe stops the request Query builds before it's sent.
+var errProbe = errors.New("probe request")
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// Query returns the query parameters opts add to a request. Request options are opaque,
+// so they're applied to a request that never leaves the process. ok is false if that
+// fails.
+func Query(opts []spotify.RequestOption) (query url.Values, ok bool) {
+	probe := spotify.New(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		query = r.URL.Query()
+		return nil, errProbe
+	})})
+	if _, err := probe.GetPlaylistTracks(context.Background(), "probe", opts...); !errors.Is(err, errProbe) {
+		return nil, false
+	}
+	return query, true
+}
+
+// PlaylistNames returns the names of the playlists in the library responses saved in
+// st, sorted, so shell completion can offer them without logging in. Playlists are only
+// saved with a positive library TTL.
+func PlaylistNames(st *store.Store) []string {
+	seen := make(map[string]bool)
+	for key, body := range st.CachedResponses("user/") {
+		if !strings.Contains(key, "/playlists/") {
+			continue
+		}
+		var page spotify.SimplePlaylistPage
+		if json.Unmarshal(body, &page) != nil {
+			continue
+		}
+		for _, p := range page.Playlists {
+			seen[p.Name] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
@@ -0,0 +1,69 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"spotify/internal/cache"
+	"spotify/internal/processor"
+	"spotify/internal/spotifytest"
+	"spotify/internal/store"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// TestFreshReads checks that a fresh read sees a library changed behind the cache, and
+// that the stale pages of the list are dropped with it.
+func TestFreshReads(t *testing.T) {
+	f := spotifytest.Fixture{User: spotify.PrivateUser{User: spotify.User{ID: "me"}}}
+	for i := range 5 {
+		id := spotify.ID(fmt.Sprintf("t%d", i))
+		f.Tracks = append(f.Tracks, spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: id}})
+		if i > 0 {
+			f.Liked = append(f.Liked, spotifytest.Saved{ID: id})
+		}
+	}
+	library, err := spotifytest.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	client := cache.NewClient(library, st, 0, time.Hour)
+	first := func(ctx context.Context, offset int) spotify.ID {
+		t.Helper()
+		page, err := client.CurrentUsersTracks(ctx, spotify.Limit(2), spotify.Offset(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page.Tracks[0].ID
+	}
+	if got := first(ctx, 0); got != "t1" {
+		t.Fatalf("first liked song = %s, want t1", got)
+	}
+	if got := first(ctx, 2); got != "t3" {
+		t.Fatalf("third liked song = %s, want t3", got)
+	}
+
+	// Liked in the app, past the cache.
+	if err := library.AddTracksToLibrary(ctx, "t0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := first(ctx, 0); got != "t1" {
+		t.Fatalf("cached first liked song = %s, want the stale t1", got)
+	}
+	if got := first(processor.WithFreshReads(ctx), 0); got != "t0" {
+		t.Errorf("fresh first liked song = %s, want t0", got)
+	}
+	if got := first(ctx, 0); got != "t0" {
+		t.Errorf("first liked song after a fresh read = %s, want t0 saved", got)
+	}
+	if got := first(ctx, 2); got != "t2" {
+		t.Errorf("third liked song after a fresh read = %s, want the stale page dropped", got)
+	}
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/url"
+	"spotify/internal/processor"
+	"spotify/internal/store"
+	"strconv"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// LoadLibrary returns the liked songs and playlists of userID that earlier runs saved in
+// st, for browsing them without fetching them again. Only complete lists count: one
+// missing page, e.g. after a write dropped it, leaves the list out. Library responses
+// are only saved with a positive library TTL.
+func LoadLibrary(st *store.Store, userID string) processor.CachedLibrary {
+	scope := "user/" + userID + "/"
+	liked := make(map[int]spotify.SavedTrackPage)
+	playlists := make(map[int]spotify.SimplePlaylistPage)
+	tracks := make(map[spotify.ID]map[int]spotify.PlaylistTrackPage)
+	for key, body := range st.CachedResponses(scope) {
+		path, offset, ok := pagedKey(strings.TrimPrefix(key, scope))
+		if !ok {
+			continue
+		}
+		switch {
+		case path == "liked":
+			addPage(liked, offset, body, func(p spotify.SavedTrackPage) int { return len(p.Tracks) })
+		case path == "playlists/"+userID:
+			addPage(playlists, offset, body, func(p spotify.SimplePlaylistPage) int { return len(p.Playlists) })
+		case strings.HasPrefix(path, playlistTracksKey("")):
+			id := spotify.ID(strings.TrimPrefix(path, playlistTracksKey("")))
+			if tracks[id] == nil {
+				tracks[id] = make(map[int]spotify.PlaylistTrackPage)
+			}
+			addPage(tracks[id], offset, body, func(p spotify.PlaylistTrackPage) int { return len(p.Tracks) })
+		}
+	}
+
+	var lib processor.CachedLibrary
+	if pages, ok := inOrder(liked, func(p spotify.SavedTrackPage) (int, int) { return len(p.Tracks), int(p.Total) }); ok {
+		lib.Liked = []spotify.SavedTrack{}
+		for _, p := range pages {
+			lib.Liked = append(lib.Liked, p.Tracks...)
+		}
+	}
+	if pages, ok := inOrder(playlists, func(p spotify.SimplePlaylistPage) (int, int) { return len(p.Playlists), int(p.Total) }); ok {
+		lib.Playlists = []spotify.SimplePlaylist{}
+		for _, p := range pages {
+			for _, pl := range p.Playlists {
+				if pl.Owner.ID == userID {
+					lib.Playlists = append(lib.Playlists, pl)
+				}
+			}
+		}
+	}
+	lib.PlaylistTracks = make(map[spotify.ID][]spotify.FullTrack)
+	for id, byOffset := range tracks {
+		pages, ok := inOrder(byOffset, func(p spotify.PlaylistTrackPage) (int, int) { return len(p.Tracks), int(p.Total) })
+		if !ok {
+			continue
+		}
+		list := []spotify.FullTrack{}
+		for _, p := range pages {
+			for _, item := range p.Tracks {
+				if item.Track.ID != "" {
+					list = append(list, item.Track)
+				}
+			}
+		}
+		lib.PlaylistTracks[id] = list
+	}
+	return lib
+}
+
+// pagedKey splits the key of a library response into its path and offset. Responses
+// narrowed with fields hold partial items and are skipped.
+func pagedKey(key string) (path string, offset int, ok bool) {
+	path, rawQuery, _ := strings.Cut(key, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil || query.Has("fields") {
+		return "", 0, false
+	}
+	if o := query.Get("offset"); o != "" {
+		if offset, err = strconv.Atoi(o); err != nil {
+			return "", 0, false
+		}
+	}
+	return path, offset, true
+}
+
+// addPage decodes a page into pages by offset, keeping the longest page of an offset
+// saved with several page sizes.
+func addPage[P any](pages map[int]P, offset int, body []byte, size func(P) int) {
+	var page P
+	if json.Unmarshal(body, &page) != nil {
+		return
+	}
+	if old, ok := pages[offset]; !ok || size(page) > size(old) {
+		pages[offset] = page
+	}
+}
+
+// inOrder returns the pages that follow each other from offset 0 to the total of the
+// first one, or false if any is missing.
+func inOrder[P any](pages map[int]P, size func(P) (n, total int)) ([]P, bool) {
+	first, ok := pages[0]
+	if !ok {
+		return nil, false
+	}
+	_, total := size(first)
+	var out []P
+	for offset := 0; offset < total; {
+		page, ok := pages[offset]
+		n, _ := size(page)
+		if !ok || n == 0 {
+			return nil, false
+		}
+		out = append(out, page)
+		offset += n
+	}
+	return out, true
+}
@@ -0,0 +1,76 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"spotify/internal/cache"
+	"spotify/internal/spotifytest"
+	"spotify/internal/store"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestLoadLibrary(t *testing.T) {
+	f := spotifytest.Fixture{User: spotify.PrivateUser{User: spotify.User{ID: "me"}}}
+	for i := range 5 {
+		id := spotify.ID(fmt.Sprintf("t%d", i))
+		f.Tracks = append(f.Tracks, spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: id}})
+		f.Liked = append(f.Liked, spotifytest.Saved{ID: id})
+	}
+	f.Playlists = []spotifytest.Playlist{
+		{ID: "mine", Name: "Mine", Tracks: []spotify.ID{"t0", "t1", "t2"}},
+		{ID: "theirs", Name: "Theirs", Owner: "someone"},
+	}
+	library, err := spotifytest.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if lib := cache.LoadLibrary(st, "me"); lib.Liked != nil || lib.Playlists != nil || len(lib.PlaylistTracks) != 0 {
+		t.Fatalf("empty store gave %+v", lib)
+	}
+
+	client := cache.NewClient(library, st, 0, time.Hour)
+	for offset := 0; offset <= 5; offset += 2 {
+		if _, err := client.CurrentUsersTracks(ctx, spotify.Limit(2), spotify.Offset(offset)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.GetPlaylistsForUser(ctx, "me", spotify.Limit(50), spotify.Offset(0)); err != nil {
+		t.Fatal(err)
+	}
+	// Only the first page of the playlist's tracks is read.
+	if _, err := client.GetPlaylistTracks(ctx, "mine", spotify.Limit(2), spotify.Offset(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	lib := cache.LoadLibrary(st, "me")
+	if len(lib.Liked) != 5 || lib.Liked[4].ID != "t4" {
+		t.Errorf("liked songs = %d ending with %v, want the 5 in order", len(lib.Liked), lib.Liked)
+	}
+	if len(lib.Playlists) != 1 || lib.Playlists[0].ID != "mine" {
+		t.Errorf("playlists = %v, want only the user's own", lib.Playlists)
+	}
+	if _, ok := lib.PlaylistTracks["mine"]; ok {
+		t.Error("a playlist with only some pages of tracks cached was returned")
+	}
+	if other := cache.LoadLibrary(st, "someone"); other.Liked != nil {
+		t.Error("another account's library was returned")
+	}
+
+	// A removal drops the cached liked songs, so they're fetched again.
+	if err := client.RemoveTracksFromLibrary(ctx, "t0"); err != nil {
+		t.Fatal(err)
+	}
+	if lib := cache.LoadLibrary(st, "me"); lib.Liked != nil {
+		t.Errorf("liked songs after a removal = %v, want none cached", lib.Liked)
+	}
+}
@@ -0,0 +1,716 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the user-editable configuration, read from a YAML file.
+type Config struct {
+	Playlists     Playlists     `yaml:"playlists"`
+	Sorter        Sorter        `yaml:"sorter"`
+	TopPlayed     TopPlayed     `yaml:"top_played"`
+	Covers        Covers        `yaml:"covers"`
+	Charts        Charts        `yaml:"charts"`
+	Daemon        Daemon        `yaml:"daemon"`
+	Folders       Folders       `yaml:"folders"`
+	Lists         Lists         `yaml:"lists"`
+	Split         Split         `yaml:"split"`
+	Matching      Matching      `yaml:"matching"`
+	Cache         Cache         `yaml:"cache"`
+	RateLimit     RateLimit     `yaml:"rate_limit"`
+	Languages     Languages     `yaml:"languages"`
+	DateRange     DateRange     `yaml:"date_range"`
+	OnThisDay     OnThisDay     `yaml:"on_this_day"`
+	Rolling       Rolling       `yaml:"rolling"`
+	ForgottenGems ForgottenGems `yaml:"forgotten_gems"`
+	LastFM        LastFM        `yaml:"lastfm"`
+	Blend         Blend         `yaml:"blend"`
+	Completionist Completionist `yaml:"completionist"`
+	Popularity    Popularity    `yaml:"popularity"`
+	Workout       Workout       `yaml:"workout"`
+	Seasons       Seasons       `yaml:"seasons"`
+	// Pipelines are named sequences of commands run together by the pipeline command.
+	Pipelines []Pipeline `yaml:"pipelines"`
+	// SmartPlaylists are playlists kept in sync with the liked songs matching a rule.
+	SmartPlaylists []SmartPlaylist `yaml:"smart_playlists"`
+}
+
+// Playlists holds settings shared by every processor that writes playlists.
+type Playlists struct {
+	// StatsStamp appends track count, total duration and the update date to generated
+	// playlist descriptions, e.g. "· 213 tracks · 14h 32m · updated 2025-03-02 by spotify-manager".
+	StatsStamp bool `yaml:"stats_stamp"`
+}
+
+// Sorter configures the yearly playlist sorter.
+type Sorter struct {
+	// NameTemplate and DescriptionTemplate are text/template strings rendered for each
+	// playlist. Available fields: .Year, .Period (e.g. "2023/24" with a custom YearStart,
+	// or "2024 Q3" with quarters), .TrackCount, .Duration and .Date.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// CoverSubtitle is drawn under the year on generated covers when cover text is enabled.
+	CoverSubtitle string `yaml:"cover_subtitle"`
+	// Order sets the order tracks are written in.
+	Order Ordering `yaml:"order"`
+	// GroupBy is "liked" to group tracks by the year they were liked in, or "release" to
+	// group them by the release year of their album.
+	GroupBy string `yaml:"group_by"`
+	// ReleaseNameTemplate, ReleaseDescriptionTemplate and ReleaseCoverSubtitle replace the
+	// templates and subtitle above when grouping by release year.
+	ReleaseNameTemplate        string `yaml:"release_name_template"`
+	ReleaseDescriptionTemplate string `yaml:"release_description_template"`
+	ReleaseCoverSubtitle       string `yaml:"release_cover_subtitle"`
+	// Timezone is the IANA time zone, e.g. "Europe/Rome", in which like dates are
+	// grouped. "" means UTC and "Local" the machine's zone.
+	Timezone string `yaml:"timezone"`
+	// YearStart is the "MM-DD" day each yearly playlist starts on, e.g. "09-01" for
+	// academic years. Such playlists are labelled "2023/24".
+	YearStart string `yaml:"year_start"`
+	// Granularity is "year" (the default) for a playlist per year, or "half" or "quarter"
+	// to split calendar years into H1/H2 or Q1–Q4 playlists by like date.
+	Granularity string `yaml:"granularity"`
+	// Conflicts decides what happens to songs added to or removed from a sorted playlist
+	// by hand since the last run: "overwrite" (the default) reverts the edits,
+	// "preserve-manual-additions" keeps the added songs, and "prompt" asks each time.
+	Conflicts string `yaml:"conflicts"`
+	// Visibility is "private" (the default), "public" or "collaborative". Existing
+	// playlists are made public or private to match, but Spotify's API can't change
+	// whether one is collaborative, so that only applies to new playlists.
+	Visibility string `yaml:"visibility"`
+	// Visibilities overrides Visibility for playlists whose name matches a glob pattern,
+	// e.g. "*2024*": public.
+	Visibilities map[string]string `yaml:"visibilities"`
+	// HugeLibrary is the number of liked songs from which a first run needs to be
+	// confirmed with --yes-huge. 0 disables the check.
+	HugeLibrary int `yaml:"huge_library"`
+	// Parallelism is how many years are written at once. 1 writes them one after another.
+	Parallelism int `yaml:"parallelism"`
+	// AppendParallelism is how many batches of 100 songs are added to a playlist at once.
+	// Spotify's API has no way to add at a position through this client, so above 1 the
+	// batches land in the order their requests finish, which needs Order.Strategy
+	// "unordered". An interrupted write of that kind starts the playlist over.
+	AppendParallelism int `yaml:"append_parallelism"`
+}
+
+// Ordering controls the order tracks are written to a playlist in.
+type Ordering struct {
+	// Strategy is "added" to order tracks by when they were liked, so the playlist reads
+	// like a timeline, "diverse" to space out tracks by the same artist or genre so the
+	// playlist plays like a shuffle, "harmonic" to move between compatible keys and
+	// close tempos like a DJ set, or "unordered" for playlists that are only played
+	// shuffled: tracks start out like "added" but may be written in any order.
+	Strategy string `yaml:"strategy"`
+	// Descending puts the most recently liked tracks first with the "added" strategy.
+	Descending bool `yaml:"descending"`
+	// ArtistSpacing is the minimum number of tracks between two by the same artist.
+	ArtistSpacing int `yaml:"artist_spacing"`
+	// GenreSpacing is the minimum number of tracks between two whose primary artist shares
+	// a genre. 0 disables it and skips the genre lookup.
+	GenreSpacing int `yaml:"genre_spacing"`
+	// MaxPerArtist caps the tracks of any one primary artist in a playlist; 0 means no cap.
+	MaxPerArtist int `yaml:"max_per_artist"`
+	// ArtistCapKeep picks which tracks of a capped artist stay: "earliest" liked,
+	// most "popular", or "random", which is stable between runs.
+	ArtistCapKeep string `yaml:"artist_cap_keep"`
+}
+
+// TopPlayed configures the "Most Played" playlists built from imported streaming history.
+type TopPlayed struct {
+	// NameTemplate and DescriptionTemplate take the same fields as the sorter's; .Duration
+	// is the total time spent listening to the included tracks.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// Limit is the maximum number of tracks per playlist; 0 means no limit.
+	Limit int `yaml:"limit"`
+	// MinPlaySeconds is how long a play must last to count; shorter plays are skips.
+	MinPlaySeconds int `yaml:"min_play_seconds"`
+	// MinPlays is the number of counted plays a track needs to be included.
+	MinPlays int `yaml:"min_plays"`
+	// CoverSubtitle is drawn under the year on generated covers when cover text is enabled.
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// Covers configures the generated playlist cover art.
+type Covers struct {
+	// Style is one of "waves", "gradient-mesh", "shards", "noise" or "rings". "auto" (the
+	// default) picks one per playlist, derived from its name.
+	Style string `yaml:"style"`
+	// SimilarityThreshold is the maximum perceptual-hash distance (out of 64 bits) at which
+	// two covers count as duplicates and the newer one is re-seeded. 0 disables the check.
+	SimilarityThreshold int `yaml:"similarity_threshold"`
+	// Theme gives covers seasonal colors: "auto" (the default) derives a theme from a
+	// month or season in the playlist name, "none" disables themes, and "winter",
+	// "spring", "summer", "autumn" or "holiday" applies one to every cover.
+	Theme string `yaml:"theme"`
+	// Themes overrides the theme of playlists whose name matches a glob pattern, e.g.
+	// {"Road Trip*": "summer"}.
+	Themes map[string]string `yaml:"themes"`
+	// Text controls the label drawn on top of the artwork.
+	Text CoverText `yaml:"text"`
+	// Mosaic builds covers from the album art of the playlist's first tracks instead.
+	Mosaic Mosaic `yaml:"mosaic"`
+	// CustomDir holds the user's own covers, named after the playlist ("Liked Songs
+	// (2021).jpg") or its label ("2021.png"). A match is uploaded as-is.
+	CustomDir string `yaml:"custom_dir"`
+	// TemplateImage is a base image used instead of generated artwork, with the label
+	// drawn on top.
+	TemplateImage string `yaml:"template_image"`
+}
+
+// Mosaic configures album-art mosaic covers. Playlists without enough distinct albums
+// fall back to procedural art.
+type Mosaic struct {
+	Enabled bool `yaml:"enabled"`
+	// Grid is 2 for a 2x2 mosaic or 3 for 3x3.
+	Grid int `yaml:"grid"`
+}
+
+// CoverText configures the label overlay on generated covers.
+type CoverText struct {
+	Enabled bool `yaml:"enabled"`
+	// Position is "top", "center" or "bottom".
+	Position string `yaml:"position"`
+	// Size is the label size in points; the subtitle is drawn at 40% of it.
+	Size float64 `yaml:"size"`
+	// Color is a hex color such as "#FFFFFF".
+	Color string `yaml:"color"`
+}
+
+// Languages configures the playlists that group liked songs by the language they're sung in.
+type Languages struct {
+	// NameTemplate and DescriptionTemplate take the same fields as the sorter's, with
+	// .Name set to the language's own name, e.g. "Italiano".
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// MinTracks is how many tracks a language needs for its own playlist; smaller groups
+	// join the unknown-language playlist.
+	MinTracks int `yaml:"min_tracks"`
+	// UnknownName is the .Name of the playlist for tracks whose language is unknown.
+	UnknownName string `yaml:"unknown_name"`
+	// LyricsHook is the URL of an optional service that knows the language of a track's
+	// lyrics. It's asked once per track and the answer is remembered.
+	LyricsHook string `yaml:"lyrics_hook"`
+	// CoverSubtitle is drawn under the language name on generated covers.
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// DateRange configures playlists built from a date window with the range command.
+type DateRange struct {
+	// DescriptionTemplate takes .Name, .From and .To (the window's first and last day),
+	// .TrackCount, .Duration and .Date.
+	DescriptionTemplate string `yaml:"description_template"`
+}
+
+// OnThisDay configures the playlist of songs liked on today's date in previous years.
+type OnThisDay struct {
+	// NameTemplate and DescriptionTemplate take .TrackCount, .Duration and .Date (today).
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// WindowDays widens the match to this many days before and after the date.
+	WindowDays int `yaml:"window_days"`
+	// CoverSubtitle is drawn under today's date on generated covers.
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// Rolling configures the playlist of recent likes kept by the rolling command.
+type Rolling struct {
+	// NameTemplate and DescriptionTemplate take .Days, .TrackCount, .Duration and .Date.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// Days is the size of the window.
+	Days          int    `yaml:"days"`
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// ForgottenGems configures the playlist of old likes missing from recent listening
+// history.
+type ForgottenGems struct {
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// LikedYearsAgo is how long ago a song must have been liked to qualify.
+	LikedYearsAgo int `yaml:"liked_years_ago"`
+	// NotPlayedMonths is how long a song must be absent from the history.
+	NotPlayedMonths int `yaml:"not_played_months"`
+	// MinPlaySeconds is how long a play must last to count, as for top_played.
+	MinPlaySeconds int `yaml:"min_play_seconds"`
+	// Limit is the maximum number of tracks; 0 means no limit.
+	Limit         int    `yaml:"limit"`
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// LastFM configures the Last.fm integration. The API key, secret and session key come
+// from the LASTFM_API_KEY, LASTFM_API_SECRET and LASTFM_SESSION_KEY environment variables.
+type LastFM struct {
+	// User is the Last.fm user whose scrobbles and loved tracks are read.
+	User string `yaml:"user"`
+	// NameTemplate and DescriptionTemplate name the yearly "most scrobbled" playlists and
+	// take the same fields as top_played's; .Duration is the playlist's length.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// Limit is the maximum number of tracks per playlist.
+	Limit int `yaml:"limit"`
+	// MinScrobbles is the number of scrobbles a track needs to be included.
+	MinScrobbles  int    `yaml:"min_scrobbles"`
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// Blend configures the playlists built from two people's libraries by the blend command.
+type Blend struct {
+	// NameTemplate and DescriptionTemplate name the playlist of songs both people like;
+	// .Name is both names, e.g. "Ann & Bob".
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// PicksNameTemplate and PicksDescriptionTemplate name the playlist of the other
+	// person's songs; .Name is their name.
+	PicksNameTemplate        string `yaml:"picks_name_template"`
+	PicksDescriptionTemplate string `yaml:"picks_description_template"`
+	// PicksLimit is the maximum number of songs in each picks playlist; 0 means no limit.
+	PicksLimit    int    `yaml:"picks_limit"`
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// Popularity configures the split of songs into hits and deep cuts by the popularity
+// command.
+type Popularity struct {
+	// Threshold is the Spotify popularity, 1 to 100, from which a song counts as a hit.
+	Threshold int `yaml:"threshold"`
+	// The name and description templates take .Name (the source, e.g. "Liked Songs"),
+	// .TrackCount, .Duration and .Date.
+	HitsNameTemplate            string `yaml:"hits_name_template"`
+	HitsDescriptionTemplate     string `yaml:"hits_description_template"`
+	DeepCutsNameTemplate        string `yaml:"deep_cuts_name_template"`
+	DeepCutsDescriptionTemplate string `yaml:"deep_cuts_description_template"`
+	CoverSubtitle               string `yaml:"cover_subtitle"`
+}
+
+// Workout configures the BPM-ordered workout playlist built by the workout command.
+type Workout struct {
+	// NameTemplate and DescriptionTemplate take .TrackCount, .Duration and .Date.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// Phases are the parts of the workout, in order.
+	Phases        []WorkoutPhase `yaml:"phases"`
+	CoverSubtitle string         `yaml:"cover_subtitle"`
+}
+
+// WorkoutPhase is a part of the workout filled with songs whose tempo is between MinBPM
+// and MaxBPM, both included, for about Minutes minutes.
+type WorkoutPhase struct {
+	Name    string  `yaml:"name"`
+	MinBPM  float64 `yaml:"min_bpm"`
+	MaxBPM  float64 `yaml:"max_bpm"`
+	Minutes int     `yaml:"minutes"`
+}
+
+// Seasons configures the playlists of the seasons command, one per season across all
+// years.
+type Seasons struct {
+	// NameTemplate and DescriptionTemplate take .Name (the season, e.g. "Summer"),
+	// .TrackCount, .Duration and .Date.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	// Hemisphere is "north" (the default) or "south", where summer runs from December to
+	// February.
+	Hemisphere string `yaml:"hemisphere"`
+	// Names are the display names of spring, summer, autumn and winter, in that order.
+	Names         []string `yaml:"names"`
+	CoverSubtitle string   `yaml:"cover_subtitle"`
+}
+
+// Completionist configures which albums the complete-albums command saves. An album
+// qualifies when at least MinTracks or MinPercent of its tracks are liked.
+type Completionist struct {
+	MinTracks  int     `yaml:"min_tracks"`
+	MinPercent float64 `yaml:"min_percent"`
+	// NameTemplate and DescriptionTemplate name the playlist of the qualifying albums'
+	// tracks that aren't liked yet; they take .TrackCount, .Duration and .Date.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+	CoverSubtitle       string `yaml:"cover_subtitle"`
+}
+
+// SmartPlaylist declares a playlist of the liked songs matching Rule.
+type SmartPlaylist struct {
+	// Name is used verbatim; Description is a template taking .Name, .TrackCount,
+	// .Duration and .Date.
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Rule        Rule     `yaml:"rule"`
+	Order       Ordering `yaml:"order"`
+	// Limit keeps only the first tracks in order; 0 means no limit.
+	Limit         int    `yaml:"limit"`
+	CoverSubtitle string `yaml:"cover_subtitle"`
+}
+
+// Rule is a condition on a liked song. Every condition set on a rule must hold; All, Any
+// and Not combine nested rules.
+type Rule struct {
+	All []Rule `yaml:"all"`
+	Any []Rule `yaml:"any"`
+	Not *Rule  `yaml:"not"`
+	// Title, Artist and Genre are case-insensitive glob patterns, matched against the
+	// song's title, every artist of the song and the genres of its primary artist.
+	Title  string `yaml:"title"`
+	Artist string `yaml:"artist"`
+	Genre  string `yaml:"genre"`
+	// Tag is a glob pattern matched against the song's local tags (see the tag command).
+	// Tagged songs match even if they aren't liked.
+	Tag string `yaml:"tag"`
+	// AddedAfter (included) and AddedBefore (excluded) are dates, e.g. "2024-01-01", read
+	// in the sorter's time zone. AddedWithinDays counts back from now.
+	AddedAfter      string `yaml:"added_after"`
+	AddedBefore     string `yaml:"added_before"`
+	AddedWithinDays int    `yaml:"added_within_days"`
+	Explicit        *bool  `yaml:"explicit"`
+	Popularity      *Range `yaml:"popularity"` // 0-100
+	ReleaseYear     *Range `yaml:"release_year"`
+	// Features bounds audio features by name: acousticness, danceability, energy,
+	// instrumentalness, liveness, speechiness and valence (0-1), loudness (dB) and tempo
+	// (BPM).
+	Features map[string]Range `yaml:"features"`
+}
+
+// Range bounds a number; either end may be omitted. Both ends are included.
+type Range struct {
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+// Charts configures the chart playlist archiver.
+type Charts struct {
+	// Playlists are the chart playlists to snapshot, e.g. the "Top 50" of each country.
+	Playlists []ChartPlaylist `yaml:"playlists"`
+	// Target is "playlist" to copy each snapshot into a dated playlist, or "json" to
+	// write it to JSONDir.
+	Target  string `yaml:"target"`
+	JSONDir string `yaml:"json_dir"`
+	// NameTemplate and DescriptionTemplate name archive playlists. Available fields:
+	// .Name (the chart's name), .TrackCount, .Duration and .Date.
+	NameTemplate        string `yaml:"name_template"`
+	DescriptionTemplate string `yaml:"description_template"`
+}
+
+// ChartPlaylist identifies one chart to archive.
+type ChartPlaylist struct {
+	Name       string `yaml:"name"`
+	PlaylistID string `yaml:"playlist_id"`
+}
+
+// Folders configures the virtual folder manifest.
+type Folders struct {
+	// Manifest is the YAML file describing the desired folder structure.
+	Manifest string `yaml:"manifest"`
+	// PrefixNames renames playlists to "[Folder/Path] Name" so the structure is visible
+	// even outside the desktop client.
+	PrefixNames bool `yaml:"prefix_names"`
+}
+
+// Lists names the files of tracks never to write to a playlist and tracks to pin into
+// playlists. Missing files are empty lists.
+type Lists struct {
+	// Blocklist has one spotify:track:, spotify:artist: or spotify:album: URI per line.
+	Blocklist string `yaml:"blocklist"`
+	// Allowlist is a YAML file of pins, each naming playlist patterns and track URIs.
+	Allowlist string `yaml:"allowlist"`
+}
+
+// Split configures the playlist splitter.
+type Split struct {
+	// PartSize is the number of tracks per part, at most 10000.
+	PartSize int `yaml:"part_size"`
+}
+
+// Cache configures the on-disk cache of downloaded images such as album art.
+type Cache struct {
+	// Dir holds the cached files. Empty disables the cache.
+	Dir string `yaml:"dir"`
+	// MaxSizeMB is the size past which the least recently used files are evicted.
+	MaxSizeMB int64 `yaml:"max_size_mb"`
+	// CatalogTTL is how long artist, track and album lookups are reused by later runs.
+	// LibraryTTL is the same for your liked songs and playlists; runs within it don't see
+	// changes made in the app. 0 reuses responses within a run only.
+	CatalogTTL time.Duration `yaml:"catalog_ttl"`
+	LibraryTTL time.Duration `yaml:"library_ttl"`
+}
+
+// RateLimit caps how fast requests are sent to Spotify, e.g. for unattended daemon runs.
+type RateLimit struct {
+	// RequestsPerSecond is the average rate allowed; 0 doesn't limit requests.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Burst is how many requests may be sent at once before the rate applies.
+	Burst int `yaml:"burst"`
+	// BatchSize caps the songs, albums or artists changed per request, below the 50 or
+	// 100 Spotify accepts; 0 keeps those. BatchDelay is the pause between such requests.
+	BatchSize  int           `yaml:"batch_size"`
+	BatchDelay time.Duration `yaml:"batch_delay"`
+}
+
+// Matching tunes how tracks without a Spotify ID are matched to catalog tracks.
+type Matching struct {
+	Weights MatchWeights `yaml:"weights"`
+	// Threshold is the minimum score, between 0 and 1, for a candidate to count as a match.
+	Threshold float64 `yaml:"threshold"`
+	// DurationTolerance is how far apart two durations can be and still fully match.
+	DurationTolerance time.Duration `yaml:"duration_tolerance"`
+	// ReviewBelow flags matches scoring under it, but above Threshold, for review in
+	// import reports.
+	ReviewBelow float64 `yaml:"review_below"`
+}
+
+// MatchWeights sets how much each field contributes to a match score. Fields missing
+// on either side are left out.
+type MatchWeights struct {
+	Title    float64 `yaml:"title"`
+	Artist   float64 `yaml:"artist"`
+	ISRC     float64 `yaml:"isrc"`
+	Duration float64 `yaml:"duration"`
+}
+
+// Daemon configures the jobs run by the daemon command.
+type Daemon struct {
+	Jobs []Job `yaml:"jobs"`
+	// Email is where the jobs that ask for it send a summary of their runs.
+	Email Email `yaml:"email"`
+}
+
+// Job runs a command on a fixed interval, e.g. "24h".
+type Job struct {
+	Command  string        `yaml:"command"`
+	Args     []string      `yaml:"args"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+	// Notify emails a summary of each run ("always") or of failed runs only ("failure").
+	// Empty sends nothing.
+	Notify string `yaml:"notify"`
+}
+
+// Email configures the SMTP server the daemon sends run summaries through. The password
+// comes from the SPOTIFY_MANAGER_SMTP_PASSWORD environment variable.
+type Email struct {
+	Host string `yaml:"host"`
+	// Port defaults to 587. The connection is upgraded with STARTTLS when the server
+	// offers it.
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// Pipeline runs commands one after another in a single session.
+type Pipeline struct {
+	Name  string `yaml:"name"`
+	Steps []Step `yaml:"steps"`
+	// OnError is "stop" to end the pipeline at the first failing step, or "continue" to
+	// run the remaining steps anyway.
+	OnError string `yaml:"on_error"`
+}
+
+// Step is one command of a pipeline, with its arguments.
+type Step struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+}
+
+// Default returns the configuration used when no file is present.
+func Default() Config {
+	return Config{
+		Playlists: Playlists{
+			StatsStamp: true,
+		},
+		Sorter: Sorter{
+			NameTemplate:               "Liked Songs ({{.Period}})",
+			DescriptionTemplate:        "All songs I liked that were added in {{.Period}}.",
+			CoverSubtitle:              "Liked Songs",
+			GroupBy:                    "liked",
+			ReleaseNameTemplate:        "Music of {{.Year}}",
+			ReleaseDescriptionTemplate: "Songs I liked that came out in {{.Year}}.",
+			ReleaseCoverSubtitle:       "Music of",
+			HugeLibrary:                20000,
+			Parallelism:                3,
+			Order: Ordering{
+				Strategy:      "added",
+				ArtistSpacing: 5,
+				GenreSpacing:  1,
+			},
+		},
+		TopPlayed: TopPlayed{
+			NameTemplate:        "Most Played of {{.Year}}",
+			DescriptionTemplate: "My {{.TrackCount}} most played songs of {{.Year}}, ranked by listening time ({{.Duration}}).",
+			Limit:               100,
+			MinPlaySeconds:      30,
+			MinPlays:            2,
+			CoverSubtitle:       "Most Played",
+		},
+		Languages: Languages{
+			NameTemplate:        "Liked · {{.Name}}",
+			DescriptionTemplate: "My liked songs sung in {{.Name}}.",
+			MinTracks:           5,
+			UnknownName:         "Other",
+			CoverSubtitle:       "Liked Songs",
+		},
+		DateRange: DateRange{
+			DescriptionTemplate: "Songs I liked between {{.From}} and {{.To}}.",
+		},
+		OnThisDay: OnThisDay{
+			NameTemplate:        "On This Day",
+			DescriptionTemplate: "Songs I liked around {{.Date}} in previous years.",
+			WindowDays:          3,
+			CoverSubtitle:       "On This Day",
+		},
+		Rolling: Rolling{
+			NameTemplate:        "Last {{.Days}} Days",
+			DescriptionTemplate: "Everything I liked in the last {{.Days}} days.",
+			Days:                90,
+			CoverSubtitle:       "Recently Liked",
+		},
+		ForgottenGems: ForgottenGems{
+			NameTemplate:        "Forgotten Gems",
+			DescriptionTemplate: "Songs I liked years ago and haven't played in a while.",
+			LikedYearsAgo:       2,
+			NotPlayedMonths:     12,
+			MinPlaySeconds:      30,
+			Limit:               100,
+			CoverSubtitle:       "Forgotten Gems",
+		},
+		Completionist: Completionist{
+			MinTracks:           6,
+			MinPercent:          60,
+			NameTemplate:        "Complete the Album",
+			DescriptionTemplate: "{{.TrackCount}} songs I haven't liked yet from albums I almost fully like.",
+			CoverSubtitle:       "Complete the Album",
+		},
+		Workout: Workout{
+			NameTemplate:        "Workout",
+			DescriptionTemplate: "{{.TrackCount}} songs, {{.Duration}}: warm up, push, cool down.",
+			Phases: []WorkoutPhase{
+				{Name: "warmup", MinBPM: 100, MaxBPM: 120, Minutes: 10},
+				{Name: "main", MinBPM: 140, MaxBPM: 160, Minutes: 40},
+				{Name: "cooldown", MinBPM: 0, MaxBPM: 100, Minutes: 10},
+			},
+			CoverSubtitle: "Workout",
+		},
+		Seasons: Seasons{
+			NameTemplate:        "{{.Name}} Songs",
+			DescriptionTemplate: "The {{.TrackCount}} songs I liked in {{.Name}}, over the years.",
+			Hemisphere:          "north",
+			Names:               []string{"Spring", "Summer", "Autumn", "Winter"},
+			CoverSubtitle:       "Liked Songs",
+		},
+		Popularity: Popularity{
+			Threshold:                   50,
+			HitsNameTemplate:            "{{.Name}} · Hits",
+			HitsDescriptionTemplate:     "The {{.TrackCount}} most popular songs of {{.Name}}.",
+			DeepCutsNameTemplate:        "{{.Name}} · Deep Cuts",
+			DeepCutsDescriptionTemplate: "The {{.TrackCount}} least known songs of {{.Name}}.",
+			CoverSubtitle:               "Popularity",
+		},
+		Blend: Blend{
+			NameTemplate:             "Blend: {{.Name}}",
+			DescriptionTemplate:      "{{.TrackCount}} songs we both like.",
+			PicksNameTemplate:        "Picks from {{.Name}}",
+			PicksDescriptionTemplate: "Songs {{.Name}} likes that aren't in my library yet.",
+			PicksLimit:               100,
+			CoverSubtitle:            "Blend",
+		},
+		LastFM: LastFM{
+			NameTemplate:        "Most Scrobbled of {{.Year}}",
+			DescriptionTemplate: "My {{.TrackCount}} most scrobbled songs of {{.Year}} on Last.fm.",
+			Limit:               100,
+			MinScrobbles:        2,
+			CoverSubtitle:       "Most Scrobbled",
+		},
+		Charts: Charts{
+			Target:              "playlist",
+			JSONDir:             "charts",
+			NameTemplate:        "{{.Name}} · {{.Date}}",
+			DescriptionTemplate: "Snapshot of {{.Name}} taken on {{.Date}}.",
+		},
+		Folders: Folders{
+			Manifest: "folders.yaml",
+		},
+		Lists: Lists{
+			Blocklist: "blocklist.txt",
+			Allowlist: "allowlist.yaml",
+		},
+		Split: Split{
+			PartSize: 1000,
+		},
+		Cache: Cache{
+			Dir:        ".cache/images",
+			MaxSizeMB:  200,
+			CatalogTTL: 7 * 24 * time.Hour,
+		},
+		RateLimit: RateLimit{
+			Burst: 10,
+		},
+		Matching: Matching{
+			Weights: MatchWeights{
+				Title:    3,
+				Artist:   2,
+				ISRC:     6,
+				Duration: 1,
+			},
+			Threshold:         0.8,
+			DurationTolerance: 3 * time.Second,
+			ReviewBelow:       0.95,
+		},
+		Covers: Covers{
+			Style:               "auto",
+			Theme:               "auto",
+			SimilarityThreshold: 10,
+			Text: CoverText{
+				Enabled:  true,
+				Position: "bottom",
+				Size:     120,
+				Color:    "#FFFFFF",
+			},
+			Mosaic: Mosaic{
+				Grid: 2,
+			},
+		},
+	}
+}
+
+// Load reads the config file at path on top of the defaults. A missing file is not an error.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("could not read config '%s': %w", path, err)
+	}
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("could not parse config '%s': %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Check reads the config file at path like Load, but also rejects keys that don't
+// exist, which Load ignores, so typos don't silently fall back to defaults.
+func Check(path string) error {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read config '%s': %w", path, err)
+	}
+	cfg := Default()
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config '%s': %w", path, err)
+	}
+	return nil
+}
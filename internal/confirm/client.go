@@ -0,0 +1,166 @@
+// Package confirm asks before destructive changes to the library, so a misconfigured
+// command can't silently empty playlists or unlike songs.
+package confirm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"spotify/internal/processor"
+	"strings"
+	"sync"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// ErrDeclined is returned for a change the user didn't confirm.
+var ErrDeclined = errors.New("not confirmed")
+
+// Client is a SpotifyClient that asks for a y/N confirmation before clearing or deleting
+// a playlist, removing tracks from one, unliking songs or albums and unfollowing artists.
+// An answer covers the rest of the run: a playlist is asked about once, and so are
+// library removals, which come in many small batches. Other calls pass straight through.
+type Client struct {
+	processor.SpotifyClient
+
+	mu      sync.Mutex
+	in      *bufio.Reader
+	out     io.Writer
+	answers map[string]bool
+}
+
+// NewClient wraps client, asking on out and reading answers from in. Without an answer,
+// e.g. when in isn't a terminal, changes are declined.
+func NewClient(client processor.SpotifyClient, in io.Reader, out io.Writer) *Client {
+	return &Client{
+		SpotifyClient: client,
+		in:            bufio.NewReader(in),
+		out:           out,
+		answers:       make(map[string]bool),
+	}
+}
+
+// confirmedKey marks a context whose changes are confirmed already.
+type confirmedKey struct{}
+
+// WithConfirmed returns a context whose changes pass through without asking, for a task
+// that took its own --yes or asked about the whole plan itself. Other tasks of the same
+// run, such as the next step of a pipeline, still ask.
+func WithConfirmed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, confirmedKey{}, true)
+}
+
+// confirmed reports whether the changes made with ctx are confirmed already.
+func confirmed(ctx context.Context) bool {
+	yes, _ := ctx.Value(confirmedKey{}).(bool)
+	return yes
+}
+
+// ask asks question unless the change identified by key was answered before.
+func (c *Client) ask(key, question string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	yes, asked := c.answers[key]
+	if !asked {
+		fmt.Fprintf(c.out, "⚠️  %s [y/N] ", question)
+		answer, err := c.in.ReadString('\n')
+		if err != nil && answer == "" {
+			fmt.Fprintln(c.out)
+			return fmt.Errorf("%w: %s (no answer; pass --yes to run unattended)", ErrDeclined, question)
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		yes = answer == "y" || answer == "yes"
+		c.answers[key] = yes
+	}
+	if !yes {
+		return fmt.Errorf("%w: %s", ErrDeclined, question)
+	}
+	return nil
+}
+
+// playlist returns the name and track count of a playlist, for the question.
+func (c *Client) playlist(ctx context.Context, playlistID spotify.ID) (string, int, error) {
+	playlist, err := c.SpotifyClient.GetPlaylist(ctx, playlistID, spotify.Fields("name,tracks.total"))
+	if err != nil {
+		return "", 0, fmt.Errorf("could not read playlist to confirm the change: %w", err)
+	}
+	return playlist.Name, int(playlist.Tracks.Total), nil
+}
+
+// ReplacePlaylistItems asks before clearing a playlist that has tracks; new playlists
+// are filled without asking.
+func (c *Client) ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error) {
+	if confirmed(ctx) {
+		return c.SpotifyClient.ReplacePlaylistItems(ctx, playlistID, items...)
+	}
+	name, total, err := c.playlist(ctx, playlistID)
+	if err != nil {
+		return "", err
+	}
+	if total > 0 {
+		if err := c.ask("playlist/"+string(playlistID), fmt.Sprintf("Replace the %d tracks of '%s'?", total, name)); err != nil {
+			return "", err
+		}
+	}
+	return c.SpotifyClient.ReplacePlaylistItems(ctx, playlistID, items...)
+}
+
+func (c *Client) RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	if confirmed(ctx) {
+		return c.SpotifyClient.RemoveTracksFromPlaylist(ctx, playlistID, trackIDs...)
+	}
+	name, total, err := c.playlist(ctx, playlistID)
+	if err != nil {
+		return "", err
+	}
+	if err := c.ask("playlist/"+string(playlistID), fmt.Sprintf("Remove %d tracks from '%s' (%d tracks)?", len(trackIDs), name, total)); err != nil {
+		return "", err
+	}
+	return c.SpotifyClient.RemoveTracksFromPlaylist(ctx, playlistID, trackIDs...)
+}
+
+func (c *Client) UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error {
+	if confirmed(ctx) {
+		return c.SpotifyClient.UnfollowPlaylist(ctx, playlistID)
+	}
+	name, total, err := c.playlist(ctx, playlistID)
+	if err != nil {
+		return err
+	}
+	if err := c.ask("playlist/"+string(playlistID), fmt.Sprintf("Delete '%s' (%d tracks)?", name, total)); err != nil {
+		return err
+	}
+	return c.SpotifyClient.UnfollowPlaylist(ctx, playlistID)
+}
+
+func (c *Client) RemoveTracksFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	if confirmed(ctx) {
+		return c.SpotifyClient.RemoveTracksFromLibrary(ctx, ids...)
+	}
+	if err := c.ask("liked", fmt.Sprintf("Unlike %d songs? Later removals in this run won't ask again.", len(ids))); err != nil {
+		return err
+	}
+	return c.SpotifyClient.RemoveTracksFromLibrary(ctx, ids...)
+}
+
+func (c *Client) UnfollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	if confirmed(ctx) {
+		return c.SpotifyClient.UnfollowArtist(ctx, ids...)
+	}
+	if err := c.ask("artists", fmt.Sprintf("Unfollow %d artists? Later removals in this run won't ask again.", len(ids))); err != nil {
+		return err
+	}
+	return c.SpotifyClient.UnfollowArtist(ctx, ids...)
+}
+
+func (c *Client) RemoveAlbumsFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	if confirmed(ctx) {
+		return c.SpotifyClient.RemoveAlbumsFromLibrary(ctx, ids...)
+	}
+	if err := c.ask("albums", fmt.Sprintf("Remove %d saved albums? Later removals in this run won't ask again.", len(ids))); err != nil {
+		return err
+	}
+	return c.SpotifyClient.RemoveAlbumsFromLibrary(ctx, ids...)
+}
@@ -0,0 +1,36 @@
+package confirm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"spotify/internal/spotifytest"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestWithConfirmedIsScopedToItsContext(t *testing.T) {
+	f := spotifytest.Fixture{
+		User:   spotify.PrivateUser{User: spotify.User{ID: "me"}},
+		Tracks: []spotify.FullTrack{{SimpleTrack: spotify.SimpleTrack{ID: "t1"}}, {SimpleTrack: spotify.SimpleTrack{ID: "t2"}}},
+		Liked:  []spotifytest.Saved{{ID: "t1"}, {ID: "t2"}},
+	}
+	library, err := spotifytest.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nobody answers, so anything asked is declined.
+	client := NewClient(library, strings.NewReader(""), io.Discard)
+
+	if err := client.RemoveTracksFromLibrary(WithConfirmed(context.Background()), "t1"); err != nil {
+		t.Fatalf("confirmed removal: %v", err)
+	}
+	if err := client.RemoveTracksFromLibrary(context.Background(), "t2"); !errors.Is(err, ErrDeclined) {
+		t.Errorf("removal after a confirmed task = %v, want it asked and declined", err)
+	}
+	if liked := library.Fixture().Liked; len(liked) != 1 || liked[0].ID != "t2" {
+		t.Errorf("liked songs = %v, want only t2 left", liked)
+	}
+}
@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"spotify/internal/processor"
+	"spotify/internal/store"
+	"time"
+)
+
+// Job is a processor that the daemon runs on a fixed interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Timeout bounds a single run; zero means no limit.
+	Timeout time.Duration
+	Task    processor.Processor
+	// Notify emails a summary of each run ("always") or of failed runs only ("failure").
+	Notify string
+}
+
+// Notifier delivers the summary of a job run.
+type Notifier interface {
+	Send(subject, body string) error
+}
+
+type scheduler struct {
+	jobs     []Job
+	store    *store.Store
+	logger   *log.Logger
+	notifier Notifier
+}
+
+// New returns a Processor that runs every job immediately and then again each time its
+// interval elapses, until the context is cancelled. Jobs run one at a time so they never
+// compete for the API rate limit, and a failing job doesn't stop the others. Jobs with
+// Notify set send their summary through notifier, which may be nil when none do.
+func New(jobs []Job, st *store.Store, logger *log.Logger, notifier Notifier) processor.Processor {
+	return &scheduler{
+		jobs:     jobs,
+		store:    st,
+		logger:   logger,
+		notifier: notifier,
+	}
+}
+
+// Run executes due jobs until ctx is cancelled.
+func (s *scheduler) Run(ctx context.Context) error {
+	next := make([]time.Time, len(s.jobs))
+	for {
+		now := time.Now()
+		wakeAt := now.Add(24 * time.Hour)
+		for i, job := range s.jobs {
+			if !next[i].After(now) {
+				s.runJob(ctx, job)
+				next[i] = time.Now().Add(job.Interval)
+				s.logger.Printf("⏰ Next '%s' run at %s.", job.Name, next[i].Format(time.DateTime))
+			}
+			if next[i].Before(wakeAt) {
+				wakeAt = next[i]
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Println("Daemon stopping.")
+			return nil
+		case <-time.After(time.Until(wakeAt)):
+		}
+	}
+}
+
+// runJob runs a single job, persisting the store afterwards so collected data survives restarts.
+func (s *scheduler) runJob(ctx context.Context, job Job) {
+	jobCtx, cancel := ctx, context.CancelFunc(func() {})
+	if job.Timeout > 0 {
+		jobCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+	}
+	defer cancel()
+
+	// Capture the job's log for the summary while still writing it as usual.
+	var report bytes.Buffer
+	if job.Notify != "" && s.notifier != nil {
+		out := s.logger.Writer()
+		s.logger.SetOutput(io.MultiWriter(out, &report))
+		defer s.logger.SetOutput(out)
+	}
+
+	started := time.Now()
+	s.logger.Printf("▶️  Running job '%s'...", job.Name)
+	err := job.Task.Run(jobCtx)
+	if errors.Is(err, processor.ErrNothingToDo) {
+		err = nil
+	}
+	if err != nil {
+		s.logger.Printf("❌ Job '%s' failed: %v", job.Name, err)
+	} else {
+		s.logger.Printf("✅ Job '%s' finished.", job.Name)
+	}
+	if err := s.store.Save(); err != nil {
+		s.logger.Printf("⚠️  Could not save local store: %v", err)
+	}
+	s.notify(job, started, err, report.String())
+}
+
+// notify sends the summary of a run when the job asks for it.
+func (s *scheduler) notify(job Job, started time.Time, runErr error, report string) {
+	if s.notifier == nil || job.Notify == "" || (job.Notify == "failure" && runErr == nil) {
+		return
+	}
+	status := "succeeded"
+	if runErr != nil {
+		status = "failed"
+	}
+	subject := fmt.Sprintf("spotify-manager: job '%s' %s", job.Name, status)
+	body := fmt.Sprintf("Job:      %s\nStarted:  %s\nDuration: %s\nStatus:   %s\n",
+		job.Name, started.Format(time.DateTime), time.Since(started).Round(time.Second), status)
+	if runErr != nil {
+		body += fmt.Sprintf("Error:    %v\n", runErr)
+	}
+	body += "\n" + report
+	if err := s.notifier.Send(subject, body); err != nil {
+		s.logger.Printf("⚠️  Could not email the summary of '%s': %v", job.Name, err)
+	}
+}
@@ -0,0 +1,91 @@
+package folders
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes the desired folder structure of the user's playlists. Spotify's
+// folders aren't reachable through the Web API, so the manifest drives reconciliation
+// instructions for the desktop client and an optional name-prefix fallback.
+type Manifest struct {
+	Folders []Folder `yaml:"folders"`
+}
+
+// Folder is a desktop-client folder, given as a slash separated path such as
+// "Archive/Yearly", and the playlists that belong in it as name glob patterns.
+type Folder struct {
+	Path      string   `yaml:"path"`
+	Playlists []string `yaml:"playlists"`
+}
+
+// Load reads a manifest file. A missing file yields an empty manifest.
+func Load(file string) (*Manifest, error) {
+	m := &Manifest{}
+	raw, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read folder manifest '%s': %w", file, err)
+	}
+	if err := yaml.Unmarshal(raw, m); err != nil {
+		return nil, fmt.Errorf("could not parse folder manifest '%s': %w", file, err)
+	}
+	for _, f := range m.Folders {
+		for _, pattern := range f.Playlists {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid playlist pattern '%s' in folder '%s': %w", pattern, f.Path, err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// FolderFor returns the path of the first folder whose patterns match the playlist name,
+// ignoring any folder prefix the name already carries.
+func (m *Manifest) FolderFor(name string) (string, bool) {
+	base := StripPrefix(name)
+	for _, f := range m.Folders {
+		for _, pattern := range f.Playlists {
+			if ok, _ := path.Match(pattern, base); ok {
+				return f.Path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// prefixPattern matches the folder prefix added by PrefixedName, e.g. "[Archive/Yearly] ".
+var prefixPattern = regexp.MustCompile(`^\[[^\]]*\] `)
+
+// PrefixedName returns name filed under folder using the prefix-naming fallback.
+func PrefixedName(folder, name string) string {
+	base := StripPrefix(name)
+	if folder == "" {
+		return base
+	}
+	return "[" + folder + "] " + base
+}
+
+// StripPrefix removes a folder prefix from a playlist name, if present.
+func StripPrefix(name string) string {
+	return prefixPattern.ReplaceAllString(name, "")
+}
+
+// Parents returns every ancestor path of folder, outermost first, including folder itself.
+func Parents(folder string) []string {
+	parts := strings.Split(folder, "/")
+	paths := make([]string, len(parts))
+	for i := range parts {
+		paths[i] = strings.Join(parts[:i+1], "/")
+	}
+	return paths
+}
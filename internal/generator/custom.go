@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"spotify/internal/processor"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+var customExtensions = []string{".jpg", ".jpeg", ".png"}
+
+// renderCustom loads the user's own cover for spec from the custom directory, matched by
+// playlist name first and label (e.g. "2021.jpg") second. It returns nil when no custom
+// cover exists.
+func (g *imageGenerator) renderCustom(spec processor.CoverSpec) (*gg.Context, error) {
+	if g.cfg.CustomDir == "" {
+		return nil, nil
+	}
+	for _, base := range []string{spec.Name, spec.Label} {
+		if base == "" {
+			continue
+		}
+		// Playlist names may contain characters that can't appear in file names.
+		base = strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(base)
+		for _, ext := range customExtensions {
+			dc, err := loadCanvas(filepath.Join(g.cfg.CustomDir, base+ext))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return dc, err
+		}
+	}
+	return nil, nil
+}
+
+// renderTemplate returns the configured template image as the base of the cover, or nil
+// when there is none.
+func (g *imageGenerator) renderTemplate() (*gg.Context, error) {
+	if g.cfg.TemplateImage == "" {
+		return nil, nil
+	}
+	return loadCanvas(g.cfg.TemplateImage)
+}
+
+// loadCanvas decodes the image file at path onto a cover-sized canvas.
+func loadCanvas(path string) (*gg.Context, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode cover image '%s': %w", path, err)
+	}
+	dc := gg.NewContext(imgWidth, imgHeight)
+	drawScaled(dc, img, 0, 0, imgWidth)
+	return dc, nil
+}
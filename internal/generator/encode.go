@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	"github.com/fogleman/gg"
+)
+
+// maxUploadSize is the largest cover Spotify accepts. The limit applies to the
+// base64-encoded JPEG that is sent to the API.
+const maxUploadSize = 256 * 1024
+
+// encodeCover encodes img as a JPEG that fits Spotify's upload limit, lowering the
+// quality first and then the resolution until it does.
+func encodeCover(img image.Image) (*bytes.Buffer, error) {
+	for _, size := range []int{imgWidth, 480, 320} {
+		scaled := img
+		if size != img.Bounds().Dx() {
+			dc := gg.NewContext(size, size)
+			drawScaled(dc, img, 0, 0, size)
+			scaled = dc.Image()
+		}
+		for _, quality := range []int{95, 90, 85, 75, 65, 55} {
+			buf := new(bytes.Buffer)
+			if err := jpeg.Encode(buf, scaled, &jpeg.Options{Quality: quality}); err != nil {
+				return nil, fmt.Errorf("failed to encode image to jpeg: %w", err)
+			}
+			if base64.StdEncoding.EncodedLen(buf.Len()) <= maxUploadSize {
+				return buf, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("cover doesn't fit the %d KB upload limit even at low quality", maxUploadSize/1024)
+}
@@ -1,13 +1,15 @@
 package generator
 
 import (
-	"bytes"
 	"fmt"
-	"image/jpeg"
 	"io"
 	"math"
 	"math/rand"
+	"spotify/internal/assets"
+	"spotify/internal/config"
 	"spotify/internal/processor"
+	"strings"
+	"sync"
 
 	"hash/fnv"
 
@@ -17,64 +19,177 @@ import (
 const (
 	imgWidth  = 640
 	imgHeight = 640
+
+	// maxReseeds bounds how many alternative seeds are tried to escape a cover collision.
+	maxReseeds = 8
 )
 
-type imageGenerator struct{}
+// HashRegistry persists the perceptual hashes of generated covers so collisions can be
+// detected across runs.
+type HashRegistry interface {
+	CoverHashes() map[string]uint64
+	SetCoverHash(name string, hash uint64)
+}
+
+type imageGenerator struct {
+	cfg      config.Covers
+	registry HashRegistry
+	assets   *assets.Cache
+	style    style // nil derives the style from the playlist name
+
+	mu     sync.Mutex
+	hashes map[string]uint64
+}
+
+// NewImageGenerator creates a new generator. If registry is non-nil, covers that look
+// too similar to another managed playlist's cover are re-seeded until they're distinct.
+// Album art for mosaics is loaded through cache. It fails if the configured style
+// doesn't exist.
+func NewImageGenerator(cfg config.Covers, registry HashRegistry, cache *assets.Cache) (processor.ImageGenerator, error) {
+	var selected style
+	if cfg.Style != "" && cfg.Style != "auto" {
+		var ok bool
+		if selected, ok = styles[cfg.Style]; !ok {
+			return nil, fmt.Errorf("unknown cover style '%s' (available: auto, %s)", cfg.Style, strings.Join(styleNames(), ", "))
+		}
+	}
+
+	for _, name := range append([]string{cfg.Theme}, sortedValues(cfg.Themes)...) {
+		if _, ok := themes[name]; !ok && name != "" && name != "auto" && name != "none" {
+			return nil, fmt.Errorf("unknown cover theme '%s' (available: auto, none, %s)", name, strings.Join(themeNames(), ", "))
+		}
+	}
+
+	if cfg.Mosaic.Enabled && cfg.Mosaic.Grid != 2 && cfg.Mosaic.Grid != 3 {
+		return nil, fmt.Errorf("mosaic grid must be 2 or 3, got %d", cfg.Mosaic.Grid)
+	}
+
+	hashes := make(map[string]uint64)
+	if registry != nil {
+		hashes = registry.CoverHashes()
+	}
+	return &imageGenerator{
+		cfg:      cfg,
+		registry: registry,
+		assets:   cache,
+		style:    selected,
+		hashes:   hashes,
+	}, nil
+}
+
+// GenerateForPlaylist creates the cover for a playlist. In order of preference it uses a
+// user-supplied image as-is, a template image with the label overlaid, an album-art
+// mosaic, or procedural artwork, re-seeded if it collides with another playlist's cover.
+// It's safe to call concurrently: only the collision check is serialized.
+func (g *imageGenerator) GenerateForPlaylist(spec processor.CoverSpec) (io.Reader, error) {
+	name := spec.Name
+	dc, err := g.renderCustom(spec)
+	if err != nil {
+		return nil, err
+	}
+	custom := dc != nil
+	if dc == nil {
+		dc, err = g.renderTemplate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if dc == nil {
+		dc = g.renderMosaic(spec)
+	}
+
+	if dc != nil {
+		g.claim(name, differenceHash(dc.Image()), true)
+	} else {
+		for attempt := range maxReseeds {
+			dc = g.render(name, uint64(attempt))
+			// Hash the artwork alone: the label would make any two covers look distinct.
+			if g.claim(name, differenceHash(dc.Image()), attempt == maxReseeds-1) {
+				break
+			}
+		}
+	}
+
+	if g.cfg.Text.Enabled && !custom {
+		drawText(dc, spec, g.cfg.Text)
+	}
+	// Encode the final image to a JPEG small enough to upload.
+	return encodeCover(dc.Image())
+}
+
+// claim records hash as the cover of name unless it collides with another playlist's
+// cover, and reports whether it was recorded. With force it's recorded regardless.
+func (g *imageGenerator) claim(name string, hash uint64, force bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !force && g.registry != nil && g.collides(name, hash) {
+		return false
+	}
+	g.hashes[name] = hash
+	if g.registry != nil {
+		g.registry.SetCoverHash(name, hash)
+	}
+	return true
+}
+
+// renderMosaic builds an album-art mosaic when enabled, returning nil when it's disabled
+// or can't be built so the caller falls back to procedural art. Mosaics come from real
+// artwork and are never re-seeded.
+func (g *imageGenerator) renderMosaic(spec processor.CoverSpec) *gg.Context {
+	if !g.cfg.Mosaic.Enabled {
+		return nil
+	}
+	dc, err := renderMosaic(spec.Tracks, g.cfg.Mosaic.Grid, g.assets)
+	if err != nil {
+		return nil
+	}
+	return dc
+}
 
-// NewImageGenerator creates a new generator.
-func NewImageGenerator() processor.ImageGenerator {
-	return &imageGenerator{}
+// collides reports whether hash is within the similarity threshold of another playlist's
+// cover. The caller must hold g.mu.
+func (g *imageGenerator) collides(name string, hash uint64) bool {
+	if g.cfg.SimilarityThreshold <= 0 {
+		return false
+	}
+	for other, otherHash := range g.hashes {
+		if other != name && hashDistance(hash, otherHash) <= g.cfg.SimilarityThreshold {
+			return true
+		}
+	}
+	return false
 }
 
-// GenerateForPlaylist creates an image with flowing, multi-colored waves.
-func (g *imageGenerator) GenerateForPlaylist(name string) (io.Reader, error) {
+// render draws the cover artwork for name. A non-zero reseed derives an alternative seed.
+func (g *imageGenerator) render(name string, reseed uint64) *gg.Context {
 	// 1. Create a deterministic seed from the playlist name.
 	h := fnv.New64a()
 	h.Write([]byte(name))
-	seed := h.Sum64()
+	nameHash := h.Sum64()
+	seed := nameHash + reseed*0x9e3779b97f4a7c15
 	rng := rand.New(rand.NewSource(int64(seed)))
 
-	// 2. Generate a harmonious color palette from the seed.
+	// 2. Generate a harmonious color palette from the seed, in seasonal colors when the
+	// playlist has a theme.
 	palette := generateAnalogousPalette(rng)
+	if t, ok := themes[g.themeFor(name)]; ok {
+		palette = themedPalette(rng, t)
+	}
 
 	// 3. Setup the drawing context and a dark background.
 	dc := gg.NewContext(imgWidth, imgHeight)
 	dc.SetRGB(0.1, 0.1, 0.15)
 	dc.Clear()
 
-	// 4. Draw several layers of waves.
-	for range 7 {
-		color := palette[rng.Intn(len(palette))]
-		dc.SetRGB(color[0], color[1], color[2])
-
-		// Randomize wave properties for variety.
-		lineWidth := 2 + rng.Float64()*15
-		amplitude := 50 + rng.Float64()*100
-		frequency := 0.5 + rng.Float64()*2
-		yOffset := float64(imgHeight/2) + (rng.Float64()-0.5)*300
-
-		dc.SetLineWidth(lineWidth)
-
-		// Draw a single sine wave across the canvas.
-		for x := 0.0; x < float64(imgWidth); x++ {
-			y := yOffset + math.Sin(x/float64(imgWidth)*math.Pi*2*frequency)*amplitude
-			if x == 0 {
-				dc.MoveTo(x, y)
-			} else {
-				dc.LineTo(x, y)
-			}
-		}
-		dc.Stroke()
-	}
-
-	// 5. Encode the final image to a JPEG.
-	img := dc.Image()
-	buf := new(bytes.Buffer)
-	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 95}); err != nil {
-		return nil, fmt.Errorf("failed to encode image to jpeg: %w", err)
+	// 4. Draw the artwork in the configured style, or one picked from the name so the
+	// choice survives re-seeding.
+	draw := g.style
+	if draw == nil {
+		draw = styles[styleNames()[nameHash%uint64(len(styles))]]
 	}
+	draw(dc, rng, palette)
 
-	return buf, nil
+	return dc
 }
 
 // generateAnalogousPalette creates a set of 3 harmonious colors.
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"spotify/internal/assets"
+
+	"github.com/fogleman/gg"
+	"github.com/zmb3/spotify/v2"
+)
+
+// renderMosaic composes a grid×grid mosaic from the album art of the first distinct
+// albums in tracks. It fails when there aren't enough albums with art, so the caller can
+// fall back to procedural artwork. Album art is fetched through cache.
+func renderMosaic(tracks []spotify.FullTrack, grid int, cache *assets.Cache) (*gg.Context, error) {
+	cellSize := imgWidth / grid
+	urls := albumArtURLs(tracks, grid*grid, cellSize)
+	if len(urls) < grid*grid {
+		return nil, fmt.Errorf("need %d albums with art for a mosaic, found %d", grid*grid, len(urls))
+	}
+
+	dc := gg.NewContext(imgWidth, imgHeight)
+	for i, url := range urls {
+		img, err := cache.Image(url)
+		if err != nil {
+			return nil, fmt.Errorf("could not load album art: %w", err)
+		}
+		drawScaled(dc, img, (i%grid)*cellSize, (i/grid)*cellSize, cellSize)
+	}
+	return dc, nil
+}
+
+// albumArtURLs returns up to n image URLs, one per distinct album in track order, choosing
+// the smallest image that is still at least minSize pixels wide.
+func albumArtURLs(tracks []spotify.FullTrack, n, minSize int) []string {
+	seen := make(map[spotify.ID]struct{})
+	var urls []string
+	for _, t := range tracks {
+		if len(urls) == n {
+			break
+		}
+		if _, dup := seen[t.Album.ID]; dup || len(t.Album.Images) == 0 {
+			continue
+		}
+		seen[t.Album.ID] = struct{}{}
+
+		best := t.Album.Images[0] // Spotify lists images widest first
+		for _, img := range t.Album.Images {
+			if int(img.Width) >= minSize && img.Width < best.Width {
+				best = img
+			}
+		}
+		urls = append(urls, best.URL)
+	}
+	return urls
+}
+
+// drawScaled draws img stretched into the size×size square at (x, y).
+func drawScaled(dc *gg.Context, img image.Image, x, y, size int) {
+	b := img.Bounds()
+	dc.Push()
+	dc.Translate(float64(x), float64(y))
+	dc.Scale(float64(size)/float64(b.Dx()), float64(size)/float64(b.Dy()))
+	dc.DrawImage(img, -b.Min.X, -b.Min.Y)
+	dc.Pop()
+}
@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"image"
+	"math/bits"
+)
+
+// differenceHash computes a 64-bit perceptual hash (dHash) of img: the image is reduced
+// to a 9x8 grid of average luminance and each bit records whether a cell is brighter
+// than its right-hand neighbour. Visually similar images have hashes that differ in
+// few bits.
+func differenceHash(img image.Image) uint64 {
+	const cols, rows = 9, 8
+	var grid [rows][cols]float64
+
+	b := img.Bounds()
+	for gy := range rows {
+		y0 := b.Min.Y + gy*b.Dy()/rows
+		y1 := b.Min.Y + (gy+1)*b.Dy()/rows
+		for gx := range cols {
+			x0 := b.Min.X + gx*b.Dx()/cols
+			x1 := b.Min.X + (gx+1)*b.Dx()/cols
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, bl, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+				}
+			}
+			grid[gy][gx] = sum / float64((x1-x0)*(y1-y0))
+		}
+	}
+
+	var hash uint64
+	for y := range rows {
+		for x := range cols - 1 {
+			hash <<= 1
+			if grid[y][x] > grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// hashDistance returns the number of differing bits between two perceptual hashes.
+func hashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
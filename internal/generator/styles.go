@@ -0,0 +1,190 @@
+package generator
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/fogleman/gg"
+)
+
+// style draws cover artwork onto a dark background using colors from palette. All
+// randomness must come from rng so covers stay deterministic.
+type style func(dc *gg.Context, rng *rand.Rand, palette [][3]float64)
+
+// styles is the registry of available cover styles, keyed by their config name.
+var styles = map[string]style{
+	"waves":         drawWaves,
+	"gradient-mesh": drawGradientMesh,
+	"shards":        drawShards,
+	"noise":         drawNoiseField,
+	"rings":         drawRings,
+}
+
+// styleNames returns the registered style names in a stable order.
+func styleNames() []string {
+	names := make([]string, 0, len(styles))
+	for name := range styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// drawWaves draws several layers of flowing, multi-colored sine waves.
+func drawWaves(dc *gg.Context, rng *rand.Rand, palette [][3]float64) {
+	for range 7 {
+		color := palette[rng.Intn(len(palette))]
+		dc.SetRGB(color[0], color[1], color[2])
+
+		// Randomize wave properties for variety.
+		lineWidth := 2 + rng.Float64()*15
+		amplitude := 50 + rng.Float64()*100
+		frequency := 0.5 + rng.Float64()*2
+		yOffset := float64(imgHeight/2) + (rng.Float64()-0.5)*300
+
+		dc.SetLineWidth(lineWidth)
+
+		// Draw a single sine wave across the canvas.
+		for x := 0.0; x < float64(imgWidth); x++ {
+			y := yOffset + math.Sin(x/float64(imgWidth)*math.Pi*2*frequency)*amplitude
+			if x == 0 {
+				dc.MoveTo(x, y)
+			} else {
+				dc.LineTo(x, y)
+			}
+		}
+		dc.Stroke()
+	}
+}
+
+// drawGradientMesh blends palette colors placed on a coarse 3x3 grid of control points.
+func drawGradientMesh(dc *gg.Context, rng *rand.Rand, palette [][3]float64) {
+	const n = 3
+	var mesh [n][n][3]float64
+	for y := range n {
+		for x := range n {
+			c := palette[rng.Intn(len(palette))]
+			shade := 0.55 + rng.Float64()*0.45
+			mesh[y][x] = [3]float64{c[0] * shade, c[1] * shade, c[2] * shade}
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, imgWidth, imgHeight))
+	for py := range imgHeight {
+		fy := float64(py) / float64(imgHeight-1) * (n - 1)
+		y0 := min(int(fy), n-2)
+		ty := smoothstep(fy - float64(y0))
+		for px := range imgWidth {
+			fx := float64(px) / float64(imgWidth-1) * (n - 1)
+			x0 := min(int(fx), n-2)
+			tx := smoothstep(fx - float64(x0))
+			var rgb [3]float64
+			for i := range 3 {
+				top := lerp(mesh[y0][x0][i], mesh[y0][x0+1][i], tx)
+				bottom := lerp(mesh[y0+1][x0][i], mesh[y0+1][x0+1][i], tx)
+				rgb[i] = lerp(top, bottom, ty)
+			}
+			img.SetRGBA(px, py, toRGBA(rgb))
+		}
+	}
+	dc.DrawImage(img, 0, 0)
+}
+
+// drawShards scatters translucent triangles, like broken colored glass.
+func drawShards(dc *gg.Context, rng *rand.Rand, palette [][3]float64) {
+	for range 40 {
+		c := palette[rng.Intn(len(palette))]
+		dc.SetRGBA(c[0], c[1], c[2], 0.25+rng.Float64()*0.5)
+		cx := rng.Float64() * imgWidth
+		cy := rng.Float64() * imgHeight
+		size := 60 + rng.Float64()*220
+		for i := range 3 {
+			angle := rng.Float64()*math.Pi/1.5 + float64(i)*2*math.Pi/3
+			x := cx + math.Cos(angle)*size
+			y := cy + math.Sin(angle)*size
+			if i == 0 {
+				dc.MoveTo(x, y)
+			} else {
+				dc.LineTo(x, y)
+			}
+		}
+		dc.ClosePath()
+		dc.Fill()
+	}
+}
+
+// drawNoiseField maps layered value noise onto a gradient through the palette.
+func drawNoiseField(dc *gg.Context, rng *rand.Rand, palette [][3]float64) {
+	const cells = 8
+	// The lattice wraps around (its last row and column repeat the first) so octaves
+	// sampled past its edge tile without seams.
+	var grid [cells + 1][cells + 1]float64
+	for y := range cells {
+		for x := range cells {
+			grid[y][x] = rng.Float64()
+		}
+		grid[y][cells] = grid[y][0]
+	}
+	grid[cells] = grid[0]
+	sample := func(x, y float64) float64 {
+		x, y = math.Mod(x, cells), math.Mod(y, cells)
+		x0, y0 := int(x), int(y)
+		tx, ty := smoothstep(x-float64(x0)), smoothstep(y-float64(y0))
+		top := lerp(grid[y0][x0], grid[y0][x0+1], tx)
+		bottom := lerp(grid[y0+1][x0], grid[y0+1][x0+1], tx)
+		return lerp(top, bottom, ty)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, imgWidth, imgHeight))
+	for py := range imgHeight {
+		for px := range imgWidth {
+			x := float64(px) / imgWidth * 3
+			y := float64(py) / imgHeight * 3
+			v := (sample(x, y) + 0.5*sample(x*2, y*2) + 0.25*sample(x*4, y*4)) / 1.75
+			// Position along the palette, with bands for a contour-map look.
+			t := v * float64(len(palette)-1)
+			i := min(int(t), len(palette)-2)
+			f := t - float64(i)
+			band := 0.75 + 0.25*math.Cos(v*40)
+			var rgb [3]float64
+			for c := range 3 {
+				rgb[c] = lerp(palette[i][c], palette[i+1][c], f) * band
+			}
+			img.SetRGBA(px, py, toRGBA(rgb))
+		}
+	}
+	dc.DrawImage(img, 0, 0)
+}
+
+// drawRings draws concentric rings of varying width around an off-center point.
+func drawRings(dc *gg.Context, rng *rand.Rand, palette [][3]float64) {
+	cx := imgWidth * (0.25 + rng.Float64()*0.5)
+	cy := imgHeight * (0.25 + rng.Float64()*0.5)
+	for r := 20 + rng.Float64()*20; r < imgWidth*1.2; r += 14 + rng.Float64()*36 {
+		c := palette[rng.Intn(len(palette))]
+		dc.SetRGBA(c[0], c[1], c[2], 0.5+rng.Float64()*0.5)
+		dc.SetLineWidth(2 + rng.Float64()*12)
+		dc.DrawCircle(cx, cy, r)
+		dc.Stroke()
+	}
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+func smoothstep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+func toRGBA(rgb [3]float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(math.Min(rgb[0], 1) * 255),
+		G: uint8(math.Min(rgb[1], 1) * 255),
+		B: uint8(math.Min(rgb[2], 1) * 255),
+		A: 255,
+	}
+}
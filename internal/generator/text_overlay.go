@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"spotify/internal/config"
+	"spotify/internal/processor"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/gobold"
+)
+
+// coverFont is the embedded font used for cover labels, so covers render identically on
+// every machine.
+var coverFont = mustParseFont(gobold.TTF)
+
+func mustParseFont(ttf []byte) *truetype.Font {
+	f, err := truetype.Parse(ttf)
+	if err != nil {
+		panic("generator: embedded font is invalid: " + err.Error())
+	}
+	return f
+}
+
+// drawText renders the spec's label, and its subtitle if any, onto the cover with a soft
+// shadow so it stays readable on bright artwork.
+func drawText(dc *gg.Context, spec processor.CoverSpec, cfg config.CoverText) {
+	label := spec.Label
+	if label == "" {
+		label = spec.Name
+	}
+	subtitleSize := cfg.Size * 0.4
+	margin := float64(imgWidth) * 0.08
+	maxWidth := float64(imgWidth) - 2*margin
+
+	// Vertical anchor of the label baseline area, expressed as a fraction of the height.
+	var anchor float64
+	switch cfg.Position {
+	case "top":
+		anchor = 0.2
+	case "center":
+		anchor = 0.5
+	default: // "bottom"
+		anchor = 0.72
+	}
+	labelY := float64(imgHeight) * anchor
+	subtitleY := labelY + cfg.Size*0.5 + subtitleSize*0.8
+
+	draw := func(text string, size, y float64) {
+		dc.SetFontFace(truetype.NewFace(coverFont, &truetype.Options{Size: size}))
+		// Shrink long texts until they fit between the margins.
+		for w, _ := dc.MeasureString(text); w > maxWidth && size > 8; w, _ = dc.MeasureString(text) {
+			size *= 0.9
+			dc.SetFontFace(truetype.NewFace(coverFont, &truetype.Options{Size: size}))
+		}
+		shadow := size * 0.04
+		dc.SetRGBA(0, 0, 0, 0.55)
+		dc.DrawStringAnchored(text, float64(imgWidth)/2+shadow, y+shadow, 0.5, 0.5)
+		dc.SetHexColor(cfg.Color)
+		dc.DrawStringAnchored(text, float64(imgWidth)/2, y, 0.5, 0.5)
+	}
+
+	draw(label, cfg.Size, labelY)
+	if spec.Subtitle != "" {
+		draw(spec.Subtitle, subtitleSize, subtitleY)
+	}
+}
@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// theme constrains the palette to a set of hues, e.g. icy blues for winter.
+type theme struct {
+	hues       []float64 // anchor hues in degrees; palette colors cycle through them
+	spread     float64   // random offset applied to each anchor, in degrees
+	saturation float64
+	value      float64
+}
+
+// themes is the registry of seasonal palettes, keyed by their config name.
+var themes = map[string]theme{
+	"winter":  {hues: []float64{200, 215, 190}, spread: 15, saturation: 0.4, value: 0.95},
+	"spring":  {hues: []float64{100, 330, 60}, spread: 20, saturation: 0.45, value: 0.95},
+	"summer":  {hues: []float64{45, 20, 190}, spread: 15, saturation: 0.75, value: 1},
+	"autumn":  {hues: []float64{25, 10, 40}, spread: 10, saturation: 0.75, value: 0.8},
+	"holiday": {hues: []float64{355, 135, 45}, spread: 8, saturation: 0.7, value: 0.8},
+}
+
+// monthThemes maps each month to its theme. December is the holiday season.
+var monthThemes = [12]string{
+	"winter", "winter", "spring", "spring", "spring", "summer",
+	"summer", "summer", "autumn", "autumn", "autumn", "holiday",
+}
+
+var (
+	// numericMonth matches dates such as "2024-10" or "10/2024".
+	numericMonth = regexp.MustCompile(`\b(?:\d{4}-(\d{1,2})|(\d{1,2})/\d{4})\b`)
+	// seasonWords maps words in playlist names straight to a theme.
+	seasonWords = map[string]string{
+		"winter": "winter", "spring": "spring", "summer": "summer", "autumn": "autumn",
+		"christmas": "holiday", "xmas": "holiday", "holiday": "holiday", "holidays": "holiday",
+	}
+)
+
+// themeNames returns the registered theme names in a stable order.
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// themeFor returns the theme for a playlist: a per-playlist override, the configured
+// theme, or with "auto" one derived from a month or season in the name. The empty string
+// means no theme.
+func (g *imageGenerator) themeFor(name string) string {
+	for _, pattern := range sortedKeys(g.cfg.Themes) {
+		if ok, _ := path.Match(pattern, name); ok {
+			return g.cfg.Themes[pattern]
+		}
+	}
+	switch g.cfg.Theme {
+	case "", "none":
+		return ""
+	case "auto":
+		return themeFromName(name)
+	default:
+		return g.cfg.Theme
+	}
+}
+
+// themeFromName finds a month or a season in a playlist name.
+func themeFromName(name string) string {
+	if m := numericMonth.FindStringSubmatch(name); m != nil {
+		month, _ := strconv.Atoi(m[1] + m[2])
+		if month >= 1 && month <= 12 {
+			return monthThemes[month-1]
+		}
+	}
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r < 'a' || r > 'z'
+	}) {
+		if t, ok := seasonWords[word]; ok {
+			return t
+		}
+		for month := time.January; month <= time.December; month++ {
+			if word == strings.ToLower(month.String()) {
+				return monthThemes[month-1]
+			}
+		}
+	}
+	return ""
+}
+
+// themedPalette creates a 3-color palette within the hues of t.
+func themedPalette(rng *rand.Rand, t theme) [][3]float64 {
+	palette := make([][3]float64, 3)
+	for i := range palette {
+		hue := t.hues[i%len(t.hues)] + (rng.Float64()*2-1)*t.spread
+		palette[i] = hsvToRgb(math.Mod(hue+360, 360), t.saturation, t.value)
+	}
+	return palette
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		values = append(values, m[k])
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"spotify/internal/store"
+	"time"
+)
+
+// basicRecord mirrors one entry of the streaming history in the basic "Account data"
+// export (StreamingHistory0.json, or StreamingHistory_music_0.json in newer archives).
+// It covers the last year only and has no track IDs.
+type basicRecord struct {
+	EndTime    string `json:"endTime"` // UTC, minute precision: "2023-01-31 21:04"
+	ArtistName string `json:"artistName"`
+	TrackName  string `json:"trackName"`
+	MsPlayed   int64  `json:"msPlayed"`
+}
+
+// ReadBasic decodes a single basic streaming history file. Plays have no TrackID until
+// they're resolved against Spotify.
+func ReadBasic(r io.Reader) ([]store.Play, error) {
+	var records []basicRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("could not decode streaming history: %w", err)
+	}
+
+	plays := make([]store.Play, 0, len(records))
+	for _, rec := range records {
+		if rec.TrackName == "" {
+			continue
+		}
+		playedAt, err := time.Parse("2006-01-02 15:04", rec.EndTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end time '%s': %w", rec.EndTime, err)
+		}
+		plays = append(plays, store.Play{
+			PlayedAt:   playedAt,
+			MsPlayed:   rec.MsPlayed,
+			TrackName:  rec.TrackName,
+			ArtistName: rec.ArtistName,
+		})
+	}
+	return plays, nil
+}
+
+// FindBasicFiles returns the music streaming history files of a basic export. Podcast
+// history files are left out.
+func FindBasicFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"StreamingHistory[0-9]*.json", "StreamingHistory_music_*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadBasicFile opens and decodes one basic history file.
+func LoadBasicFile(path string) ([]store.Play, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBasic(f)
+}
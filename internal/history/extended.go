@@ -0,0 +1,94 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"spotify/internal/store"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// extendedRecord mirrors one entry of the "extended streaming history" export
+// (Streaming_History_Audio_*.json, formerly endsong_*.json). Podcast and
+// audiobook entries leave the track fields null.
+type extendedRecord struct {
+	Timestamp  string  `json:"ts"`
+	MsPlayed   int64   `json:"ms_played"`
+	TrackName  *string `json:"master_metadata_track_name"`
+	ArtistName *string `json:"master_metadata_album_artist_name"`
+	AlbumName  *string `json:"master_metadata_album_album_name"`
+	TrackURI   *string `json:"spotify_track_uri"`
+	Skipped    *bool   `json:"skipped"`
+}
+
+// ReadExtended decodes a single extended streaming history file, dropping
+// non-music entries.
+func ReadExtended(r io.Reader) ([]store.Play, error) {
+	var records []extendedRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("could not decode extended history: %w", err)
+	}
+
+	plays := make([]store.Play, 0, len(records))
+	for _, rec := range records {
+		if rec.TrackURI == nil || rec.TrackName == nil {
+			continue
+		}
+		playedAt, err := time.Parse(time.RFC3339, rec.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp '%s': %w", rec.Timestamp, err)
+		}
+		plays = append(plays, store.Play{
+			PlayedAt:   playedAt,
+			MsPlayed:   rec.MsPlayed,
+			TrackID:    IDFromURI(*rec.TrackURI),
+			TrackName:  *rec.TrackName,
+			ArtistName: deref(rec.ArtistName),
+			AlbumName:  deref(rec.AlbumName),
+			Skipped:    rec.Skipped != nil && *rec.Skipped,
+		})
+	}
+	return plays, nil
+}
+
+// FindExtendedFiles returns the extended history files inside an unpacked export directory.
+func FindExtendedFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"Streaming_History_Audio_*.json", "endsong_*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadExtendedFile opens and decodes one extended history file.
+func LoadExtendedFile(path string) ([]store.Play, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadExtended(f)
+}
+
+// IDFromURI extracts the track ID from a "spotify:track:<id>" URI.
+func IDFromURI(uri string) spotify.ID {
+	return spotify.ID(uri[strings.LastIndex(uri, ":")+1:])
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,87 @@
+package history
+
+import (
+	"sort"
+	"spotify/internal/matching"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// TrackStat aggregates the listening activity of one track. TrackID is empty for tracks
+// only known by name, from exports without IDs.
+type TrackStat struct {
+	TrackID    spotify.ID
+	TrackName  string
+	ArtistName string
+	Plays      int
+	MsPlayed   int64
+}
+
+// RankTracks aggregates plays between from (inclusive) and to (exclusive) and returns
+// tracks ordered by total listening time, most played first. Plays shorter than
+// minPlay are ignored, which filters out skips and previews. Plays without a track ID are
+// grouped by title and artist.
+func RankTracks(plays []store.Play, from, to time.Time, minPlay time.Duration) []TrackStat {
+	byTrack := make(map[string]*TrackStat)
+	for _, p := range plays {
+		if p.PlayedAt.Before(from) || !p.PlayedAt.Before(to) {
+			continue
+		}
+		if time.Duration(p.MsPlayed)*time.Millisecond < minPlay {
+			continue
+		}
+		key := string(p.TrackID)
+		if key == "" {
+			key = "?" + matching.Key(p.TrackName, p.ArtistName)
+		}
+		stat, ok := byTrack[key]
+		if !ok {
+			stat = &TrackStat{TrackID: p.TrackID, TrackName: p.TrackName, ArtistName: p.ArtistName}
+			byTrack[key] = stat
+		}
+		stat.Plays++
+		stat.MsPlayed += p.MsPlayed
+	}
+
+	ranked := make([]TrackStat, 0, len(byTrack))
+	for _, stat := range byTrack {
+		ranked = append(ranked, *stat)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].MsPlayed != ranked[j].MsPlayed {
+			return ranked[i].MsPlayed > ranked[j].MsPlayed
+		}
+		if ranked[i].TrackID != ranked[j].TrackID {
+			return ranked[i].TrackID < ranked[j].TrackID
+		}
+		return ranked[i].TrackName < ranked[j].TrackName
+	})
+	return ranked
+}
+
+// Years returns the distinct calendar years (in UTC) covered by plays, in ascending order.
+func Years(plays []store.Play) []int {
+	seen := make(map[int]struct{})
+	for _, p := range plays {
+		seen[p.PlayedAt.UTC().Year()] = struct{}{}
+	}
+	years := make([]int, 0, len(seen))
+	for y := range seen {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	return years
+}
+
+// Latest returns the time of the most recent play, or the zero time without plays.
+func Latest(plays []store.Play) time.Time {
+	var latest time.Time
+	for _, p := range plays {
+		if p.PlayedAt.After(latest) {
+			latest = p.PlayedAt
+		}
+	}
+	return latest
+}
@@ -0,0 +1,41 @@
+// Package httplog traces the Spotify API requests of a client, to diagnose failures the
+// client's errors don't explain.
+package httplog
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+type transport struct {
+	next   http.RoundTripper
+	logger *log.Logger
+}
+
+// NewTransport returns a transport that sends requests through next and logs one line
+// for each: method, path and query, status, duration and, when Spotify rate-limits the
+// request, how long it asked to wait. Hosts, headers and bodies are never logged, so
+// access tokens don't end up in the output.
+func NewTransport(next http.RoundTripper, logger *log.Logger) http.RoundTripper {
+	return &transport{next: next, logger: logger}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	took := time.Since(start).Round(time.Millisecond)
+	target := req.URL.EscapedPath()
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	switch {
+	case err != nil:
+		t.logger.Printf("🌐 %s %s ❌ %v (%s)", req.Method, target, err, took)
+	case resp.Header.Get("Retry-After") != "":
+		t.logger.Printf("🌐 %s %s → %d, retry after %ss (%s)", req.Method, target, resp.StatusCode, resp.Header.Get("Retry-After"), took)
+	default:
+		t.logger.Printf("🌐 %s %s → %d (%s)", req.Method, target, resp.StatusCode, took)
+	}
+	return resp, err
+}
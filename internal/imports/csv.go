@@ -0,0 +1,107 @@
+// Package imports reads track lists exported from other music services.
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Row is one track of an imported list.
+type Row struct {
+	Line     int // line in the source file, for reports
+	Title    string
+	Artist   string
+	Album    string
+	ISRC     string
+	Duration time.Duration
+}
+
+// columns maps the header names used by common exporters to the field they hold.
+var columns = map[string]string{
+	"title": "title", "name": "title", "track": "title", "track name": "title", "song": "title", "song name": "title",
+	"artist": "artist", "artists": "artist", "artist name": "artist", "artist name(s)": "artist",
+	"album": "album", "album name": "album", "isrc": "isrc",
+	"duration": "duration", "duration (ms)": "duration", "duration_ms": "duration", "length": "duration", "time": "duration",
+}
+
+// LoadCSV reads a CSV file with a header row. Columns are recognized by name, so exports
+// of most services work as they are; a title column is required.
+func LoadCSV(file string) ([]Row, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open '%s': %w", file, err)
+	}
+	defer f.Close()
+	return ReadCSV(f)
+}
+
+// ReadCSV is LoadCSV for an open reader.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := columns[name]; ok {
+			if _, seen := index[field]; !seen {
+				index[field] = i
+			}
+		}
+	}
+	if _, ok := index["title"]; !ok {
+		return nil, errors.New("no title column found (expected one of: title, name, track name, song)")
+	}
+
+	var rows []Row
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		get := func(field string) string {
+			if i, ok := index[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := Row{Line: line, Title: get("title"), Artist: get("artist"), Album: get("album"), ISRC: get("isrc")}
+		if row.Title == "" {
+			continue
+		}
+		row.Duration = parseDuration(get("duration"))
+		rows = append(rows, row)
+	}
+}
+
+// parseDuration reads milliseconds ("215000") or minutes and seconds ("3:35"). Anything
+// else is unknown and yields 0.
+func parseDuration(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if ms, err := strconv.Atoi(s); err == nil {
+		return time.Duration(ms) * time.Millisecond
+	}
+	var d time.Duration
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		d = d*60 + time.Duration(n)*time.Second
+	}
+	return d
+}
@@ -0,0 +1,104 @@
+// Package interrupt stops a run gracefully on Ctrl-C: work stops at the next safe point,
+// and requests already sent to Spotify complete, so their outcome is known and recorded.
+package interrupt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ErrInterrupted is the cause of a context cancelled by Context.
+var ErrInterrupted = errors.New("interrupted")
+
+// Context returns a context that is cancelled with ErrInterrupted on the first interrupt
+// or termination signal. A second signal exits at once. stop releases the signals and
+// cancels the context.
+func Context(parent context.Context, logger *log.Logger) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			logger.Println("🛑 Stopping after the requests in flight. Press Ctrl-C again to quit at once.")
+			cancel(ErrInterrupted)
+		case <-done:
+			return
+		}
+		select {
+		case <-signals:
+			logger.Println("🛑 Quitting without waiting.")
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			cancel(context.Canceled)
+		})
+	}
+}
+
+// Interrupted reports whether ctx was cancelled by a signal.
+func Interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInterrupted)
+}
+
+// Transport returns a transport that refuses requests once their context is done but
+// lets a request already in flight complete when the run is interrupted. Other reasons
+// for the context to end, such as a timeout, still abort it.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if err := ctx.Err(); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		stopWatching := context.AfterFunc(ctx, func() {
+			if !Interrupted(ctx) {
+				cancel()
+			}
+		})
+		release := func() {
+			stopWatching()
+			cancel()
+		}
+		resp, err := next.RoundTrip(req.WithContext(detached))
+		if err != nil {
+			release()
+			return nil, err
+		}
+		// The body is read after RoundTrip returns, so the request lives until it's closed.
+		resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	})
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,136 @@
+// Package language infers the language a song is sung in from its metadata. Lyrics
+// aren't available through the Spotify API, so detection relies on the script of the
+// title, the genres of the artist and common words in the title, with an optional
+// external lookup for better accuracy.
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Track is the metadata detection works from.
+type Track struct {
+	Title   string
+	Album   string
+	Artists []string
+	// Genres are the genres of the track's primary artist.
+	Genres []string
+}
+
+// Detect returns the ISO 639-1 code of the most likely language of t, or "" if there
+// isn't enough evidence.
+func Detect(t Track) string {
+	if lang := detectScript(t.Title + " " + t.Album + " " + strings.Join(t.Artists, " ")); lang != "" {
+		return lang
+	}
+	if lang := detectGenre(t.Genres); lang != "" {
+		return lang
+	}
+	return detectWords(t.Title)
+}
+
+// scripts maps writing systems to the language they most likely indicate.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"}, // after kana: Japanese titles mix kanji with kana
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// detectScript returns the language indicated by the first non-Latin script in s.
+func detectScript(s string) string {
+	for _, script := range scripts {
+		for _, r := range s {
+			if unicode.Is(script.table, r) {
+				return script.lang
+			}
+		}
+	}
+	return ""
+}
+
+// genreHints maps words found in Spotify genre names to languages.
+var genreHints = []struct {
+	word string
+	lang string
+}{
+	{"italian", "it"}, {"cantautori", "it"},
+	{"k-pop", "ko"}, {"k-indie", "ko"}, {"korean", "ko"},
+	{"j-pop", "ja"}, {"j-rock", "ja"}, {"japanese", "ja"}, {"anime", "ja"},
+	{"c-pop", "zh"}, {"mandopop", "zh"}, {"cantopop", "zh"},
+	{"reggaeton", "es"}, {"spanish", "es"}, {"latin", "es"}, {"mexican", "es"}, {"argentin", "es"}, {"urbano", "es"},
+	{"brazilian", "pt"}, {"mpb", "pt"}, {"sertanejo", "pt"}, {"portuguese", "pt"}, {"pagode", "pt"},
+	{"french", "fr"}, {"chanson", "fr"}, {"francoton", "fr"},
+	{"german", "de"}, {"deutsch", "de"}, {"schlager", "de"},
+	{"russian", "ru"}, {"turkish", "tr"}, {"polish", "pl"}, {"dutch", "nl"}, {"swedish", "sv"},
+}
+
+// detectGenre returns the language named most often by the artist's genres.
+func detectGenre(genres []string) string {
+	votes := make(map[string]int)
+	best := ""
+	for _, g := range genres {
+		g = strings.ToLower(g)
+		for _, hint := range genreHints {
+			if strings.Contains(g, hint.word) {
+				votes[hint.lang]++
+				if best == "" || votes[hint.lang] > votes[best] {
+					best = hint.lang
+				}
+				break
+			}
+		}
+	}
+	return best
+}
+
+// stopwords are frequent words that rarely appear in titles in other languages.
+var stopwords = map[string][]string{
+	"en": {"the", "you", "my", "me", "your", "love", "and", "don't", "i'm", "it's", "of"},
+	"it": {"il", "che", "non", "di", "per", "sono", "gli", "della", "ti", "amore", "io", "mi"},
+	"es": {"el", "los", "que", "mi", "tu", "te", "y", "con", "amor", "corazón", "las", "yo"},
+	"pt": {"não", "você", "eu", "meu", "coração", "da", "em", "um", "uma", "os"},
+	"fr": {"le", "les", "je", "tu", "pas", "et", "des", "est", "une", "mon", "moi", "toi", "ne", "rien", "c'est"},
+	"de": {"ich", "und", "nicht", "der", "die", "das", "du", "ein", "mein", "liebe", "mit"},
+}
+
+// detectWords guesses the language of a Latin-script title from its stopwords. A tie
+// or a single weak hit yields "".
+func detectWords(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	scores := make(map[string]int)
+	for _, w := range words {
+		for lang, list := range stopwords {
+			for _, s := range list {
+				if w == s {
+					scores[lang]++
+				}
+			}
+		}
+	}
+	best, bestScore, tied := "", 0, false
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = lang, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied || bestScore == 0 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,59 @@
+package language
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Hook asks an external lyrics service for a track's language. The service receives a
+// GET request with isrc, title and artist query parameters and answers with JSON such
+// as {"language": "it"}; an empty language means it doesn't know.
+type Hook struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewHook returns a hook calling endpoint.
+func NewHook(endpoint string) *Hook {
+	return &Hook{endpoint: endpoint, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Lookup returns the language the service reports for the track.
+func (h *Hook) Lookup(ctx context.Context, isrc, title, artist string) (string, error) {
+	u, err := url.Parse(h.endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid lyrics hook URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("isrc", isrc)
+	q.Set("title", title)
+	q.Set("artist", artist)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("lyrics hook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("lyrics hook returned %s", resp.Status)
+	}
+	var body struct {
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("could not decode lyrics hook response: %w", err)
+	}
+	return body.Language, nil
+}
@@ -0,0 +1,33 @@
+package language
+
+// endonyms are the names of languages in the languages themselves.
+var endonyms = map[string]string{
+	"ar": "العربية",
+	"de": "Deutsch",
+	"el": "Ελληνικά",
+	"en": "English",
+	"es": "Español",
+	"fr": "Français",
+	"he": "עברית",
+	"hi": "हिन्दी",
+	"it": "Italiano",
+	"ja": "日本語",
+	"ko": "한국어",
+	"nl": "Nederlands",
+	"pl": "Polski",
+	"pt": "Português",
+	"ru": "Русский",
+	"sv": "Svenska",
+	"th": "ไทย",
+	"tr": "Türkçe",
+	"zh": "中文",
+}
+
+// Name returns the name of the language with the given code in that language, e.g.
+// "Italiano" for "it". Unknown codes are returned unchanged.
+func Name(code string) string {
+	if name, ok := endonyms[code]; ok {
+		return name
+	}
+	return code
+}
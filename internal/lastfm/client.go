@@ -0,0 +1,270 @@
+// Package lastfm is a small client for the parts of the Last.fm API the processors use:
+// scrobbles, loved tracks and loving tracks.
+package lastfm
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiURL = "https://ws.audioscrobbler.com/2.0/"
+
+// requestInterval spaces out requests to stay within Last.fm's rate limit.
+const requestInterval = 250 * time.Millisecond
+
+// Track is a track as Last.fm knows it: by name only.
+type Track struct {
+	Title  string
+	Artist string
+	// Plays is the number of scrobbles, where it applies.
+	Plays int
+}
+
+// Client calls the Last.fm API for one user. Reading needs only an API key; loving
+// tracks also needs the secret and a session key from the login flow.
+type Client struct {
+	apiKey     string
+	secret     string
+	sessionKey string
+	user       string
+	http       *http.Client
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// New returns a client acting for user.
+func New(apiKey, secret, sessionKey, user string) *Client {
+	return &Client{
+		apiKey:     apiKey,
+		secret:     secret,
+		sessionKey: sessionKey,
+		user:       user,
+		http:       &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Scrobbles counts the user's scrobbles per track between from (inclusive) and to
+// (exclusive), most played first.
+func (c *Client) Scrobbles(ctx context.Context, from, to time.Time) ([]Track, error) {
+	counts := make(map[[2]string]*Track)
+	for page := 1; ; page++ {
+		var body struct {
+			RecentTracks struct {
+				Track []struct {
+					Name   string `json:"name"`
+					Artist struct {
+						Name string `json:"#text"`
+					} `json:"artist"`
+					Attr struct {
+						NowPlaying string `json:"nowplaying"`
+					} `json:"@attr"`
+				} `json:"track"`
+				Attr struct {
+					TotalPages string `json:"totalPages"`
+				} `json:"@attr"`
+			} `json:"recenttracks"`
+		}
+		err := c.call(ctx, "user.getRecentTracks", url.Values{
+			"user":  {c.user},
+			"from":  {strconv.FormatInt(from.Unix(), 10)},
+			"to":    {strconv.FormatInt(to.Unix()-1, 10)},
+			"limit": {"200"},
+			"page":  {strconv.Itoa(page)},
+		}, false, &body)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range body.RecentTracks.Track {
+			if t.Attr.NowPlaying == "true" {
+				continue
+			}
+			key := [2]string{strings.ToLower(t.Name), strings.ToLower(t.Artist.Name)}
+			if counts[key] == nil {
+				counts[key] = &Track{Title: t.Name, Artist: t.Artist.Name}
+			}
+			counts[key].Plays++
+		}
+		total, _ := strconv.Atoi(body.RecentTracks.Attr.TotalPages)
+		if page >= total {
+			break
+		}
+	}
+
+	tracks := make([]Track, 0, len(counts))
+	for _, t := range counts {
+		tracks = append(tracks, *t)
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].Plays != tracks[j].Plays {
+			return tracks[i].Plays > tracks[j].Plays
+		}
+		return tracks[i].Artist+tracks[i].Title < tracks[j].Artist+tracks[j].Title
+	})
+	return tracks, nil
+}
+
+// LovedTracks returns every track the user loved, most recent first.
+func (c *Client) LovedTracks(ctx context.Context) ([]Track, error) {
+	var tracks []Track
+	for page := 1; ; page++ {
+		var body struct {
+			LovedTracks struct {
+				Track []struct {
+					Name   string `json:"name"`
+					Artist struct {
+						Name string `json:"name"`
+					} `json:"artist"`
+				} `json:"track"`
+				Attr struct {
+					TotalPages string `json:"totalPages"`
+				} `json:"@attr"`
+			} `json:"lovedtracks"`
+		}
+		err := c.call(ctx, "user.getLovedTracks", url.Values{
+			"user":  {c.user},
+			"limit": {"1000"},
+			"page":  {strconv.Itoa(page)},
+		}, false, &body)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range body.LovedTracks.Track {
+			tracks = append(tracks, Track{Title: t.Name, Artist: t.Artist.Name})
+		}
+		total, _ := strconv.Atoi(body.LovedTracks.Attr.TotalPages)
+		if page >= total {
+			return tracks, nil
+		}
+	}
+}
+
+// Love marks a track as loved.
+func (c *Client) Love(ctx context.Context, title, artist string) error {
+	if c.sessionKey == "" {
+		return fmt.Errorf("loving tracks needs a session key; run 'lastfm login' first")
+	}
+	return c.call(ctx, "track.love", url.Values{"track": {title}, "artist": {artist}, "sk": {c.sessionKey}}, true, nil)
+}
+
+// Token starts the login flow. The user authorizes it at the returned URL, after which
+// Session exchanges it for a session key.
+func (c *Client) Token(ctx context.Context) (token, authURL string, err error) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := c.call(ctx, "auth.getToken", url.Values{}, true, &body); err != nil {
+		return "", "", err
+	}
+	authURL = "https://www.last.fm/api/auth/?" + url.Values{"api_key": {c.apiKey}, "token": {body.Token}}.Encode()
+	return body.Token, authURL, nil
+}
+
+// Session returns the session key and user name of an authorized token.
+func (c *Client) Session(ctx context.Context, token string) (key, user string, err error) {
+	var body struct {
+		Session struct {
+			Name string `json:"name"`
+			Key  string `json:"key"`
+		} `json:"session"`
+	}
+	if err := c.call(ctx, "auth.getSession", url.Values{"token": {token}}, true, &body); err != nil {
+		return "", "", err
+	}
+	return body.Session.Key, body.Session.Name, nil
+}
+
+// call invokes method and decodes the JSON response into out. Signed calls are sent as
+// POST requests with an api_sig, as the API requires for authentication and writes.
+func (c *Client) call(ctx context.Context, method string, params url.Values, signed bool, out any) error {
+	if c.apiKey == "" {
+		return fmt.Errorf("LASTFM_API_KEY must be set")
+	}
+	params.Set("method", method)
+	params.Set("api_key", c.apiKey)
+	var req *http.Request
+	var err error
+	if signed {
+		if c.secret == "" {
+			return fmt.Errorf("LASTFM_API_SECRET must be set")
+		}
+		params.Set("api_sig", c.sign(params))
+		params.Set("format", "json")
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(params.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		params.Set("format", "json")
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"?"+params.Encode(), nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.wait()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("last.fm %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("last.fm %s returned %s", method, resp.Status)
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Error != 0 {
+		return fmt.Errorf("last.fm %s failed: %s (error %d)", method, body.Message, body.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("last.fm %s returned %s", method, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("could not decode last.fm %s response: %w", method, err)
+	}
+	return nil
+}
+
+// sign computes the api_sig of params: the MD5 of the sorted name-value pairs followed
+// by the secret. The format parameter isn't signed.
+func (c *Client) sign(params url.Values) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString(params.Get(name))
+	}
+	b.WriteString(c.secret)
+	sum := md5.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// wait blocks until requestInterval has passed since the previous request.
+func (c *Client) wait() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d := requestInterval - time.Since(c.last); d > 0 {
+		time.Sleep(d)
+	}
+	c.last = time.Now()
+}
@@ -0,0 +1,269 @@
+package lists
+
+import (
+	"context"
+	"log"
+	"spotify/internal/processor"
+	"strings"
+	"sync"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxItems is the most tracks a single playlist items call accepts.
+const maxItems = 100
+
+// Client is a SpotifyClient that enforces the lists on every playlist write, so no
+// processor has to know about them: blocked tracks are dropped from writes, and pinned
+// tracks are put at the top of a playlist whenever it's rewritten. Blocking by artist
+// or album uses the tracks seen in earlier reads, looking up the rest.
+type Client struct {
+	processor.SpotifyClient
+	lists  *Lists
+	logger *log.Logger
+
+	mu     sync.Mutex
+	tracks map[spotify.ID]trackInfo
+	names  map[spotify.ID]string
+	// pinned holds the tracks pinned into each playlist by its latest rewrite, so the
+	// appends that follow don't add them twice.
+	pinned map[spotify.ID]map[spotify.ID]struct{}
+}
+
+// trackInfo is what blocking needs to know about a track.
+type trackInfo struct {
+	artists []spotify.ID
+	album   spotify.ID
+}
+
+// NewClient wraps client, enforcing lists.
+func NewClient(client processor.SpotifyClient, lists *Lists, logger *log.Logger) *Client {
+	return &Client{
+		SpotifyClient: client,
+		lists:         lists,
+		logger:        logger,
+		tracks:        make(map[spotify.ID]trackInfo),
+		names:         make(map[spotify.ID]string),
+		pinned:        make(map[spotify.ID]map[spotify.ID]struct{}),
+	}
+}
+
+func (c *Client) CurrentUsersTracks(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedTrackPage, error) {
+	page, err := c.SpotifyClient.CurrentUsersTracks(ctx, opts...)
+	if err == nil {
+		for _, t := range page.Tracks {
+			c.remember(&t.FullTrack)
+		}
+	}
+	return page, err
+}
+
+func (c *Client) GetPlaylistTracks(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.PlaylistTrackPage, error) {
+	page, err := c.SpotifyClient.GetPlaylistTracks(ctx, playlistID, opts...)
+	if err == nil {
+		for _, t := range page.Tracks {
+			c.remember(&t.Track)
+		}
+	}
+	return page, err
+}
+
+func (c *Client) GetTracks(ctx context.Context, ids []spotify.ID, opts ...spotify.RequestOption) ([]*spotify.FullTrack, error) {
+	tracks, err := c.SpotifyClient.GetTracks(ctx, ids, opts...)
+	if err == nil {
+		for _, t := range tracks {
+			c.remember(t)
+		}
+	}
+	return tracks, err
+}
+
+func (c *Client) GetPlaylistsForUser(ctx context.Context, userID string, opts ...spotify.RequestOption) (*spotify.SimplePlaylistPage, error) {
+	page, err := c.SpotifyClient.GetPlaylistsForUser(ctx, userID, opts...)
+	if err == nil {
+		c.mu.Lock()
+		for _, pl := range page.Playlists {
+			c.names[pl.ID] = pl.Name
+		}
+		c.mu.Unlock()
+	}
+	return page, err
+}
+
+func (c *Client) CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error) {
+	playlist, err := c.SpotifyClient.CreatePlaylistForUser(ctx, userID, playlistName, description, public, collaborative)
+	if err == nil {
+		c.mu.Lock()
+		c.names[playlist.ID] = playlist.Name
+		c.mu.Unlock()
+	}
+	return playlist, err
+}
+
+func (c *Client) ChangePlaylistName(ctx context.Context, playlistID spotify.ID, newName string) error {
+	err := c.SpotifyClient.ChangePlaylistName(ctx, playlistID, newName)
+	if err == nil {
+		c.mu.Lock()
+		c.names[playlistID] = newName
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// ReplacePlaylistItems drops blocked tracks and puts the playlist's pinned tracks first.
+// If that makes more items than one call takes, the rest are appended.
+func (c *Client) ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error) {
+	pins, err := c.pinsFor(ctx, playlistID)
+	if err != nil {
+		return "", err
+	}
+	pins, err = c.allowed(ctx, pins)
+	if err != nil {
+		return "", err
+	}
+	pinSet := make(map[spotify.ID]struct{}, len(pins))
+	for _, id := range pins {
+		pinSet[id] = struct{}{}
+	}
+	c.mu.Lock()
+	c.pinned[playlistID] = pinSet
+	c.mu.Unlock()
+
+	var ids []spotify.ID
+	var others []spotify.URI
+	for _, uri := range items {
+		if id, ok := strings.CutPrefix(string(uri), "spotify:track:"); ok {
+			ids = append(ids, spotify.ID(id))
+		} else {
+			others = append(others, uri)
+		}
+	}
+	if ids, err = c.allowed(ctx, ids); err != nil {
+		return "", err
+	}
+	uris := trackURIs(pins)
+	for _, id := range ids {
+		if _, dup := pinSet[id]; !dup {
+			uris = append(uris, spotify.URI("spotify:track:"+id))
+		}
+	}
+	uris = append(uris, others...)
+
+	first := uris[:min(maxItems, len(uris))]
+	snapshotID, err := c.SpotifyClient.ReplacePlaylistItems(ctx, playlistID, first...)
+	for i := len(first); err == nil && i < len(uris); i += maxItems {
+		batch := uris[i:min(i+maxItems, len(uris))]
+		snapshotID, err = c.SpotifyClient.AddTracksToPlaylist(ctx, playlistID, uriIDs(batch)...)
+	}
+	return snapshotID, err
+}
+
+// AddTracksToPlaylist drops blocked tracks and tracks already pinned into the playlist.
+// If nothing is left it returns the playlist's current snapshot without writing.
+func (c *Client) AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	ids, err := c.allowed(ctx, trackIDs)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	pinned := c.pinned[playlistID]
+	c.mu.Unlock()
+	var keep []spotify.ID
+	for _, id := range ids {
+		if _, ok := pinned[id]; !ok {
+			keep = append(keep, id)
+		}
+	}
+	if len(keep) == 0 {
+		playlist, err := c.SpotifyClient.GetPlaylist(ctx, playlistID, spotify.Fields("snapshot_id"))
+		if err != nil {
+			return "", err
+		}
+		return playlist.SnapshotID, nil
+	}
+	return c.SpotifyClient.AddTracksToPlaylist(ctx, playlistID, keep...)
+}
+
+// allowed returns ids without the blocked tracks, looking up tracks never seen before
+// when artists or albums are blocked.
+func (c *Client) allowed(ctx context.Context, ids []spotify.ID) ([]spotify.ID, error) {
+	if c.lists.blocksByMetadata() {
+		var unknown []spotify.ID
+		c.mu.Lock()
+		for _, id := range ids {
+			if _, ok := c.tracks[id]; !ok {
+				unknown = append(unknown, id)
+			}
+		}
+		c.mu.Unlock()
+		for i := 0; i < len(unknown); i += 50 {
+			// Remembered by GetTracks.
+			if _, err := c.GetTracks(ctx, unknown[i:min(i+50, len(unknown))]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keep []spotify.ID
+	for _, id := range ids {
+		info := c.tracks[id]
+		if c.lists.Blocked(id, info.artists, info.album) {
+			continue
+		}
+		keep = append(keep, id)
+	}
+	if dropped := len(ids) - len(keep); dropped > 0 {
+		c.logger.Printf("  Skipped %d blocked tracks.", dropped)
+	}
+	return keep, nil
+}
+
+// pinsFor returns the tracks pinned into a playlist, looking up its name if it wasn't
+// seen before.
+func (c *Client) pinsFor(ctx context.Context, playlistID spotify.ID) ([]spotify.ID, error) {
+	if len(c.lists.pins) == 0 {
+		return nil, nil
+	}
+	c.mu.Lock()
+	name, ok := c.names[playlistID]
+	c.mu.Unlock()
+	if !ok {
+		playlist, err := c.SpotifyClient.GetPlaylist(ctx, playlistID, spotify.Fields("name"))
+		if err != nil {
+			return nil, err
+		}
+		name = playlist.Name
+	}
+	return c.lists.PinsFor(name), nil
+}
+
+// remember records what blocking needs to know about t.
+func (c *Client) remember(t *spotify.FullTrack) {
+	if t == nil || t.ID == "" {
+		return
+	}
+	info := trackInfo{album: t.Album.ID}
+	for _, a := range t.Artists {
+		info.artists = append(info.artists, a.ID)
+	}
+	c.mu.Lock()
+	c.tracks[t.ID] = info
+	c.mu.Unlock()
+}
+
+func trackURIs(ids []spotify.ID) []spotify.URI {
+	uris := make([]spotify.URI, len(ids))
+	for i, id := range ids {
+		uris[i] = spotify.URI("spotify:track:" + id)
+	}
+	return uris
+}
+
+func uriIDs(uris []spotify.URI) []spotify.ID {
+	ids := make([]spotify.ID, len(uris))
+	for i, uri := range uris {
+		ids[i] = spotify.ID(uri[strings.LastIndex(string(uri), ":")+1:])
+	}
+	return ids
+}
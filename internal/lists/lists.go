@@ -0,0 +1,187 @@
+// Package lists loads the user's blocklist of tracks, artists and albums that must never
+// be written to a playlist, and the allowlist of tracks pinned into playlists.
+package lists
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"spotify/internal/folders"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Lists holds the blocklist and the allowlist.
+type Lists struct {
+	blockedTracks  map[spotify.ID]struct{}
+	blockedArtists map[spotify.ID]struct{}
+	blockedAlbums  map[spotify.ID]struct{}
+	pins           []Pin
+}
+
+// Pin pins tracks into every playlist whose name matches one of the patterns.
+type Pin struct {
+	Playlists []string `yaml:"playlists"`
+	Tracks    []string `yaml:"tracks"`
+}
+
+// allowlist is the layout of the allowlist file.
+type allowlist struct {
+	Pins []Pin `yaml:"pins"`
+}
+
+// Load reads the blocklist, one spotify:track:, spotify:artist: or spotify:album: URI per
+// line with # comments, and the allowlist YAML file. Missing files are empty lists.
+func Load(blocklistFile, allowlistFile string) (*Lists, error) {
+	l := &Lists{
+		blockedTracks:  make(map[spotify.ID]struct{}),
+		blockedArtists: make(map[spotify.ID]struct{}),
+		blockedAlbums:  make(map[spotify.ID]struct{}),
+	}
+	if err := l.loadBlocklist(blocklistFile); err != nil {
+		return nil, err
+	}
+	if err := l.loadAllowlist(allowlistFile); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Lists) loadBlocklist(file string) error {
+	if file == "" {
+		return nil
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read blocklist '%s': %w", file, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		kind, id, ok := parseURI(line)
+		switch {
+		case ok && kind == "track":
+			l.blockedTracks[id] = struct{}{}
+		case ok && kind == "artist":
+			l.blockedArtists[id] = struct{}{}
+		case ok && kind == "album":
+			l.blockedAlbums[id] = struct{}{}
+		default:
+			return fmt.Errorf("blocklist '%s' line %d: expected a track, artist or album URI, got '%s'", file, n, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read blocklist '%s': %w", file, err)
+	}
+	return nil
+}
+
+func (l *Lists) loadAllowlist(file string) error {
+	if file == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read allowlist '%s': %w", file, err)
+	}
+	var a allowlist
+	if err := yaml.Unmarshal(raw, &a); err != nil {
+		return fmt.Errorf("could not parse allowlist '%s': %w", file, err)
+	}
+	for _, pin := range a.Pins {
+		for _, pattern := range pin.Playlists {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid playlist pattern '%s' in allowlist: %w", pattern, err)
+			}
+		}
+		for _, uri := range pin.Tracks {
+			if kind, _, ok := parseURI(uri); !ok || kind != "track" {
+				return fmt.Errorf("allowlist can only pin tracks, got '%s'", uri)
+			}
+		}
+	}
+	l.pins = a.Pins
+	return nil
+}
+
+// parseURI splits "spotify:<kind>:<id>".
+func parseURI(uri string) (kind string, id spotify.ID, ok bool) {
+	parts := strings.Split(uri, ":")
+	if len(parts) != 3 || parts[0] != "spotify" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], spotify.ID(parts[2]), true
+}
+
+// Empty reports whether there is nothing to block or pin.
+func (l *Lists) Empty() bool {
+	return len(l.blockedTracks) == 0 && !l.blocksByMetadata() && len(l.pins) == 0
+}
+
+// blocksByMetadata reports whether blocking needs to know tracks' artists and albums.
+func (l *Lists) blocksByMetadata() bool {
+	return len(l.blockedArtists) > 0 || len(l.blockedAlbums) > 0
+}
+
+// Blocked reports whether a track, by the given artists and on the given album, is
+// blocked.
+func (l *Lists) Blocked(trackID spotify.ID, artistIDs []spotify.ID, albumID spotify.ID) bool {
+	if _, ok := l.blockedTracks[trackID]; ok {
+		return true
+	}
+	if _, ok := l.blockedAlbums[albumID]; ok && albumID != "" {
+		return true
+	}
+	for _, id := range artistIDs {
+		if _, ok := l.blockedArtists[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PinsFor returns the tracks pinned into the playlist called name, in allowlist order.
+// Folder prefixes are ignored.
+func (l *Lists) PinsFor(name string) []spotify.ID {
+	base := folders.StripPrefix(name)
+	var pinned []spotify.ID
+	seen := make(map[spotify.ID]struct{})
+	for _, pin := range l.pins {
+		if !matchesAny(pin.Playlists, base) {
+			continue
+		}
+		for _, uri := range pin.Tracks {
+			_, id, _ := parseURI(uri)
+			if _, dup := seen[id]; !dup {
+				seen[id] = struct{}{}
+				pinned = append(pinned, id)
+			}
+		}
+	}
+	return pinned
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Package matching scores how likely two track descriptions refer to the same recording.
+// It's used wherever tracks have to be found without a Spotify ID, such as imported
+// listening history, cross-service imports and duplicate detection.
+package matching
+
+import (
+	"math"
+	"spotify/internal/config"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Track is the metadata a match is scored on. Empty fields are left out of the score.
+type Track struct {
+	ID       spotify.ID
+	Title    string
+	Artist   string
+	ISRC     string
+	Duration time.Duration
+}
+
+// FromFull converts a Spotify track, joining its artists with commas.
+func FromFull(t spotify.FullTrack) Track {
+	names := make([]string, len(t.Artists))
+	for i, a := range t.Artists {
+		names[i] = a.Name
+	}
+	return Track{
+		ID:       t.ID,
+		Title:    t.Name,
+		Artist:   strings.Join(names, ", "),
+		ISRC:     ISRC(t),
+		Duration: time.Duration(t.Duration) * time.Millisecond,
+	}
+}
+
+// ISRC returns the recording code of t, or "" if Spotify doesn't know it.
+func ISRC(t spotify.FullTrack) string {
+	return t.ExternalIDs["isrc"]
+}
+
+// Matcher scores candidate tracks using configurable weights.
+type Matcher struct {
+	cfg config.Matching
+}
+
+// New returns a Matcher configured by cfg.
+func New(cfg config.Matching) *Matcher {
+	return &Matcher{cfg: cfg}
+}
+
+// Score returns a value between 0 and 1 describing how well got matches want. Each
+// field present on both sides contributes its similarity, weighted by the config.
+func (m *Matcher) Score(want, got Track) float64 {
+	w := m.cfg.Weights
+	var total, weights float64
+	add := func(weight, similarity float64) {
+		total += weight * similarity
+		weights += weight
+	}
+	if want.ISRC != "" && got.ISRC != "" {
+		add(w.ISRC, boolScore(strings.EqualFold(want.ISRC, got.ISRC)))
+	}
+	if want.Title != "" && got.Title != "" {
+		add(w.Title, similarity(NormalizeTitle(want.Title), NormalizeTitle(got.Title)))
+	}
+	if want.Artist != "" && got.Artist != "" {
+		add(w.Artist, artistSimilarity(want.Artist, got.Artist))
+	}
+	if want.Duration > 0 && got.Duration > 0 {
+		add(w.Duration, m.durationSimilarity(want.Duration, got.Duration))
+	}
+	if weights == 0 {
+		return 0
+	}
+	return total / weights
+}
+
+// Best returns the highest-scoring candidate, or false if none reaches the threshold.
+func (m *Matcher) Best(want Track, candidates []Track) (Track, float64, bool) {
+	var best Track
+	bestScore := -1.0
+	for _, c := range candidates {
+		if s := m.Score(want, c); s > bestScore {
+			best, bestScore = c, s
+		}
+	}
+	if bestScore < m.cfg.Threshold {
+		return Track{}, bestScore, false
+	}
+	return best, bestScore, true
+}
+
+// durationSimilarity is 1 within the tolerance and falls to 0 at three times it.
+func (m *Matcher) durationSimilarity(a, b time.Duration) float64 {
+	tolerance := m.cfg.DurationTolerance
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff <= tolerance {
+		return 1
+	}
+	if tolerance <= 0 {
+		return 0
+	}
+	return math.Max(0, 1-float64(diff-tolerance)/float64(2*tolerance))
+}
+
+// artistSimilarity compares the best-matching pair of credited artists, so a feature
+// credited on only one side doesn't sink the score.
+func artistSimilarity(a, b string) float64 {
+	var best float64
+	for _, x := range splitArtists(a) {
+		for _, y := range splitArtists(b) {
+			best = math.Max(best, similarity(x, y))
+		}
+	}
+	return best
+}
+
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package matching
+
+import (
+	"math"
+	"spotify/internal/config"
+	"testing"
+	"time"
+)
+
+// corpus pairs a track to find with a candidate, and the score and verdict expected of
+// them. cfg is the default config unless set.
+var corpus = []struct {
+	name      string
+	cfg       *config.Matching
+	want, got Track
+	score     float64
+	match     bool
+}{
+	{
+		name:  "exact",
+		want:  Track{Title: "Paranoid Android", Artist: "Radiohead", Duration: 387 * time.Second},
+		got:   Track{Title: "Paranoid Android", Artist: "Radiohead", Duration: 387 * time.Second},
+		score: 1, match: true,
+	},
+	{
+		name:  "remaster suffix",
+		want:  Track{Title: "Paranoid Android", Artist: "Radiohead"},
+		got:   Track{Title: "Paranoid Android - Remastered 2017", Artist: "Radiohead"},
+		score: 1, match: true,
+	},
+	{
+		name:  "bracketed live version",
+		want:  Track{Title: "Creep (Live)", Artist: "Radiohead"},
+		got:   Track{Title: "Creep", Artist: "Radiohead"},
+		score: 1, match: true,
+	},
+	{
+		name:  "featured artist",
+		want:  Track{Title: "Stay (feat. Justin Bieber)", Artist: "The Kid LAROI, Justin Bieber"},
+		got:   Track{Title: "Stay", Artist: "The Kid LAROI"},
+		score: 1, match: true,
+	},
+	{
+		name:  "accents",
+		want:  Track{Title: "Déjà Vu", Artist: "Beyoncé"},
+		got:   Track{Title: "Deja Vu", Artist: "Beyonce"},
+		score: 1, match: true,
+	},
+	{
+		// ISRC 6, title 3 and artist 2 × 7/11 over 11.
+		name:  "isrc hit outweighs a different credit",
+		want:  Track{Title: "Yesterday", Artist: "The Beatles", ISRC: "GBAYE0601498"},
+		got:   Track{Title: "Yesterday", Artist: "Beatles", ISRC: "gbaye0601498"},
+		score: (6 + 3 + 2*(7.0/11)) / 11, match: true,
+	},
+	{
+		name:  "isrc miss sinks matching metadata",
+		want:  Track{Title: "Yesterday", Artist: "The Beatles", ISRC: "GBAYE0601498", Duration: 125 * time.Second},
+		got:   Track{Title: "Yesterday", Artist: "The Beatles", ISRC: "GBAYE0601499", Duration: 125 * time.Second},
+		score: 0.5, match: false,
+	},
+	{
+		name:  "different song by the same artist",
+		want:  Track{Title: "Yesterday", Artist: "The Beatles"},
+		got:   Track{Title: "Let It Be", Artist: "The Beatles"},
+		score: (3*(1.0/9) + 2) / 5, match: false,
+	},
+	{
+		name:  "duration within tolerance",
+		want:  Track{Title: "Creep", Artist: "Radiohead", Duration: 200 * time.Second},
+		got:   Track{Title: "Creep", Artist: "Radiohead", Duration: 202 * time.Second},
+		score: 1, match: true,
+	},
+	{
+		// 6s apart is 3s past the tolerance, halfway to 0 at three times it.
+		name:  "duration past tolerance",
+		want:  Track{Title: "Creep", Artist: "Radiohead", Duration: 200 * time.Second},
+		got:   Track{Title: "Creep", Artist: "Radiohead", Duration: 206 * time.Second},
+		score: 5.5 / 6, match: true,
+	},
+	{
+		name:  "duration far off",
+		want:  Track{Title: "Creep", Artist: "Radiohead", Duration: 200 * time.Second},
+		got:   Track{Title: "Creep", Artist: "Radiohead", Duration: 215 * time.Second},
+		score: 5.0 / 6, match: true,
+	},
+	{
+		name:  "missing fields are left out",
+		want:  Track{Title: "Creep", Artist: "Radiohead"},
+		got:   Track{Title: "Creep", Artist: "Radiohead", ISRC: "GBAYE9200070", Duration: 238 * time.Second},
+		score: 1, match: true,
+	},
+	{
+		name:  "nothing to compare",
+		want:  Track{ISRC: "GBAYE9200070"},
+		got:   Track{Title: "Creep"},
+		score: 0, match: false,
+	},
+	{
+		name: "custom weights ignore the isrc",
+		cfg: &config.Matching{
+			Weights:   config.MatchWeights{Title: 1, Artist: 1},
+			Threshold: 0.9,
+		},
+		want:  Track{Title: "Yesterday", Artist: "The Beatles", ISRC: "GBAYE0601498"},
+		got:   Track{Title: "Yesterday", Artist: "The Beatles", ISRC: "GBAYE0601499"},
+		score: 1, match: true,
+	},
+	{
+		name: "custom threshold rejects a close title",
+		cfg: &config.Matching{
+			Weights:   config.MatchWeights{Title: 1, Artist: 1},
+			Threshold: 0.95,
+		},
+		want:  Track{Title: "The Beatles", Artist: "Radiohead"},
+		got:   Track{Title: "Beatles", Artist: "Radiohead"},
+		score: (7.0/11 + 1) / 2, match: false,
+	},
+	{
+		name: "no duration tolerance",
+		cfg: &config.Matching{
+			Weights:   config.MatchWeights{Title: 3, Artist: 2, ISRC: 6, Duration: 1},
+			Threshold: 0.9,
+		},
+		want:  Track{Title: "Creep", Artist: "Radiohead", Duration: 200 * time.Second},
+		got:   Track{Title: "Creep", Artist: "Radiohead", Duration: 201 * time.Second},
+		score: 5.0 / 6, match: false,
+	},
+}
+
+func TestScoreCorpus(t *testing.T) {
+	for _, tt := range corpus {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default().Matching
+			if tt.cfg != nil {
+				cfg = *tt.cfg
+			}
+			m := New(cfg)
+			if got := m.Score(tt.want, tt.got); math.Abs(got-tt.score) > 1e-9 {
+				t.Errorf("Score = %.4f, want %.4f", got, tt.score)
+			}
+			if _, _, ok := m.Best(tt.want, []Track{tt.got}); ok != tt.match {
+				t.Errorf("Best matched = %t, want %t (threshold %.2f)", ok, tt.match, cfg.Threshold)
+			}
+		})
+	}
+}
+
+func TestBestPicksHighestScore(t *testing.T) {
+	m := New(config.Default().Matching)
+	want := Track{Title: "Creep", Artist: "Radiohead", Duration: 238 * time.Second}
+	candidates := []Track{
+		{ID: "cover", Title: "Creep", Artist: "Scala & Kolacny Brothers", Duration: 290 * time.Second},
+		{ID: "live", Title: "Creep - Live", Artist: "Radiohead", Duration: 250 * time.Second},
+		{ID: "original", Title: "Creep", Artist: "Radiohead", Duration: 239 * time.Second},
+	}
+	got, score, ok := m.Best(want, candidates)
+	if !ok || got.ID != "original" {
+		t.Fatalf("Best = %s (%.2f, %t), want original", got.ID, score, ok)
+	}
+}
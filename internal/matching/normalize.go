@@ -0,0 +1,134 @@
+package matching
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// versionKeywords mark decorations that don't change the song, e.g. "2011 Remaster",
+	// "Deluxe Edition" or "Radio Edit".
+	versionKeywords = `remaster|remastered|deluxe|edition|version|edit|mix|mono|stereo|live|anniversary|bonus|explicit|clean|single`
+	// dashSuffix matches " - Remastered 2011" and the same with en or em dashes.
+	dashSuffix = regexp.MustCompile(`(?i)\s+[-–—]\s+.*\b(` + versionKeywords + `)\b.*$`)
+	// bracketed matches "(feat. X)", "(2011 Remaster)", "[Live]" and the like.
+	bracketed = regexp.MustCompile(`(?i)\s*[(\[]\s*(feat\.?|ft\.?|with\b|[^)\]]*\b(` + versionKeywords + `)\b)[^)\]]*[)\]]`)
+	// artistSeparators splits multi-artist credits such as "A, B & C feat. D".
+	artistSeparators = regexp.MustCompile(`(?i)\s*(,|&|\bfeat\.?|\bft\.?|\band\b)\s*`)
+)
+
+// NormalizeTitle strips version decorations, accents, punctuation and case from a track
+// title, so "Song – 2011 Remaster", "Song (Live)" and "Sóng" all become "song".
+func NormalizeTitle(title string) string {
+	stripped := bracketed.ReplaceAllString(dashSuffix.ReplaceAllString(title, ""), "")
+	if normalized := normalize(stripped); normalized != "" {
+		return normalized
+	}
+	// A title that is all decoration, like "Live", is its own name.
+	return normalize(title)
+}
+
+// Key identifies a song by its normalized title and first credited artist, for grouping
+// versions of the same song when deduplicating or diffing.
+func Key(title, artist string) string {
+	var first string
+	if artists := splitArtists(artist); len(artists) > 0 {
+		first = artists[0]
+	}
+	return NormalizeTitle(title) + "|" + first
+}
+
+// normalize folds accents, lowercases s, drops punctuation and collapses whitespace.
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if folded, ok := accents[r]; ok {
+			r = folded
+		}
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// accents maps accented Latin letters to their base letter.
+var accents = func() map[rune]rune {
+	groups := map[rune]string{
+		'a': "àáâãäåāăą",
+		'c': "çćĉċč",
+		'd': "ďđ",
+		'e': "èéêëēĕėęě",
+		'g': "ĝğġģ",
+		'h': "ĥħ",
+		'i': "ìíîïĩīĭįı",
+		'j': "ĵ",
+		'k': "ķ",
+		'l': "ĺļľŀł",
+		'n': "ñńņňŉ",
+		'o': "òóôõöøōŏő",
+		'r': "ŕŗř",
+		's': "śŝşšß",
+		't': "ţťŧ",
+		'u': "ùúûüũūŭůűų",
+		'w': "ŵ",
+		'y': "ýÿŷ",
+		'z': "źżž",
+	}
+	m := make(map[rune]rune)
+	for base, letters := range groups {
+		for _, r := range letters {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// splitArtists returns the normalized names in a credit string.
+func splitArtists(credit string) []string {
+	var names []string
+	for _, name := range artistSeparators.Split(credit, -1) {
+		if n := normalize(name); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// similarity returns 1 minus the normalized edit distance between a and b.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein computes the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// windowsToast shows a toast with the title and message passed in the environment, as
+// PowerShell's own app so no registration is needed.
+const windowsToast = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:NOTIFY_MESSAGE)) > $null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($template))
+`
+
+// Desktop shows a native desktop notification: through osascript on macOS, PowerShell
+// on Windows and notify-send elsewhere. The text is passed as arguments, never as
+// script, so it needs no escaping.
+func Desktop(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToast)
+		cmd.Env = append(os.Environ(), "NOTIFY_TITLE="+title, "NOTIFY_MESSAGE="+message)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=spotify-manager", title, message)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("could not show a desktop notification with %s: %w %s", cmd.Path, err, out)
+	}
+	return nil
+}
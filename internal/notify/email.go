@@ -0,0 +1,62 @@
+// Package notify tells the user how a run went: by email for unattended runs on servers
+// where webhooks can't reach anything, or with a desktop notification.
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"spotify/internal/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mailer sends plain-text emails through an SMTP server.
+type Mailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+// NewMailer returns a mailer for cfg, logging in with cfg.Username and password when a
+// username is set.
+func NewMailer(cfg config.Email, password string) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("email needs an SMTP host")
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("email needs a from address and at least one recipient")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	m := &Mailer{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		from: cfg.From,
+		to:   cfg.To,
+	}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, password, cfg.Host)
+	}
+	return m, nil
+}
+
+// Send emails subject and body to the recipients.
+func (m *Mailer) Send(subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if err := smtp.SendMail(m.addr, m.auth, m.from, m.to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("could not send email through %s: %w", m.addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"spotify/internal/cache"
+	"spotify/internal/processor"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// DryRun is a SpotifyClient that reads from the wrapped client but only records writes,
+// answering them the way Spotify would so processors run to completion. Playlists it has
+// written read back with their simulated contents and snapshots; the library doesn't,
+// so a later step still sees songs an earlier one would have unliked.
+type DryRun struct {
+	processor.SpotifyClient
+
+	mu        sync.Mutex
+	step      string
+	steps     []*stepReport
+	names     map[spotify.ID]string
+	playlists map[spotify.ID]*simPlaylist
+	created   int
+}
+
+// simPlaylist is the simulated state of a written playlist.
+type simPlaylist struct {
+	tracks   []spotify.ID
+	snapshot int
+}
+
+// stepReport collects what one step would have changed.
+type stepReport struct {
+	name string
+	// changes counts library changes by description, e.g. "unlike songs".
+	changes map[string]int
+	order   []string
+	// playlists holds the playlist writes, in the order the playlists were first touched.
+	playlists []*playlistChange
+}
+
+type playlistChange struct {
+	id                 spotify.ID
+	created            bool
+	rewritten          bool
+	total              int
+	added, removed     int
+	renamed, described bool
+	// published is set for playlists made public, unpublished for ones made private.
+	published, unpublished bool
+	cover                  bool
+}
+
+// NewDryRun wraps client.
+func NewDryRun(client processor.SpotifyClient) *DryRun {
+	return &DryRun{
+		SpotifyClient: client,
+		names:         make(map[spotify.ID]string),
+		playlists:     make(map[spotify.ID]*simPlaylist),
+	}
+}
+
+// Step attributes the writes that follow to the step called name.
+func (d *DryRun) Step(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.step = name
+}
+
+// current returns the report of the running step. d.mu must be held.
+func (d *DryRun) current() *stepReport {
+	if n := len(d.steps); n > 0 && d.steps[n-1].name == d.step {
+		return d.steps[n-1]
+	}
+	r := &stepReport{name: d.step, changes: make(map[string]int)}
+	d.steps = append(d.steps, r)
+	return r
+}
+
+// count records n library changes of a kind.
+func (d *DryRun) count(change string, n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := d.current()
+	if _, ok := r.changes[change]; !ok {
+		r.order = append(r.order, change)
+	}
+	r.changes[change] += n
+}
+
+// playlist returns the running step's change record of a playlist. d.mu must be held.
+func (d *DryRun) playlist(id spotify.ID) *playlistChange {
+	r := d.current()
+	for _, c := range r.playlists {
+		if c.id == id {
+			return c
+		}
+	}
+	c := &playlistChange{id: id}
+	r.playlists = append(r.playlists, c)
+	return c
+}
+
+func (d *DryRun) AddTracksToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	d.count("like songs", len(ids))
+	return nil
+}
+
+func (d *DryRun) RemoveTracksFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	d.count("unlike songs", len(ids))
+	return nil
+}
+
+func (d *DryRun) AddAlbumsToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	d.count("save albums", len(ids))
+	return nil
+}
+
+func (d *DryRun) RemoveAlbumsFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	d.count("unsave albums", len(ids))
+	return nil
+}
+
+func (d *DryRun) FollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	d.count("follow artists", len(ids))
+	return nil
+}
+
+func (d *DryRun) UnfollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	d.count("unfollow artists", len(ids))
+	return nil
+}
+
+func (d *DryRun) TransferPlayback(ctx context.Context, deviceID spotify.ID, play bool) error {
+	d.count("transfer playback", 1)
+	return nil
+}
+
+func (d *DryRun) PlayOpt(ctx context.Context, opt *spotify.PlayOptions) error {
+	d.count("start playback", 1)
+	return nil
+}
+
+func (d *DryRun) QueueSongOpt(ctx context.Context, trackID spotify.ID, opt *spotify.PlayOptions) error {
+	d.count("queue songs", 1)
+	return nil
+}
+
+func (d *DryRun) UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error {
+	d.count("delete playlists", 1)
+	return nil
+}
+
+// GetPlaylistsForUser passes through, remembering playlist names for the report.
+func (d *DryRun) GetPlaylistsForUser(ctx context.Context, userID string, opts ...spotify.RequestOption) (*spotify.SimplePlaylistPage, error) {
+	page, err := d.SpotifyClient.GetPlaylistsForUser(ctx, userID, opts...)
+	if err == nil {
+		d.mu.Lock()
+		for _, pl := range page.Playlists {
+			d.names[pl.ID] = pl.Name
+		}
+		d.mu.Unlock()
+	}
+	return page, err
+}
+
+func (d *DryRun) CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.created++
+	id := spotify.ID("dry-run-" + strconv.Itoa(d.created))
+	d.names[id] = playlistName
+	d.playlists[id] = &simPlaylist{}
+	d.playlist(id).created = true
+	playlist := &spotify.FullPlaylist{}
+	playlist.ID = id
+	playlist.Name = playlistName
+	playlist.Description = description
+	playlist.SnapshotID = snapshotID(id, 0)
+	return playlist, nil
+}
+
+func (d *DryRun) ChangePlaylistName(ctx context.Context, playlistID spotify.ID, newName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.playlist(playlistID).renamed = true
+	return nil
+}
+
+func (d *DryRun) ChangePlaylistDescription(ctx context.Context, playlistID spotify.ID, newDescription string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.playlist(playlistID).described = true
+	return nil
+}
+
+func (d *DryRun) ChangePlaylistAccess(ctx context.Context, playlistID spotify.ID, public bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.playlist(playlistID).published = public
+	d.playlist(playlistID).unpublished = !public
+	return nil
+}
+
+func (d *DryRun) SetPlaylistImage(ctx context.Context, playlistID spotify.ID, img io.Reader) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.playlist(playlistID).cover = true
+	return nil
+}
+
+func (d *DryRun) ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error) {
+	ids := make([]spotify.ID, len(items))
+	for i, uri := range items {
+		ids[i] = spotify.ID(strings.TrimPrefix(string(uri), "spotify:track:"))
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sim := d.sim(playlistID)
+	sim.tracks = ids
+	c := d.playlist(playlistID)
+	c.rewritten, c.added, c.removed = true, 0, 0
+	return d.write(playlistID, sim), nil
+}
+
+func (d *DryRun) AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	if err := d.seed(ctx, playlistID); err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sim := d.sim(playlistID)
+	sim.tracks = append(sim.tracks, trackIDs...)
+	d.playlist(playlistID).added += len(trackIDs)
+	return d.write(playlistID, sim), nil
+}
+
+func (d *DryRun) RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	if err := d.seed(ctx, playlistID); err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sim := d.sim(playlistID)
+	before := len(sim.tracks)
+	// Like Spotify, every occurrence of a track is removed.
+	sim.tracks = slices.DeleteFunc(sim.tracks, func(id spotify.ID) bool { return slices.Contains(trackIDs, id) })
+	d.playlist(playlistID).removed += before - len(sim.tracks)
+	return d.write(playlistID, sim), nil
+}
+
+// GetPlaylist answers for written playlists with their simulated snapshot, so writers
+// checking for concurrent edits see their own.
+func (d *DryRun) GetPlaylist(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.FullPlaylist, error) {
+	d.mu.Lock()
+	sim, ok := d.playlists[playlistID]
+	playlist := &spotify.FullPlaylist{}
+	if ok {
+		playlist.ID = playlistID
+		playlist.Name = d.names[playlistID]
+		playlist.SnapshotID = snapshotID(playlistID, sim.snapshot)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return d.SpotifyClient.GetPlaylist(ctx, playlistID, opts...)
+	}
+	return playlist, nil
+}
+
+// GetPlaylistTracks answers for written playlists with their simulated contents.
+func (d *DryRun) GetPlaylistTracks(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.PlaylistTrackPage, error) {
+	d.mu.Lock()
+	sim, ok := d.playlists[playlistID]
+	var tracks []spotify.ID
+	if ok {
+		tracks = slices.Clone(sim.tracks)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return d.SpotifyClient.GetPlaylistTracks(ctx, playlistID, opts...)
+	}
+	query, _ := cache.Query(opts)
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		limit = 100
+	}
+	page := &spotify.PlaylistTrackPage{}
+	page.Total = spotify.Numeric(len(tracks))
+	for _, id := range tracks[min(offset, len(tracks)):min(offset+limit, len(tracks))] {
+		var item spotify.PlaylistTrack
+		item.Track.ID = id
+		page.Tracks = append(page.Tracks, item)
+	}
+	return page, nil
+}
+
+// seed loads the real contents of a playlist before its first simulated edit, so appends
+// and removals apply to what's there.
+func (d *DryRun) seed(ctx context.Context, playlistID spotify.ID) error {
+	d.mu.Lock()
+	_, ok := d.playlists[playlistID]
+	d.mu.Unlock()
+	if ok {
+		return nil
+	}
+	var tracks []spotify.ID
+	for offset := 0; ; {
+		page, err := d.SpotifyClient.GetPlaylistTracks(ctx, playlistID, spotify.Limit(100), spotify.Offset(offset))
+		if err != nil {
+			return fmt.Errorf("could not read playlist for the dry run: %w", err)
+		}
+		if len(page.Tracks) == 0 {
+			break
+		}
+		for _, item := range page.Tracks {
+			tracks = append(tracks, item.Track.ID)
+		}
+		offset += len(page.Tracks)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.playlists[playlistID]; !ok {
+		d.playlists[playlistID] = &simPlaylist{tracks: tracks}
+	}
+	return nil
+}
+
+// sim returns the simulated state of a playlist, starting it empty. d.mu must be held.
+func (d *DryRun) sim(playlistID spotify.ID) *simPlaylist {
+	sim, ok := d.playlists[playlistID]
+	if !ok {
+		sim = &simPlaylist{}
+		d.playlists[playlistID] = sim
+	}
+	return sim
+}
+
+// write records a simulated write and returns the playlist's new snapshot. d.mu must
+// be held.
+func (d *DryRun) write(playlistID spotify.ID, sim *simPlaylist) string {
+	sim.snapshot++
+	d.playlist(playlistID).total = len(sim.tracks)
+	return snapshotID(playlistID, sim.snapshot)
+}
+
+func snapshotID(playlistID spotify.ID, n int) string {
+	return fmt.Sprintf("dry-run-%s-%d", playlistID, n)
+}
+
+// WriteReport prints what every step would have changed.
+func (d *DryRun) WriteReport(out io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fmt.Fprintln(out, "\n🧪 Dry run: nothing was changed. The pipeline would:")
+	if len(d.steps) == 0 {
+		fmt.Fprintln(out, "  change nothing.")
+		return
+	}
+	for _, r := range d.steps {
+		fmt.Fprintf(out, "\n  %s:\n", r.name)
+		if len(r.order) == 0 && len(r.playlists) == 0 {
+			fmt.Fprintln(out, "    - change nothing")
+		}
+		for _, change := range r.order {
+			fmt.Fprintf(out, "    - %s: %d\n", change, r.changes[change])
+		}
+		for _, c := range r.playlists {
+			fmt.Fprintf(out, "    - %s\n", d.describe(c))
+		}
+	}
+}
+
+// describe summarizes the changes to one playlist. d.mu must be held.
+func (d *DryRun) describe(c *playlistChange) string {
+	name := d.names[c.id]
+	if name == "" {
+		name = string(c.id)
+	}
+	var what string
+	switch {
+	case c.created:
+		what = fmt.Sprintf("create '%s' with %d tracks", name, c.total)
+	case c.rewritten:
+		what = fmt.Sprintf("rewrite '%s' with %d tracks", name, c.total)
+	case c.added > 0 || c.removed > 0:
+		what = fmt.Sprintf("add %d and remove %d tracks in '%s' (%d after)", c.added, c.removed, name, c.total)
+	default:
+		what = fmt.Sprintf("update '%s'", name)
+	}
+	if c.renamed {
+		what += ", renaming it"
+	}
+	if c.described {
+		what += ", updating its description"
+	}
+	if c.published {
+		what += ", making it public"
+	}
+	if c.unpublished {
+		what += ", making it private"
+	}
+	if c.cover {
+		what += ", uploading a cover"
+	}
+	return what
+}
@@ -0,0 +1,102 @@
+// Package pipeline runs several processors one after another in a single session, so
+// they share the login and the cache of API responses.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"spotify/internal/processor"
+	"strings"
+)
+
+// Step is a built command of a pipeline.
+type Step struct {
+	Name string
+	Task processor.Processor
+}
+
+type runner struct {
+	name            string
+	steps           []Step
+	continueOnError bool
+	dryRun          *DryRun
+	out             io.Writer
+	logger          *log.Logger
+}
+
+// New returns a Processor that runs steps in order. By default the first failing step
+// ends the pipeline; with continueOnError the remaining steps run anyway and the
+// failures are reported together. If dryRun is non-nil, it must be the client the
+// steps were built with, and what they would have changed is printed to out at the end.
+func New(name string, steps []Step, continueOnError bool, dryRun *DryRun, out io.Writer, logger *log.Logger) processor.Processor {
+	return &runner{
+		name:            name,
+		steps:           steps,
+		continueOnError: continueOnError,
+		dryRun:          dryRun,
+		out:             out,
+		logger:          logger,
+	}
+}
+
+// Run runs the steps.
+func (r *runner) Run(ctx context.Context) error {
+	if r.dryRun != nil {
+		defer r.dryRun.WriteReport(r.out)
+	}
+	var failures []error
+	for i, step := range r.steps {
+		if err := ctx.Err(); err != nil {
+			r.reportStopped(ctx, i)
+			failures = append(failures, err)
+			break
+		}
+		if r.dryRun != nil {
+			r.dryRun.Step(step.Name)
+		}
+		r.logger.Printf("▶️  Pipeline '%s', step %d/%d: %s", r.name, i+1, len(r.steps), step.Name)
+		if err := step.Task.Run(ctx); err != nil && !errors.Is(err, processor.ErrNothingToDo) {
+			r.logger.Printf("❌ Step '%s' failed: %v", step.Name, err)
+			if ctx.Err() != nil {
+				r.reportStopped(ctx, i+1)
+				failures = append(failures, fmt.Errorf("%s: %w", step.Name, err))
+				break
+			}
+			if !r.continueOnError {
+				return fmt.Errorf("pipeline '%s' stopped at step '%s': %w", r.name, step.Name, err)
+			}
+			failures = append(failures, fmt.Errorf("%s: %w", step.Name, err))
+		}
+	}
+	if len(failures) == 0 {
+		r.logger.Printf("✅ Pipeline '%s' finished.", r.name)
+		return nil
+	}
+	return &processor.PartialError{
+		Failed: len(failures),
+		Total:  len(r.steps),
+		Err:    fmt.Errorf("%d of %d steps of pipeline '%s' failed:\n%w", len(failures), len(r.steps), r.name, errors.Join(failures...)),
+	}
+}
+
+// reportStopped logs the steps run and not run when ctx stopped the pipeline before step
+// next.
+func (r *runner) reportStopped(ctx context.Context, next int) {
+	names := func(steps []Step) string {
+		var s []string
+		for _, step := range steps {
+			s = append(s, step.Name)
+		}
+		return strings.Join(s, ", ")
+	}
+	r.logger.Printf("🛑 Pipeline '%s' stopped early (%v) after %d of %d steps.", r.name, context.Cause(ctx), next, len(r.steps))
+	if next > 0 {
+		r.logger.Printf("   Ran: %s", names(r.steps[:next]))
+	}
+	if next < len(r.steps) {
+		r.logger.Printf("   Not run: %s", names(r.steps[next:]))
+	}
+}
@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"spotify/internal/config"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type albumCompletionist struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	cfg       config.Completionist
+	// playlist enables the playlist of the qualifying albums' missing tracks.
+	playlist bool
+}
+
+// NewAlbumCompletionist returns a Processor that saves the albums most of whose tracks
+// are liked, as set by cfg, and with playlist also writes their tracks that aren't liked
+// yet into a playlist, album by album.
+func NewAlbumCompletionist(client SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfg config.Completionist, shared config.Playlists, playlist bool) (Processor, error) {
+	if cfg.MinTracks <= 0 && cfg.MinPercent <= 0 {
+		return nil, fmt.Errorf("set completionist.min_tracks or completionist.min_percent")
+	}
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &albumCompletionist{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, st, logger),
+		templates: templates,
+		cfg:       cfg,
+		playlist:  playlist,
+	}, nil
+}
+
+// Run finds the qualifying albums, saves the ones that aren't saved and writes the
+// playlist.
+func (p *albumCompletionist) Run(ctx context.Context) error {
+	likedTracks, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	liked := make(map[spotify.ID]struct{}, len(likedTracks))
+	likedByAlbum := make(map[spotify.ID][]spotify.FullTrack)
+	for _, t := range likedTracks {
+		liked[t.ID] = struct{}{}
+		likedByAlbum[t.Album.ID] = append(likedByAlbum[t.Album.ID], t.FullTrack)
+	}
+
+	var qualifying []spotify.SimpleAlbum
+	for id, tracks := range likedByAlbum {
+		album := tracks[0].Album
+		if id == "" || album.AlbumType == "single" || !p.qualifies(len(tracks), int(album.TotalTracks)) {
+			continue
+		}
+		qualifying = append(qualifying, album)
+	}
+	sort.Slice(qualifying, func(i, j int) bool {
+		if a, b := artistNames(qualifying[i].Artists), artistNames(qualifying[j].Artists); a != b {
+			return a < b
+		}
+		return qualifying[i].ReleaseDate < qualifying[j].ReleaseDate
+	})
+	if len(qualifying) == 0 {
+		p.logger.Println("No album has enough liked tracks. Nothing to do.")
+		return ErrNothingToDo
+	}
+
+	savedAlbums, err := fetchSavedAlbums(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch saved albums: %w", err)
+	}
+	saved := make(map[spotify.ID]struct{}, len(savedAlbums))
+	for _, a := range savedAlbums {
+		saved[a.ID] = struct{}{}
+	}
+	var toSave []spotify.ID
+	for _, a := range qualifying {
+		if _, ok := saved[a.ID]; !ok {
+			p.logger.Printf("  saving '%s' by %s (%d of %d tracks liked)", a.Name, artistNames(a.Artists), len(likedByAlbum[a.ID]), a.TotalTracks)
+			toSave = append(toSave, a.ID)
+		}
+	}
+	if err := inBatches(toSave, 20, func(batch []spotify.ID) error {
+		return p.client.AddAlbumsToLibrary(ctx, batch...)
+	}); err != nil {
+		return fmt.Errorf("failed to save albums: %w", err)
+	}
+	p.logger.Printf("✅ Saved %d albums; %d more qualifying albums were already saved.", len(toSave), len(qualifying)-len(toSave))
+
+	if !p.playlist {
+		return nil
+	}
+	return p.writeMissing(ctx, qualifying, liked)
+}
+
+// qualifies reports whether an album with total tracks, of which likedCount are liked,
+// is liked enough to save. At least two tracks must be liked, so one favorite doesn't
+// make a two-track release qualify.
+func (p *albumCompletionist) qualifies(likedCount, total int) bool {
+	if likedCount < 2 {
+		return false
+	}
+	if p.cfg.MinTracks > 0 && likedCount >= p.cfg.MinTracks {
+		return true
+	}
+	return p.cfg.MinPercent > 0 && total > 0 && float64(likedCount)*100 >= p.cfg.MinPercent*float64(total)
+}
+
+// writeMissing writes the qualifying albums' tracks that aren't liked into the playlist,
+// in album order.
+func (p *albumCompletionist) writeMissing(ctx context.Context, albums []spotify.SimpleAlbum, liked map[spotify.ID]struct{}) error {
+	var missing []spotify.ID
+	for _, a := range albums {
+		trackIDs, err := fetchAlbumTrackIDs(ctx, p.client, a.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch tracks of album '%s': %w", a.Name, err)
+		}
+		for _, id := range trackIDs {
+			if _, ok := liked[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		p.logger.Println("You've liked every track of those albums. No playlist needed.")
+		return nil
+	}
+	tracks, err := getTracks(ctx, p.client, missing)
+	if err != nil {
+		return fmt.Errorf("failed to fetch tracks: %w", err)
+	}
+	var length time.Duration
+	for _, t := range tracks {
+		length += time.Duration(t.Duration) * time.Millisecond
+	}
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		TrackCount: len(missing),
+		Duration:   formatDuration(length),
+		Date:       time.Now().Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{Name: playlistName, Subtitle: p.cfg.CoverSubtitle, Tracks: tracks})
+	if err := p.writer.Replace(ctx, playlistID, missing); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	return nil
+}
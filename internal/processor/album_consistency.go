@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// AlbumConsistencyOptions selects the automatic fixes applied by the album consistency check.
+type AlbumConsistencyOptions struct {
+	// UnlikedAlbums fixes saved albums with no liked tracks: "unsave" removes the album,
+	// "like-tracks" likes all its tracks, and "" only reports them.
+	UnlikedAlbums string
+	// SaveCompleteAlbums saves albums whose every track is liked.
+	SaveCompleteAlbums bool
+}
+
+type albumConsistencyChecker struct {
+	client SpotifyClient
+	opts   AlbumConsistencyOptions
+	out    io.Writer
+	logger *log.Logger
+}
+
+// NewAlbumConsistencyChecker returns a Processor that compares saved albums with liked
+// tracks and reports albums that are saved with none of their tracks liked, or fully
+// liked but not saved, optionally fixing either case.
+func NewAlbumConsistencyChecker(client SpotifyClient, opts AlbumConsistencyOptions, out io.Writer, logger *log.Logger) (Processor, error) {
+	switch opts.UnlikedAlbums {
+	case "", "unsave", "like-tracks":
+	default:
+		return nil, fmt.Errorf("unknown fix '%s' for saved albums without liked tracks", opts.UnlikedAlbums)
+	}
+	return &albumConsistencyChecker{
+		client: client,
+		opts:   opts,
+		out:    out,
+		logger: logger,
+	}, nil
+}
+
+// Run builds the report and applies the selected fixes.
+func (p *albumConsistencyChecker) Run(ctx context.Context) error {
+	likedTracks, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	liked := make(map[spotify.ID]struct{}, len(likedTracks))
+	likedByAlbum := make(map[spotify.ID][]spotify.FullTrack)
+	for _, t := range likedTracks {
+		liked[t.ID] = struct{}{}
+		likedByAlbum[t.Album.ID] = append(likedByAlbum[t.Album.ID], t.FullTrack)
+	}
+
+	savedAlbums, err := fetchSavedAlbums(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch saved albums: %w", err)
+	}
+	saved := make(map[spotify.ID]struct{}, len(savedAlbums))
+
+	// Saved albums without a single liked track.
+	var unliked []spotify.SavedAlbum
+	for _, album := range savedAlbums {
+		saved[album.ID] = struct{}{}
+		if len(likedByAlbum[album.ID]) == 0 {
+			unliked = append(unliked, album)
+		}
+	}
+
+	// Albums whose every track is liked but that aren't saved.
+	var complete []spotify.SimpleAlbum
+	albumIDs := make([]spotify.ID, 0, len(likedByAlbum))
+	for id := range likedByAlbum {
+		albumIDs = append(albumIDs, id)
+	}
+	sort.Slice(albumIDs, func(i, j int) bool { return albumIDs[i] < albumIDs[j] })
+	for _, id := range albumIDs {
+		// Singles are trivially "complete"; only flag real albums.
+		if _, ok := saved[id]; ok || id == "" || len(likedByAlbum[id]) < 2 {
+			continue
+		}
+		trackIDs, err := fetchAlbumTrackIDs(ctx, p.client, id)
+		if err != nil {
+			return fmt.Errorf("failed to fetch tracks of album %s: %w", id, err)
+		}
+		if containsAll(liked, trackIDs) {
+			complete = append(complete, likedByAlbum[id][0].Album)
+		}
+	}
+
+	p.printReport(unliked, complete)
+	return p.fix(ctx, unliked, complete)
+}
+
+func (p *albumConsistencyChecker) printReport(unliked []spotify.SavedAlbum, complete []spotify.SimpleAlbum) {
+	fmt.Fprintf(p.out, "\n💿 Saved albums with no liked tracks (%d):\n", len(unliked))
+	for _, a := range unliked {
+		fmt.Fprintf(p.out, "  - %s — %s (%s)\n", a.Name, artistNames(a.Artists), a.URI)
+	}
+	fmt.Fprintf(p.out, "\n❤️  Fully liked albums that aren't saved (%d):\n", len(complete))
+	for _, a := range complete {
+		fmt.Fprintf(p.out, "  - %s — %s (%s)\n", a.Name, artistNames(a.Artists), a.URI)
+	}
+}
+
+func (p *albumConsistencyChecker) fix(ctx context.Context, unliked []spotify.SavedAlbum, complete []spotify.SimpleAlbum) error {
+	switch p.opts.UnlikedAlbums {
+	case "unsave":
+		ids := make([]spotify.ID, len(unliked))
+		for i, a := range unliked {
+			ids[i] = a.ID
+		}
+		if err := inBatches(ids, 20, func(batch []spotify.ID) error {
+			return p.client.RemoveAlbumsFromLibrary(ctx, batch...)
+		}); err != nil {
+			return fmt.Errorf("failed to unsave albums: %w", err)
+		}
+		p.logger.Printf("✅ Unsaved %d albums.", len(ids))
+	case "like-tracks":
+		var ids []spotify.ID
+		for _, a := range unliked {
+			trackIDs, err := fetchAlbumTrackIDs(ctx, p.client, a.ID)
+			if err != nil {
+				return fmt.Errorf("failed to fetch tracks of album '%s': %w", a.Name, err)
+			}
+			ids = append(ids, trackIDs...)
+		}
+		if err := inBatches(ids, 50, func(batch []spotify.ID) error {
+			return p.client.AddTracksToLibrary(ctx, batch...)
+		}); err != nil {
+			return fmt.Errorf("failed to like album tracks: %w", err)
+		}
+		p.logger.Printf("✅ Liked %d tracks from %d albums.", len(ids), len(unliked))
+	}
+
+	if p.opts.SaveCompleteAlbums {
+		ids := make([]spotify.ID, len(complete))
+		for i, a := range complete {
+			ids[i] = a.ID
+		}
+		if err := inBatches(ids, 20, func(batch []spotify.ID) error {
+			return p.client.AddAlbumsToLibrary(ctx, batch...)
+		}); err != nil {
+			return fmt.Errorf("failed to save albums: %w", err)
+		}
+		p.logger.Printf("✅ Saved %d albums.", len(ids))
+	}
+	return nil
+}
+
+func containsAll(set map[spotify.ID]struct{}, ids []spotify.ID) bool {
+	if len(ids) == 0 {
+		return false
+	}
+	for _, id := range ids {
+		if _, ok := set[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
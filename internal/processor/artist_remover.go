@@ -1,9 +1,14 @@
 package processor
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/zmb3/spotify/v2"
 )
@@ -29,53 +34,41 @@ func NewArtistTrackRemover(client SpotifyClient, artistsToRemove []string, logge
 	}
 }
 
-// Run contains the full logic for paging through liked songs and removing
-// tracks by the specified artists.
+// Run pages through the liked songs collecting the tracks by the specified artists, then
+// removes them. Removing while paging would shrink the library underneath the offsets
+// and skip the songs that move up into pages already read.
 func (p *artistTrackRemover) Run(ctx context.Context) error {
 	p.logger.Println("Starting artist track removal process...")
 
-	limit := 50
-	offset := 0
-	total := -1 // Sentinel value for total tracks
-
-	for {
-		p.logger.Printf("Fetching liked songs page (offset: %d)...", offset)
-		page, err := p.client.CurrentUsersTracks(ctx, spotify.Offset(offset), spotify.Limit(limit))
-		if err != nil {
-			return fmt.Errorf("couldn't get liked songs page: %w", err)
-		}
-
-		if total == -1 {
-			total = int(page.Total)
-			p.logger.Printf("Found %d total liked songs to process.", total)
-		}
-
-		if len(page.Tracks) == 0 {
-			p.logger.Println("No more liked songs found. Task complete.")
-			break
-		}
-
-		tracksToRemove := p.findTracksToRemove(page.Tracks)
+	var tracksToRemove []spotify.ID
+	err := pageLikedTracks(ctx, p.client, p.logger, 0, func(page []spotify.SavedTrack, next int) bool {
+		tracksToRemove = append(tracksToRemove, p.findTracksToRemove(page)...)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't get liked songs: %w", err)
+	}
+	if len(tracksToRemove) == 0 {
+		p.logger.Println("No tracks matching criteria. Task complete.")
+		return nil
+	}
 
-		if len(tracksToRemove) > 0 {
-			p.logger.Printf("Attempting to remove %d track(s) from this page.", len(tracksToRemove))
-			if err := p.client.RemoveTracksFromLibrary(ctx, tracksToRemove...); err != nil {
-				// Log the error but continue, as it might be a transient issue
-				p.logger.Printf("❌ ERROR: Failed to remove a batch of tracks: %v", err)
-			} else {
-				p.logger.Printf("✅ Batch removal successful.")
-			}
+	p.logger.Printf("Attempting to remove %d track(s).", len(tracksToRemove))
+	failed := 0
+	inBatches(tracksToRemove, 50, func(batch []spotify.ID) error {
+		if err := p.client.RemoveTracksFromLibrary(ctx, batch...); err != nil {
+			// Log the error but continue, as it might be a transient issue
+			p.logger.Printf("❌ ERROR: Failed to remove a batch of tracks: %v", err)
+			failed += len(batch)
 		} else {
-			p.logger.Println("No tracks matching criteria on this page.")
-		}
-
-		offset += limit
-		if total != -1 && offset >= total {
-			p.logger.Println("All songs have been processed. Task complete.")
-			break
+			p.logger.Printf("✅ Batch removal successful.")
 		}
+		return nil
+	})
+	if failed > 0 {
+		return fmt.Errorf("failed to remove %d of %d tracks", failed, len(tracksToRemove))
 	}
-
+	p.logger.Println("All songs have been processed. Task complete.")
 	return nil
 }
 
@@ -94,3 +87,240 @@ func (p *artistTrackRemover) findTracksToRemove(savedTracks []spotify.SavedTrack
 	}
 	return idsToRemove
 }
+
+// artistRef matches an artist's open.spotify.com URL or URI.
+var artistRef = regexp.MustCompile(`^(?:https?://open\.spotify\.com/(?:[a-z-]+/)?artist/|spotify:artist:)([0-9A-Za-z]{22})(?:[?#].*)?$`)
+
+// ArtistEraseOptions selects the artists to erase and how.
+type ArtistEraseOptions struct {
+	// Artists are names, matched ignoring case, or artist URLs or URIs.
+	Artists []string
+	// DryRun prints what would be removed without changing anything.
+	DryRun bool
+	// Yes skips the confirmation.
+	Yes bool
+}
+
+type artistEraser struct {
+	client SpotifyClient
+	in     *bufio.Reader
+	out    io.Writer
+	logger *log.Logger
+	opts   ArtistEraseOptions
+	ids    map[spotify.ID]bool
+	names  map[string]bool
+}
+
+// artistErasePlan is everything of the artists found in the library.
+type artistErasePlan struct {
+	liked     []spotify.FullTrack
+	playlists []playlistRemoval
+	albums    []spotify.SavedAlbum
+	followed  []spotify.FullArtist
+}
+
+// playlistRemoval is the tracks to remove from one owned playlist.
+type playlistRemoval struct {
+	playlist spotify.SimplePlaylist
+	tracks   []spotify.FullTrack
+}
+
+// NewArtistEraser returns a Processor that erases artists from the library: their tracks
+// leave the liked songs and the user's own playlists, their albums are unsaved and the
+// artists unfollowed. Everything found is listed step by step and confirmed once on in,
+// unless opts.Yes is set; with opts.DryRun nothing is changed.
+func NewArtistEraser(client SpotifyClient, in io.Reader, out io.Writer, logger *log.Logger, opts ArtistEraseOptions) (Processor, error) {
+	if len(opts.Artists) == 0 {
+		return nil, fmt.Errorf("name at least one artist to erase")
+	}
+	p := &artistEraser{client: client, in: bufio.NewReader(in), out: out, logger: logger, opts: opts, ids: make(map[spotify.ID]bool), names: make(map[string]bool)}
+	for _, artist := range opts.Artists {
+		if m := artistRef.FindStringSubmatch(artist); m != nil {
+			p.ids[spotify.ID(m[1])] = true
+		} else {
+			p.names[strings.ToLower(strings.TrimSpace(artist))] = true
+		}
+	}
+	return p, nil
+}
+
+// matches reports whether an artist is one being erased.
+func (p *artistEraser) matches(a spotify.SimpleArtist) bool {
+	return p.ids[a.ID] || p.names[strings.ToLower(a.Name)]
+}
+
+func (p *artistEraser) anyMatches(artists []spotify.SimpleArtist) bool {
+	return slices.ContainsFunc(artists, p.matches)
+}
+
+// Run finds everything of the artists, prints the plan and carries it out once confirmed.
+func (p *artistEraser) Run(ctx context.Context) error {
+	plan, err := p.plan(ctx)
+	if err != nil {
+		return err
+	}
+	if p.printPlan(plan) == 0 {
+		fmt.Fprintln(p.out, "\n✅ Nothing of these artists is in your library.")
+		return nil
+	}
+	if p.opts.DryRun {
+		fmt.Fprintln(p.out, "\n🧪 Dry run: nothing was changed.")
+		return nil
+	}
+	if !p.opts.Yes && !p.confirm() {
+		p.logger.Println("Nothing was changed.")
+		return nil
+	}
+	return p.apply(ctx, plan)
+}
+
+// plan looks for the artists in the liked songs, owned playlists, saved albums and
+// followed artists.
+func (p *artistEraser) plan(ctx context.Context) (*artistErasePlan, error) {
+	plan := &artistErasePlan{}
+	// Only the artists' songs are kept, so huge libraries don't have to fit in memory.
+	err := pageLikedTracks(ctx, p.client, p.logger, 0, func(page []spotify.SavedTrack, next int) bool {
+		for _, t := range page {
+			if p.anyMatches(t.Artists) {
+				plan.liked = append(plan.liked, t.FullTrack)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch liked songs: %w", err)
+	}
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlists, err := fetchOwnedPlaylists(ctx, p.client, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch playlists: %w", err)
+	}
+	for _, pl := range playlists {
+		tracks, err := fetchPlaylistTracks(ctx, p.client, pl.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch tracks of '%s': %w", pl.Name, err)
+		}
+		tracks = slices.DeleteFunc(tracks, func(t spotify.FullTrack) bool { return !p.anyMatches(t.Artists) })
+		if len(tracks) > 0 {
+			plan.playlists = append(plan.playlists, playlistRemoval{playlist: pl, tracks: tracks})
+		}
+	}
+
+	albums, err := fetchSavedAlbums(ctx, p.client, p.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch saved albums: %w", err)
+	}
+	for _, a := range albums {
+		if p.anyMatches(a.Artists) {
+			plan.albums = append(plan.albums, a)
+		}
+	}
+
+	followed, err := fetchFollowedArtists(ctx, p.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch followed artists: %w", err)
+	}
+	for _, a := range followed {
+		if p.matches(a.SimpleArtist) {
+			plan.followed = append(plan.followed, a)
+		}
+	}
+	return plan, nil
+}
+
+// printPlan lists what each step would remove and returns how many changes there are.
+func (p *artistEraser) printPlan(plan *artistErasePlan) int {
+	changes := 0
+	step := func(n int, title string, items []string) {
+		fmt.Fprintf(p.out, "\n%d. %s (%d)\n", n, title, len(items))
+		for _, item := range items {
+			fmt.Fprintf(p.out, "   - %s\n", item)
+		}
+		changes += len(items)
+	}
+	var items []string
+	for _, t := range plan.liked {
+		items = append(items, describeTrack(t))
+	}
+	step(1, "Unlike songs", items)
+	items = nil
+	for _, r := range plan.playlists {
+		for _, t := range r.tracks {
+			items = append(items, fmt.Sprintf("%s, from '%s'", describeTrack(t), r.playlist.Name))
+		}
+	}
+	step(2, "Remove from your playlists", items)
+	items = nil
+	for _, a := range plan.albums {
+		items = append(items, fmt.Sprintf("%s by %s", a.Name, artistNames(a.Artists)))
+	}
+	step(3, "Unsave albums", items)
+	items = nil
+	for _, a := range plan.followed {
+		items = append(items, a.Name)
+	}
+	step(4, "Unfollow artists", items)
+	return changes
+}
+
+// confirm asks once for the whole plan.
+func (p *artistEraser) confirm() bool {
+	fmt.Fprint(p.out, "\n⚠️  Erase all of the above? [y/N] ")
+	answer, _ := p.in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// apply carries out the steps in order, stopping at the first that fails.
+func (p *artistEraser) apply(ctx context.Context, plan *artistErasePlan) error {
+	if len(plan.liked) > 0 {
+		err := inBatches(trackIDs(plan.liked), 50, func(batch []spotify.ID) error {
+			return p.client.RemoveTracksFromLibrary(ctx, batch...)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unlike songs: %w", err)
+		}
+		p.logger.Printf("✅ Unliked %d songs.", len(plan.liked))
+	}
+	for _, r := range plan.playlists {
+		err := inBatches(trackIDs(r.tracks), 100, func(batch []spotify.ID) error {
+			_, err := p.client.RemoveTracksFromPlaylist(ctx, r.playlist.ID, batch...)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove tracks from '%s': %w", r.playlist.Name, err)
+		}
+		p.logger.Printf("✅ Removed %d tracks from '%s'.", len(r.tracks), r.playlist.Name)
+	}
+	if len(plan.albums) > 0 {
+		ids := make([]spotify.ID, len(plan.albums))
+		for i, a := range plan.albums {
+			ids[i] = a.ID
+		}
+		err := inBatches(ids, 50, func(batch []spotify.ID) error {
+			return p.client.RemoveAlbumsFromLibrary(ctx, batch...)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unsave albums: %w", err)
+		}
+		p.logger.Printf("✅ Unsaved %d albums.", len(plan.albums))
+	}
+	if len(plan.followed) > 0 {
+		ids := make([]spotify.ID, len(plan.followed))
+		for i, a := range plan.followed {
+			ids[i] = a.ID
+		}
+		err := inBatches(ids, 50, func(batch []spotify.ID) error {
+			return p.client.UnfollowArtist(ctx, batch...)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unfollow artists: %w", err)
+		}
+		p.logger.Printf("✅ Unfollowed %d artists.", len(plan.followed))
+	}
+	return nil
+}
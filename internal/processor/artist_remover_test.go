@@ -0,0 +1,88 @@
+package processor_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"spotify/internal/processor"
+	"spotify/internal/spotifytest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// likedFixture returns an account with n liked songs, every third one by "Banned" and
+// the rest by "Kept", and the IDs of each kind in library order. With more than 100
+// songs the matches are spread over three or more pages of liked songs.
+func likedFixture(t *testing.T, n int) (client *spotifytest.Client, banned, kept []spotify.ID) {
+	t.Helper()
+	f := spotifytest.Fixture{User: spotify.PrivateUser{User: spotify.User{ID: "me"}}}
+	added := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	for i := range n {
+		id := spotify.ID(fmt.Sprintf("track%03d", i))
+		artist := spotify.SimpleArtist{ID: "kept", Name: "Kept"}
+		if i%3 == 0 {
+			artist = spotify.SimpleArtist{ID: "banned", Name: "Banned"}
+			banned = append(banned, id)
+		} else {
+			kept = append(kept, id)
+		}
+		f.Tracks = append(f.Tracks, spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: id, Name: string(id), Artists: []spotify.SimpleArtist{artist}}})
+		f.Liked = append(f.Liked, spotifytest.Saved{ID: id, AddedAt: added.Add(-time.Duration(i) * time.Hour).Format(time.RFC3339)})
+	}
+	client, err := spotifytest.New(f)
+	if err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return client, banned, kept
+}
+
+func likedIDs(client *spotifytest.Client) []spotify.ID {
+	var ids []spotify.ID
+	for _, s := range client.Fixture().Liked {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// TestArtistRemoversShrinkingLibrary checks that removing songs doesn't skip the ones
+// that move up into pages already read.
+func TestArtistRemoversShrinkingLibrary(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	tests := []struct {
+		name  string
+		build func(client processor.SpotifyClient) (processor.Processor, error)
+	}{
+		{"remover", func(client processor.SpotifyClient) (processor.Processor, error) {
+			return processor.NewArtistTrackRemover(client, []string{"Banned"}, logger), nil
+		}},
+		{"eraser", func(client processor.SpotifyClient) (processor.Processor, error) {
+			return processor.NewArtistEraser(client, strings.NewReader(""), io.Discard, logger, processor.ArtistEraseOptions{Artists: []string{"banned"}, Yes: true})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, banned, kept := likedFixture(t, 160)
+			p, err := tt.build(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			got := likedIDs(client)
+			for _, id := range banned {
+				if slices.Contains(got, id) {
+					t.Errorf("%s is by a removed artist but is still liked", id)
+				}
+			}
+			if !slices.Equal(got, kept) {
+				t.Errorf("liked songs after removal = %v, want %v", got, kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package processor
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"spotify/internal/config"
+	"spotify/internal/imports"
+	"spotify/internal/matching"
+	"spotify/internal/store"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// BlendOptions describes whose library is blended with the user's.
+type BlendOptions struct {
+	// FriendCSV is an exported library to blend with, when the friend isn't logged in.
+	FriendCSV string
+	// FriendName is how the friend is called in playlist names; it defaults to their
+	// display name, or the CSV's file name.
+	FriendName string
+	// Out receives the user's picks for the friend as CSV in CSV mode; empty skips them.
+	Out string
+}
+
+// blendTrack is a song of the friend's library, with a Spotify ID when it's known.
+type blendTrack struct {
+	id   spotify.ID
+	isrc string
+	key  string
+	row  imports.Row
+}
+
+type blendBuilder struct {
+	mine, friend SpotifyClient
+	store        *store.Store
+	logger       *log.Logger
+	imgGen       ImageGenerator
+	blend, picks *playlistTemplates
+	matcher      *matching.Matcher
+	matchCfg     config.Matching
+	cfg          config.Blend
+	opts         BlendOptions
+}
+
+// NewBlendBuilder returns a Processor that compares the user's liked songs with a
+// friend's, matching by ISRC and then by title and artist. It writes a blend of the
+// songs both like, and a playlist of each one's songs the other doesn't have. friend is
+// the friend's logged-in client, or nil to read their library from opts.FriendCSV.
+func NewBlendBuilder(mine, friend SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfg config.Blend, matchCfg config.Matching, shared config.Playlists, opts BlendOptions) (Processor, error) {
+	if friend == nil && opts.FriendCSV == "" {
+		return nil, fmt.Errorf("a blend needs a second login or a CSV of the friend's library")
+	}
+	blend, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	picks, err := newPlaylistTemplates(cfg.PicksNameTemplate, cfg.PicksDescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &blendBuilder{
+		mine:     mine,
+		friend:   friend,
+		store:    st,
+		logger:   logger,
+		imgGen:   imgGen,
+		blend:    blend,
+		picks:    picks,
+		matcher:  matching.New(matchCfg),
+		matchCfg: matchCfg,
+		cfg:      cfg,
+		opts:     opts,
+	}, nil
+}
+
+// Run compares the libraries and writes the playlists.
+func (p *blendBuilder) Run(ctx context.Context) error {
+	me, err := p.mine.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	myLiked, err := fetchLikedTracks(ctx, p.mine, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	myLiked = uniqueSavedTracks(myLiked)
+
+	friendName := p.opts.FriendName
+	var friendUser *spotify.PrivateUser
+	var theirs []blendTrack
+	if p.friend != nil {
+		if friendUser, err = p.friend.CurrentUser(ctx); err != nil {
+			return fmt.Errorf("failed to get friend's user: %w", err)
+		}
+		if friendUser.ID == me.ID {
+			return fmt.Errorf("both logins are the same account; log in as your friend the second time")
+		}
+		if friendName == "" {
+			friendName = friendUser.DisplayName
+		}
+		liked, err := fetchLikedTracks(ctx, p.friend, p.logger)
+		if err != nil {
+			return fmt.Errorf("failed to fetch friend's liked tracks: %w", err)
+		}
+		for _, t := range uniqueSavedTracks(liked) {
+			theirs = append(theirs, blendTrack{id: t.ID, isrc: matching.ISRC(t.FullTrack), key: matching.Key(t.Name, artistNames(t.Artists))})
+		}
+	} else {
+		rows, err := imports.LoadCSV(p.opts.FriendCSV)
+		if err != nil {
+			return fmt.Errorf("could not read '%s': %w", p.opts.FriendCSV, err)
+		}
+		if friendName == "" {
+			friendName = strings.TrimSuffix(filepath.Base(p.opts.FriendCSV), filepath.Ext(p.opts.FriendCSV))
+		}
+		for _, row := range rows {
+			theirs = append(theirs, blendTrack{isrc: row.ISRC, key: matching.Key(row.Title, row.Artist), row: row})
+		}
+	}
+
+	both, onlyMine, onlyTheirs := p.compare(myLiked, theirs)
+	p.logger.Printf("You both like %d songs; %d are only yours and %d only %s's.", len(both), len(onlyMine), len(onlyTheirs), friendName)
+
+	today := time.Now().Format(time.DateOnly)
+	blendName := me.DisplayName + " & " + friendName
+	if err := p.write(ctx, p.mine, me.ID, p.blend, blendName, both, today); err != nil {
+		return err
+	}
+	picksForMe, err := p.resolvePicks(ctx, onlyTheirs)
+	if err != nil {
+		return err
+	}
+	if err := p.write(ctx, p.mine, me.ID, p.picks, friendName, picksForMe, today); err != nil {
+		return err
+	}
+
+	picksForThem := onlyMine[:p.limit(len(onlyMine))]
+	if p.friend != nil {
+		// The friend gets the blend and their picks in their own account.
+		if err := p.write(ctx, p.friend, friendUser.ID, p.blend, blendName, both, today); err != nil {
+			return err
+		}
+		return p.write(ctx, p.friend, friendUser.ID, p.picks, me.DisplayName, savedTrackIDs(picksForThem), today)
+	}
+	if p.opts.Out != "" {
+		return p.writeCSV(picksForThem)
+	}
+	return nil
+}
+
+// compare splits both libraries into the songs both like, in the user's order, and each
+// side's exclusives. A song matches by ISRC, or by normalized title and artist for songs
+// the ISRC doesn't match, such as other releases of the same recording.
+func (p *blendBuilder) compare(mine []spotify.SavedTrack, theirs []blendTrack) (both []spotify.ID, onlyMine []spotify.SavedTrack, onlyTheirs []blendTrack) {
+	theirISRCs := make(map[string]struct{})
+	theirKeys := make(map[string]struct{})
+	for _, t := range theirs {
+		if t.isrc != "" {
+			theirISRCs[strings.ToUpper(t.isrc)] = struct{}{}
+		}
+		theirKeys[t.key] = struct{}{}
+	}
+	myISRCs := make(map[string]struct{})
+	myKeys := make(map[string]struct{})
+	for _, t := range mine {
+		isrc := strings.ToUpper(matching.ISRC(t.FullTrack))
+		key := matching.Key(t.Name, artistNames(t.Artists))
+		myISRCs[isrc], myKeys[key] = struct{}{}, struct{}{}
+		_, sameISRC := theirISRCs[isrc]
+		_, sameKey := theirKeys[key]
+		if (isrc != "" && sameISRC) || sameKey {
+			both = append(both, t.ID)
+		} else {
+			onlyMine = append(onlyMine, t)
+		}
+	}
+	for _, t := range theirs {
+		_, sameISRC := myISRCs[strings.ToUpper(t.isrc)]
+		_, sameKey := myKeys[t.key]
+		if !(t.isrc != "" && sameISRC) && !sameKey {
+			onlyTheirs = append(onlyTheirs, t)
+		}
+	}
+	return both, onlyMine, onlyTheirs
+}
+
+// resolvePicks returns the Spotify IDs of the friend's exclusives, up to the limit.
+// Songs from a CSV are matched like an import; ones that aren't found are skipped.
+func (p *blendBuilder) resolvePicks(ctx context.Context, tracks []blendTrack) ([]spotify.ID, error) {
+	limit := p.limit(len(tracks))
+	var ids []spotify.ID
+	for _, t := range tracks {
+		if len(ids) == limit {
+			break
+		}
+		if t.id != "" {
+			ids = append(ids, t.id)
+			continue
+		}
+		m, err := matchRow(ctx, p.mine, p.matcher, p.matchCfg, t.row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", t.row.Line, err)
+		}
+		if m.track != nil {
+			ids = append(ids, m.track.ID)
+		}
+	}
+	return ids, nil
+}
+
+// limit caps a picks playlist of n candidates.
+func (p *blendBuilder) limit(n int) int {
+	if p.cfg.PicksLimit > 0 {
+		return min(n, p.cfg.PicksLimit)
+	}
+	return n
+}
+
+// write renders templates for name and writes trackIDs to that playlist of userID.
+func (p *blendBuilder) write(ctx context.Context, client SpotifyClient, userID string, templates *playlistTemplates, name string, trackIDs []spotify.ID, today string) error {
+	if len(trackIDs) == 0 {
+		p.logger.Printf("No songs for the playlist of %s. Skipping.", name)
+		return nil
+	}
+	tracks, err := getTracks(ctx, client, trackIDs)
+	if err != nil {
+		return fmt.Errorf("failed to fetch tracks: %w", err)
+	}
+	var length time.Duration
+	for _, t := range tracks {
+		length += time.Duration(t.Duration) * time.Millisecond
+	}
+	playlistName, description, err := templates.Render(PlaylistTemplateData{
+		Name:       name,
+		TrackCount: len(trackIDs),
+		Duration:   formatDuration(length),
+		Date:       today,
+	})
+	if err != nil {
+		return err
+	}
+	writer := newPlaylistWriter(client, p.logger)
+	playlistID, err := writer.Ensure(ctx, userID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	covers := newCoverUploader(client, p.imgGen, p.store, p.logger)
+	covers.Upload(ctx, playlistID, CoverSpec{Name: playlistName, Subtitle: p.cfg.CoverSubtitle, Tracks: tracks})
+	if err := writer.Replace(ctx, playlistID, trackIDs); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	return nil
+}
+
+// writeCSV saves the user's picks for the friend with title, artist, album and ISRC
+// columns, which import-csv and most other services read.
+func (p *blendBuilder) writeCSV(tracks []spotify.SavedTrack) error {
+	f, err := os.Create(p.opts.Out)
+	if err != nil {
+		return fmt.Errorf("could not create '%s': %w", p.opts.Out, err)
+	}
+	defer f.Close()
+	format := exportFormats["soundiiz"]
+	w := csv.NewWriter(f)
+	w.Write(format.header)
+	for _, t := range tracks {
+		w.Write(format.row(t))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("could not write '%s': %w", p.opts.Out, err)
+	}
+	p.logger.Printf("✅ Wrote %d picks for your friend to %s.", len(tracks), p.opts.Out)
+	return nil
+}
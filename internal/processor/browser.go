@@ -0,0 +1,726 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"spotify/internal/folders"
+	"spotify/internal/store"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zmb3/spotify/v2"
+)
+
+// CachedLibrary is the part of a library that earlier runs saved in the local store. A
+// nil list wasn't saved, or not completely.
+type CachedLibrary struct {
+	Liked     []spotify.SavedTrack
+	Playlists []spotify.SimplePlaylist
+	// PlaylistTracks holds the tracks of the playlists saved whole, by playlist.
+	PlaylistTracks map[spotify.ID][]spotify.FullTrack
+}
+
+const browserHelp = `↑/↓ j/k move · pgup/pgdn page · g/G first/last · / filter · esc clear filter or go back
+l liked songs · p playlists · enter open playlist · tab group by artist
+space select · * select all shown · c clear selection
+d remove from this view · a add to playlist · m move to playlist · t tag · u untag
+? help · q quit`
+
+var (
+	browserTitle    = lipgloss.NewStyle().Bold(true)
+	browserCursor   = lipgloss.NewStyle().Reverse(true)
+	browserDim      = lipgloss.NewStyle().Faint(true)
+	browserQuestion = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+)
+
+// browserView is what the browser shows.
+type browserView int
+
+const (
+	viewLiked browserView = iota
+	viewPlaylists
+	viewPlaylist
+)
+
+// browserPrompt is a line being typed at the bottom of the screen.
+type browserPrompt struct {
+	label string
+	text  []rune
+	// done is called with the text on enter.
+	done func(text string) tea.Cmd
+}
+
+// browserDone reports a finished action. apply updates the browser's state with its
+// result, on the UI's own goroutine.
+type browserDone struct {
+	status string
+	err    error
+	apply  func(b *browser)
+}
+
+type browser struct {
+	client SpotifyClient
+	tags   *store.Store
+	cached func(userID string) CachedLibrary
+	in     io.Reader
+	out    io.Writer
+	logger *log.Logger
+	// ctx is the context of Run, for the API calls of actions.
+	ctx context.Context
+
+	userID    string
+	liked     []spotify.FullTrack
+	playlists []spotify.SimplePlaylist
+	// playlistTracks holds the tracks of the playlists read so far.
+	playlistTracks map[spotify.ID][]spotify.FullTrack
+
+	view     browserView
+	playlist *spotify.SimplePlaylist
+	tracks   []spotify.FullTrack
+	byArtist bool
+	filter   string
+	cursor   int
+	top      int
+	height   int
+	selected map[spotify.ID]bool
+
+	prompt *browserPrompt
+	// confirm is the action waiting for a y/N answer to question.
+	question string
+	confirm  tea.Cmd
+	busy     bool
+	status   string
+	help     bool
+}
+
+// browserRow is a line of the current view. Selecting it selects ids.
+type browserRow struct {
+	label string
+	ids   []spotify.ID
+}
+
+// NewBrowser returns a terminal UI over the liked songs and playlists. It starts from
+// what cached returns for the user, fetching only what isn't saved there. Tracks and
+// artists can be filtered and selected, then removed, added or moved to a playlist, or
+// tagged.
+func NewBrowser(client SpotifyClient, tags *store.Store, cached func(userID string) CachedLibrary, in io.Reader, out io.Writer, logger *log.Logger) Processor {
+	return &browser{
+		client:         client,
+		tags:           tags,
+		cached:         cached,
+		in:             in,
+		out:            out,
+		logger:         logger,
+		playlistTracks: make(map[spotify.ID][]spotify.FullTrack),
+		height:         24,
+		selected:       make(map[spotify.ID]bool),
+	}
+}
+
+// Run loads the library and shows it until the user quits.
+func (b *browser) Run(ctx context.Context) error {
+	if err := b.load(ctx); err != nil {
+		return err
+	}
+	b.ctx = ctx
+	b.showLiked()
+	_, err := tea.NewProgram(b, tea.WithContext(ctx), tea.WithInput(b.in), tea.WithOutput(b.out), tea.WithAltScreen()).Run()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// load reads the liked songs and the user's own playlists from the local cache, and
+// fetches those that aren't in it.
+func (b *browser) load(ctx context.Context) error {
+	user, err := b.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	b.userID = user.ID
+	lib := b.cached(user.ID)
+	for id, tracks := range lib.PlaylistTracks {
+		b.playlistTracks[id] = tracks
+	}
+
+	if lib.Liked != nil {
+		b.logger.Printf("Read %d liked songs from the local cache.", len(lib.Liked))
+	} else {
+		b.logger.Println("Liked songs aren't in the local cache; fetching them.")
+		if lib.Liked, err = fetchLikedTracks(ctx, b.client, b.logger); err != nil {
+			return fmt.Errorf("failed to fetch liked songs: %w", err)
+		}
+	}
+	b.liked = fullTracks(lib.Liked)
+
+	if lib.Playlists != nil {
+		b.playlists = lib.Playlists
+		return nil
+	}
+	if b.playlists, err = fetchOwnedPlaylists(ctx, b.client, b.userID); err != nil {
+		return fmt.Errorf("failed to fetch playlists: %w", err)
+	}
+	return nil
+}
+
+func (b *browser) Init() tea.Cmd {
+	return nil
+}
+
+func (b *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.height = msg.Height
+	case browserDone:
+		b.busy = false
+		if msg.apply != nil {
+			msg.apply(b)
+		}
+		b.status = msg.status
+		if msg.err != nil {
+			b.status = "❌ " + msg.err.Error()
+		}
+	case tea.KeyMsg:
+		return b, b.key(msg)
+	}
+	b.scroll()
+	return b, nil
+}
+
+// key handles a key press.
+func (b *browser) key(msg tea.KeyMsg) tea.Cmd {
+	defer b.scroll()
+	key := msg.String()
+	if key == "ctrl+c" {
+		return tea.Quit
+	}
+	if b.prompt != nil {
+		return b.typed(msg)
+	}
+	if b.confirm != nil {
+		run := b.confirm
+		b.confirm, b.question = nil, ""
+		if key != "y" {
+			b.status = "Cancelled."
+			return nil
+		}
+		b.busy, b.status = true, "Working..."
+		return run
+	}
+	b.status = ""
+	rows := len(b.rows())
+	switch key {
+	case "q":
+		return tea.Quit
+	case "?":
+		b.help = !b.help
+	case "up", "k":
+		b.cursor--
+	case "down", "j":
+		b.cursor++
+	case "pgup":
+		b.cursor -= b.pageSize()
+	case "pgdown":
+		b.cursor += b.pageSize()
+	case "home", "g":
+		b.cursor = 0
+	case "end", "G":
+		b.cursor = rows - 1
+	case "/":
+		b.ask("Filter", b.filter, func(text string) tea.Cmd { return nil })
+	case "esc":
+		switch {
+		case b.filter != "":
+			b.setFilter("")
+		case b.view == viewPlaylist:
+			b.showPlaylists()
+		}
+	case "l":
+		b.showLiked()
+	case "p":
+		b.showPlaylists()
+	case "enter":
+		return b.open()
+	case "tab":
+		if b.view != viewPlaylists {
+			b.byArtist, b.cursor = !b.byArtist, 0
+		}
+	case " ":
+		b.toggle()
+	case "*":
+		for _, row := range b.rows() {
+			for _, id := range row.ids {
+				b.selected[id] = true
+			}
+		}
+	case "c":
+		clear(b.selected)
+	case "d", "a", "m", "t", "u":
+		if b.busy {
+			b.status = "Wait for the last change to finish."
+			return nil
+		}
+		return b.action(key)
+	}
+	return nil
+}
+
+// typed edits the prompt.
+func (b *browser) typed(msg tea.KeyMsg) tea.Cmd {
+	p := b.prompt
+	switch msg.Type {
+	case tea.KeyEnter:
+		b.prompt = nil
+		return p.done(strings.TrimSpace(string(p.text)))
+	case tea.KeyEsc:
+		b.prompt = nil
+		if p.label == "Filter" {
+			b.setFilter("")
+		}
+		return nil
+	case tea.KeyBackspace:
+		if len(p.text) > 0 {
+			p.text = p.text[:len(p.text)-1]
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		p.text = append(p.text, msg.Runes...)
+	}
+	if p.label == "Filter" {
+		b.setFilter(string(p.text))
+	}
+	return nil
+}
+
+// ask opens a prompt labelled label, starting with text.
+func (b *browser) ask(label, text string, done func(text string) tea.Cmd) {
+	b.prompt = &browserPrompt{label: label, text: []rune(text), done: done}
+}
+
+// confirmFirst asks question before running run.
+func (b *browser) confirmFirst(question string, run tea.Cmd) {
+	b.question, b.confirm = question, run
+}
+
+func (b *browser) setFilter(filter string) {
+	b.filter, b.cursor, b.top = strings.ToLower(strings.TrimSpace(filter)), 0, 0
+}
+
+func (b *browser) showLiked() {
+	b.view, b.playlist, b.tracks = viewLiked, nil, b.liked
+	b.setFilter("")
+}
+
+func (b *browser) showPlaylists() {
+	b.view, b.playlist, b.tracks = viewPlaylists, nil, nil
+	b.setFilter("")
+}
+
+// open shows the tracks of the playlist under the cursor, fetching them if they aren't
+// cached.
+func (b *browser) open() tea.Cmd {
+	if b.view != viewPlaylists {
+		return nil
+	}
+	visible := b.visiblePlaylists()
+	if b.cursor < 0 || b.cursor >= len(visible) {
+		return nil
+	}
+	pl := visible[b.cursor]
+	show := func(b *browser) {
+		b.view, b.playlist, b.tracks = viewPlaylist, &pl, b.playlistTracks[pl.ID]
+		b.setFilter("")
+	}
+	if _, ok := b.playlistTracks[pl.ID]; ok {
+		show(b)
+		return nil
+	}
+	b.busy, b.status = true, fmt.Sprintf("Fetching '%s'...", pl.Name)
+	ctx, client := b.ctx, b.client
+	return func() tea.Msg {
+		tracks, err := fetchPlaylistTracks(ctx, client, pl.ID)
+		if err != nil {
+			return browserDone{err: fmt.Errorf("failed to fetch '%s': %w", pl.Name, err)}
+		}
+		return browserDone{apply: func(b *browser) {
+			b.playlistTracks[pl.ID] = tracks
+			show(b)
+		}}
+	}
+}
+
+// visiblePlaylists returns the playlists that match the filter, in the order of their
+// rows.
+func (b *browser) visiblePlaylists() []spotify.SimplePlaylist {
+	return slices.DeleteFunc(slices.Clone(b.playlists), func(pl spotify.SimplePlaylist) bool {
+		return !b.matches(playlistLabel(pl))
+	})
+}
+
+func playlistLabel(pl spotify.SimplePlaylist) string {
+	return fmt.Sprintf("%s (%d tracks)", pl.Name, pl.Tracks.Total)
+}
+
+func (b *browser) matches(label string) bool {
+	return b.filter == "" || strings.Contains(strings.ToLower(label), b.filter)
+}
+
+// rows returns the rows of the current view that match the filter.
+func (b *browser) rows() []browserRow {
+	var rows []browserRow
+	add := func(label string, ids ...spotify.ID) {
+		if b.matches(label) {
+			rows = append(rows, browserRow{label: label, ids: ids})
+		}
+	}
+	switch {
+	case b.view == viewPlaylists:
+		for _, pl := range b.playlists {
+			add(playlistLabel(pl))
+		}
+	case b.byArtist:
+		var order []string
+		byArtist := make(map[string][]spotify.ID)
+		for _, t := range b.tracks {
+			for _, a := range t.Artists {
+				if _, ok := byArtist[a.Name]; !ok {
+					order = append(order, a.Name)
+				}
+				byArtist[a.Name] = append(byArtist[a.Name], t.ID)
+			}
+		}
+		slices.SortStableFunc(order, func(x, y string) int { return len(byArtist[y]) - len(byArtist[x]) })
+		for _, name := range order {
+			add(fmt.Sprintf("%s (%d tracks)", name, len(byArtist[name])), byArtist[name]...)
+		}
+	default:
+		for _, t := range b.tracks {
+			label := fmt.Sprintf("%s — %s", t.Name, artistNames(t.Artists))
+			if tags := b.tags.Tags(t.ID); len(tags) > 0 {
+				label += "  #" + strings.Join(tags, " #")
+			}
+			add(label, t.ID)
+		}
+	}
+	return rows
+}
+
+// toggle selects the row under the cursor, or unselects it if it's selected.
+func (b *browser) toggle() {
+	rows := b.rows()
+	if b.cursor < 0 || b.cursor >= len(rows) {
+		return
+	}
+	ids := rows[b.cursor].ids
+	sel := !b.allSelected(ids)
+	for _, id := range ids {
+		if sel {
+			b.selected[id] = true
+		} else {
+			delete(b.selected, id)
+		}
+	}
+	b.cursor++
+}
+
+func (b *browser) allSelected(ids []spotify.ID) bool {
+	for _, id := range ids {
+		if !b.selected[id] {
+			return false
+		}
+	}
+	return len(ids) > 0
+}
+
+// pageSize is how many rows fit on the screen under the title and above the status
+// and key lines.
+func (b *browser) pageSize() int {
+	return max(1, b.height-4)
+}
+
+// scroll keeps the cursor on a row and on the screen.
+func (b *browser) scroll() {
+	rows := len(b.rows())
+	b.cursor = max(0, min(b.cursor, rows-1))
+	size := b.pageSize()
+	if b.cursor < b.top {
+		b.top = b.cursor
+	}
+	if b.cursor >= b.top+size {
+		b.top = b.cursor - size + 1
+	}
+	b.top = max(0, min(b.top, rows-size))
+}
+
+func (b *browser) View() string {
+	if b.help {
+		return browserTitle.Render("Keys") + "\n\n" + browserHelp + "\n\n" + browserDim.Render("? to go back")
+	}
+	rows := b.rows()
+	title := "Liked songs"
+	switch b.view {
+	case viewPlaylists:
+		title = "Your playlists"
+	case viewPlaylist:
+		title = "Playlist '" + b.playlist.Name + "'"
+	}
+	if b.byArtist && b.view != viewPlaylists {
+		title += ", by artist"
+	}
+	if b.filter != "" {
+		title += fmt.Sprintf(", matching '%s'", b.filter)
+	}
+	var s strings.Builder
+	s.WriteString(browserTitle.Render(title))
+	fmt.Fprintf(&s, " %s\n", browserDim.Render(fmt.Sprintf("— %d rows, %d tracks selected", len(rows), len(b.selected))))
+	end := min(b.top+b.pageSize(), len(rows))
+	for i := b.top; i < end; i++ {
+		mark := "[ ] "
+		switch {
+		case b.view == viewPlaylists:
+			mark = ""
+		case b.allSelected(rows[i].ids):
+			mark = "[x] "
+		}
+		line := mark + rows[i].label
+		if i == b.cursor {
+			line = browserCursor.Render(line)
+		}
+		s.WriteString(line + "\n")
+	}
+	for range b.pageSize() - (end - b.top) {
+		s.WriteString("\n")
+	}
+	switch {
+	case b.prompt != nil:
+		fmt.Fprintf(&s, "%s: %s█\n", b.prompt.label, string(b.prompt.text))
+	case b.confirm != nil:
+		s.WriteString(browserQuestion.Render("⚠️  "+b.question+" [y/N]") + "\n")
+	default:
+		s.WriteString(b.status + "\n")
+	}
+	s.WriteString(browserDim.Render("space select · / filter · d remove · a add · m move · t tag · ? help · q quit"))
+	return s.String()
+}
+
+// action starts the change bound to key on the selection.
+func (b *browser) action(key string) tea.Cmd {
+	switch key {
+	case "d":
+		tracks := b.inView()
+		if len(tracks) == 0 {
+			b.status = "❌ No selected tracks in this view."
+			return nil
+		}
+		b.confirmFirst(fmt.Sprintf("Remove %d tracks from %s?", len(tracks), b.source()), b.remove(trackIDs(tracks)))
+	case "a", "m":
+		move := key == "m"
+		tracks := b.selection()
+		if move {
+			tracks = b.inView()
+		}
+		if len(tracks) == 0 {
+			b.status = "❌ No selected tracks."
+			return nil
+		}
+		label := "Add to playlist"
+		if move {
+			label = "Move to playlist"
+		}
+		b.ask(label, "", func(name string) tea.Cmd {
+			if name == "" {
+				return nil
+			}
+			run := b.addTo(name, tracks, move)
+			if !move {
+				b.busy = true
+				return run
+			}
+			b.confirmFirst(fmt.Sprintf("Move %d tracks from %s to '%s'?", len(tracks), b.source(), name), run)
+			return nil
+		})
+	case "t", "u":
+		add := key == "t"
+		label := "Tag"
+		if !add {
+			label = "Untag"
+		}
+		b.ask(label, "", func(text string) tea.Cmd {
+			if err := b.tag(strings.Fields(text), add); err != nil {
+				b.status = "❌ " + err.Error()
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// selection returns the selected tracks, in the order of the liked songs and the open
+// playlist.
+func (b *browser) selection() []spotify.FullTrack {
+	var tracks []spotify.FullTrack
+	seen := make(map[spotify.ID]bool)
+	for _, t := range slices.Concat(b.tracks, b.liked) {
+		if b.selected[t.ID] && !seen[t.ID] {
+			seen[t.ID] = true
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// inView returns the selected tracks of the current view's source.
+func (b *browser) inView() []spotify.FullTrack {
+	return slices.DeleteFunc(slices.Clone(b.tracks), func(t spotify.FullTrack) bool { return !b.selected[t.ID] })
+}
+
+// source names where the view's tracks come from, for questions.
+func (b *browser) source() string {
+	if b.playlist != nil {
+		return "'" + b.playlist.Name + "'"
+	}
+	return "your liked songs"
+}
+
+// remove returns the command removing ids from the liked songs or the open playlist.
+func (b *browser) remove(ids []spotify.ID) tea.Cmd {
+	ctx, client, playlist, source := b.ctx, b.client, b.playlist, b.source()
+	return func() tea.Msg {
+		if err := removeFrom(ctx, client, playlist, ids); err != nil {
+			return browserDone{err: fmt.Errorf("failed to remove tracks from %s: %w", source, err)}
+		}
+		return browserDone{
+			status: fmt.Sprintf("✅ Removed %d tracks from %s.", len(ids), source),
+			apply:  func(b *browser) { b.removed(playlist, ids) },
+		}
+	}
+}
+
+// removeFrom removes ids from playlist, or from the liked songs if nil.
+func removeFrom(ctx context.Context, client SpotifyClient, playlist *spotify.SimplePlaylist, ids []spotify.ID) error {
+	if playlist != nil {
+		return inBatches(ids, 100, func(batch []spotify.ID) error {
+			_, err := client.RemoveTracksFromPlaylist(ctx, playlist.ID, batch...)
+			return err
+		})
+	}
+	return inBatches(ids, 50, func(batch []spotify.ID) error {
+		return client.RemoveTracksFromLibrary(ctx, batch...)
+	})
+}
+
+// removed drops ids from playlist, or from the liked songs if nil, and from the
+// selection.
+func (b *browser) removed(playlist *spotify.SimplePlaylist, ids []spotify.ID) {
+	gone := func(t spotify.FullTrack) bool { return slices.Contains(ids, t.ID) }
+	if playlist == nil {
+		b.liked = slices.DeleteFunc(b.liked, gone)
+		if b.view == viewLiked {
+			b.tracks = b.liked
+		}
+	} else {
+		tracks := slices.DeleteFunc(b.playlistTracks[playlist.ID], gone)
+		b.playlistTracks[playlist.ID] = tracks
+		b.counted(playlist.ID, -len(ids))
+		if b.playlist != nil && b.playlist.ID == playlist.ID {
+			b.tracks = tracks
+		}
+	}
+	for _, id := range ids {
+		delete(b.selected, id)
+	}
+}
+
+// counted changes the track count shown for a playlist by n.
+func (b *browser) counted(playlistID spotify.ID, n int) {
+	for i := range b.playlists {
+		if b.playlists[i].ID == playlistID {
+			b.playlists[i].Tracks.Total += spotify.Numeric(n)
+		}
+	}
+}
+
+// addTo returns the command adding tracks to the playlist called name, creating it if
+// needed. With move, they're then removed from the view's source.
+func (b *browser) addTo(name string, tracks []spotify.FullTrack, move bool) tea.Cmd {
+	ctx, client, userID, source, playlist := b.ctx, b.client, b.userID, b.source(), b.playlist
+	var target *spotify.SimplePlaylist
+	for _, pl := range b.playlists {
+		if folders.StripPrefix(pl.Name) == name {
+			target = &pl
+			break
+		}
+	}
+	ids := trackIDs(tracks)
+	return func() tea.Msg {
+		var created *spotify.SimplePlaylist
+		if target == nil {
+			pl, err := client.CreatePlaylistForUser(ctx, userID, name, "", false, false)
+			if err != nil {
+				return browserDone{err: fmt.Errorf("failed to create playlist '%s': %w", name, err)}
+			}
+			created = &pl.SimplePlaylist
+			target = created
+		}
+		err := inBatches(ids, 100, func(batch []spotify.ID) error {
+			_, err := client.AddTracksToPlaylist(ctx, target.ID, batch...)
+			return err
+		})
+		added := func(b *browser) {
+			if created != nil {
+				b.playlists = append(b.playlists, *created)
+				b.playlistTracks[created.ID] = []spotify.FullTrack{}
+			}
+			if err == nil {
+				b.counted(target.ID, len(ids))
+				if cached, ok := b.playlistTracks[target.ID]; ok {
+					b.playlistTracks[target.ID] = append(cached, tracks...)
+				}
+			}
+		}
+		if err != nil {
+			return browserDone{err: fmt.Errorf("failed to add tracks to '%s': %w", name, err), apply: added}
+		}
+		status := fmt.Sprintf("✅ Added %d tracks to '%s'.", len(ids), name)
+		if !move {
+			return browserDone{status: status, apply: added}
+		}
+		if err := removeFrom(ctx, client, playlist, ids); err != nil {
+			return browserDone{err: fmt.Errorf("added the tracks to '%s' but failed to remove them from %s: %w", name, source, err), apply: added}
+		}
+		return browserDone{
+			status: fmt.Sprintf("✅ Moved %d tracks from %s to '%s'.", len(ids), source, name),
+			apply: func(b *browser) {
+				added(b)
+				b.removed(playlist, ids)
+			},
+		}
+	}
+}
+
+// tag adds or removes local tags on the selected tracks.
+func (b *browser) tag(names []string, add bool) error {
+	tracks := b.selection()
+	if len(names) == 0 || len(tracks) == 0 {
+		return errors.New("select tracks and name at least one tag")
+	}
+	for _, t := range tracks {
+		tags := b.tags.Tags(t.ID)
+		if add {
+			tags = append(tags, names...)
+		} else {
+			tags = slices.DeleteFunc(tags, func(tag string) bool { return slices.Contains(names, tag) })
+		}
+		b.tags.SetTags(t.ID, tags)
+	}
+	if err := b.tags.Save(); err != nil {
+		return fmt.Errorf("could not save tags: %w", err)
+	}
+	b.status = fmt.Sprintf("✅ Updated the tags of %d tracks.", len(tracks))
+	return nil
+}
@@ -0,0 +1,158 @@
+package processor_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"slices"
+	"spotify/internal/processor"
+	"spotify/internal/spotifytest"
+	"spotify/internal/store"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+)
+
+// browserFixture returns the model of a browser over an account with liked songs by
+// Radiohead and Muse, of which the cache holds all but the last, and its client and
+// store.
+func browserFixture(t *testing.T) (tea.Model, *spotifytest.Client, *store.Store) {
+	t.Helper()
+	f := spotifytest.Fixture{User: spotify.PrivateUser{User: spotify.User{ID: "me"}}}
+	for i, artist := range []string{"Radiohead", "Muse", "Radiohead", "Muse"} {
+		id := spotify.ID(fmt.Sprintf("t%d", i))
+		f.Tracks = append(f.Tracks, spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: id, Name: "Song " + string(id), Artists: []spotify.SimpleArtist{{Name: artist}}}})
+		f.Liked = append(f.Liked, spotifytest.Saved{ID: id})
+	}
+	f.Playlists = []spotifytest.Playlist{{ID: "p1", Name: "Mix", Tracks: []spotify.ID{"t1"}}}
+	client, err := spotifytest.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := func(userID string) processor.CachedLibrary {
+		page, _ := client.CurrentUsersTracks(context.Background(), spotify.Limit(3))
+		return processor.CachedLibrary{Liked: page.Tracks}
+	}
+	p := processor.NewBrowser(client, st, cached, nil, io.Discard, log.New(io.Discard, "", 0))
+	b, err := processor.BrowserModel(context.Background(), p)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return b, client, st
+}
+
+// press sends keys to b, typing strings one rune at a time, and runs the commands they
+// return.
+func press(b tea.Model, keys ...any) {
+	for _, k := range keys {
+		var msgs []tea.KeyMsg
+		switch k := k.(type) {
+		case tea.KeyType:
+			msgs = append(msgs, tea.KeyMsg{Type: k})
+		case string:
+			for _, r := range k {
+				msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+		}
+		for _, msg := range msgs {
+			_, cmd := b.Update(msg)
+			for cmd != nil {
+				_, cmd = b.Update(cmd())
+			}
+		}
+	}
+}
+
+// shows reports whether the screen of b has a line containing each of want.
+func shows(b tea.Model, want ...string) bool {
+	view := b.View()
+	for _, w := range want {
+		if !strings.Contains(view, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBrowserStartsFromTheCache(t *testing.T) {
+	b, _, _ := browserFixture(t)
+	if !shows(b, "3 rows", "Song t0", "Song t2") || shows(b, "Song t3") {
+		t.Errorf("liked songs screen doesn't show the three cached songs:\n%s", b.View())
+	}
+	// The playlists weren't cached, so they were fetched.
+	press(b, "p")
+	if !shows(b, "Mix (1 tracks)") {
+		t.Errorf("playlists screen doesn't show Mix:\n%s", b.View())
+	}
+}
+
+func TestBrowserMovesSelection(t *testing.T) {
+	b, client, _ := browserFixture(t)
+	press(b, "/", "radio", tea.KeyEnter, "*", "m", "Rainy", tea.KeyEnter)
+	if liked := likedIDs(client); len(liked) != 4 {
+		t.Fatalf("tracks were moved before the move was confirmed: %v", liked)
+	}
+	press(b, "y")
+	if liked := likedIDs(client); !slices.Equal(liked, []spotify.ID{"t1", "t3"}) {
+		t.Errorf("liked songs after the move = %v, want t1 and t3", liked)
+	}
+	var rainy []spotify.ID
+	for _, p := range client.Fixture().Playlists {
+		if p.Name == "Rainy" {
+			rainy = p.Tracks
+		}
+	}
+	if !slices.Equal(rainy, []spotify.ID{"t0", "t2"}) {
+		t.Errorf("'Rainy' holds %v, want t0 and t2", rainy)
+	}
+	if !shows(b, "Moved 2 tracks from your liked songs to 'Rainy'") {
+		t.Errorf("screen after the move:\n%s", b.View())
+	}
+	press(b, tea.KeyEsc)
+	if !shows(b, "1 rows, 0 tracks selected") {
+		t.Errorf("screen after the move:\n%s", b.View())
+	}
+	press(b, "p")
+	if !shows(b, "Rainy (2 tracks)") {
+		t.Errorf("playlists screen doesn't show the new playlist:\n%s", b.View())
+	}
+}
+
+func TestBrowserRemoveAsksFirst(t *testing.T) {
+	b, client, _ := browserFixture(t)
+	press(b, " ", "d", "n")
+	if liked := likedIDs(client); len(liked) != 4 {
+		t.Errorf("a declined removal removed tracks: %v", liked)
+	}
+	press(b, "d", "y")
+	if liked := likedIDs(client); !slices.Equal(liked, []spotify.ID{"t1", "t2", "t3"}) {
+		t.Errorf("liked songs after removing t0 = %v", liked)
+	}
+}
+
+func TestBrowserOpensAndTags(t *testing.T) {
+	b, _, st := browserFixture(t)
+	press(b, "p", tea.KeyEnter)
+	if !shows(b, "Playlist 'Mix'", "1 rows", "Song t1") {
+		t.Fatalf("screen after opening Mix:\n%s", b.View())
+	}
+	press(b, tea.KeySpace, "t", "live loud", tea.KeyEnter)
+	if got := st.Tags("t1"); !slices.Equal(got, []string{"live", "loud"}) {
+		t.Errorf("tags of t1 = %v, want live and loud", got)
+	}
+	if !shows(b, "#live #loud") {
+		t.Errorf("screen doesn't show the new tags:\n%s", b.View())
+	}
+	press(b, tea.KeyEsc)
+	if !shows(b, "Your playlists") {
+		t.Errorf("esc didn't go back to the playlists:\n%s", b.View())
+	}
+}
@@ -0,0 +1,167 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"spotify/internal/config"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// ChartEntry is one position of an archived chart snapshot.
+type ChartEntry struct {
+	Position int        `json:"position"`
+	TrackID  spotify.ID `json:"track_id"`
+	Name     string     `json:"name"`
+	Artists  []string   `json:"artists"`
+}
+
+type chartArchiver struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	templates *playlistTemplates
+	cfg       config.Charts
+}
+
+// NewChartArchiver returns a Processor that snapshots chart playlists, such as each
+// country's Top 50, into dated archive playlists or JSON files. Run it from the daemon
+// to build up a history of how the charts evolve.
+func NewChartArchiver(client SpotifyClient, logger *log.Logger, cfg config.Charts, shared config.Playlists) (Processor, error) {
+	if cfg.Target != "playlist" && cfg.Target != "json" {
+		return nil, fmt.Errorf("unknown chart archive target '%s'", cfg.Target)
+	}
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &chartArchiver{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		templates: templates,
+		cfg:       cfg,
+	}, nil
+}
+
+// Run snapshots every configured chart.
+func (p *chartArchiver) Run(ctx context.Context) error {
+	if len(p.cfg.Playlists) == 0 {
+		p.logger.Println("No chart playlists configured. Nothing to do.")
+		return ErrNothingToDo
+	}
+
+	today := time.Now().Format(time.DateOnly)
+	for _, chart := range p.cfg.Playlists {
+		p.logger.Printf("--- Archiving chart '%s' ---", chart.Name)
+		entries, duration, err := p.fetchChart(ctx, spotify.ID(chart.PlaylistID))
+		if err != nil {
+			return fmt.Errorf("could not fetch chart '%s': %w", chart.Name, err)
+		}
+
+		if p.cfg.Target == "json" {
+			if err := p.writeJSON(chart.Name, today, entries); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.writePlaylist(ctx, chart.Name, today, entries, duration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchChart returns the chart's current entries in order and their total duration.
+func (p *chartArchiver) fetchChart(ctx context.Context, playlistID spotify.ID) ([]ChartEntry, time.Duration, error) {
+	var entries []ChartEntry
+	var duration time.Duration
+	limit := 100
+	offset := 0
+
+	for {
+		page, err := p.client.GetPlaylistTracks(ctx, playlistID, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(page.Tracks) == 0 {
+			break
+		}
+		for _, item := range page.Tracks {
+			if item.Track.ID == "" {
+				continue
+			}
+			artists := make([]string, len(item.Track.Artists))
+			for i, a := range item.Track.Artists {
+				artists[i] = a.Name
+			}
+			entries = append(entries, ChartEntry{
+				Position: len(entries) + 1,
+				TrackID:  item.Track.ID,
+				Name:     item.Track.Name,
+				Artists:  artists,
+			})
+			duration += time.Duration(item.Track.Duration) * time.Millisecond
+		}
+		offset += len(page.Tracks)
+	}
+	return entries, duration, nil
+}
+
+// writePlaylist copies a snapshot into a dated playlist.
+func (p *chartArchiver) writePlaylist(ctx context.Context, chartName, date string, entries []ChartEntry, duration time.Duration) error {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	name, description, err := p.templates.Render(PlaylistTemplateData{
+		Name:       chartName,
+		TrackCount: len(entries),
+		Duration:   formatDuration(duration),
+		Date:       date,
+	})
+	if err != nil {
+		return err
+	}
+
+	trackIDs := make([]spotify.ID, len(entries))
+	for i, e := range entries {
+		trackIDs[i] = e.TrackID
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, name, description)
+	if err != nil {
+		return err
+	}
+	if err := p.writer.Replace(ctx, playlistID, trackIDs); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", name, err)
+	}
+	return nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// writeJSON stores a snapshot as <json_dir>/<chart>/<date>.json.
+func (p *chartArchiver) writeJSON(chartName, date string, entries []ChartEntry) error {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(chartName), "-"), "-")
+	dir := filepath.Join(p.cfg.JSONDir, slug)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("could not create chart archive directory: %w", err)
+	}
+	raw, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode chart snapshot: %w", err)
+	}
+	path := filepath.Join(dir, date+".json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("could not write chart snapshot: %w", err)
+	}
+	p.logger.Printf("✅ Saved %d entries to %s", len(entries), path)
+	return nil
+}
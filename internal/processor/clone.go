@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"spotify/internal/assets"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// playlistRef matches a playlist's open.spotify.com URL, its URI or its bare ID.
+var playlistRef = regexp.MustCompile(`^(?:https?://open\.spotify\.com/(?:[a-z-]+/)?playlist/|spotify:playlist:)?([0-9A-Za-z]{22})(?:[?#].*)?$`)
+
+// ParsePlaylistRef returns the ID of the playlist a URL, URI or ID refers to.
+func ParsePlaylistRef(ref string) (spotify.ID, error) {
+	m := playlistRef.FindStringSubmatch(ref)
+	if m == nil {
+		return "", fmt.Errorf("'%s' isn't a playlist URL, URI or ID", ref)
+	}
+	return spotify.ID(m[1]), nil
+}
+
+// CloneOptions selects what a clone copies besides the tracks.
+type CloneOptions struct {
+	Source spotify.ID
+	// Name of the copy; defaults to the source's name.
+	Name string
+	// Description and Cover copy the source's description and cover image.
+	Description bool
+	Cover       bool
+}
+
+type playlistCloner struct {
+	client SpotifyClient
+	store  *store.Store
+	assets *assets.Cache
+	logger *log.Logger
+	writer *playlistWriter
+	covers *coverUploader
+	opts   CloneOptions
+}
+
+// NewPlaylistCloner returns a Processor that copies any playlist the user can see into
+// one they own. The copy is remembered in st, so running it again brings the same copy
+// in line with the source.
+func NewPlaylistCloner(client SpotifyClient, st *store.Store, cache *assets.Cache, logger *log.Logger, opts CloneOptions) Processor {
+	return &playlistCloner{
+		client: client,
+		store:  st,
+		assets: cache,
+		logger: logger,
+		writer: newPlaylistWriter(client, logger),
+		covers: newCoverUploader(client, nil, st, logger),
+		opts:   opts,
+	}
+}
+
+// Run copies the source's tracks, and optionally its description and cover.
+func (p *playlistCloner) Run(ctx context.Context) error {
+	source, err := p.client.GetPlaylist(ctx, p.opts.Source)
+	if err != nil {
+		return fmt.Errorf("could not get playlist %s: %w", p.opts.Source, err)
+	}
+	trackIDs, err := fetchPlaylistTrackIDs(ctx, p.client, source.ID)
+	if err != nil {
+		return fmt.Errorf("could not fetch tracks of '%s': %w", source.Name, err)
+	}
+	p.logger.Printf("'%s' by %s has %d tracks.", source.Name, source.Owner.DisplayName, len(trackIDs))
+
+	name := p.opts.Name
+	if name == "" {
+		name = source.Name
+	}
+	description := fmt.Sprintf("Copy of '%s' by %s, synced %s.", source.Name, source.Owner.DisplayName, time.Now().Format(time.DateOnly))
+	if p.opts.Description {
+		// The API returns descriptions HTML-escaped.
+		description = html.UnescapeString(source.Description)
+	}
+
+	copyID, err := p.ensureCopy(ctx, name, description)
+	if err != nil {
+		return err
+	}
+	if p.opts.Cover && len(source.Images) > 0 {
+		if image, err := p.assets.Get(source.Images[0].URL); err != nil {
+			p.logger.Printf("⚠️  Could not download the cover of '%s': %v", source.Name, err)
+		} else {
+			p.covers.upload(ctx, copyID, name, image)
+		}
+	}
+	if err := p.writer.Replace(ctx, copyID, trackIDs); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", name, err)
+	}
+	p.store.SetClone(source.ID, copyID)
+	if err := p.store.Save(); err != nil {
+		return fmt.Errorf("could not save store: %w", err)
+	}
+	p.logger.Printf("✅ '%s' is in sync with '%s'.", name, source.Name)
+	return nil
+}
+
+// ensureCopy returns the copy made by an earlier run if the user still has it, renamed
+// and described as requested, or else the user's playlist called name, created if needed.
+func (p *playlistCloner) ensureCopy(ctx context.Context, name, description string) (spotify.ID, error) {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	if id, ok := p.store.Clone(p.opts.Source); ok {
+		// Deleted playlists can still be fetched, so look among the user's own.
+		owned, err := fetchOwnedPlaylists(ctx, p.client, user.ID)
+		if err != nil {
+			return "", fmt.Errorf("could not list playlists: %w", err)
+		}
+		for _, existing := range owned {
+			if existing.ID != id {
+				continue
+			}
+			if existing.Name != name {
+				if err := p.client.ChangePlaylistName(ctx, id, name); err != nil {
+					return "", fmt.Errorf("could not rename '%s': %w", existing.Name, err)
+				}
+			}
+			if description = signed(description); existing.Description != description {
+				if err := p.client.ChangePlaylistDescription(ctx, id, description); err != nil {
+					p.logger.Printf("⚠️  Could not update description for '%s': %v", name, err)
+				}
+			}
+			return id, nil
+		}
+		p.logger.Println("⚠️  The copy made earlier was deleted, making a new one.")
+	}
+	return p.writer.Ensure(ctx, user.ID, name, description)
+}
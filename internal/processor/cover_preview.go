@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+type coverPreview struct {
+	imgGen ImageGenerator
+	spec   CoverSpec
+	path   string
+	logger *log.Logger
+}
+
+// NewCoverPreview returns a Processor that renders the cover for spec and writes it to
+// path. It doesn't talk to Spotify, so styles can be tried out before a real run.
+func NewCoverPreview(imgGen ImageGenerator, spec CoverSpec, path string, logger *log.Logger) Processor {
+	return &coverPreview{
+		imgGen: imgGen,
+		spec:   spec,
+		path:   path,
+		logger: logger,
+	}
+}
+
+// Run generates the cover and saves it.
+func (p *coverPreview) Run(ctx context.Context) error {
+	image, err := p.imgGen.GenerateForPlaylist(p.spec)
+	if err != nil {
+		return fmt.Errorf("could not generate cover for '%s': %w", p.spec.Name, err)
+	}
+	f, err := os.Create(p.path)
+	if err != nil {
+		return fmt.Errorf("could not create '%s': %w", p.path, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, image); err != nil {
+		return fmt.Errorf("could not write '%s': %w", p.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not write '%s': %w", p.path, err)
+	}
+	p.logger.Printf("✅ Wrote cover for '%s' to %s", p.spec.Name, p.path)
+	return nil
+}
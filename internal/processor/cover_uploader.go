@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"runtime"
+	"sync"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// coverUploader generates and uploads custom playlist covers. Cover problems are never
+// fatal: they're logged and the run carries on.
+type coverUploader struct {
+	client   SpotifyClient
+	imgGen   ImageGenerator
+	registry CoverRegistry
+	logger   *log.Logger
+
+	workers chan struct{} // limits concurrent rendering to the number of CPUs
+	uploads sync.Mutex    // uploads go out one at a time
+	pending sync.WaitGroup
+}
+
+// newCoverUploader returns an uploader. If registry is non-nil, a cover identical to the
+// one last uploaded to the playlist is skipped.
+func newCoverUploader(client SpotifyClient, imgGen ImageGenerator, registry CoverRegistry, logger *log.Logger) *coverUploader {
+	return &coverUploader{
+		client:   client,
+		imgGen:   imgGen,
+		registry: registry,
+		logger:   logger,
+		workers:  make(chan struct{}, runtime.NumCPU()),
+	}
+}
+
+// Start generates and uploads the cover for spec in the background, so rendering
+// overlaps with syncing the next playlists. Wait must be called before the run ends.
+func (c *coverUploader) Start(ctx context.Context, playlistID spotify.ID, spec CoverSpec) {
+	c.pending.Add(1)
+	go func() {
+		defer c.pending.Done()
+		c.workers <- struct{}{}
+		image, err := c.generate(spec)
+		<-c.workers
+		if err != nil {
+			c.logger.Printf("⚠️  Could not generate image for '%s': %v", spec.Name, err)
+			return
+		}
+		c.uploads.Lock()
+		defer c.uploads.Unlock()
+		c.upload(ctx, playlistID, spec.Name, image)
+	}()
+}
+
+// Wait blocks until every cover passed to Start has been uploaded or has failed.
+func (c *coverUploader) Wait() {
+	c.pending.Wait()
+}
+
+// Upload generates a cover for spec and sets it on playlistID.
+func (c *coverUploader) Upload(ctx context.Context, playlistID spotify.ID, spec CoverSpec) {
+	c.logger.Println("Generating custom cover image...")
+	image, err := c.generate(spec)
+	if err != nil {
+		c.logger.Printf("⚠️  Could not generate image for '%s': %v", spec.Name, err)
+		return
+	}
+	c.upload(ctx, playlistID, spec.Name, image)
+}
+
+// generate renders the cover for spec.
+func (c *coverUploader) generate(spec CoverSpec) ([]byte, error) {
+	imageReader, err := c.imgGen.GenerateForPlaylist(spec)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(imageReader)
+}
+
+// upload sets image as the cover of playlistID unless it's the cover already there.
+func (c *coverUploader) upload(ctx context.Context, playlistID spotify.ID, name string, image []byte) {
+	sum := sha256.Sum256(image)
+	checksum := hex.EncodeToString(sum[:])
+	if c.registry != nil && c.registry.UploadedCover(playlistID) == checksum {
+		c.logger.Printf("Cover image for '%s' unchanged, skipping upload.", name)
+		return
+	}
+
+	if err := c.client.SetPlaylistImage(ctx, playlistID, bytes.NewReader(image)); err != nil {
+		c.logger.Printf("⚠️  Could not upload cover image for '%s': %v", name, err)
+		return
+	}
+	if c.registry != nil {
+		c.registry.SetUploadedCover(playlistID, checksum)
+	}
+	c.logger.Printf("✅ Custom cover image uploaded for '%s'.", name)
+}
@@ -0,0 +1,195 @@
+package processor
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"spotify/internal/config"
+	"spotify/internal/imports"
+	"spotify/internal/matching"
+	"strconv"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// CSVImportOptions describes a CSV import.
+type CSVImportOptions struct {
+	File string
+	// Playlist receives the matched tracks, in file order.
+	Playlist string
+	// Report is the CSV file the match report is written to.
+	Report string
+}
+
+// importMatch is the outcome of matching one row.
+type importMatch struct {
+	row    imports.Row
+	track  *spotify.FullTrack
+	method string // "isrc" or "search"
+	score  float64
+}
+
+type csvImporter struct {
+	client  SpotifyClient
+	logger  *log.Logger
+	writer  *playlistWriter
+	matcher *matching.Matcher
+	cfg     config.Matching
+	opts    CSVImportOptions
+}
+
+// NewCSVImporter returns a Processor that matches the tracks of a CSV exported from
+// another service to Spotify tracks and writes them to a playlist. Rows are matched by
+// ISRC when they have one, falling back to scoring search results on title, artist and
+// duration. Every row's outcome is written to a report for review.
+func NewCSVImporter(client SpotifyClient, logger *log.Logger, cfg config.Matching, opts CSVImportOptions) (Processor, error) {
+	if opts.File == "" || opts.Playlist == "" {
+		return nil, fmt.Errorf("a CSV import needs a file and a playlist name")
+	}
+	return &csvImporter{
+		client:  client,
+		logger:  logger,
+		writer:  newPlaylistWriter(client, logger),
+		matcher: matching.New(cfg),
+		cfg:     cfg,
+		opts:    opts,
+	}, nil
+}
+
+// Run matches every row, writes the report and then the playlist.
+func (p *csvImporter) Run(ctx context.Context) error {
+	rows, err := imports.LoadCSV(p.opts.File)
+	if err != nil {
+		return fmt.Errorf("could not read '%s': %w", p.opts.File, err)
+	}
+	p.logger.Printf("Matching %d rows from %s...", len(rows), p.opts.File)
+
+	matches := make([]importMatch, len(rows))
+	var trackIDs []spotify.ID
+	seen := make(map[spotify.ID]struct{})
+	var review, unmatched int
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m, err := matchRow(ctx, p.client, p.matcher, p.cfg, row)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", row.Line, err)
+		}
+		matches[i] = m
+		switch {
+		case m.track == nil:
+			unmatched++
+			continue
+		case m.score < p.cfg.ReviewBelow:
+			review++
+		}
+		if _, dup := seen[m.track.ID]; !dup {
+			seen[m.track.ID] = struct{}{}
+			trackIDs = append(trackIDs, m.track.ID)
+		}
+	}
+	if err := p.writeReport(matches); err != nil {
+		return err
+	}
+	p.logger.Printf("Matched %d of %d rows; %d need review and %d weren't found. See %s.", len(rows)-unmatched, len(rows), review, unmatched, p.opts.Report)
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	description := fmt.Sprintf("Imported from %s.", p.opts.File)
+	playlistID, err := p.writer.Ensure(ctx, user.ID, p.opts.Playlist, description)
+	if err != nil {
+		return err
+	}
+	if err := p.writer.Replace(ctx, playlistID, trackIDs); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", p.opts.Playlist, err)
+	}
+	return nil
+}
+
+// matchRow finds the Spotify track for row, by ISRC first and then by search scored
+// with matcher. Matches scoring under cfg.Threshold are dropped.
+func matchRow(ctx context.Context, client SpotifyClient, matcher *matching.Matcher, cfg config.Matching, row imports.Row) (importMatch, error) {
+	if row.ISRC != "" {
+		result, err := client.Search(ctx, "isrc:"+row.ISRC, spotify.SearchTypeTrack, spotify.Limit(5))
+		if err != nil {
+			return importMatch{}, fmt.Errorf("ISRC search failed: %w", err)
+		}
+		if result.Tracks != nil {
+			for _, t := range result.Tracks.Tracks {
+				if strings.EqualFold(matching.ISRC(t), row.ISRC) {
+					return importMatch{row: row, track: &t, method: "isrc", score: 1}, nil
+				}
+			}
+		}
+	}
+
+	want := matching.Track{Title: row.Title, Artist: row.Artist, ISRC: row.ISRC, Duration: row.Duration}
+	queries := []string{fmt.Sprintf("track:%q", row.Title)}
+	if row.Artist != "" {
+		queries[0] += fmt.Sprintf(" artist:%q", strings.SplitN(row.Artist, ",", 2)[0])
+	}
+	// Free text finds tracks whose title decorations differ from the export's.
+	queries = append(queries, strings.TrimSpace(matching.NormalizeTitle(row.Title)+" "+row.Artist))
+
+	best := importMatch{row: row, method: "search"}
+	for _, q := range queries {
+		result, err := client.Search(ctx, q, spotify.SearchTypeTrack, spotify.Limit(10))
+		if err != nil {
+			return importMatch{}, fmt.Errorf("search failed: %w", err)
+		}
+		if result.Tracks == nil {
+			continue
+		}
+		for _, t := range result.Tracks.Tracks {
+			if score := matcher.Score(want, matching.FromFull(t)); score > best.score {
+				best.track, best.score = &t, score
+			}
+		}
+		if best.score >= cfg.ReviewBelow {
+			break
+		}
+	}
+	if best.score < cfg.Threshold {
+		best.track = nil
+	}
+	return best, nil
+}
+
+// writeReport writes one line per row with its match and confidence.
+func (p *csvImporter) writeReport(matches []importMatch) error {
+	f, err := os.Create(p.opts.Report)
+	if err != nil {
+		return fmt.Errorf("could not create report '%s': %w", p.opts.Report, err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"line", "title", "artist", "isrc", "status", "method", "score", "spotify_id", "spotify_title", "spotify_artist"})
+	for _, m := range matches {
+		status := "matched"
+		record := []string{strconv.Itoa(m.row.Line), m.row.Title, m.row.Artist, m.row.ISRC}
+		switch {
+		case m.track == nil:
+			status = "not found"
+		case m.score < p.cfg.ReviewBelow:
+			status = "review"
+		}
+		record = append(record, status, m.method, strconv.FormatFloat(m.score, 'f', 2, 64))
+		if m.track != nil {
+			record = append(record, string(m.track.ID), m.track.Name, artistNames(m.track.Artists))
+		} else {
+			record = append(record, "", "", "")
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+	return nil
+}
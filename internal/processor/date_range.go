@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"spotify/internal/config"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// DateRangeOptions selects the window of a date-range playlist.
+type DateRangeOptions struct {
+	Name string
+	// From and To are the first and last day of the window, both included.
+	From, To time.Time
+}
+
+type dateRangeBuilder struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	opts      DateRangeOptions
+}
+
+// NewDateRangeBuilder returns a Processor that builds a playlist of every song liked
+// between two dates, e.g. over a summer or a trip.
+func NewDateRangeBuilder(client SpotifyClient, registry CoverRegistry, logger *log.Logger, imgGen ImageGenerator, opts DateRangeOptions, cfg config.DateRange, shared config.Playlists) (Processor, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("a date-range playlist needs a name")
+	}
+	if opts.To.Before(opts.From) {
+		return nil, fmt.Errorf("date range ends (%s) before it starts (%s)", opts.To.Format(time.DateOnly), opts.From.Format(time.DateOnly))
+	}
+	// The name is used verbatim, so it can't be mistaken for a template.
+	templates, err := newPlaylistTemplates("{{.Name}}", cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &dateRangeBuilder{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, registry, logger),
+		templates: templates,
+		opts:      opts,
+	}, nil
+}
+
+// Run collects the songs liked in the window, oldest first, and writes the playlist.
+func (p *dateRangeBuilder) Run(ctx context.Context) error {
+	from, to := p.opts.From, p.opts.To.AddDate(0, 0, 1)
+	var tracks []spotify.SavedTrack
+	err := pageLikedTracks(ctx, p.client, p.logger, 0, func(page []spotify.SavedTrack, next int) bool {
+		for _, t := range page {
+			addedAt, err := time.Parse(time.RFC3339, t.AddedAt)
+			if err != nil {
+				p.logger.Printf("Error parsing track date for '%s': %v", t.Name, err)
+				continue
+			}
+			if !addedAt.Before(from) && addedAt.Before(to) {
+				tracks = append(tracks, t)
+			}
+		}
+		// Liked songs come newest first: stop once the page reaches before the window.
+		last, err := time.Parse(time.RFC3339, page[len(page)-1].AddedAt)
+		return err != nil || !last.Before(from)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	if len(tracks) == 0 {
+		p.logger.Println("No songs were liked in this date range. Nothing to do.")
+		return ErrNothingToDo
+	}
+	slices.Reverse(tracks)
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	fromLabel, toLabel := p.opts.From.Format(time.DateOnly), p.opts.To.Format(time.DateOnly)
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		Name:       p.opts.Name,
+		From:       fromLabel,
+		To:         toLabel,
+		TrackCount: len(tracks),
+		Duration:   formatDuration(totalDuration(tracks)),
+		Date:       time.Now().Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	p.logger.Printf("--- Processing '%s' (%d tracks) ---", playlistName, len(tracks))
+
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{
+		Name:     playlistName,
+		Subtitle: fromLabel + " – " + toLabel,
+		Tracks:   fullTracks(tracks),
+	})
+	if err := p.writer.Replace(ctx, playlistID, savedTrackIDs(tracks)); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	return nil
+}
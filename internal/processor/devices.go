@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+)
+
+type deviceLister struct {
+	client SpotifyClient
+	out    io.Writer
+}
+
+// NewDeviceLister returns a Processor that prints the user's Spotify Connect devices.
+func NewDeviceLister(client SpotifyClient, out io.Writer) Processor {
+	return &deviceLister{client: client, out: out}
+}
+
+// Run prints one device per line, marking the active one.
+func (p *deviceLister) Run(ctx context.Context) error {
+	devices, err := p.client.PlayerDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	if len(devices) == 0 {
+		fmt.Fprintln(p.out, "No devices. Open Spotify on the device to use and list again.")
+		return nil
+	}
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\tNAME\tTYPE\tVOLUME\tID")
+	for _, d := range devices {
+		mark := ""
+		if d.Active {
+			mark = "▶"
+		}
+		name := d.Name
+		if d.Restricted {
+			name += " (can't be controlled)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d%%\t%s\n", mark, name, d.Type, int(d.Volume), d.ID)
+	}
+	return w.Flush()
+}
+
+type playbackTransfer struct {
+	client   SpotifyClient
+	logger   *log.Logger
+	writer   *playlistWriter
+	device   string
+	playlist string
+	play     bool
+}
+
+// NewPlaybackTransfer returns a Processor that moves playback to the device whose name
+// contains device, ignoring case. With a playlist, given by URL, URI, ID or the name of
+// one of the user's own, it starts that playlist there; otherwise play resumes what was
+// playing.
+func NewPlaybackTransfer(client SpotifyClient, logger *log.Logger, device, playlist string, play bool) (Processor, error) {
+	if device == "" {
+		return nil, fmt.Errorf("name the device to move playback to")
+	}
+	return &playbackTransfer{client: client, logger: logger, writer: newPlaylistWriter(client, logger), device: device, playlist: playlist, play: play}, nil
+}
+
+// Run transfers playback.
+func (p *playbackTransfer) Run(ctx context.Context) error {
+	if p.playlist != "" {
+		playlistID, name, err := resolvePlaylist(ctx, p.client, p.writer, p.playlist)
+		if err != nil {
+			return err
+		}
+		return startPlayback(ctx, p.client, p.logger, p.device, playlistID, name)
+	}
+	devices, err := p.client.PlayerDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	target, err := chooseDevice(devices, p.device)
+	if err != nil {
+		return err
+	}
+	if err := p.client.TransferPlayback(ctx, target.ID, p.play); err != nil {
+		return fmt.Errorf("failed to move playback to '%s': %w", target.Name, err)
+	}
+	p.logger.Printf("✅ Moved playback to %s.", target.Name)
+	return nil
+}
@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// ErrNothingToDo is returned by processors that found nothing to change, after logging
+// why.
+var ErrNothingToDo = errors.New("nothing to do")
+
+// PartialError is the error of a run in which Failed of Total items failed.
+type PartialError struct {
+	Failed, Total int
+	Err           error
+}
+
+func (e *PartialError) Error() string { return e.Err.Error() }
+
+func (e *PartialError) Unwrap() error { return e.Err }
+
+// forEachPlaylist calls sync for every item, logging failures and carrying on with the
+// remaining items instead of stopping at the first one. It only stops early when ctx is
+// done. The returned error joins every failure and is nil if all items succeeded.
+func forEachPlaylist[T any](ctx context.Context, logger *log.Logger, items []T, describe func(T) string, sync func(T) error) error {
+	var failures []error
+	states := make([]itemState, len(items))
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			reportStopped(ctx, logger, items, states, describe)
+			failures = append(failures, err)
+			break
+		}
+		states[i] = itemDone
+		if err := sync(item); err != nil {
+			logger.Printf("❌ %s failed: %v", describe(item), err)
+			failures = append(failures, fmt.Errorf("%s: %w", describe(item), err))
+			states[i] = itemFailed
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &PartialError{Failed: len(failures), Total: len(items), Err: fmt.Errorf("%d of %d playlists failed:\n%w", len(failures), len(items), errors.Join(failures...))}
+}
+
+// forEachPlaylistParallel is forEachPlaylist with up to workers items synced at once.
+// Failures are reported in item order.
+func forEachPlaylistParallel[T any](ctx context.Context, logger *log.Logger, items []T, workers int, describe func(T) string, syncItem func(T) error) error {
+	if workers <= 1 || len(items) <= 1 {
+		return forEachPlaylist(ctx, logger, items, describe, syncItem)
+	}
+	errs := make([]error, len(items))
+	states := make([]itemState, len(items))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				states[i] = itemDone
+				if errs[i] = syncItem(items[i]); errs[i] != nil {
+					logger.Printf("❌ %s failed: %v", describe(items[i]), errs[i])
+					states[i] = itemFailed
+				}
+			}
+		}()
+	}
+	for i := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var failures []error
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", describe(items[i]), err))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		reportStopped(ctx, logger, items, states, describe)
+		failures = append(failures, err)
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &PartialError{Failed: len(failures), Total: len(items), Err: fmt.Errorf("%d of %d playlists failed:\n%w", len(failures), len(items), errors.Join(failures...))}
+}
+
+// itemState is how far an item got before a run stopped.
+type itemState int
+
+const (
+	itemNotStarted itemState = iota
+	itemDone
+	itemFailed
+)
+
+// reportStopped logs which items were done and which weren't started when ctx ended the
+// run early, so an interrupted run leaves no doubt about the state of the playlists.
+func reportStopped[T any](ctx context.Context, logger *log.Logger, items []T, states []itemState, describe func(T) string) {
+	var done, notStarted []string
+	failed := 0
+	for i, item := range items {
+		switch states[i] {
+		case itemDone:
+			done = append(done, describe(item))
+		case itemFailed:
+			failed++
+		default:
+			notStarted = append(notStarted, describe(item))
+		}
+	}
+	logger.Printf("🛑 Stopped early (%v): %d of %d playlists done, %d failed, %d not started.", context.Cause(ctx), len(done), len(items), failed, len(notStarted))
+	if len(done) > 0 {
+		logger.Printf("   Done: %s", strings.Join(done, ", "))
+	}
+	if len(notStarted) > 0 {
+		logger.Printf("   Not started: %s", strings.Join(notStarted, ", "))
+	}
+}
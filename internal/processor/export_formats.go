@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"slices"
+	"spotify/internal/matching"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// exportFormat lays out the CSV written by the exporter.
+type exportFormat struct {
+	header []string
+	row    func(t spotify.SavedTrack) []string
+}
+
+// exportFormats are the export presets. The soundiiz and tunemymusic presets use the
+// columns those services' CSV importers expect, for moving a library to another service.
+var exportFormats = map[string]exportFormat{
+	"default": {
+		header: []string{"id", "title", "artists", "album", "release_date", "added_at", "uri"},
+		row: func(t spotify.SavedTrack) []string {
+			return []string{string(t.ID), t.Name, artistNames(t.Artists), t.Album.Name, t.Album.ReleaseDate, t.AddedAt, string(t.URI)}
+		},
+	},
+	"soundiiz": {
+		header: []string{"title", "artist", "album", "isrc"},
+		row: func(t spotify.SavedTrack) []string {
+			return []string{t.Name, artistNames(t.Artists), t.Album.Name, matching.ISRC(t.FullTrack)}
+		},
+	},
+	"tunemymusic": {
+		header: []string{"Track name", "Artist name", "Album", "Playlist name", "Type", "ISRC", "Spotify - id"},
+		row: func(t spotify.SavedTrack) []string {
+			return []string{t.Name, artistNames(t.Artists), t.Album.Name, "Liked Songs", "Favorite", matching.ISRC(t.FullTrack), string(t.ID)}
+		},
+	},
+}
+
+// exportFormatNames lists the presets, sorted.
+func exportFormatNames() string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,26 @@
+package processor
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zmb3/spotify/v2"
+)
+
+// BrowserModel loads the browser p and returns its model, for tests to send keys to
+// without a terminal.
+func BrowserModel(ctx context.Context, p Processor) (tea.Model, error) {
+	b := p.(*browser)
+	if err := b.load(ctx); err != nil {
+		return nil, err
+	}
+	b.ctx = ctx
+	b.showLiked()
+	return b, nil
+}
+
+// FetchSorterLibrary fetches the liked songs the sorter p keeps, and the IDs of every
+// liked song, as its Run does.
+func FetchSorterLibrary(ctx context.Context, p Processor) ([]spotify.SavedTrack, []spotify.ID, error) {
+	return p.(*playlistSorter).fetchLikedTracks(ctx)
+}
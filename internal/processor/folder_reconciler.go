@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"spotify/internal/folders"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type folderReconciler struct {
+	client      SpotifyClient
+	manifest    *folders.Manifest
+	prefixNames bool
+	out         io.Writer
+	logger      *log.Logger
+}
+
+// NewFolderReconciler returns a Processor that compares the user's playlists with the
+// folder manifest and prints step-by-step instructions, with deep links, for filing them
+// in the desktop client. With prefixNames set it also renames playlists so their
+// "[Folder] " prefix matches the manifest.
+func NewFolderReconciler(client SpotifyClient, manifest *folders.Manifest, prefixNames bool, out io.Writer, logger *log.Logger) Processor {
+	return &folderReconciler{
+		client:      client,
+		manifest:    manifest,
+		prefixNames: prefixNames,
+		out:         out,
+		logger:      logger,
+	}
+}
+
+// Run prints the reconciliation instructions and syncs name prefixes.
+func (p *folderReconciler) Run(ctx context.Context) error {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlists, err := fetchOwnedPlaylists(ctx, p.client, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get user playlists: %w", err)
+	}
+
+	byFolder := make(map[string][]spotify.SimplePlaylist)
+	for _, pl := range playlists {
+		folder, ok := p.manifest.FolderFor(pl.Name)
+		if ok {
+			byFolder[folder] = append(byFolder[folder], pl)
+		}
+		if p.prefixNames {
+			if err := p.syncPrefix(ctx, pl, folder); err != nil {
+				return err
+			}
+		}
+	}
+	if len(byFolder) == 0 {
+		p.logger.Println("No playlists match the folder manifest. Nothing to do.")
+		return ErrNothingToDo
+	}
+
+	paths := make([]string, 0, len(byFolder))
+	for path := range byFolder {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintln(p.out, "\n📁 Folders can't be managed through the API. In the desktop app:")
+	created := make(map[string]bool)
+	step := 1
+	for _, path := range paths {
+		for _, parent := range folders.Parents(path) {
+			if !created[parent] {
+				created[parent] = true
+				fmt.Fprintf(p.out, "%3d. Create folder '%s' (right-click > Create folder), if it doesn't exist.\n", step, parent)
+				step++
+			}
+		}
+		for _, pl := range byFolder[path] {
+			fmt.Fprintf(p.out, "%3d. Move '%s' into '%s': spotify:playlist:%s\n", step, pl.Name, path, pl.ID)
+			step++
+		}
+	}
+	return nil
+}
+
+// syncPrefix renames a playlist so its name prefix reflects its manifest folder.
+func (p *folderReconciler) syncPrefix(ctx context.Context, pl spotify.SimplePlaylist, folder string) error {
+	want := folders.PrefixedName(folder, pl.Name)
+	if want == pl.Name {
+		return nil
+	}
+	p.logger.Printf("Renaming '%s' to '%s'.", pl.Name, want)
+	if err := p.client.ChangePlaylistName(ctx, pl.ID, want); err != nil {
+		return fmt.Errorf("failed to rename playlist '%s': %w", pl.Name, err)
+	}
+	return nil
+}
+
+// fetchOwnedPlaylists pages through every playlist owned by userID.
+func fetchOwnedPlaylists(ctx context.Context, client SpotifyClient, userID string) ([]spotify.SimplePlaylist, error) {
+	var owned []spotify.SimplePlaylist
+	limit := 50
+	offset := 0
+
+	for {
+		page, err := client.GetPlaylistsForUser(ctx, userID, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Playlists) == 0 {
+			break
+		}
+		for _, pl := range page.Playlists {
+			if pl.Owner.ID == userID {
+				owned = append(owned, pl)
+			}
+		}
+		offset += len(page.Playlists)
+	}
+	return owned, nil
+}
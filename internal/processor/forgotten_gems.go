@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"spotify/internal/config"
+	"spotify/internal/history"
+	"spotify/internal/matching"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type forgottenGemsBuilder struct {
+	client    SpotifyClient
+	store     *store.Store
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	cfg       config.ForgottenGems
+}
+
+// NewForgottenGemsBuilder returns a Processor that resurfaces songs liked long ago that
+// haven't been played recently, according to the streaming history in the local store.
+func NewForgottenGemsBuilder(client SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfg config.ForgottenGems, shared config.Playlists) (Processor, error) {
+	if cfg.LikedYearsAgo < 0 || cfg.NotPlayedMonths <= 0 {
+		return nil, fmt.Errorf("liked_years_ago can't be negative and not_played_months must be positive")
+	}
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &forgottenGemsBuilder{
+		client:    client,
+		store:     st,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, st, logger),
+		templates: templates,
+		cfg:       cfg,
+	}, nil
+}
+
+// Run picks the liked songs missing from recent history, most played overall first, and
+// rewrites the playlist.
+func (p *forgottenGemsBuilder) Run(ctx context.Context) error {
+	plays := p.store.Plays()
+	if len(plays) == 0 {
+		p.logger.Println("No streaming history in the local store. Run import-history first.")
+		return nil
+	}
+	now := time.Now()
+	quietSince := now.AddDate(0, -p.cfg.NotPlayedMonths, 0)
+	if latest := history.Latest(plays); latest.Before(quietSince) {
+		p.logger.Printf("⚠️ The newest play in the local history is from %s; import a recent export or every old like will look forgotten.", latest.Format(time.DateOnly))
+	}
+
+	// Plays are matched by ID and by title and artist, since relinked tracks change ID.
+	minPlay := time.Duration(p.cfg.MinPlaySeconds) * time.Second
+	lastPlayed := make(map[string]time.Time)
+	playCount := make(map[string]int)
+	for _, play := range plays {
+		if time.Duration(play.MsPlayed)*time.Millisecond < minPlay {
+			continue
+		}
+		for _, key := range []string{string(play.TrackID), matching.Key(play.TrackName, play.ArtistName)} {
+			playCount[key]++
+			if play.PlayedAt.After(lastPlayed[key]) {
+				lastPlayed[key] = play.PlayedAt
+			}
+		}
+	}
+
+	likedBefore := now.AddDate(-p.cfg.LikedYearsAgo, 0, 0)
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	var gems []spotify.SavedTrack
+	timesPlayed := make(map[spotify.ID]int)
+	for _, t := range liked {
+		addedAt, err := time.Parse(time.RFC3339, t.AddedAt)
+		if err != nil || !addedAt.Before(likedBefore) {
+			continue
+		}
+		keys := []string{string(t.ID), matching.Key(t.Name, artistNames(t.Artists))}
+		if lastPlayed[keys[0]].After(quietSince) || lastPlayed[keys[1]].After(quietSince) {
+			continue
+		}
+		gems = append(gems, t)
+		timesPlayed[t.ID] = max(playCount[keys[0]], playCount[keys[1]])
+	}
+	// Old favorites first; ties keep the oldest like first.
+	gems = sortByAdded(gems, false)
+	sort.SliceStable(gems, func(i, j int) bool { return timesPlayed[gems[i].ID] > timesPlayed[gems[j].ID] })
+	if p.cfg.Limit > 0 && len(gems) > p.cfg.Limit {
+		gems = gems[:p.cfg.Limit]
+	}
+	p.logger.Printf("Found %d forgotten gems.", len(gems))
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		TrackCount: len(gems),
+		Duration:   formatDuration(totalDuration(gems)),
+		Date:       now.Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{
+		Name:     playlistName,
+		Subtitle: p.cfg.CoverSubtitle,
+		Tracks:   fullTracks(gems),
+	})
+	if err := p.writer.Replace(ctx, playlistID, savedTrackIDs(gems)); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	return nil
+}
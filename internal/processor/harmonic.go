@@ -0,0 +1,179 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// camelotKey is a position on the Camelot wheel: 1 to 12, and minor ("A") or major
+// ("B"). Neighbouring numbers, and the two modes of one number, mix smoothly.
+type camelotKey struct {
+	number int
+	minor  bool
+}
+
+// String renders the key as DJs write it, e.g. "8A".
+func (k camelotKey) String() string {
+	if k.minor {
+		return fmt.Sprintf("%dA", k.number)
+	}
+	return fmt.Sprintf("%dB", k.number)
+}
+
+// camelot converts Spotify's pitch class (0 is C) and mode (1 is major) into a wheel
+// position. It returns false when Spotify couldn't detect the key.
+func camelot(f *spotify.AudioFeatures) (camelotKey, bool) {
+	if f == nil || f.Key < 0 || f.Key > 11 {
+		return camelotKey{}, false
+	}
+	// A step of a fifth is a step on the wheel; C major is 8B and A minor 8A.
+	fifths := int(f.Key) * 7 % 12
+	if f.Mode == 1 {
+		return camelotKey{number: (fifths+7)%12 + 1}, true
+	}
+	return camelotKey{number: (fifths+4)%12 + 1, minor: true}, true
+}
+
+// keyDistance counts the wheel steps between two keys: 0 for the same key, 1 for a
+// compatible one, more for a clash.
+func keyDistance(a, b camelotKey) int {
+	d := a.number - b.number
+	if d < 0 {
+		d = -d
+	}
+	d = min(d, 12-d)
+	if a.minor != b.minor {
+		d++
+	}
+	return d
+}
+
+// harmonicKeyWeight is how many BPM of tempo difference one step on the wheel is worth.
+const harmonicKeyWeight = 8
+
+// transitionCost rates the move from one track to the next; lower is smoother. Tempos
+// are compared at double and half time too, as a DJ would mix them.
+func transitionCost(from, to *spotify.AudioFeatures) float64 {
+	cost := 0.0
+	keyFrom, okFrom := camelot(from)
+	keyTo, okTo := camelot(to)
+	if okFrom && okTo {
+		cost += float64(harmonicKeyWeight * keyDistance(keyFrom, keyTo))
+	} else {
+		cost += 3 * harmonicKeyWeight
+	}
+	a, b := float64(from.Tempo), float64(to.Tempo)
+	if a > 0 && b > 0 {
+		cost += min(math.Abs(a-b), math.Abs(a-2*b), math.Abs(2*a-b))
+	}
+	return cost
+}
+
+// harmonicOrder returns a permutation of tracks that moves between compatible keys and
+// close tempos. It starts from the first track and always plays the smoothest remaining
+// transition next. Tracks without audio features keep their order at the end.
+func harmonicOrder(tracks []spotify.FullTrack, features map[spotify.ID]*spotify.AudioFeatures) []int {
+	var remaining, unknown []int
+	for i, t := range tracks {
+		if features[t.ID] != nil {
+			remaining = append(remaining, i)
+		} else {
+			unknown = append(unknown, i)
+		}
+	}
+	order := make([]int, 0, len(tracks))
+	for len(remaining) > 0 {
+		pick := 0
+		if len(order) > 0 {
+			last := features[tracks[order[len(order)-1]].ID]
+			best := math.Inf(1)
+			for i, idx := range remaining {
+				if c := transitionCost(last, features[tracks[idx].ID]); c < best {
+					pick, best = i, c
+				}
+			}
+		}
+		order = append(order, remaining[pick])
+		remaining = slices.Delete(remaining, pick, pick+1)
+	}
+	return append(order, unknown...)
+}
+
+// harmonize reorders tracks for harmonic mixing.
+func harmonize(tracks []spotify.SavedTrack, features map[spotify.ID]*spotify.AudioFeatures) []spotify.SavedTrack {
+	order := harmonicOrder(fullTracks(tracks), features)
+	ordered := make([]spotify.SavedTrack, len(order))
+	for i, idx := range order {
+		ordered[i] = tracks[idx]
+	}
+	return ordered
+}
+
+type playlistMixer struct {
+	client SpotifyClient
+	logger *log.Logger
+	writer *playlistWriter
+	ref    string
+}
+
+// NewPlaylistMixer returns a Processor that reorders one of the user's playlists for
+// harmonic mixing, by key compatibility on the Camelot wheel and tempo. ref is the
+// playlist's URL, URI or ID, or its name.
+func NewPlaylistMixer(client SpotifyClient, logger *log.Logger, ref string) (Processor, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("name the playlist to mix")
+	}
+	return &playlistMixer{client: client, logger: logger, writer: newPlaylistWriter(client, logger), ref: ref}, nil
+}
+
+// Run reorders the playlist and logs the resulting keys and tempos.
+func (p *playlistMixer) Run(ctx context.Context) error {
+	playlistID, name, err := resolvePlaylist(ctx, p.client, p.writer, p.ref)
+	if err != nil {
+		return err
+	}
+	tracks, err := fetchPlaylistTracks(ctx, p.client, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", name, err)
+	}
+	if len(tracks) < 2 {
+		p.logger.Printf("✅ '%s' has nothing to reorder.", name)
+		return ErrNothingToDo
+	}
+	features, err := fetchAudioFeatures(ctx, p.client, trackIDs(tracks))
+	if err != nil {
+		return err
+	}
+	order := harmonicOrder(tracks, features)
+	mixed := make([]spotify.FullTrack, len(order))
+	clashes := 0
+	for i, idx := range order {
+		mixed[i] = tracks[idx]
+		f := features[mixed[i].ID]
+		key, ok := camelot(f)
+		label := "?"
+		if ok {
+			label = key.String()
+		}
+		bpm := 0.0
+		if f != nil {
+			bpm = float64(f.Tempo)
+		}
+		if i > 0 && ok {
+			if prev, okPrev := camelot(features[mixed[i-1].ID]); okPrev && keyDistance(prev, key) > 1 {
+				clashes++
+			}
+		}
+		p.logger.Printf("  %3d. %-3s %5.0f BPM  %s - %s", i+1, label, bpm, artistNames(mixed[i].Artists), mixed[i].Name)
+	}
+	if err := p.writer.Replace(ctx, playlistID, trackIDs(mixed)); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", name, err)
+	}
+	p.logger.Printf("✅ Reordered %d tracks of '%s'; %d transitions clash in key.", len(mixed), name, clashes)
+	return nil
+}
@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"spotify/internal/matching"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// healthTag is the tag the suggested commands put on liked songs to remove.
+const healthTag = "health-check"
+
+// stampDate finds the update date in the stats stamp of a generated playlist.
+var stampDate = regexp.MustCompile(`updated (\d{4}-\d{2}-\d{2}) ` + stampSignature)
+
+// healthIssue is one kind of problem found by the health check, most urgent first.
+type healthIssue struct {
+	title string
+	// items describe each occurrence; commands fix all of them, if any can.
+	items    []string
+	commands []string
+	advice   string
+}
+
+type healthCheck struct {
+	client SpotifyClient
+	out    io.Writer
+	logger *log.Logger
+	// staleAfter is how long a generated playlist can go without an update before it's
+	// reported as orphaned.
+	staleAfter time.Duration
+}
+
+// NewHealthCheck returns a Processor that scans liked songs and owned playlists for
+// problems and prints a report, most urgent first, with the commands that fix them. It
+// changes nothing.
+func NewHealthCheck(client SpotifyClient, out io.Writer, logger *log.Logger, staleAfter time.Duration) Processor {
+	return &healthCheck{client: client, out: out, logger: logger, staleAfter: staleAfter}
+}
+
+// Run scans the library and prints the report.
+func (p *healthCheck) Run(ctx context.Context) error {
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlists, err := fetchOwnedPlaylists(ctx, p.client, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get user playlists: %w", err)
+	}
+
+	issues := []healthIssue{p.unavailableLikes(liked), p.duplicateLikes(liked)}
+	playlistIssues, err := p.checkPlaylists(ctx, playlists)
+	if err != nil {
+		return err
+	}
+	issues = append(issues, playlistIssues...)
+	p.printReport(issues)
+	return nil
+}
+
+// unavailableLikes finds liked songs that can't be played anywhere, usually because the
+// release was pulled or replaced.
+func (p *healthCheck) unavailableLikes(liked []spotify.SavedTrack) healthIssue {
+	issue := healthIssue{
+		title:  "Liked songs that are no longer available",
+		advice: "Look for another release of these songs before removing them.",
+	}
+	var ids []spotify.ID
+	for _, t := range liked {
+		if len(t.AvailableMarkets) == 0 || t.Duration == 0 {
+			issue.items = append(issue.items, describeTrack(t.FullTrack))
+			ids = append(ids, t.ID)
+		}
+	}
+	issue.commands = removeCommands(ids)
+	return issue
+}
+
+// duplicateLikes finds songs liked more than once under different releases, and keeps
+// the earliest like of each.
+func (p *healthCheck) duplicateLikes(liked []spotify.SavedTrack) healthIssue {
+	issue := healthIssue{title: "Songs liked more than once (other releases of the same song)"}
+	earliest := make(map[string]spotify.SavedTrack)
+	// Liked songs come newest first, so walking backwards meets each song's first like first.
+	var ids []spotify.ID
+	seen := make(map[spotify.ID]struct{})
+	for i := len(liked) - 1; i >= 0; i-- {
+		t := liked[i]
+		if _, dup := seen[t.ID]; dup {
+			continue
+		}
+		seen[t.ID] = struct{}{}
+		key := matching.Key(t.Name, artistNames(t.Artists))
+		first, ok := earliest[key]
+		if !ok {
+			earliest[key] = t
+			continue
+		}
+		issue.items = append(issue.items, fmt.Sprintf("%s — liked again (first liked as %s)", describeTrack(t.FullTrack), first.URI))
+		ids = append(ids, t.ID)
+	}
+	issue.commands = removeCommands(ids)
+	return issue
+}
+
+// checkPlaylists looks through every owned playlist for repeated tracks, local or
+// unplayable entries, emptiness, and generated playlists that stopped being updated.
+func (p *healthCheck) checkPlaylists(ctx context.Context, playlists []spotify.SimplePlaylist) ([]healthIssue, error) {
+	repeated := healthIssue{title: "Tracks repeated within a playlist", advice: "Remove the extra copies in the app, or re-run the command that generates the playlist."}
+	orphaned := healthIssue{title: "Generated playlists that stopped being updated", advice: "Their generator was probably renamed or removed; delete them in the app if you no longer need them."}
+	empty := healthIssue{title: "Empty playlists", advice: "Delete them in the app."}
+	local := healthIssue{title: "Local files and unplayable entries in playlists", advice: "Local files only play on devices that have them; nothing to fix unless that's unexpected."}
+
+	now := time.Now()
+	for _, pl := range playlists {
+		if m := stampDate.FindStringSubmatch(pl.Description); m != nil {
+			if updated, err := time.Parse(time.DateOnly, m[1]); err == nil && now.Sub(updated) > p.staleAfter {
+				orphaned.items = append(orphaned.items, fmt.Sprintf("%s — last updated %s (%s)", pl.Name, m[1], pl.URI))
+			}
+		}
+		if pl.Tracks.Total == 0 {
+			empty.items = append(empty.items, fmt.Sprintf("%s (%s)", pl.Name, pl.URI))
+			continue
+		}
+
+		counts := make(map[spotify.ID]int)
+		offset := 0
+		for {
+			page, err := p.client.GetPlaylistTracks(ctx, pl.ID, spotify.Limit(100), spotify.Offset(offset))
+			if err != nil {
+				return nil, fmt.Errorf("could not fetch tracks of '%s': %w", pl.Name, err)
+			}
+			if len(page.Tracks) == 0 {
+				break
+			}
+			for _, item := range page.Tracks {
+				if item.IsLocal || item.Track.ID == "" || item.Track.Duration == 0 {
+					local.items = append(local.items, fmt.Sprintf("%s — in %s", item.Track.Name, pl.Name))
+					continue
+				}
+				counts[item.Track.ID]++
+			}
+			offset += len(page.Tracks)
+		}
+		for _, id := range sortedIDs(counts) {
+			if counts[id] > 1 {
+				repeated.items = append(repeated.items, fmt.Sprintf("spotify:track:%s — %d times in %s", id, counts[id], pl.Name))
+			}
+		}
+	}
+	return []healthIssue{repeated, orphaned, empty, local}, nil
+}
+
+// printReport prints the issues found, in order, with their fixes.
+func (p *healthCheck) printReport(issues []healthIssue) {
+	total := 0
+	for _, issue := range issues {
+		total += len(issue.items)
+	}
+	if total == 0 {
+		fmt.Fprintln(p.out, "\n✅ Your library is healthy: no problems found.")
+		return
+	}
+	fmt.Fprintf(p.out, "\n🩺 Found %d problems, most urgent first:\n", total)
+	n := 0
+	for _, issue := range issues {
+		if len(issue.items) == 0 {
+			continue
+		}
+		n++
+		fmt.Fprintf(p.out, "\n%d. %s (%d):\n", n, issue.title, len(issue.items))
+		for _, item := range issue.items {
+			fmt.Fprintf(p.out, "   - %s\n", item)
+		}
+		if issue.advice != "" {
+			fmt.Fprintf(p.out, "   👉 %s\n", issue.advice)
+		}
+		if len(issue.commands) > 0 {
+			fmt.Fprintln(p.out, "   👉 To fix, run:")
+			for _, c := range issue.commands {
+				fmt.Fprintf(p.out, "      %s\n", c)
+			}
+		}
+	}
+}
+
+// removeCommands returns the commands that unlike ids: tag them, then remove the tagged
+// songs, so the removal can be reviewed with a dry run first.
+func removeCommands(ids []spotify.ID) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	var commands []string
+	for _, id := range ids {
+		commands = append(commands, fmt.Sprintf("go run ./cmd tag add spotify:track:%s %s", id, healthTag))
+	}
+	return append(commands, fmt.Sprintf("go run ./cmd remove --query 'tag:%s' --yes", healthTag))
+}
+
+// describeTrack names a track for the report.
+func describeTrack(t spotify.FullTrack) string {
+	return fmt.Sprintf("%s by %s (%s)", t.Name, artistNames(t.Artists), t.URI)
+}
+
+// sortedIDs returns the keys of counts in a stable order.
+func sortedIDs(counts map[spotify.ID]int) []spotify.ID {
+	ids := make([]spotify.ID, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/history"
+	"spotify/internal/store"
+)
+
+type historyImporter struct {
+	dir    string
+	store  *store.Store
+	logger *log.Logger
+}
+
+// NewHistoryImporter returns a Processor that loads an unpacked "extended streaming
+// history" or basic "Account data" export from dir into the local store. It doesn't talk
+// to Spotify; plays from the basic export, which has no track IDs, are resolved when
+// they're first ranked.
+func NewHistoryImporter(dir string, st *store.Store, logger *log.Logger) Processor {
+	return &historyImporter{
+		dir:    dir,
+		store:  st,
+		logger: logger,
+	}
+}
+
+// Run parses every history file in the export directory and merges the plays into the store.
+func (p *historyImporter) Run(ctx context.Context) error {
+	extended, err := history.FindExtendedFiles(p.dir)
+	if err != nil {
+		return fmt.Errorf("could not list history files: %w", err)
+	}
+	basic, err := history.FindBasicFiles(p.dir)
+	if err != nil {
+		return fmt.Errorf("could not list history files: %w", err)
+	}
+	if len(extended)+len(basic) == 0 {
+		return fmt.Errorf("no streaming history files found in '%s'", p.dir)
+	}
+
+	// Extended files go first: their plays carry track IDs, so the same plays from the
+	// basic files are recognized as duplicates.
+	load := make(map[string]func(string) ([]store.Play, error))
+	for _, file := range extended {
+		load[file] = history.LoadExtendedFile
+	}
+	for _, file := range basic {
+		load[file] = history.LoadBasicFile
+	}
+	total := 0
+	for _, file := range append(extended, basic...) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		plays, err := load[file](file)
+		if err != nil {
+			return fmt.Errorf("could not load '%s': %w", file, err)
+		}
+		added := p.store.AddPlays(plays)
+		total += added
+		p.logger.Printf("Imported %d new plays from %s (%d entries).", added, file, len(plays))
+	}
+
+	if err := p.store.Save(); err != nil {
+		return fmt.Errorf("could not save store: %w", err)
+	}
+	p.logger.Printf("✅ Imported %d new plays in total.", total)
+	return nil
+}
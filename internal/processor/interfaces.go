@@ -12,14 +12,49 @@ type SpotifyClient interface {
 	CurrentUser(ctx context.Context) (*spotify.PrivateUser, error)
 	CurrentUsersTracks(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedTrackPage, error)
 	RemoveTracksFromLibrary(ctx context.Context, ids ...spotify.ID) error
+	AddTracksToLibrary(ctx context.Context, ids ...spotify.ID) error
+	CurrentUsersAlbums(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedAlbumPage, error)
+	GetArtists(ctx context.Context, ids ...spotify.ID) ([]*spotify.FullArtist, error)
+	GetAudioFeatures(ctx context.Context, ids ...spotify.ID) ([]*spotify.AudioFeatures, error)
+	GetTracks(ctx context.Context, ids []spotify.ID, opts ...spotify.RequestOption) ([]*spotify.FullTrack, error)
+	GetAlbumTracks(ctx context.Context, id spotify.ID, opts ...spotify.RequestOption) (*spotify.SimpleTrackPage, error)
+	AddAlbumsToLibrary(ctx context.Context, ids ...spotify.ID) error
+	CurrentUsersFollowedArtists(ctx context.Context, opts ...spotify.RequestOption) (*spotify.FullArtistCursorPage, error)
+	FollowArtist(ctx context.Context, ids ...spotify.ID) error
+	UnfollowArtist(ctx context.Context, ids ...spotify.ID) error
+	RemoveAlbumsFromLibrary(ctx context.Context, ids ...spotify.ID) error
 	Search(ctx context.Context, query string, t spotify.SearchType, opts ...spotify.RequestOption) (*spotify.SearchResult, error)
 	UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error
+	ChangePlaylistName(ctx context.Context, playlistID spotify.ID, newName string) error
+	ChangePlaylistDescription(ctx context.Context, playlistID spotify.ID, newDescription string) error
+	ChangePlaylistAccess(ctx context.Context, playlistID spotify.ID, public bool) error
 	CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error)
 	AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error)
+	ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error)
+	GetPlaylist(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.FullPlaylist, error)
 	SetPlaylistImage(ctx context.Context, playlistID spotify.ID, img io.Reader) error
 	GetPlaylistsForUser(ctx context.Context, userID string, opts ...spotify.RequestOption) (*spotify.SimplePlaylistPage, error)
 	GetPlaylistTracks(context.Context, spotify.ID, ...spotify.RequestOption) (*spotify.PlaylistTrackPage, error)
 	RemoveTracksFromPlaylist(context.Context, spotify.ID, ...spotify.ID) (string, error)
+	PlayerDevices(ctx context.Context) ([]spotify.PlayerDevice, error)
+	TransferPlayback(ctx context.Context, deviceID spotify.ID, play bool) error
+	PlayOpt(ctx context.Context, opt *spotify.PlayOptions) error
+	QueueSongOpt(ctx context.Context, trackID spotify.ID, opt *spotify.PlayOptions) error
+}
+
+// freshKey marks a context whose reads must reach Spotify.
+type freshKey struct{}
+
+// WithFreshReads returns a context whose reads skip any cache in front of the client, for
+// checks that must see the library as it is now. Caching clients still save what's read.
+func WithFreshReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// FreshReads reports whether reads made with ctx must skip caches.
+func FreshReads(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshKey{}).(bool)
+	return fresh
 }
 
 // Processor defines a generic task that can be executed.
@@ -27,7 +62,37 @@ type Processor interface {
 	Run(ctx context.Context) error
 }
 
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(ctx context.Context) error
+
+// Run calls f.
+func (f ProcessorFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CoverSpec describes the playlist a cover image is generated for.
+type CoverSpec struct {
+	Name     string // playlist name; also seeds the artwork
+	Label    string // large text drawn on the cover, e.g. "2021"; defaults to Name
+	Subtitle string // optional smaller line under the label
+	// Tracks are the playlist's tracks in order, for generators that derive artwork from
+	// its contents. May be empty.
+	Tracks []spotify.FullTrack
+}
+
+// CoverRegistry remembers a checksum of the cover last uploaded to each playlist so
+// unchanged covers aren't uploaded again.
+type CoverRegistry interface {
+	UploadedCover(playlistID spotify.ID) string
+	SetUploadedCover(playlistID spotify.ID, checksum string)
+}
+
+// TagSource provides the user's local tags of tracks.
+type TagSource interface {
+	AllTags() map[spotify.ID][]string
+}
+
 // ImageGenerator defines a component that can generate an image.
 type ImageGenerator interface {
-	GenerateForPlaylist(name string) (io.Reader, error)
+	GenerateForPlaylist(spec CoverSpec) (io.Reader, error)
 }
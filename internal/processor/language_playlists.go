@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"spotify/internal/config"
+	"spotify/internal/language"
+	"spotify/internal/matching"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// unknownLanguage is the code hooks and overrides use for "not known".
+const unknownLanguage = "und"
+
+type languagePlaylistBuilder struct {
+	client    SpotifyClient
+	store     *store.Store
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	hook      *language.Hook
+	cfg       config.Languages
+}
+
+// NewLanguagePlaylistBuilder returns a Processor that groups liked songs by the language
+// they're sung in, e.g. "Liked · Italiano". Manual overrides in the store take
+// precedence over the lyrics hook, which takes precedence over metadata heuristics.
+func NewLanguagePlaylistBuilder(client SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfg config.Languages, shared config.Playlists) (Processor, error) {
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	var hook *language.Hook
+	if cfg.LyricsHook != "" {
+		hook = language.NewHook(cfg.LyricsHook)
+	}
+	return &languagePlaylistBuilder{
+		client:    client,
+		store:     st,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, st, logger),
+		templates: templates,
+		hook:      hook,
+		cfg:       cfg,
+	}, nil
+}
+
+// Run detects the language of every liked song and writes one playlist per language.
+func (p *languagePlaylistBuilder) Run(ctx context.Context) error {
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	if len(liked) == 0 {
+		p.logger.Println("No liked tracks found. Nothing to do.")
+		return ErrNothingToDo
+	}
+	genres, err := fetchArtistGenres(ctx, p.client, uniqueArtistIDs(fullTracks(liked)))
+	if err != nil {
+		return err
+	}
+
+	byLanguage := make(map[string][]spotify.SavedTrack)
+	for _, t := range liked {
+		lang := p.languageOf(ctx, t.FullTrack, genres)
+		byLanguage[lang] = append(byLanguage[lang], t)
+	}
+	for lang, tracks := range byLanguage {
+		if lang != "" && len(tracks) < p.cfg.MinTracks {
+			byLanguage[""] = append(byLanguage[""], tracks...)
+			delete(byLanguage, lang)
+		}
+	}
+
+	// Languages by name, with the unknown-language playlist last.
+	langs := make([]string, 0, len(byLanguage))
+	for lang := range byLanguage {
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if (langs[i] == "") != (langs[j] == "") {
+			return langs[j] == ""
+		}
+		return language.Name(langs[i]) < language.Name(langs[j])
+	})
+	p.logger.Printf("Found liked songs in %d languages.", len(langs))
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	// Covers render in the background while the tracks are written.
+	defer p.covers.Wait()
+	today := time.Now().Format(time.DateOnly)
+	// A failing language doesn't stop the others; failures are reported together at the end.
+	return forEachPlaylist(ctx, p.logger, langs, p.languageName, func(lang string) error {
+		tracks := byLanguage[lang]
+		name := p.languageName(lang)
+		playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+			Name:       name,
+			TrackCount: len(tracks),
+			Duration:   formatDuration(totalDuration(tracks)),
+			Date:       today,
+		})
+		if err != nil {
+			return err
+		}
+		p.logger.Printf("--- Processing %s (%d tracks) ---", name, len(tracks))
+
+		playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+		if err != nil {
+			return err
+		}
+		p.covers.Start(ctx, playlistID, CoverSpec{
+			Name:     playlistName,
+			Label:    name,
+			Subtitle: p.cfg.CoverSubtitle,
+			Tracks:   fullTracks(tracks),
+		})
+		if err := p.writer.Replace(ctx, playlistID, savedTrackIDs(tracks)); err != nil {
+			return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+		}
+		return nil
+	})
+}
+
+// languageName returns the display name of a language code, or the unknown-language name for "".
+func (p *languagePlaylistBuilder) languageName(lang string) string {
+	if lang == "" {
+		return p.cfg.UnknownName
+	}
+	return language.Name(lang)
+}
+
+// languageOf returns the language code of t, or "" if it's unknown.
+func (p *languagePlaylistBuilder) languageOf(ctx context.Context, t spotify.FullTrack, genres map[spotify.ID][]string) string {
+	annotation := p.store.Annotation(t.ID)
+	if annotation.Language != "" {
+		return knownLanguage(annotation.Language)
+	}
+
+	if p.hook != nil && annotation.LyricsLanguage == "" {
+		lang, err := p.hook.Lookup(ctx, matching.ISRC(t), t.Name, artistNames(t.Artists))
+		if err != nil {
+			// A broken hook would fail the same way for every track.
+			p.logger.Printf("⚠️  Lyrics hook failed, using metadata only for the rest of the run: %v", err)
+			p.hook = nil
+		} else {
+			if lang == "" {
+				lang = unknownLanguage
+			}
+			p.store.Annotate(t.ID, func(a *store.Annotation) { a.LyricsLanguage = lang })
+			annotation.LyricsLanguage = lang
+		}
+	}
+	if lang := knownLanguage(annotation.LyricsLanguage); lang != "" {
+		return lang
+	}
+
+	detect := language.Track{Title: t.Name, Album: t.Album.Name}
+	for _, a := range t.Artists {
+		detect.Artists = append(detect.Artists, a.Name)
+	}
+	if len(t.Artists) > 0 {
+		detect.Genres = genres[t.Artists[0].ID]
+	}
+	return language.Detect(detect)
+}
+
+// knownLanguage maps the "unknown" code to "".
+func knownLanguage(code string) string {
+	if code == unknownLanguage {
+		return ""
+	}
+	return code
+}
+
+type languageOverride struct {
+	store   *store.Store
+	trackID spotify.ID
+	code    string
+	logger  *log.Logger
+}
+
+// NewLanguageOverride returns a Processor that records the language of a track in the
+// store, overriding detection. "und" files it under the unknown language and "" removes
+// the override.
+func NewLanguageOverride(st *store.Store, trackID spotify.ID, code string, logger *log.Logger) Processor {
+	return &languageOverride{store: st, trackID: trackID, code: code, logger: logger}
+}
+
+// Run stores the override.
+func (p *languageOverride) Run(ctx context.Context) error {
+	p.store.Annotate(p.trackID, func(a *store.Annotation) { a.Language = p.code })
+	if p.code == "" {
+		p.logger.Printf("✅ Removed the language override of %s.", p.trackID)
+		return nil
+	}
+	p.logger.Printf("✅ %s is now filed under %s.", p.trackID, language.Name(p.code))
+	return nil
+}
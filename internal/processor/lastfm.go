@@ -0,0 +1,250 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"spotify/internal/lastfm"
+	"spotify/internal/matching"
+	"spotify/internal/store"
+	"strconv"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type lastfmTopBuilder struct {
+	client    SpotifyClient
+	lastfm    *lastfm.Client
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	resolver  *trackResolver
+	cfg       config.LastFM
+	year      int
+}
+
+// NewLastfmTopBuilder returns a Processor that builds a "Most Scrobbled" playlist of the
+// tracks scrobbled most often to Last.fm in year, looked up on Spotify by name with
+// matches scored by matchCfg.
+func NewLastfmTopBuilder(client SpotifyClient, lf *lastfm.Client, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfg config.LastFM, matchCfg config.Matching, shared config.Playlists, year int) (Processor, error) {
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &lastfmTopBuilder{
+		client:    client,
+		lastfm:    lf,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, st, logger),
+		templates: templates,
+		resolver:  newTrackResolver(client, matchCfg, logger),
+		cfg:       cfg,
+		year:      year,
+	}, nil
+}
+
+// Run counts the year's scrobbles and writes the top tracks into the year's playlist.
+func (p *lastfmTopBuilder) Run(ctx context.Context) error {
+	from := time.Date(p.year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	p.logger.Printf("Fetching %d scrobbles from Last.fm...", p.year)
+	scrobbled, err := p.lastfm.Scrobbles(ctx, from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return err
+	}
+
+	var trackIDs []spotify.ID
+	seen := make(map[spotify.ID]struct{})
+	for _, t := range scrobbled {
+		if len(trackIDs) == p.cfg.Limit || t.Plays < p.cfg.MinScrobbles {
+			break
+		}
+		id, err := p.resolver.Resolve(ctx, t.Title, t.Artist)
+		if err != nil {
+			return err
+		}
+		if _, dup := seen[id]; id == "" || dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		trackIDs = append(trackIDs, id)
+	}
+	if len(trackIDs) == 0 {
+		p.logger.Printf("No tracks scrobbled at least %d times in %d. Nothing to do.", p.cfg.MinScrobbles, p.year)
+		return ErrNothingToDo
+	}
+	tracks, err := getTracks(ctx, p.client, trackIDs)
+	if err != nil {
+		return fmt.Errorf("failed to fetch tracks: %w", err)
+	}
+	var length time.Duration
+	for _, t := range tracks {
+		length += time.Duration(t.Duration) * time.Millisecond
+	}
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		Year:       p.year,
+		TrackCount: len(trackIDs),
+		Duration:   formatDuration(length),
+		Date:       time.Now().Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{Name: playlistName, Label: strconv.Itoa(p.year), Subtitle: p.cfg.CoverSubtitle, Tracks: tracks})
+	if err := p.writer.Replace(ctx, playlistID, trackIDs); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	return nil
+}
+
+type lastfmLoveImporter struct {
+	client   SpotifyClient
+	lastfm   *lastfm.Client
+	logger   *log.Logger
+	resolver *trackResolver
+}
+
+// NewLastfmLoveImporter returns a Processor that likes on Spotify the tracks loved on
+// Last.fm.
+func NewLastfmLoveImporter(client SpotifyClient, lf *lastfm.Client, logger *log.Logger, matchCfg config.Matching) Processor {
+	return &lastfmLoveImporter{client: client, lastfm: lf, logger: logger, resolver: newTrackResolver(client, matchCfg, logger)}
+}
+
+// Run looks up the loved tracks that aren't liked yet and likes them.
+func (p *lastfmLoveImporter) Run(ctx context.Context) error {
+	loved, err := p.lastfm.LovedTracks(ctx)
+	if err != nil {
+		return err
+	}
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	have := make(map[string]struct{}, 2*len(liked))
+	for _, t := range liked {
+		have[string(t.ID)] = struct{}{}
+		have[matching.Key(t.Name, artistNames(t.Artists))] = struct{}{}
+	}
+
+	var toLike []spotify.ID
+	// Oldest loves first, so they keep their order in Liked Songs.
+	for i := len(loved) - 1; i >= 0; i-- {
+		t := loved[i]
+		key := matching.Key(t.Title, t.Artist)
+		if _, ok := have[key]; ok {
+			continue
+		}
+		id, err := p.resolver.Resolve(ctx, t.Title, t.Artist)
+		if err != nil {
+			return err
+		}
+		if _, ok := have[string(id)]; id == "" || ok {
+			continue
+		}
+		have[key], have[string(id)] = struct{}{}, struct{}{}
+		toLike = append(toLike, id)
+	}
+	if len(toLike) == 0 {
+		p.logger.Printf("All %d loved tracks are already liked.", len(loved))
+		return nil
+	}
+	err = inBatches(toLike, 50, func(batch []spotify.ID) error {
+		return p.client.AddTracksToLibrary(ctx, batch...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to like tracks: %w", err)
+	}
+	p.logger.Printf("✅ Liked %d tracks loved on Last.fm.", len(toLike))
+	return nil
+}
+
+type lastfmLovePusher struct {
+	client SpotifyClient
+	lastfm *lastfm.Client
+	logger *log.Logger
+}
+
+// NewLastfmLovePusher returns a Processor that loves on Last.fm the tracks liked on
+// Spotify.
+func NewLastfmLovePusher(client SpotifyClient, lf *lastfm.Client, logger *log.Logger) Processor {
+	return &lastfmLovePusher{client: client, lastfm: lf, logger: logger}
+}
+
+// Run loves every liked song that isn't loved yet, under its first credited artist.
+func (p *lastfmLovePusher) Run(ctx context.Context) error {
+	loved, err := p.lastfm.LovedTracks(ctx)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]struct{}, len(loved))
+	for _, t := range loved {
+		have[matching.Key(t.Title, t.Artist)] = struct{}{}
+	}
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+
+	count := 0
+	for _, t := range uniqueSavedTracks(liked) {
+		if len(t.Artists) == 0 {
+			continue
+		}
+		key := matching.Key(t.Name, t.Artists[0].Name)
+		if _, ok := have[key]; ok {
+			continue
+		}
+		if err := p.lastfm.Love(ctx, t.Name, t.Artists[0].Name); err != nil {
+			return fmt.Errorf("could not love '%s': %w", t.Name, err)
+		}
+		have[key] = struct{}{}
+		count++
+	}
+	p.logger.Printf("✅ Loved %d liked songs on Last.fm.", count)
+	return nil
+}
+
+type lastfmLogin struct {
+	lastfm *lastfm.Client
+	logger *log.Logger
+	// confirm blocks until the user has authorized the token in their browser.
+	confirm func() error
+}
+
+// NewLastfmLogin returns a Processor that walks through Last.fm's login flow and prints
+// the session key to put in LASTFM_SESSION_KEY.
+func NewLastfmLogin(lf *lastfm.Client, logger *log.Logger, confirm func() error) Processor {
+	return &lastfmLogin{lastfm: lf, logger: logger, confirm: confirm}
+}
+
+// Run requests a token, waits for it to be authorized and exchanges it for a session.
+func (p *lastfmLogin) Run(ctx context.Context) error {
+	token, authURL, err := p.lastfm.Token(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("👉 Allow access on Last.fm by visiting this URL, then press Enter:")
+	fmt.Println(authURL)
+	if err := p.confirm(); err != nil {
+		return err
+	}
+	key, user, err := p.lastfm.Session(ctx, token)
+	if err != nil {
+		return err
+	}
+	p.logger.Printf("✅ Logged in to Last.fm as %s. Add this line to your .env file:", user)
+	fmt.Printf("LASTFM_SESSION_KEY=%s\n", key)
+	return nil
+}
@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// LengthFilterOptions selects the liked songs the length filter removes.
+type LengthFilterOptions struct {
+	// Min and Max bound the length of the songs kept; zero leaves that side open.
+	Min, Max time.Duration
+	// Quarantine names a playlist the songs are moved to for review instead of being
+	// just unliked.
+	Quarantine string
+	// Confirm removes the songs; without it they're only listed.
+	Confirm bool
+}
+
+type lengthFilter struct {
+	client SpotifyClient
+	logger *log.Logger
+	writer *playlistWriter
+	opts   LengthFilterOptions
+}
+
+// NewLengthFilter returns a Processor that unlikes the songs shorter than opts.Min or
+// longer than opts.Max, such as interludes, skits and long live jams.
+func NewLengthFilter(client SpotifyClient, logger *log.Logger, opts LengthFilterOptions) (Processor, error) {
+	if opts.Min <= 0 && opts.Max <= 0 {
+		return nil, fmt.Errorf("give a minimum or maximum length")
+	}
+	if opts.Min < 0 || opts.Max < 0 || opts.Max > 0 && opts.Min >= opts.Max {
+		return nil, fmt.Errorf("invalid lengths: minimum %s, maximum %s", opts.Min, opts.Max)
+	}
+	return &lengthFilter{client: client, logger: logger, writer: newPlaylistWriter(client, logger), opts: opts}, nil
+}
+
+// Run finds the songs out of bounds and removes or lists them.
+func (p *lengthFilter) Run(ctx context.Context) error {
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	var outside []spotify.FullTrack
+	for _, t := range uniqueSavedTracks(liked) {
+		length := time.Duration(t.Duration) * time.Millisecond
+		if length > 0 && (length < p.opts.Min || p.opts.Max > 0 && length > p.opts.Max) {
+			outside = append(outside, t.FullTrack)
+		}
+	}
+	if len(outside) == 0 {
+		p.logger.Printf("✅ No liked songs are %s.", p.bounds())
+		return nil
+	}
+	if !p.opts.Confirm {
+		for _, t := range outside {
+			p.logger.Printf("  would remove '%s' by %s (%s)", t.Name, artistNames(t.Artists), trackLength(t))
+		}
+		p.logger.Printf("Dry run: %d songs are %s. Run again with --yes to remove them.", len(outside), p.bounds())
+		return nil
+	}
+
+	if p.opts.Quarantine != "" {
+		if err := p.quarantine(ctx, outside); err != nil {
+			return err
+		}
+	}
+	err = inBatches(trackIDs(outside), 50, func(batch []spotify.ID) error {
+		return p.client.RemoveTracksFromLibrary(ctx, batch...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove tracks: %w", err)
+	}
+	p.logger.Printf("✅ Removed %d songs %s from your library.", len(outside), p.bounds())
+	return nil
+}
+
+// quarantine adds the tracks to the review playlist, keeping what's already there.
+func (p *lengthFilter) quarantine(ctx context.Context, tracks []spotify.FullTrack) error {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	description := fmt.Sprintf("Liked songs %s, unliked for review. Like the ones to keep again.", p.bounds())
+	playlistID, err := p.writer.Ensure(ctx, user.ID, p.opts.Quarantine, description)
+	if err != nil {
+		return err
+	}
+	present, err := fetchPlaylistTrackIDs(ctx, p.client, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", p.opts.Quarantine, err)
+	}
+	ids := slices.DeleteFunc(trackIDs(tracks), func(id spotify.ID) bool { return slices.Contains(present, id) })
+	err = inBatches(ids, 100, func(batch []spotify.ID) error {
+		_, err := p.client.AddTracksToPlaylist(ctx, playlistID, batch...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add tracks to '%s': %w", p.opts.Quarantine, err)
+	}
+	p.logger.Printf("✅ Moved %d songs to '%s' for review.", len(ids), p.opts.Quarantine)
+	return nil
+}
+
+// bounds describes the songs removed, e.g. "shorter than 1:00 or longer than 20:00".
+func (p *lengthFilter) bounds() string {
+	var parts []string
+	if p.opts.Min > 0 {
+		parts = append(parts, "shorter than "+formatLength(p.opts.Min))
+	}
+	if p.opts.Max > 0 {
+		parts = append(parts, "longer than "+formatLength(p.opts.Max))
+	}
+	return strings.Join(parts, " or ")
+}
+
+func trackLength(t spotify.FullTrack) string {
+	return formatLength(time.Duration(t.Duration) * time.Millisecond)
+}
+
+// formatLength renders a track length as minutes and seconds, e.g. "3:07".
+func formatLength(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
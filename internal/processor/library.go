@@ -0,0 +1,225 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// fetchLikedTracks pages through the entire "Liked Songs" library. Processors that only
+// keep some of the songs, or little of each, should page with pageLikedTracks instead:
+// full tracks of a library of 50k songs take hundreds of megabytes.
+func fetchLikedTracks(ctx context.Context, client SpotifyClient, logger *log.Logger) ([]spotify.SavedTrack, error) {
+	var allTracks []spotify.SavedTrack
+	err := pageLikedTracks(ctx, client, logger, 0, func(page []spotify.SavedTrack, next int) bool {
+		allTracks = append(allTracks, page...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Total liked songs fetched: %d", len(allTracks))
+	return allTracks, nil
+}
+
+// withoutMarkets drops the lists of markets of tracks, most of their size, for
+// processors that keep many tracks and don't check where they're available.
+func withoutMarkets(tracks []spotify.SavedTrack) []spotify.SavedTrack {
+	for i := range tracks {
+		tracks[i].AvailableMarkets = nil
+		tracks[i].Album.AvailableMarkets = nil
+	}
+	return tracks
+}
+
+// pageLikedTracks pages through "Liked Songs", most recently liked first, starting at
+// offset. It calls onPage with each page and the offset of the next one, and stops early
+// when onPage returns false. Only one page is held at a time.
+func pageLikedTracks(ctx context.Context, client SpotifyClient, logger *log.Logger, offset int, onPage func(page []spotify.SavedTrack, next int) bool) error {
+	limit := 50
+	for {
+		page, err := client.CurrentUsersTracks(ctx, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return err
+		}
+		if len(page.Tracks) == 0 {
+			return nil
+		}
+		offset += len(page.Tracks)
+		more := onPage(page.Tracks, offset)
+		logger.Printf("Fetched %d/%d liked songs...", offset, page.Total)
+		if !more {
+			return nil
+		}
+	}
+}
+
+// fetchSavedAlbums pages through the user's saved albums.
+func fetchSavedAlbums(ctx context.Context, client SpotifyClient, logger *log.Logger) ([]spotify.SavedAlbum, error) {
+	var albums []spotify.SavedAlbum
+	limit := 50
+	offset := 0
+
+	for {
+		page, err := client.CurrentUsersAlbums(ctx, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Albums) == 0 {
+			break
+		}
+		albums = append(albums, page.Albums...)
+		logger.Printf("Fetched %d/%d saved albums...", len(albums), page.Total)
+		offset += len(page.Albums)
+	}
+	return albums, nil
+}
+
+// fetchAlbumTrackIDs pages through an album's tracks.
+func fetchAlbumTrackIDs(ctx context.Context, client SpotifyClient, albumID spotify.ID) ([]spotify.ID, error) {
+	var ids []spotify.ID
+	limit := 50
+	offset := 0
+
+	for {
+		page, err := client.GetAlbumTracks(ctx, albumID, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Tracks) == 0 {
+			break
+		}
+		for _, t := range page.Tracks {
+			ids = append(ids, t.ID)
+		}
+		offset += len(page.Tracks)
+	}
+	return ids, nil
+}
+
+// fetchFollowedArtists pages through the artists the user follows.
+func fetchFollowedArtists(ctx context.Context, client SpotifyClient) ([]spotify.FullArtist, error) {
+	var artists []spotify.FullArtist
+	after := ""
+	for {
+		opts := []spotify.RequestOption{spotify.Limit(50)}
+		if after != "" {
+			opts = append(opts, spotify.After(after))
+		}
+		page, err := client.CurrentUsersFollowedArtists(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		artists = append(artists, page.Artists...)
+		if len(page.Artists) == 0 || page.Cursor.After == "" {
+			return artists, nil
+		}
+		after = page.Cursor.After
+	}
+}
+
+// fetchPlaylistTrackIDs returns the IDs of the tracks in a playlist, in order. Local
+// files and unavailable tracks, which have no ID, are left out.
+func fetchPlaylistTrackIDs(ctx context.Context, client SpotifyClient, playlistID spotify.ID) ([]spotify.ID, error) {
+	tracks, err := fetchPlaylistTracks(ctx, client, playlistID)
+	if err != nil {
+		return nil, err
+	}
+	return trackIDs(tracks), nil
+}
+
+// fetchPlaylistTracks returns the tracks of a playlist, in order, without the local files
+// and unavailable tracks.
+func fetchPlaylistTracks(ctx context.Context, client SpotifyClient, playlistID spotify.ID) ([]spotify.FullTrack, error) {
+	var tracks []spotify.FullTrack
+	limit := 100
+	offset := 0
+	for {
+		page, err := client.GetPlaylistTracks(ctx, playlistID, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Tracks) == 0 {
+			return tracks, nil
+		}
+		for _, item := range page.Tracks {
+			if item.Track.ID != "" {
+				tracks = append(tracks, item.Track)
+			}
+		}
+		offset += len(page.Tracks)
+	}
+}
+
+// resolvePlaylist returns the ID and name of the playlist ref refers to: the URL, URI
+// or ID of any playlist, or the name of one of the user's own.
+func resolvePlaylist(ctx context.Context, client SpotifyClient, writer *playlistWriter, ref string) (spotify.ID, string, error) {
+	if id, err := ParsePlaylistRef(ref); err == nil {
+		playlist, err := client.GetPlaylist(ctx, id, spotify.Fields("name"))
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get playlist: %w", err)
+		}
+		return id, playlist.Name, nil
+	}
+	user, err := client.CurrentUser(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlist, err := writer.Find(ctx, user.ID, ref)
+	if err != nil {
+		return "", "", err
+	}
+	if playlist == nil {
+		return "", "", fmt.Errorf("you have no playlist called '%s'", ref)
+	}
+	return playlist.ID, playlist.Name, nil
+}
+
+// getTracks looks up tracks by ID, preserving order. Unknown IDs are left out.
+func getTracks(ctx context.Context, client SpotifyClient, ids []spotify.ID) ([]spotify.FullTrack, error) {
+	var tracks []spotify.FullTrack
+	err := inBatches(ids, 50, func(batch []spotify.ID) error {
+		page, err := client.GetTracks(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, t := range page {
+			if t != nil {
+				tracks = append(tracks, *t)
+			}
+		}
+		return nil
+	})
+	return tracks, err
+}
+
+// inBatches calls fn with consecutive slices of ids no longer than size.
+func inBatches(ids []spotify.ID, size int, fn func(batch []spotify.ID) error) error {
+	for i := 0; i < len(ids); i += size {
+		if err := fn(ids[i:min(i+size, len(ids))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// artistNames joins the names of artists with commas.
+func artistNames(artists []spotify.SimpleArtist) string {
+	names := make([]string, len(artists))
+	for i, a := range artists {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// trackIDs returns the IDs of tracks, in order.
+func trackIDs(tracks []spotify.FullTrack) []spotify.ID {
+	ids := make([]spotify.ID, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+	return ids
+}
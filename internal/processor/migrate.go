@@ -0,0 +1,335 @@
+package processor
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"slices"
+	"spotify/internal/assets"
+	"spotify/internal/store"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// MigrateOptions selects what a migration copies and where it reports.
+type MigrateOptions struct {
+	Liked, Albums, Artists, Playlists bool
+	// Resume skips the steps and playlists an interrupted run already finished.
+	Resume bool
+	// Report is the CSV file listing everything that couldn't be transferred.
+	Report string
+}
+
+// migrationFailure is one line of the migration report.
+type migrationFailure struct {
+	kind, name, reason string
+}
+
+type migrator struct {
+	source, target SpotifyClient
+	store          *store.Store
+	assets         *assets.Cache
+	logger         *log.Logger
+	writer         *playlistWriter
+	covers         *coverUploader
+	opts           MigrateOptions
+
+	checkpointName string
+	checkpoint     store.Checkpoint
+	failures       []migrationFailure
+}
+
+// NewMigrator returns a Processor that copies the library of the account behind source
+// into the one behind target: liked songs in the order they were liked, saved albums,
+// followed artists, and owned playlists with their descriptions and covers. Items the
+// target already has are skipped, so a migration can be re-run safely.
+func NewMigrator(source, target SpotifyClient, st *store.Store, cache *assets.Cache, logger *log.Logger, opts MigrateOptions) Processor {
+	return &migrator{
+		source: source,
+		target: target,
+		store:  st,
+		assets: cache,
+		logger: logger,
+		writer: &playlistWriter{client: target, logger: logger, unsigned: true},
+		covers: newCoverUploader(target, nil, nil, logger),
+		opts:   opts,
+	}
+}
+
+// Run migrates each selected part of the library, checkpointing after each step and
+// playlist, then writes the report.
+func (p *migrator) Run(ctx context.Context) error {
+	from, err := p.source.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get source user: %w", err)
+	}
+	to, err := p.target.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get target user: %w", err)
+	}
+	if from.ID == to.ID {
+		return errors.New("both logins are the same account; log in to the target account the second time")
+	}
+	p.logger.Printf("Migrating from %s to %s.", from.DisplayName, to.DisplayName)
+
+	p.checkpointName = fmt.Sprintf("migrate:%s:%s", from.ID, to.ID)
+	p.checkpoint = store.Checkpoint{}
+	if cp, ok := p.store.Checkpoint(p.checkpointName); ok && p.opts.Resume {
+		p.logger.Printf("Resuming: %d steps and playlists were done before.", len(cp.Completed))
+		p.checkpoint = cp
+	}
+
+	steps := []struct {
+		name    string
+		enabled bool
+		run     func(context.Context) error
+	}{
+		{"liked songs", p.opts.Liked, p.migrateLiked},
+		{"saved albums", p.opts.Albums, p.migrateAlbums},
+		{"followed artists", p.opts.Artists, p.migrateArtists},
+		{"playlists", p.opts.Playlists, func(ctx context.Context) error { return p.migratePlaylists(ctx, from.ID, to.ID) }},
+	}
+	for _, step := range steps {
+		if !step.enabled || p.done(step.name) {
+			continue
+		}
+		p.logger.Printf("--- Migrating %s ---", step.name)
+		if err := step.run(ctx); err != nil {
+			p.saveCheckpoint()
+			return fmt.Errorf("could not migrate %s: %w", step.name, err)
+		}
+		p.markDone(step.name)
+	}
+
+	if err := p.writeReport(); err != nil {
+		return err
+	}
+	p.store.ClearCheckpoint(p.checkpointName)
+	return p.store.Save()
+}
+
+// migrateLiked likes the source's liked songs on the target, oldest first so Liked Songs
+// keeps its order.
+func (p *migrator) migrateLiked(ctx context.Context) error {
+	liked, err := fetchLikedTracks(ctx, p.source, p.logger)
+	if err != nil {
+		return err
+	}
+	have, err := fetchLikedTracks(ctx, p.target, p.logger)
+	if err != nil {
+		return err
+	}
+	missing := missingIDs(savedTrackIDs(liked), savedTrackIDs(have))
+	slices.Reverse(missing)
+	return p.copyBatches(missing, "liked songs", func(batch []spotify.ID) error {
+		return p.target.AddTracksToLibrary(ctx, batch...)
+	})
+}
+
+// migrateAlbums saves the source's saved albums on the target, oldest first.
+func (p *migrator) migrateAlbums(ctx context.Context) error {
+	albumIDs := func(client SpotifyClient) ([]spotify.ID, error) {
+		albums, err := fetchSavedAlbums(ctx, client, p.logger)
+		ids := make([]spotify.ID, len(albums))
+		for i, a := range albums {
+			ids[i] = a.ID
+		}
+		return ids, err
+	}
+	saved, err := albumIDs(p.source)
+	if err != nil {
+		return err
+	}
+	have, err := albumIDs(p.target)
+	if err != nil {
+		return err
+	}
+	missing := missingIDs(saved, have)
+	slices.Reverse(missing)
+	return p.copyBatches(missing, "saved albums", func(batch []spotify.ID) error {
+		return p.target.AddAlbumsToLibrary(ctx, batch...)
+	})
+}
+
+// migrateArtists follows the source's followed artists on the target.
+func (p *migrator) migrateArtists(ctx context.Context) error {
+	artistIDs := func(client SpotifyClient) ([]spotify.ID, error) {
+		artists, err := fetchFollowedArtists(ctx, client)
+		ids := make([]spotify.ID, len(artists))
+		for i, a := range artists {
+			ids[i] = a.ID
+		}
+		return ids, err
+	}
+	followed, err := artistIDs(p.source)
+	if err != nil {
+		return err
+	}
+	have, err := artistIDs(p.target)
+	if err != nil {
+		return err
+	}
+	return p.copyBatches(missingIDs(followed, have), "followed artists", func(batch []spotify.ID) error {
+		return p.target.FollowArtist(ctx, batch...)
+	})
+}
+
+// copyBatches writes ids in batches of 50, logging progress.
+func (p *migrator) copyBatches(ids []spotify.ID, what string, write func(batch []spotify.ID) error) error {
+	if len(ids) == 0 {
+		p.logger.Printf("The target already has all %s.", what)
+		return nil
+	}
+	done := 0
+	err := inBatches(ids, 50, func(batch []spotify.ID) error {
+		if err := write(batch); err != nil {
+			return err
+		}
+		done += len(batch)
+		p.logger.Printf("Copied %d/%d %s...", done, len(ids), what)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	p.logger.Printf("✅ Copied %d %s.", len(ids), what)
+	return nil
+}
+
+// migratePlaylists copies every playlist the source user owns. A failing playlist is
+// reported and doesn't stop the others.
+func (p *migrator) migratePlaylists(ctx context.Context, sourceUser, targetUser string) error {
+	owned, err := fetchOwnedPlaylists(ctx, p.source, sourceUser)
+	if err != nil {
+		return fmt.Errorf("could not list playlists: %w", err)
+	}
+	names := make(map[string]int)
+	for _, playlist := range owned {
+		// Playlists sharing a name get numbered, so each gets its own copy.
+		names[playlist.Name]++
+		name := playlist.Name
+		if n := names[playlist.Name]; n > 1 {
+			name = fmt.Sprintf("%s (%d)", playlist.Name, n)
+		}
+		if p.done("playlist:" + string(playlist.ID)) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.copyPlaylist(ctx, playlist, name, targetUser); err != nil {
+			p.logger.Printf("❌ Could not copy '%s': %v", playlist.Name, err)
+			p.failures = append(p.failures, migrationFailure{"playlist", playlist.Name, err.Error()})
+			continue
+		}
+		p.markDone("playlist:" + string(playlist.ID))
+	}
+	return nil
+}
+
+// copyPlaylist writes one playlist's tracks, description and cover to the target.
+// Local files can't be transferred and are reported.
+func (p *migrator) copyPlaylist(ctx context.Context, playlist spotify.SimplePlaylist, name, targetUser string) error {
+	var trackIDs []spotify.ID
+	offset := 0
+	for {
+		page, err := p.source.GetPlaylistTracks(ctx, playlist.ID, spotify.Limit(100), spotify.Offset(offset))
+		if err != nil {
+			return err
+		}
+		if len(page.Tracks) == 0 {
+			break
+		}
+		for _, item := range page.Tracks {
+			if item.Track.ID == "" {
+				p.failures = append(p.failures, migrationFailure{"track", fmt.Sprintf("%s (in '%s')", item.Track.Name, playlist.Name), "local file or unavailable track"})
+				continue
+			}
+			trackIDs = append(trackIDs, item.Track.ID)
+		}
+		offset += len(page.Tracks)
+	}
+	p.logger.Printf("Copying '%s' (%d tracks)...", name, len(trackIDs))
+
+	playlistID, err := p.writer.Ensure(ctx, targetUser, name, html.UnescapeString(playlist.Description))
+	if err != nil {
+		return err
+	}
+	if len(playlist.Images) > 0 {
+		if image, err := p.assets.Get(playlist.Images[0].URL); err != nil {
+			p.failures = append(p.failures, migrationFailure{"cover", playlist.Name, err.Error()})
+		} else {
+			p.covers.upload(ctx, playlistID, name, image)
+		}
+	}
+	cp := &p.checkpoint
+	return p.writer.ReplaceResumable(ctx, playlistID, trackIDs, cp.Partial[playlistID], func(w store.PartialWrite) {
+		if cp.Partial == nil {
+			cp.Partial = make(map[spotify.ID]store.PartialWrite)
+		}
+		cp.Partial[playlistID] = w
+		p.saveCheckpoint()
+	})
+}
+
+// done reports whether the checkpoint marks step as finished.
+func (p *migrator) done(step string) bool {
+	return slices.Contains(p.checkpoint.Completed, step)
+}
+
+// markDone records step as finished and saves the checkpoint to disk.
+func (p *migrator) markDone(step string) {
+	p.checkpoint.Completed = append(p.checkpoint.Completed, step)
+	p.saveCheckpoint()
+}
+
+func (p *migrator) saveCheckpoint() {
+	p.store.SetCheckpoint(p.checkpointName, p.checkpoint)
+	if err := p.store.Save(); err != nil {
+		p.logger.Printf("⚠️  Could not save checkpoint: %v", err)
+	}
+}
+
+// writeReport lists what couldn't be transferred, if anything.
+func (p *migrator) writeReport() error {
+	if len(p.failures) == 0 {
+		p.logger.Println("✅ Everything was transferred.")
+		return nil
+	}
+	f, err := os.Create(p.opts.Report)
+	if err != nil {
+		return fmt.Errorf("could not create report '%s': %w", p.opts.Report, err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"kind", "name", "reason"})
+	for _, failure := range p.failures {
+		w.Write([]string{failure.kind, failure.name, failure.reason})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+	p.logger.Printf("⚠️  %d items couldn't be transferred; see %s.", len(p.failures), p.opts.Report)
+	return nil
+}
+
+// missingIDs returns the ids not in have, preserving order.
+func missingIDs(ids, have []spotify.ID) []spotify.ID {
+	present := make(map[spotify.ID]struct{}, len(have))
+	for _, id := range have {
+		present[id] = struct{}{}
+	}
+	var missing []spotify.ID
+	for _, id := range ids {
+		if _, ok := present[id]; !ok {
+			present[id] = struct{}{}
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type onThisDayBuilder struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	cfg       config.OnThisDay
+	loc       *time.Location
+}
+
+// NewOnThisDayBuilder returns a Processor that refreshes a playlist of the songs liked
+// around today's date in previous years. Dates are compared in loc. It's meant to run
+// daily from the daemon.
+func NewOnThisDayBuilder(client SpotifyClient, registry CoverRegistry, logger *log.Logger, imgGen ImageGenerator, cfg config.OnThisDay, shared config.Playlists, loc *time.Location) (Processor, error) {
+	if cfg.WindowDays < 0 {
+		return nil, fmt.Errorf("window_days can't be negative")
+	}
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &onThisDayBuilder{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, registry, logger),
+		templates: templates,
+		cfg:       cfg,
+		loc:       loc,
+	}, nil
+}
+
+// Run collects the matching songs, oldest year first, and rewrites the playlist.
+func (p *onThisDayBuilder) Run(ctx context.Context) error {
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	today := time.Now().In(p.loc)
+	var tracks []spotify.SavedTrack
+	for _, t := range liked {
+		addedAt, err := time.Parse(time.RFC3339, t.AddedAt)
+		if err != nil {
+			continue
+		}
+		if p.onThisDay(addedAt.In(p.loc), today) {
+			tracks = append(tracks, t)
+		}
+	}
+	tracks = sortByAdded(tracks, false)
+	p.logger.Printf("Found %d songs liked on this day in previous years.", len(tracks))
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		TrackCount: len(tracks),
+		Duration:   formatDuration(totalDuration(tracks)),
+		Date:       today.Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{
+		Name:     playlistName,
+		Label:    today.Format("Jan 2"),
+		Subtitle: p.cfg.CoverSubtitle,
+		Tracks:   fullTracks(tracks),
+	})
+	// An empty day still clears yesterday's memories.
+	if err := p.writer.Replace(ctx, playlistID, savedTrackIDs(tracks)); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	return nil
+}
+
+// onThisDay reports whether addedAt is within the window around an anniversary of today
+// in a previous year. Neighbouring years are checked so windows can span New Year.
+func (p *onThisDayBuilder) onThisDay(addedAt, today time.Time) bool {
+	day := time.Date(addedAt.Year(), addedAt.Month(), addedAt.Day(), 0, 0, 0, 0, p.loc)
+	for year := addedAt.Year() - 1; year <= addedAt.Year()+1 && year < today.Year(); year++ {
+		anniversary := time.Date(year, today.Month(), today.Day(), 0, 0, 0, 0, p.loc)
+		days := int(day.Sub(anniversary).Round(24*time.Hour) / (24 * time.Hour))
+		if days >= -p.cfg.WindowDays && days <= p.cfg.WindowDays {
+			return true
+		}
+	}
+	return false
+}
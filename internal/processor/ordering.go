@@ -0,0 +1,249 @@
+package processor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"spotify/internal/config"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// validateOrdering checks that the ordering strategy and artist cap are known.
+func validateOrdering(cfg config.Ordering) error {
+	switch cfg.Strategy {
+	case "", "added", "diverse", "harmonic", "unordered":
+	default:
+		return fmt.Errorf("unknown track order '%s' (available: added, diverse, harmonic, unordered)", cfg.Strategy)
+	}
+	switch cfg.ArtistCapKeep {
+	case "", "earliest", "popular", "random":
+	default:
+		return fmt.Errorf("unknown artist_cap_keep '%s' (available: earliest, popular, random)", cfg.ArtistCapKeep)
+	}
+	if cfg.MaxPerArtist < 0 {
+		return fmt.Errorf("max_per_artist can't be negative")
+	}
+	return nil
+}
+
+// capPerArtist keeps at most cfg.MaxPerArtist tracks of each primary artist, chosen by
+// cfg.ArtistCapKeep, and returns them in their original order. seed makes the "random"
+// choice differ between playlists while staying the same across runs.
+func capPerArtist(tracks []spotify.SavedTrack, cfg config.Ordering, seed string) []spotify.SavedTrack {
+	if cfg.MaxPerArtist <= 0 {
+		return tracks
+	}
+	// rank orders candidates for keeping, best first.
+	rank := make([]int, len(tracks))
+	for i := range rank {
+		rank[i] = i
+	}
+	switch cfg.ArtistCapKeep {
+	case "popular":
+		slices.SortStableFunc(rank, func(a, b int) int { return int(tracks[b].Popularity) - int(tracks[a].Popularity) })
+	case "random":
+		keys := make([]uint64, len(tracks))
+		for i, t := range tracks {
+			h := fnv.New64a()
+			h.Write([]byte(seed + "|" + string(t.ID)))
+			keys[i] = h.Sum64()
+		}
+		slices.SortFunc(rank, func(a, b int) int { return cmp.Compare(keys[a], keys[b]) })
+	default:
+		slices.SortStableFunc(rank, func(a, b int) int { return strings.Compare(tracks[a].AddedAt, tracks[b].AddedAt) })
+	}
+
+	kept := make([]bool, len(tracks))
+	perArtist := make(map[spotify.ID]int)
+	for _, i := range rank {
+		var artist spotify.ID
+		if len(tracks[i].Artists) > 0 {
+			artist = tracks[i].Artists[0].ID
+		}
+		if artist != "" && perArtist[artist] >= cfg.MaxPerArtist {
+			continue
+		}
+		perArtist[artist]++
+		kept[i] = true
+	}
+	capped := make([]spotify.SavedTrack, 0, len(tracks))
+	for i, t := range tracks {
+		if kept[i] {
+			capped = append(capped, t)
+		}
+	}
+	return capped
+}
+
+// sortByAdded orders tracks by when they were liked, oldest first unless descending.
+// Tracks with unparsable dates keep their relative order at the end.
+func sortByAdded(tracks []spotify.SavedTrack, descending bool) []spotify.SavedTrack {
+	addedAt := make(map[spotify.ID]time.Time, len(tracks))
+	for _, t := range tracks {
+		if at, err := time.Parse(time.RFC3339, t.AddedAt); err == nil {
+			addedAt[t.ID] = at
+		}
+	}
+	sorted := slices.Clone(tracks)
+	slices.SortStableFunc(sorted, func(a, b spotify.SavedTrack) int {
+		atA, okA := addedAt[a.ID]
+		atB, okB := addedAt[b.ID]
+		switch {
+		case !okA || !okB:
+			return boolCmp(okB, okA)
+		case descending:
+			return atB.Compare(atA)
+		default:
+			return atA.Compare(atB)
+		}
+	})
+	return sorted
+}
+
+// boolCmp orders false before true.
+func boolCmp(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// fetchArtistGenres returns the genres of each artist, looked up 50 at a time. Artists
+// without genres are left out.
+func fetchArtistGenres(ctx context.Context, client SpotifyClient, artistIDs []spotify.ID) (map[spotify.ID][]string, error) {
+	genres := make(map[spotify.ID][]string, len(artistIDs))
+	err := inBatches(artistIDs, 50, func(batch []spotify.ID) error {
+		artists, err := client.GetArtists(ctx, batch...)
+		if err != nil {
+			return err
+		}
+		for _, a := range artists {
+			if a != nil && len(a.Genres) > 0 {
+				genres[a.ID] = a.Genres
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch artist genres: %w", err)
+	}
+	return genres, nil
+}
+
+// diverseOrder returns a permutation of tracks in which no artist repeats within
+// cfg.ArtistSpacing tracks and no genre within cfg.GenreSpacing, as far as the mix
+// allows. Each slot takes the earliest remaining track that satisfies both rules, then
+// one that satisfies the artist rule, and otherwise the track whose artists were heard
+// longest ago, so the original order is kept wherever it's already diverse.
+func diverseOrder(tracks []spotify.FullTrack, genres map[spotify.ID][]string, cfg config.Ordering) []int {
+	lastArtist := make(map[spotify.ID]int)
+	lastGenre := make(map[string]int)
+	// gap returns how many tracks were placed since key was last seen at pos.
+	gap := func(last map[spotify.ID]int, id spotify.ID, pos int) int {
+		if at, ok := last[id]; ok {
+			return pos - at - 1
+		}
+		return len(tracks)
+	}
+	artistGap := func(t spotify.FullTrack, pos int) int {
+		smallest := len(tracks)
+		for _, a := range t.Artists {
+			smallest = min(smallest, gap(lastArtist, a.ID, pos))
+		}
+		return smallest
+	}
+	genreOK := func(t spotify.FullTrack, pos int) bool {
+		genre, ok := primaryGenre(t, genres)
+		if !ok {
+			return true
+		}
+		at, seen := lastGenre[genre]
+		return !seen || pos-at-1 >= cfg.GenreSpacing
+	}
+
+	remaining := make([]int, len(tracks))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	order := make([]int, 0, len(tracks))
+	for pos := 0; len(remaining) > 0; pos++ {
+		pick := -1
+		for i, idx := range remaining {
+			if artistGap(tracks[idx], pos) >= cfg.ArtistSpacing && genreOK(tracks[idx], pos) {
+				pick = i
+				break
+			}
+		}
+		if pick < 0 {
+			for i, idx := range remaining {
+				if artistGap(tracks[idx], pos) >= cfg.ArtistSpacing {
+					pick = i
+					break
+				}
+			}
+		}
+		if pick < 0 {
+			best := -1
+			for i, idx := range remaining {
+				if g := artistGap(tracks[idx], pos); g > best {
+					pick, best = i, g
+				}
+			}
+		}
+
+		idx := remaining[pick]
+		remaining = slices.Delete(remaining, pick, pick+1)
+		order = append(order, idx)
+		for _, a := range tracks[idx].Artists {
+			lastArtist[a.ID] = pos
+		}
+		if genre, ok := primaryGenre(tracks[idx], genres); ok {
+			lastGenre[genre] = pos
+		}
+	}
+	return order
+}
+
+// primaryGenre returns the first genre of the track's primary artist.
+func primaryGenre(t spotify.FullTrack, genres map[spotify.ID][]string) (string, bool) {
+	if len(t.Artists) == 0 || len(genres[t.Artists[0].ID]) == 0 {
+		return "", false
+	}
+	return genres[t.Artists[0].ID][0], true
+}
+
+// diversify reorders tracks so artists and genres are spaced out.
+func diversify(tracks []spotify.SavedTrack, genres map[spotify.ID][]string, cfg config.Ordering) []spotify.SavedTrack {
+	order := diverseOrder(fullTracks(tracks), genres, cfg)
+	ordered := make([]spotify.SavedTrack, len(order))
+	for i, idx := range order {
+		ordered[i] = tracks[idx]
+	}
+	return ordered
+}
+
+// uniqueArtistIDs returns the IDs of the primary artists of tracks, in first-seen order.
+func uniqueArtistIDs(tracks []spotify.FullTrack) []spotify.ID {
+	seen := make(map[spotify.ID]struct{})
+	var ids []spotify.ID
+	for _, t := range tracks {
+		if len(t.Artists) == 0 {
+			continue
+		}
+		id := t.Artists[0].ID
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+)
+
+// yearPeriods assigns moments to yearly periods in a time zone, optionally with a custom
+// start of the year such as September 1st for academic years, or to the halves or
+// quarters of calendar years.
+type yearPeriods struct {
+	loc   *time.Location
+	month time.Month
+	day   int
+	// parts is how many periods a year is split into: 1, 2 or 4.
+	parts int
+}
+
+// period is a year, or a half or quarter of one.
+type period struct {
+	Year int
+	// Part counts the halves or quarters from 1, and is 0 for whole years.
+	Part int
+}
+
+// comparePeriods orders periods chronologically.
+func comparePeriods(a, b period) int {
+	if a.Year != b.Year {
+		return a.Year - b.Year
+	}
+	return a.Part - b.Part
+}
+
+// newYearPeriods parses an IANA time zone ("" means UTC, "Local" the machine's zone) and
+// a "MM-DD" start of the year ("" means January 1st).
+func newYearPeriods(timezone, start string) (yearPeriods, error) {
+	p := yearPeriods{loc: time.UTC, month: time.January, day: 1, parts: 1}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return p, fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+		}
+		p.loc = loc
+	}
+	if start != "" {
+		// Parse against a leap year so "02-29" is accepted.
+		t, err := time.Parse("2006-01-02", "2000-"+start)
+		if err != nil {
+			return p, fmt.Errorf("invalid year start '%s', expected MM-DD", start)
+		}
+		p.month, p.day = t.Month(), t.Day()
+	}
+	return p, nil
+}
+
+// Year returns the period t falls in, named after the year it starts in.
+func (p yearPeriods) Year(t time.Time) int {
+	t = t.In(p.loc)
+	if t.Month() < p.month || (t.Month() == p.month && t.Day() < p.day) {
+		return t.Year() - 1
+	}
+	return t.Year()
+}
+
+// Label names a year: "2023" for calendar years and "2023/24" for custom ones.
+func (p yearPeriods) Label(year int) string {
+	if p.month == time.January && p.day == 1 {
+		return fmt.Sprint(year)
+	}
+	return fmt.Sprintf("%d/%02d", year, (year+1)%100)
+}
+
+// split divides calendar years into halves or quarters, for granularity "half" or
+// "quarter"; "" and "year" keep whole years.
+func (p *yearPeriods) split(granularity string) error {
+	switch granularity {
+	case "", "year":
+		p.parts = 1
+	case "half":
+		p.parts = 2
+	case "quarter":
+		p.parts = 4
+	default:
+		return fmt.Errorf("unknown granularity '%s' (available: year, half, quarter)", granularity)
+	}
+	if p.parts > 1 && (p.month != time.January || p.day != 1) {
+		return fmt.Errorf("granularity '%s' splits calendar years and can't be combined with a year start", granularity)
+	}
+	return nil
+}
+
+// Period returns the period t falls in.
+func (p yearPeriods) Period(t time.Time) period {
+	if p.parts == 1 {
+		return period{Year: p.Year(t)}
+	}
+	t = t.In(p.loc)
+	return period{Year: t.Year(), Part: (int(t.Month())-1)*p.parts/12 + 1}
+}
+
+// PeriodLabel names a period: like Label for years, and "2024 H1" or "2024 Q3" for
+// halves and quarters.
+func (p yearPeriods) PeriodLabel(pd period) string {
+	switch {
+	case pd.Part == 0:
+		return p.Label(pd.Year)
+	case p.parts == 2:
+		return fmt.Sprintf("%d H%d", pd.Year, pd.Part)
+	default:
+		return fmt.Sprintf("%d Q%d", pd.Year, pd.Part)
+	}
+}
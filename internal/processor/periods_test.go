@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYearPeriods(t *testing.T) {
+	tests := []struct {
+		name, timezone, start string
+		at                    string
+		year                  int
+		label                 string
+	}{
+		{"utc new year", "", "", "2024-01-01T00:00:00Z", 2024, "2024"},
+		{"utc new year's eve", "", "", "2023-12-31T23:59:59Z", 2023, "2023"},
+		// Sydney is on summer time (UTC+11) over its new year.
+		{"ahead of utc", "Australia/Sydney", "", "2023-12-31T13:00:00Z", 2024, "2024"},
+		{"just before the new year ahead of utc", "Australia/Sydney", "", "2023-12-31T12:59:59Z", 2023, "2023"},
+		{"behind utc", "America/New_York", "", "2024-01-01T04:59:59Z", 2023, "2023"},
+		// Rome is on summer time (UTC+2) on August 31st.
+		{"academic year", "Europe/Rome", "09-01", "2024-08-31T22:00:00Z", 2024, "2024/25"},
+		{"end of academic year", "Europe/Rome", "09-01", "2024-08-31T21:59:59Z", 2023, "2023/24"},
+		{"academic year across the century", "", "09-01", "1999-12-31T00:00:00Z", 1999, "1999/00"},
+		{"leap day start in a leap year", "", "02-29", "2024-02-29T00:00:00Z", 2024, "2024/25"},
+		{"leap day start in a common year", "", "02-29", "2023-02-28T23:59:59Z", 2022, "2022/23"},
+		{"leap day start passed in a common year", "", "02-29", "2023-03-01T00:00:00Z", 2023, "2023/24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newYearPeriods(tt.timezone, tt.start)
+			if err != nil {
+				t.Skipf("newYearPeriods: %v", err)
+			}
+			at, err := time.Parse(time.RFC3339, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			year := p.Year(at)
+			if year != tt.year {
+				t.Errorf("Year(%s) = %d, want %d", tt.at, year, tt.year)
+			}
+			if label := p.Label(year); label != tt.label {
+				t.Errorf("Label(%d) = %q, want %q", year, label, tt.label)
+			}
+		})
+	}
+}
+
+func TestYearPeriodsErrors(t *testing.T) {
+	tests := []struct {
+		timezone, start, want string
+	}{
+		{"Mars/Olympus_Mons", "", "invalid timezone 'Mars/Olympus_Mons'"},
+		{"", "13-01", "invalid year start '13-01', expected MM-DD"},
+		{"", "02-30", "invalid year start '02-30'"},
+		{"", "9-1", "invalid year start '9-1'"},
+	}
+	for _, tt := range tests {
+		_, err := newYearPeriods(tt.timezone, tt.start)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("newYearPeriods(%q, %q) error = %v, want it to contain %q", tt.timezone, tt.start, err, tt.want)
+		}
+	}
+}
+
+func TestPeriodSplit(t *testing.T) {
+	tests := []struct {
+		name, timezone, granularity string
+		at                          string
+		want                        period
+		label                       string
+	}{
+		{"year", "", "year", "2024-06-30T12:00:00Z", period{Year: 2024}, "2024"},
+		{"first half", "", "half", "2024-06-30T23:59:59Z", period{2024, 1}, "2024 H1"},
+		{"second half", "", "half", "2024-07-01T00:00:00Z", period{2024, 2}, "2024 H2"},
+		{"first quarter", "", "quarter", "2024-03-31T23:59:59Z", period{2024, 1}, "2024 Q1"},
+		{"last quarter", "", "quarter", "2024-12-31T23:59:59Z", period{2024, 4}, "2024 Q4"},
+		// Rome moves to summer time (UTC+2) on March 31st 2024, the last day of Q1.
+		{"quarter after the clocks go forward", "Europe/Rome", "quarter", "2024-03-31T22:00:00Z", period{2024, 2}, "2024 Q2"},
+		{"quarter before the clocks go forward", "Europe/Rome", "quarter", "2024-03-31T21:59:59Z", period{2024, 1}, "2024 Q1"},
+		{"half in summer time", "Europe/Rome", "half", "2024-06-30T22:00:00Z", period{2024, 2}, "2024 H2"},
+		// Rome is back on UTC+1 for the new year.
+		{"new year's quarter in winter time", "Europe/Rome", "quarter", "2024-12-31T23:00:00Z", period{2025, 1}, "2025 Q1"},
+		{"last quarter in winter time", "Europe/Rome", "quarter", "2024-12-31T22:59:59Z", period{2024, 4}, "2024 Q4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newYearPeriods(tt.timezone, "")
+			if err != nil {
+				t.Skipf("newYearPeriods: %v", err)
+			}
+			if err := p.split(tt.granularity); err != nil {
+				t.Fatal(err)
+			}
+			at, err := time.Parse(time.RFC3339, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.Period(at)
+			if got != tt.want {
+				t.Errorf("Period(%s) = %+v, want %+v", tt.at, got, tt.want)
+			}
+			if label := p.PeriodLabel(got); label != tt.label {
+				t.Errorf("PeriodLabel(%+v) = %q, want %q", got, label, tt.label)
+			}
+		})
+	}
+}
+
+func TestPeriodSplitErrors(t *testing.T) {
+	p, err := newYearPeriods("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.split("month"); err == nil || !strings.Contains(err.Error(), "unknown granularity 'month'") {
+		t.Errorf("split(month) error = %v", err)
+	}
+	if p, err = newYearPeriods("", "09-01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.split("quarter"); err == nil || !strings.Contains(err.Error(), "can't be combined with a year start") {
+		t.Errorf("split(quarter) with a year start error = %v", err)
+	}
+}
+
+func TestComparePeriods(t *testing.T) {
+	ordered := []period{{2023, 4}, {2024, 1}, {2024, 2}, {2025, 0}}
+	for i := 1; i < len(ordered); i++ {
+		if comparePeriods(ordered[i-1], ordered[i]) >= 0 || comparePeriods(ordered[i], ordered[i-1]) <= 0 {
+			t.Errorf("%+v and %+v are out of order", ordered[i-1], ordered[i])
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// startPlayback plays a playlist on the device whose name contains device, ignoring case,
+// or when device is empty on the active device or else the first one available. Playback
+// moves to the chosen device first if another one is active. Spotify only allows this
+// for Premium accounts, and only to devices with Spotify open.
+func startPlayback(ctx context.Context, client SpotifyClient, logger *log.Logger, device string, playlistID spotify.ID, name string) error {
+	devices, err := client.PlayerDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	target, err := chooseDevice(devices, device)
+	if err != nil {
+		return err
+	}
+	if !target.Active {
+		if err := client.TransferPlayback(ctx, target.ID, false); err != nil {
+			return fmt.Errorf("failed to move playback to '%s': %w", target.Name, err)
+		}
+	}
+	uri := spotify.URI("spotify:playlist:" + string(playlistID))
+	if err := client.PlayOpt(ctx, &spotify.PlayOptions{DeviceID: &target.ID, PlaybackContext: &uri}); err != nil {
+		return fmt.Errorf("failed to play '%s' on '%s': %w", name, target.Name, err)
+	}
+	logger.Printf("▶️  Playing '%s' on %s.", name, target.Name)
+	return nil
+}
+
+// chooseDevice picks the device to play on.
+func chooseDevice(devices []spotify.PlayerDevice, name string) (spotify.PlayerDevice, error) {
+	var usable []spotify.PlayerDevice
+	for _, d := range devices {
+		if !d.Restricted && d.ID != "" {
+			usable = append(usable, d)
+		}
+	}
+	if len(usable) == 0 {
+		return spotify.PlayerDevice{}, fmt.Errorf("no Spotify device is available; open Spotify on the device to play on")
+	}
+	if name != "" {
+		names := make([]string, len(usable))
+		for i, d := range usable {
+			if strings.Contains(strings.ToLower(d.Name), strings.ToLower(name)) {
+				return d, nil
+			}
+			names[i] = d.Name
+		}
+		return spotify.PlayerDevice{}, fmt.Errorf("no device called '%s' (available: %s)", name, strings.Join(names, ", "))
+	}
+	for _, d := range usable {
+		if d.Active {
+			return d, nil
+		}
+	}
+	return usable[0], nil
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type playlistPruner struct {
+	client SpotifyClient
+	logger *log.Logger
+	writer *playlistWriter
+	ref    string
+	maxAge time.Duration
+}
+
+// NewPlaylistPruner returns a Processor that removes the tracks added to a playlist more
+// than maxAge ago, keeping it fresh. ref is the playlist's URL, URI or ID, or the name of
+// one of the user's own playlists.
+func NewPlaylistPruner(client SpotifyClient, logger *log.Logger, ref string, maxAge time.Duration) (Processor, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("name the playlist to prune")
+	}
+	if maxAge <= 0 {
+		return nil, fmt.Errorf("the age must be positive, got %s", maxAge)
+	}
+	return &playlistPruner{client: client, logger: logger, writer: newPlaylistWriter(client, logger), ref: ref, maxAge: maxAge}, nil
+}
+
+// Run removes the expired tracks. A track added more than once stays while any of its
+// additions is recent, since removals take every copy. Entries without an addition date,
+// which Spotify omits on very old playlists, are kept.
+func (p *playlistPruner) Run(ctx context.Context) error {
+	playlistID, name, err := resolvePlaylist(ctx, p.client, p.writer, p.ref)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-p.maxAge)
+	latest := make(map[spotify.ID]time.Time)
+	var order []spotify.ID
+	offset := 0
+	for {
+		page, err := p.client.GetPlaylistTracks(ctx, playlistID, spotify.Limit(100), spotify.Offset(offset))
+		if err != nil {
+			return fmt.Errorf("failed to read playlist '%s': %w", name, err)
+		}
+		if len(page.Tracks) == 0 {
+			break
+		}
+		for _, item := range page.Tracks {
+			id := item.Track.ID
+			if id == "" {
+				continue
+			}
+			addedAt, err := time.Parse(time.RFC3339, item.AddedAt)
+			if err != nil {
+				// Unknown dates count as recent.
+				addedAt = time.Now()
+			}
+			if seen, ok := latest[id]; !ok {
+				order = append(order, id)
+				latest[id] = addedAt
+			} else if addedAt.After(seen) {
+				latest[id] = addedAt
+			}
+		}
+		offset += len(page.Tracks)
+	}
+
+	var expired []spotify.ID
+	for _, id := range order {
+		if latest[id].Before(cutoff) {
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) == 0 {
+		p.logger.Printf("✅ Nothing in '%s' was added before %s.", name, cutoff.Format(time.DateOnly))
+		return nil
+	}
+	err = inBatches(expired, 100, func(batch []spotify.ID) error {
+		_, err := p.client.RemoveTracksFromPlaylist(ctx, playlistID, batch...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove expired tracks from '%s': %w", name, err)
+	}
+	p.logger.Printf("✅ Removed %d tracks added to '%s' before %s; %d remain.", len(expired), name, cutoff.Format(time.DateOnly), len(order)-len(expired))
+	return nil
+}
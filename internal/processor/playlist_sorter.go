@@ -2,234 +2,1049 @@ package processor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
-	"sort"
+	"maps"
+	"path"
+	"slices"
+	"spotify/internal/config"
+	"spotify/internal/folders"
+	"spotify/internal/store"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/zmb3/spotify/v2"
 )
 
+// sorterCheckpoint names the sorter's checkpoint in the store.
+const sorterCheckpoint = "sort"
+
+// checkpointTTL is how long a checkpoint stays usable. Older ones describe a library that
+// has likely changed, so the run starts over.
+const checkpointTTL = 24 * time.Hour
+
+// checkpointEvery is how many fetched pages go by between saves of the store.
+const checkpointEvery = 20
+
+// restoreWindow is how close to its restore a like must be to be unarchive's, and keep
+// the song's original like date.
+const restoreWindow = time.Hour
+
 type playlistSorter struct {
-	client SpotifyClient
-	logger *log.Logger
-	imgGen ImageGenerator
+	client    SpotifyClient
+	store     *store.Store
+	logger    *log.Logger
+	imgGen    ImageGenerator
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	cfg       config.Sorter
+	opts      SorterOptions
+	periods   yearPeriods
+	// visibility is the configured access of the playlists, and visibilities its
+	// overrides by name pattern.
+	visibility   playlistAccess
+	visibilities map[string]playlistAccess
+
+	// mu guards checkpoint and mismatches while years are written in parallel.
+	mu         sync.Mutex
+	checkpoint store.Checkpoint
+	// mismatches lists the playlists that didn't hold what was written to them.
+	mismatches []string
+	// names are the names of this run's playlists by period.
+	names map[period]string
+	// changes records how this run changed each period's playlist.
+	changes map[period]periodChange
+	// sorted holds the liked songs this run wrote to each playlist.
+	sorted map[spotify.ID][]spotify.ID
+	// liked is the set of songs in Liked Songs, as opposed to archived ones.
+	liked map[spotify.ID]bool
+
+	// settings fingerprints the configuration for the library snapshot, and snapshot is
+	// the one taken at the start of this run.
+	settings string
+	snapshot string
+
+	// owned caches the user's playlists for renames, fetched when first needed.
+	ownedMu sync.Mutex
+	owned   []spotify.SimplePlaylist
 }
 
-func NewPlaylistSorter(client SpotifyClient, logger *log.Logger, imgGen ImageGenerator) *playlistSorter {
-	return &playlistSorter{
-		client: client,
-		logger: logger,
-		imgGen: imgGen,
+// periodChange is how a run changed the playlist of a period, for the summary.
+type periodChange struct {
+	tracks         int
+	duration       time.Duration
+	added, removed int
+	// skipped is set for playlists an interrupted run already wrote.
+	skipped bool
+}
+
+// SorterOptions are the command-line switches of the sorter.
+type SorterOptions struct {
+	// Resume picks up a recent checkpoint instead of starting over.
+	Resume bool
+	// YesHuge confirms a first run on a library larger than the configured threshold.
+	YesHuge bool
+	// Years limits the run to the playlists of these years, and Since to the playlists
+	// from that year on, so scheduled runs only touch recent playlists. Empty values
+	// mean every year.
+	Years []int
+	Since int
+	// Play starts the newest playlist after the run, on the device whose name contains
+	// Device or, without one, on the active device.
+	Play   bool
+	Device string
+	// Ask asks a yes/no question for the "prompt" conflict policy. Without it, or without
+	// an answer, hand edits are overwritten.
+	Ask func(question string) bool
+	// Archive unlikes the songs of past periods once their playlist holds them. Archived
+	// songs stay in their playlists on later runs.
+	Archive bool
+	// PruneEmpty deletes the playlists of periods without liked songs anymore.
+	PruneEmpty bool
+	// Full runs even when the library and settings are those of the last completed run.
+	Full bool
+}
+
+// NewPlaylistSorter returns a sorter configured by cfg and the shared playlist settings.
+// Progress is checkpointed in st. It fails if the configured name or description
+// templates don't parse.
+func NewPlaylistSorter(client SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfg config.Sorter, shared config.Playlists, opts SorterOptions) (*playlistSorter, error) {
+	nameTemplate, descriptionTemplate := cfg.NameTemplate, cfg.DescriptionTemplate
+	switch cfg.GroupBy {
+	case "", "liked":
+	case "release":
+		nameTemplate, descriptionTemplate = cfg.ReleaseNameTemplate, cfg.ReleaseDescriptionTemplate
+		cfg.CoverSubtitle = cfg.ReleaseCoverSubtitle
+	default:
+		return nil, fmt.Errorf("unknown group_by '%s' (available: liked, release)", cfg.GroupBy)
+	}
+	templates, err := newPlaylistTemplates(nameTemplate, descriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateOrdering(cfg.Order); err != nil {
+		return nil, err
+	}
+	visibility, err := parseVisibility(cfg.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	visibilities := make(map[string]playlistAccess, len(cfg.Visibilities))
+	for pattern, v := range cfg.Visibilities {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid visibilities pattern '%s': %w", pattern, err)
+		}
+		if visibilities[pattern], err = parseVisibility(v); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Archive && cfg.GroupBy == "release" {
+		return nil, fmt.Errorf("archiving needs like dates; it can't be combined with group_by: release")
+	}
+	switch cfg.Conflicts {
+	case "", "overwrite", "preserve-manual-additions", "prompt":
+	default:
+		return nil, fmt.Errorf("unknown conflicts policy '%s' (available: overwrite, preserve-manual-additions, prompt)", cfg.Conflicts)
+	}
+	periods, err := newYearPeriods(cfg.Timezone, cfg.YearStart)
+	if err != nil {
+		return nil, err
+	}
+	if err := periods.split(cfg.Granularity); err != nil {
+		return nil, err
+	}
+	if periods.parts > 1 {
+		if cfg.GroupBy == "release" {
+			return nil, fmt.Errorf("granularity '%s' needs like dates; it can't be combined with group_by: release", cfg.Granularity)
+		}
+		// Two periods of a year must not write to the same playlist.
+		first, _, err := templates.Render(PlaylistTemplateData{Year: 2000, Period: periods.PeriodLabel(period{2000, 1})})
+		if err != nil {
+			return nil, err
+		}
+		second, _, err := templates.Render(PlaylistTemplateData{Year: 2000, Period: periods.PeriodLabel(period{2000, 2})})
+		if err != nil {
+			return nil, err
+		}
+		if first == second {
+			return nil, fmt.Errorf("with granularity '%s', name_template must tell the periods of a year apart, e.g. with {{.Period}}", cfg.Granularity)
+		}
+	}
+	if cfg.AppendParallelism < 0 {
+		return nil, fmt.Errorf("append_parallelism must not be negative, got %d", cfg.AppendParallelism)
 	}
+	if cfg.AppendParallelism > 1 && cfg.Order.Strategy != "unordered" {
+		return nil, fmt.Errorf("append_parallelism above 1 writes songs out of order, so it needs order.strategy 'unordered'")
+	}
+	writer := newPlaylistWriter(client, logger)
+	writer.appenders = cfg.AppendParallelism
+	return &playlistSorter{
+		client:       client,
+		store:        st,
+		logger:       logger,
+		imgGen:       imgGen,
+		writer:       writer,
+		covers:       newCoverUploader(client, imgGen, st, logger),
+		templates:    templates,
+		cfg:          cfg,
+		opts:         opts,
+		periods:      periods,
+		visibility:   visibility,
+		visibilities: visibilities,
+		settings:     fmt.Sprintf("%+v|%+v", cfg, shared),
+	}, nil
 }
 
-// Run fetches liked songs, groups them by the year they were added, and creates or updates
-// a playlist for each year. If a playlist for a year already exists, it will be cleared
-// and repopulated with the correct tracks.
+// Run fetches liked songs, groups them by the year (or half or quarter) they were added,
+// and creates or updates a playlist for each. If a playlist for a period already exists,
+// its contents are atomically replaced with the correct tracks.
 func (p *playlistSorter) Run(ctx context.Context) error {
 	p.logger.Println("Starting liked songs sorter...")
-	allTracks, err := p.fetchAllLikedTracks(ctx)
+	p.loadCheckpoint()
+	p.mismatches = nil
+	p.names = make(map[period]string)
+	p.changes = make(map[period]periodChange)
+	p.sorted = make(map[spotify.ID][]spotify.ID)
+	p.owned = nil
+	if unchanged, err := p.takeSnapshot(ctx); err != nil {
+		return err
+	} else if unchanged {
+		p.logger.Println("Liked songs and settings haven't changed since the last run. Nothing to do.")
+		return ErrNothingToDo
+	}
+	if err := p.checkLibrarySize(ctx); err != nil {
+		return err
+	}
+	liked, likedIDs, err := p.fetchLikedTracks(ctx)
 	if err != nil {
+		p.hintResume(ctx)
 		return fmt.Errorf("failed to fetch liked tracks: %w", err)
 	}
-	if len(allTracks) == 0 {
+	if len(likedIDs) == 0 {
 		p.logger.Println("No liked tracks found. Nothing to do.")
-		return nil
+		p.store.ClearCheckpoint(sorterCheckpoint)
+		return ErrNothingToDo
+	}
+	tracksByPeriod := p.groupTracksByPeriod(p.withArchive(liked, likedIDs))
+	for pd := range tracksByPeriod {
+		if !p.wantsYear(pd.Year) {
+			delete(tracksByPeriod, pd)
+		}
+	}
+	if len(tracksByPeriod) == 0 {
+		p.logger.Println("No liked songs in the selected years. Nothing to do.")
+		p.store.ClearCheckpoint(sorterCheckpoint)
+		return ErrNothingToDo
 	}
-	tracksByYear := p.groupTracksByYear(allTracks)
 	user, err := p.client.CurrentUser(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get current user: %w", err)
 	}
-	years := make([]int, 0, len(tracksByYear))
-	for year := range tracksByYear {
-		years = append(years, year)
+	periods := slices.SortedFunc(maps.Keys(tracksByPeriod), comparePeriods)
+	labels := make([]string, len(periods))
+	for i, pd := range periods {
+		labels[i] = p.label(pd)
+	}
+	p.logger.Printf("Found songs spanning %d periods: %v", len(periods), labels)
+	var allTracks []spotify.SavedTrack
+	for _, pd := range periods {
+		allTracks = append(allTracks, tracksByPeriod[pd]...)
 	}
-	sort.Ints(years)
-	p.logger.Printf("Found songs spanning %d years: %v", len(years), years)
-
-	for _, year := range years {
-		playlistName := fmt.Sprintf("Liked Songs (%d)", year)
-		trackIDs := tracksByYear[year]
-		p.logger.Printf("--- Processing year %d (%d tracks) ---", year, len(trackIDs))
-
-		var playlistID spotify.ID
 
-		existingPlaylist, err := p.findExistingPlaylist(ctx, user.ID, playlistName)
-		if err != nil {
+	var genres map[spotify.ID][]string
+	if p.cfg.Order.Strategy == "diverse" && p.cfg.Order.GenreSpacing > 0 {
+		if genres, err = fetchArtistGenres(ctx, p.client, uniqueArtistIDs(fullTracks(allTracks))); err != nil {
+			return err
+		}
+	}
+	var features map[spotify.ID]*spotify.AudioFeatures
+	if p.cfg.Order.Strategy == "harmonic" {
+		if features, err = fetchAudioFeatures(ctx, p.client, savedTrackIDs(allTracks)); err != nil {
 			return err
 		}
+	}
 
-		if existingPlaylist != nil {
-			playlistID = existingPlaylist.ID
-			p.logger.Printf("Found existing playlist: '%s'. Clearing it now.", existingPlaylist.Name)
+	// Covers render in the background while the tracks are written.
+	defer p.covers.Wait()
+	today := time.Now().Format(time.DateOnly)
+	// A failing period doesn't stop the others; failures are reported together at the end.
+	err = forEachPlaylistParallel(ctx, p.logger, periods, p.cfg.Parallelism, p.describe, func(pd period) error {
+		// Diverse ordering starts from the timeline and only moves tracks it must.
+		tracks := sortByAdded(capPerArtist(tracksByPeriod[pd], p.cfg.Order, p.label(pd)), p.cfg.Order.Descending)
+		switch p.cfg.Order.Strategy {
+		case "diverse":
+			tracks = diversify(tracks, genres, p.cfg.Order)
+		case "harmonic":
+			tracks = harmonize(tracks, features)
+		}
+		return p.syncPeriod(ctx, user.ID, pd, tracks, today)
+	})
+	p.removeMoved(ctx)
+	p.reportChanges(periods)
+	p.reportMismatches()
+	if err != nil {
+		p.hintResume(ctx)
+		return err
+	}
+	p.store.ClearCheckpoint(sorterCheckpoint)
+	if p.opts.PruneEmpty {
+		p.pruneEmpty(ctx, user.ID, tracksByPeriod)
+	}
+	if p.fetchCutoff() == 0 {
+		// Only a full run counts for the first-run check on huge libraries.
+		p.store.SetLastRun(sorterCheckpoint, time.Now())
+	}
+	p.store.SetSnapshot(sorterCheckpoint, p.snapshot)
+	if p.opts.Play {
+		return p.playNewest(ctx, user.ID, periods[len(periods)-1])
+	}
+	return nil
+}
 
-			tracksToRemove, err := p.fetchAllPlaylistTracks(ctx, playlistID)
-			if err != nil {
-				return fmt.Errorf("could not fetch tracks from existing playlist '%s': %w", playlistName, err)
-			}
-			if err := p.removeTracksInBatches(ctx, playlistID, tracksToRemove); err != nil {
-				return fmt.Errorf("could not clear existing playlist '%s': %w", playlistName, err)
-			}
-		} else {
-			description := fmt.Sprintf("All songs I liked that were added in %d.", year)
-			newPlaylist, err := p.client.CreatePlaylistForUser(ctx, user.ID, playlistName, description, false, false)
-			if err != nil {
-				return fmt.Errorf("failed to create playlist for year %d: %w", year, err)
+// playNewest starts playback of the playlist written for pd.
+func (p *playlistSorter) playNewest(ctx context.Context, userID string, pd period) error {
+	name := p.names[pd]
+	playlist, err := p.writer.Find(ctx, userID, name)
+	if err != nil {
+		return err
+	}
+	if playlist == nil {
+		return fmt.Errorf("could not find '%s' to play", name)
+	}
+	return startPlayback(ctx, p.client, p.logger, p.opts.Device, playlist.ID, name)
+}
+
+// takeSnapshot fingerprints the newest liked songs, their total and the settings of this
+// run, and reports whether they're those of the last completed run. Any change to the
+// library adds a song at the top or changes the total, so one page of it is enough.
+// Resumed runs and runs that play a playlist afterwards aren't skipped.
+func (p *playlistSorter) takeSnapshot(ctx context.Context) (bool, error) {
+	// A cached page would make a changed library look unchanged.
+	page, err := p.client.CurrentUsersTracks(WithFreshReads(ctx), spotify.Limit(50), spotify.Offset(0))
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	h := sha256.New()
+	// The current period is part of it, so playlists are rewritten when a year ends.
+	fmt.Fprintf(h, "%s|%v|%d|%v|%v|%s|%d\n", p.settings, p.opts.Years, p.opts.Since, p.opts.Archive, p.opts.PruneEmpty, p.label(p.periods.Period(time.Now())), page.Total)
+	for _, t := range page.Tracks {
+		fmt.Fprintf(h, "%s %s\n", t.ID, t.AddedAt)
+	}
+	p.snapshot = hex.EncodeToString(h.Sum(nil))
+	if !p.opts.Full && !p.opts.Resume && !p.opts.Play && p.store.Snapshot(sorterCheckpoint) == p.snapshot {
+		return true, nil
+	}
+	// Forgotten until this run completes, so a failed run is never skipped over.
+	p.store.SetSnapshot(sorterCheckpoint, "")
+	return false, nil
+}
+
+// checkLibrarySize refuses a first run on a huge library unless it was confirmed, since
+// it can take hours and risks rate limiting. Resumed and repeat runs aren't checked.
+func (p *playlistSorter) checkLibrarySize(ctx context.Context) error {
+	if p.cfg.HugeLibrary <= 0 || p.opts.YesHuge || p.checkpoint.Offset > 0 || p.fetchCutoff() > 0 {
+		return nil
+	}
+	if _, ok := p.store.LastRun(sorterCheckpoint); ok {
+		return nil
+	}
+	page, err := p.client.CurrentUsersTracks(ctx, spotify.Limit(1))
+	if err != nil {
+		return fmt.Errorf("failed to count liked tracks: %w", err)
+	}
+	total := int(page.Total)
+	if total < p.cfg.HugeLibrary {
+		return nil
+	}
+	// Assume a playlist per year over a typical account lifetime.
+	estimate := estimateSort(total, 10)
+	p.logger.Printf("🚨 This is the first run on a library of %d liked songs: %s.", total, estimate)
+	p.logger.Println("   Runs are checkpointed, so you can stop at any time and continue with `sort --resume`,")
+	p.logger.Println("   e.g. a chunk per day from a scheduled daemon job with a timeout.")
+	return fmt.Errorf("library has %d liked songs (over sorter.huge_library = %d); re-run with --yes-huge to proceed", total, p.cfg.HugeLibrary)
+}
+
+// hintResume saves the checkpoint of a run that ctx ended early and tells how to continue
+// it.
+func (p *playlistSorter) hintResume(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	p.saveCheckpoint(true)
+	p.logger.Println("🛑 Progress is saved: run `sort --resume` to continue where this run stopped.")
+}
+
+// loadCheckpoint picks up the previous run's progress when resuming, and otherwise
+// starts a fresh checkpoint.
+func (p *playlistSorter) loadCheckpoint() {
+	p.checkpoint = store.Checkpoint{}
+	if !p.opts.Resume {
+		p.store.ClearCheckpoint(sorterCheckpoint)
+		return
+	}
+	cp, ok := p.store.Checkpoint(sorterCheckpoint)
+	switch {
+	case !ok:
+		p.logger.Println("No checkpoint to resume from, starting from scratch.")
+	case cp.Since != p.fetchCutoff():
+		p.logger.Println("⚠️  Checkpoint was made with a different year filter, starting from scratch.")
+	case time.Since(cp.UpdatedAt) > checkpointTTL:
+		p.logger.Printf("⚠️  Checkpoint from %s is too old to trust, starting from scratch.", cp.UpdatedAt.Format(time.DateTime))
+	default:
+		p.logger.Printf("Resuming from checkpoint of %s: %d liked songs fetched, %d playlists done.", cp.UpdatedAt.Format(time.DateTime), len(cp.LikedIDs), len(cp.Completed))
+		p.checkpoint = cp
+	}
+}
+
+// saveCheckpoint records the current progress and, with flush, writes the store to disk
+// so it survives the process being killed.
+func (p *playlistSorter) saveCheckpoint(flush bool) {
+	p.updateCheckpoint(func(*store.Checkpoint) {}, flush)
+}
+
+// updateCheckpoint applies change to the checkpoint and saves it like saveCheckpoint.
+func (p *playlistSorter) updateCheckpoint(change func(cp *store.Checkpoint), flush bool) {
+	p.mu.Lock()
+	change(&p.checkpoint)
+	// The store keeps its own copy, so other years can go on changing ours while it's
+	// being written to disk.
+	cp := p.checkpoint
+	cp.Completed = slices.Clone(cp.Completed)
+	cp.Partial = maps.Clone(cp.Partial)
+	p.store.SetCheckpoint(sorterCheckpoint, cp)
+	p.mu.Unlock()
+	if !flush {
+		return
+	}
+	if err := p.store.Save(); err != nil {
+		p.logger.Printf("⚠️  Could not save checkpoint: %v", err)
+	}
+}
+
+// fetchLikedTracks fetches the library page by page, continuing from the checkpoint's
+// offset. Only the songs of the selected periods are kept, slimmed; of the others only the
+// IDs are, which is all withArchive needs.
+func (p *playlistSorter) fetchLikedTracks(ctx context.Context) ([]spotify.SavedTrack, []spotify.ID, error) {
+	cp := &p.checkpoint
+	if cp.FetchDone {
+		p.logger.Printf("Using the %d liked songs fetched before the interruption.", len(cp.LikedIDs))
+		return cp.Liked, cp.LikedIDs, nil
+	}
+	cp.Since = p.fetchCutoff()
+	pages := 0
+	err := pageLikedTracks(ctx, p.client, p.logger, cp.Offset, func(page []spotify.SavedTrack, next int) bool {
+		for _, item := range withoutMarkets(page) {
+			cp.LikedIDs = append(cp.LikedIDs, item.ID)
+			// Songs without a usable date are kept, so grouping reports them.
+			if pd, err := p.periodOf(item); err != nil || p.wantsYear(pd.Year) {
+				cp.Liked = append(cp.Liked, item)
 			}
-			playlistID = newPlaylist.ID
-			p.logger.Printf("✅ Created new playlist: '%s'", newPlaylist.Name)
 		}
+		cp.Offset = next
+		pages++
+		p.saveCheckpoint(pages%checkpointEvery == 0)
+		// Liked songs come newest first, so once a page reaches past the oldest wanted
+		// year the rest of the library isn't needed.
+		year, err := p.yearOf(page[len(page)-1])
+		return cp.Since == 0 || err != nil || year >= cp.Since
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// Songs liked between the interrupted run and this one shift the offsets, which can
+	// fetch a track twice.
+	cp.Liked = uniqueSavedTracks(cp.Liked)
+	slices.Sort(cp.LikedIDs)
+	cp.LikedIDs = slices.Compact(cp.LikedIDs)
+	cp.FetchDone = true
+	p.saveCheckpoint(true)
+	p.logger.Printf("Total liked songs fetched: %d, %d of them in the selected years", len(cp.LikedIDs), len(cp.Liked))
+	return cp.Liked, cp.LikedIDs, nil
+}
 
-		p.logger.Println("Generating custom cover image...")
-		imageReader, err := p.imgGen.GenerateForPlaylist(playlistName)
-		if err != nil {
-			p.logger.Printf("⚠️  Could not generate image for '%s': %v", playlistName, err)
-		} else {
-			if err := p.client.SetPlaylistImage(ctx, playlistID, imageReader); err != nil {
-				p.logger.Printf("⚠️  Could not upload cover image for '%s': %v", playlistName, err)
-			} else {
-				p.logger.Println("✅ Custom cover image uploaded.")
+// syncPeriod writes one period's playlist and starts its cover, skipping periods the
+// checkpoint marks as done.
+func (p *playlistSorter) syncPeriod(ctx context.Context, userID string, pd period, tracks []spotify.SavedTrack, today string) error {
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		Year:       pd.Year,
+		Period:     p.label(pd),
+		TrackCount: len(tracks),
+		Duration:   formatDuration(totalDuration(tracks)),
+		Date:       today,
+	})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.names[pd] = playlistName
+	done := slices.Contains(p.checkpoint.Completed, playlistName)
+	p.mu.Unlock()
+	if done {
+		p.logger.Printf("%s was written before the interruption. Skipping.", p.describe(pd))
+		p.mu.Lock()
+		p.changes[pd] = periodChange{tracks: len(tracks), duration: totalDuration(tracks), skipped: true}
+		p.mu.Unlock()
+		return nil
+	}
+	ids := savedTrackIDs(tracks)
+	p.logger.Printf("--- Processing %s (%d tracks) ---", p.label(pd), len(ids))
+
+	p.renameOutdated(ctx, userID, pd, playlistName, description)
+	playlistID, err := p.writer.EnsureAccess(ctx, userID, playlistName, description, p.accessOf(playlistName))
+	if err != nil {
+		return err
+	}
+	p.store.SetSortedPlaylist(p.key(pd), playlistID)
+
+	p.covers.Start(ctx, playlistID, CoverSpec{
+		Name:     playlistName,
+		Label:    p.label(pd),
+		Subtitle: p.cfg.CoverSubtitle,
+		Tracks:   fullTracks(tracks),
+	})
+
+	// What the playlist held before, for the summary. After an interrupted write that's
+	// partly this run's tracks already.
+	before, err := fetchPlaylistTracks(ctx, p.client, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", playlistName, err)
+	}
+
+	p.mu.Lock()
+	from := p.checkpoint.Partial[playlistID]
+	p.mu.Unlock()
+	// After an interrupted write the playlist holds some of this run's tracks, which
+	// aren't edits.
+	sorted := ids
+	if from.Written == 0 {
+		ids = p.resolveEdits(playlistID, playlistName, trackIDs(before), ids)
+	}
+	err = p.writer.ReplaceResumable(ctx, playlistID, ids, from, func(w store.PartialWrite) {
+		p.updateCheckpoint(func(cp *store.Checkpoint) {
+			if cp.Partial == nil {
+				cp.Partial = make(map[spotify.ID]store.PartialWrite)
 			}
-		}
+			cp.Partial[playlistID] = w
+		}, false)
+	})
+	if err != nil {
+		p.saveCheckpoint(true)
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	p.store.SetWrittenTracks(playlistID, ids)
+	p.updateCheckpoint(func(cp *store.Checkpoint) {
+		delete(cp.Partial, playlistID)
+		cp.Completed = append(cp.Completed, playlistName)
+	}, true)
+	added, removed := trackDiff(trackIDs(before), ids)
+	p.mu.Lock()
+	p.changes[pd] = periodChange{tracks: len(ids), duration: totalDuration(tracks), added: added, removed: removed}
+	p.sorted[playlistID] = sorted
+	p.mu.Unlock()
 
-		if err := p.addTracksInBatches(ctx, playlistID, trackIDs); err != nil {
-			return err
+	// A failed check is only reported: rewriting wouldn't bring back tracks Spotify dropped.
+	mismatch, err := p.writer.Verify(ctx, playlistID, ids)
+	switch {
+	case err != nil:
+		p.logger.Printf("⚠️  Could not verify '%s': %v", playlistName, err)
+	case mismatch != nil:
+		p.logger.Printf("⚠️  '%s' doesn't match what was written: %s", playlistName, mismatch)
+		p.mu.Lock()
+		p.mismatches = append(p.mismatches, fmt.Sprintf("%s: %s", playlistName, mismatch))
+		p.mu.Unlock()
+		for _, id := range mismatch.Missing {
+			p.logger.Printf("     missing spotify:track:%s", id)
 		}
 	}
+	// Only songs the playlist is known to hold are unliked, and never those of the
+	// current period.
+	if p.opts.Archive && err == nil && mismatch == nil && comparePeriods(pd, p.periods.Period(time.Now())) < 0 {
+		return p.archive(ctx, pd, playlistName, tracks)
+	}
 	return nil
 }
 
-// fetchAllLikedTracks pages through the entire "Liked Songs" library using manual pagination.
-func (p *playlistSorter) fetchAllLikedTracks(ctx context.Context) ([]spotify.SavedTrack, error) {
-	var allTracks []spotify.SavedTrack
-	limit := 50
-	offset := 0
+// renameOutdated renames and redescribes the playlist a previous run wrote pd to when
+// the name template changed since, so the period keeps its playlist instead of getting a
+// second one. Playlists that aren't the user's or generated anymore are left alone.
+func (p *playlistSorter) renameOutdated(ctx context.Context, userID string, pd period, name, description string) {
+	id, ok := p.store.SortedPlaylist(p.key(pd))
+	if !ok {
+		return
+	}
+	owned, err := p.ownedPlaylists(ctx, userID)
+	if err != nil {
+		p.logger.Printf("⚠️  Could not list playlists to find the previous one of %s: %v", p.label(pd), err)
+		return
+	}
+	i := slices.IndexFunc(owned, func(pl spotify.SimplePlaylist) bool { return pl.ID == id })
+	if i < 0 || folders.StripPrefix(owned[i].Name) == name || !isGenerated(owned[i].Description) {
+		return
+	}
+	old := owned[i]
+	if slices.ContainsFunc(owned, func(pl spotify.SimplePlaylist) bool { return folders.StripPrefix(pl.Name) == name }) {
+		p.logger.Printf("⚠️  Not renaming '%s': a playlist called '%s' already exists.", old.Name, name)
+		return
+	}
+	// A folder prefix stays, so the playlist stays filed.
+	newName := strings.TrimSuffix(old.Name, folders.StripPrefix(old.Name)) + name
+	if err := p.client.ChangePlaylistName(ctx, id, newName); err != nil {
+		p.logger.Printf("⚠️  Could not rename '%s' to '%s': %v", old.Name, newName, err)
+		return
+	}
+	p.logger.Printf("✅ Renamed '%s' to '%s' to match the name template.", old.Name, newName)
+	if err := p.client.ChangePlaylistDescription(ctx, id, signed(description)); err != nil {
+		p.logger.Printf("⚠️  Could not update the description of '%s': %v", newName, err)
+	}
+}
 
-	for {
-		page, err := p.client.CurrentUsersTracks(ctx, spotify.Limit(limit), spotify.Offset(offset))
+// pruneEmpty unfollows, which is how Spotify deletes, the playlists of the selected
+// periods that have no songs left, e.g. after unliking every song of a year.
+func (p *playlistSorter) pruneEmpty(ctx context.Context, userID string, tracksByPeriod map[period][]spotify.SavedTrack) {
+	playlists := p.store.SortedPlaylists()
+	for _, key := range slices.Sorted(maps.Keys(playlists)) {
+		pd, ok := p.periodOfKey(key)
+		if !ok || !p.wantsYear(pd.Year) || len(tracksByPeriod[pd]) > 0 {
+			continue
+		}
+		owned, err := p.ownedPlaylists(ctx, userID)
 		if err != nil {
-			return nil, err
+			p.logger.Printf("⚠️  Could not list playlists to delete empty ones: %v", err)
+			return
+		}
+		i := slices.IndexFunc(owned, func(pl spotify.SimplePlaylist) bool { return pl.ID == playlists[key] })
+		if i < 0 {
+			// Deleted by hand already.
+			p.store.ForgetSortedPlaylist(key)
+			continue
 		}
-		if len(page.Tracks) == 0 {
-			break
+		if !isGenerated(owned[i].Description) {
+			continue
+		}
+		if err := p.client.UnfollowPlaylist(ctx, owned[i].ID); err != nil {
+			p.logger.Printf("⚠️  Could not delete '%s': %v", owned[i].Name, err)
+			continue
 		}
-		allTracks = append(allTracks, page.Tracks...)
-		p.logger.Printf("Fetched %d/%d liked songs...", len(allTracks), page.Total)
-		offset += len(page.Tracks)
+		p.store.ForgetSortedPlaylist(key)
+		p.logger.Printf("✅ Deleted '%s': no liked songs of %s are left.", owned[i].Name, p.label(pd))
 	}
-	p.logger.Printf("Total liked songs fetched: %d", len(allTracks))
-	return allTracks, nil
 }
 
-// groupTracksByYear categorizes tracks into a map where the key is the year.
-func (p *playlistSorter) groupTracksByYear(tracks []spotify.SavedTrack) map[int][]spotify.ID {
-	grouped := make(map[int][]spotify.ID)
-	for _, item := range tracks {
-		t, err := time.Parse(time.RFC3339, item.AddedAt)
+// periodOfKey returns the period a store key names, if it's one of the current grouping
+// and granularity.
+func (p *playlistSorter) periodOfKey(key string) (period, bool) {
+	label := strings.TrimPrefix(key, "release/")
+	if len(label) < 4 {
+		return period{}, false
+	}
+	year, err := strconv.Atoi(label[:4])
+	if err != nil {
+		return period{}, false
+	}
+	candidates := []period{{Year: year}}
+	if p.periods.parts > 1 && p.cfg.GroupBy != "release" {
+		candidates = nil
+		for part := 1; part <= p.periods.parts; part++ {
+			candidates = append(candidates, period{Year: year, Part: part})
+		}
+	}
+	for _, pd := range candidates {
+		if p.key(pd) == key {
+			return pd, true
+		}
+	}
+	return period{}, false
+}
+
+// ownedPlaylists returns the user's playlists, fetching them on the first call of a run.
+func (p *playlistSorter) ownedPlaylists(ctx context.Context, userID string) ([]spotify.SimplePlaylist, error) {
+	p.ownedMu.Lock()
+	defer p.ownedMu.Unlock()
+	if p.owned == nil {
+		owned, err := fetchOwnedPlaylists(ctx, p.client, userID)
 		if err != nil {
-			p.logger.Printf("Error parsing track date for '%s': %v", item.Name, err)
-			continue
+			return nil, err
 		}
-		year := t.Year()
-		grouped[year] = append(grouped[year], item.ID)
+		p.owned = owned
 	}
-	return grouped
+	return p.owned, nil
 }
 
-// findExistingPlaylist searches for a playlist by name using manual pagination.
-func (p *playlistSorter) findExistingPlaylist(ctx context.Context, userID, name string) (*spotify.SimplePlaylist, error) {
-	p.logger.Printf("Searching for existing playlist named '%s'...", name)
-	limit := 50
-	offset := 0
+// key names pd in the store, apart from the periods of the other grouping.
+func (p *playlistSorter) key(pd period) string {
+	if p.cfg.GroupBy == "release" {
+		return "release/" + p.label(pd)
+	}
+	return p.label(pd)
+}
 
-	for {
-		page, err := p.client.GetPlaylistsForUser(ctx, userID, spotify.Limit(limit), spotify.Offset(offset))
-		if err != nil {
-			return nil, fmt.Errorf("failed to get user playlists: %w", err)
+// withArchive adds the archived songs to the liked ones, so their playlists keep them,
+// and gives songs liked again by unarchive back their original like dates. likedIDs lists
+// every liked song, including those of periods left out of liked.
+func (p *playlistSorter) withArchive(liked []spotify.SavedTrack, likedIDs []spotify.ID) []spotify.SavedTrack {
+	archived := p.store.Archived()
+	p.liked = make(map[spotify.ID]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		p.liked[id] = true
+	}
+	tracks := slices.Clone(liked)
+	for i, t := range tracks {
+		a, ok := archived[t.ID]
+		if !ok || a.RestoredAt.IsZero() {
+			continue
+		}
+		if likedAt, err := time.Parse(time.RFC3339, t.AddedAt); err == nil && likedAt.Sub(a.RestoredAt).Abs() < restoreWindow {
+			tracks[i].AddedAt = a.Track.AddedAt
+		}
+	}
+	for id, a := range archived {
+		// Restored songs that aren't liked anymore were unliked since, on purpose.
+		if a.RestoredAt.IsZero() && !p.liked[id] {
+			tracks = append(tracks, a.Track)
+		}
+	}
+	return tracks
+}
+
+// archive unlikes the liked songs of a past period once its playlist holds them.
+func (p *playlistSorter) archive(ctx context.Context, pd period, playlistName string, tracks []spotify.SavedTrack) error {
+	var liked []spotify.SavedTrack
+	for _, t := range tracks {
+		if p.liked[t.ID] {
+			liked = append(liked, t)
 		}
+	}
+	if len(liked) == 0 {
+		return nil
+	}
+	// Recorded first: a song unliked but not recorded would drop out of its playlist.
+	p.store.Archive(liked, time.Now())
+	p.saveCheckpoint(true)
+	err := inBatches(savedTrackIDs(liked), 50, func(batch []spotify.ID) error {
+		return p.client.RemoveTracksFromLibrary(ctx, batch...)
+	})
+	if err != nil {
+		return fmt.Errorf("could not archive the liked songs of %s: %w", p.label(pd), err)
+	}
+	p.logger.Printf("✅ Archived %d songs of %s: unliked, and kept in '%s'.", len(liked), p.label(pd), playlistName)
+	return nil
+}
 
-		for _, pl := range page.Playlists {
-			if pl.Name == name && pl.Owner.ID == userID {
-				p.logger.Printf("Found existing playlist: '%s' (ID: %s)", pl.Name, pl.ID)
-				found := pl // Create a new variable to ensure we don't return a pointer to the loop variable.
-				return &found, nil
+// removeMoved takes songs that moved to another period since they were last sorted, e.g.
+// when liked again, out of their previous playlist. Playlists rewritten by this run have
+// already dropped them; the others, like older years left out with --since, haven't.
+func (p *playlistSorter) removeMoved(ctx context.Context) {
+	moved := make(map[spotify.ID][]spotify.ID)
+	movedTo := make(map[spotify.ID]spotify.ID)
+	for playlistID, ids := range p.sorted {
+		for _, id := range ids {
+			previous, ok := p.store.SortedInto(id)
+			if _, rewritten := p.sorted[previous]; ok && previous != playlistID && !rewritten {
+				moved[previous] = append(moved[previous], id)
+				movedTo[id] = playlistID
+				continue
 			}
+			p.store.SetSortedInto(id, playlistID)
+		}
+	}
+	for previous, ids := range moved {
+		if ctx.Err() != nil {
+			return
+		}
+		name := string(previous)
+		if playlist, err := p.client.GetPlaylist(ctx, previous, spotify.Fields("name")); err == nil {
+			name = playlist.Name
+		}
+		err := inBatches(ids, 100, func(batch []spotify.ID) error {
+			_, err := p.client.RemoveTracksFromPlaylist(ctx, previous, batch...)
+			return err
+		})
+		if err != nil {
+			// The songs stay recorded in their previous playlist, so the next run tries again.
+			p.logger.Printf("⚠️  Could not remove %d songs that moved to another period from '%s': %v", len(ids), name, err)
+			continue
+		}
+		p.logger.Printf("✅ Removed %d songs that moved to another period from '%s'.", len(ids), name)
+		gone := make(map[spotify.ID]bool, len(ids))
+		for _, id := range ids {
+			gone[id] = true
+			p.store.SetSortedInto(id, movedTo[id])
 		}
-		if len(page.Playlists) == 0 {
-			break
+		// They were removed on purpose, not by hand.
+		if written, ok := p.store.WrittenTracks(previous); ok {
+			p.store.SetWrittenTracks(previous, slices.DeleteFunc(written, func(id spotify.ID) bool { return gone[id] }))
 		}
-		offset += len(page.Playlists)
 	}
+}
 
-	p.logger.Println("No existing playlist found.")
-	return nil, nil
+// resolveEdits compares a playlist with what the last run wrote to it and applies the
+// conflicts policy to the songs added by hand since, returning the tracks to write.
+// Songs removed by hand are written again. Playlists the store has no record of are
+// adopted as they are overwritten.
+func (p *playlistSorter) resolveEdits(playlistID spotify.ID, name string, current, ids []spotify.ID) []spotify.ID {
+	written, ok := p.store.WrittenTracks(playlistID)
+	if !ok {
+		return ids
+	}
+	added, removed := editedTracks(written, current)
+	if len(added) == 0 && len(removed) == 0 {
+		return ids
+	}
+	p.logger.Printf("⚠️  '%s' was edited by hand since the last run: %d songs added, %d removed.", name, len(added), len(removed))
+	keep := p.cfg.Conflicts == "preserve-manual-additions"
+	if p.cfg.Conflicts == "prompt" && len(added) > 0 && p.opts.Ask != nil {
+		keep = p.opts.Ask(fmt.Sprintf("Keep the %d songs added by hand to '%s'?", len(added), name))
+	}
+	if !keep || len(added) == 0 {
+		p.logger.Printf("   Overwriting '%s' with the sorted songs.", name)
+		return ids
+	}
+	p.logger.Printf("   Keeping the songs added by hand to '%s' at its end.", name)
+	sorted := make(map[spotify.ID]bool, len(ids))
+	for _, id := range ids {
+		sorted[id] = true
+	}
+	ids = slices.Clone(ids)
+	for _, id := range added {
+		if !sorted[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
-// fetchAllPlaylistTracks pages through a playlist's items using manual pagination.
-func (p *playlistSorter) fetchAllPlaylistTracks(ctx context.Context, playlistID spotify.ID) ([]spotify.ID, error) {
-	var allTrackIDs []spotify.ID
-	limit := 100
-	offset := 0
+// editedTracks returns the tracks in current but not in written, and the other way
+// round, in playlist order.
+func editedTracks(written, current []spotify.ID) (added, removed []spotify.ID) {
+	return missingFrom(written, current), missingFrom(current, written)
+}
 
-	for {
-		page, err := p.client.GetPlaylistTracks(ctx, playlistID, spotify.Limit(limit), spotify.Offset(offset))
-		if err != nil {
-			return nil, err
+// missingFrom returns the tracks of ids that aren't in from, once each.
+func missingFrom(from, ids []spotify.ID) []spotify.ID {
+	seen := make(map[spotify.ID]bool, len(from))
+	for _, id := range from {
+		seen[id] = true
+	}
+	var missing []spotify.ID
+	for _, id := range ids {
+		if !seen[id] {
+			missing = append(missing, id)
+			seen[id] = true
 		}
-		if len(page.Tracks) == 0 {
-			break
+	}
+	return missing
+}
+
+// reportChanges logs a table of the playlists of periods: their length and how many
+// tracks this run added and removed.
+func (p *playlistSorter) reportChanges(periods []period) {
+	var table strings.Builder
+	w := tabwriter.NewWriter(&table, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PERIOD\tTRACKS\tDURATION\tNEW\tREMOVED")
+	var total periodChange
+	for _, pd := range periods {
+		c, ok := p.changes[pd]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "%s\t\t\tfailed\t\n", p.label(pd))
+			continue
+		case c.skipped:
+			fmt.Fprintf(w, "%s\t%d\t%s\twritten before\t\n", p.label(pd), c.tracks, formatDuration(c.duration))
+		default:
+			fmt.Fprintf(w, "%s\t%d\t%s\t+%d\t-%d\n", p.label(pd), c.tracks, formatDuration(c.duration), c.added, c.removed)
 		}
-		for _, item := range page.Tracks {
-			// A track might be unavailable in the user's region or deleted, so we check for a valid ID.
-			if item.Track.ID != "" {
-				allTrackIDs = append(allTrackIDs, item.Track.ID)
-			}
+		total.tracks += c.tracks
+		total.duration += c.duration
+		total.added += c.added
+		total.removed += c.removed
+	}
+	fmt.Fprintf(w, "Total\t%d\t%s\t+%d\t-%d\n", total.tracks, formatDuration(total.duration), total.added, total.removed)
+	w.Flush()
+	p.logger.Println("📊 Summary of this run:")
+	for _, line := range strings.Split(strings.TrimRight(table.String(), "\n"), "\n") {
+		p.logger.Printf("   %s", line)
+	}
+}
+
+// trackDiff counts the tracks in after but not before, and the other way round. Repeats
+// count as extra tracks.
+func trackDiff(before, after []spotify.ID) (added, removed int) {
+	counts := make(map[spotify.ID]int, len(before))
+	for _, id := range before {
+		counts[id]++
+	}
+	for _, id := range after {
+		if counts[id] > 0 {
+			counts[id]--
+		} else {
+			added++
 		}
-		p.logger.Printf("Fetched %d/%d existing tracks from playlist...", len(allTrackIDs), page.Total)
-		offset += len(page.Tracks)
 	}
-	return allTrackIDs, nil
+	for _, n := range counts {
+		removed += n
+	}
+	return added, removed
 }
 
-// removeTracksInBatches removes tracks from a playlist in batches of 100.
-func (p *playlistSorter) removeTracksInBatches(ctx context.Context, playlistID spotify.ID, trackIDs []spotify.ID) error {
-	if len(trackIDs) == 0 {
-		p.logger.Println("Playlist is already empty. No tracks to remove.")
-		return nil
+// reportMismatches summarizes the playlists whose contents failed verification.
+func (p *playlistSorter) reportMismatches() {
+	if len(p.mismatches) == 0 {
+		return
+	}
+	p.logger.Printf("🚨 %d playlists don't hold exactly the songs written to them:", len(p.mismatches))
+	for _, m := range p.mismatches {
+		p.logger.Printf("   - %s", m)
 	}
+	p.logger.Println("   Missing songs are usually no longer available; `go run ./cmd health` lists them.")
+}
 
-	batchSize := 100
-	for i := 0; i < len(trackIDs); i += batchSize {
-		end := i + batchSize
-		if end > len(trackIDs) {
-			end = len(trackIDs)
-		}
-		batch := trackIDs[i:end]
-		p.logger.Printf("  Removing batch of %d tracks...", len(batch))
-		if _, err := p.client.RemoveTracksFromPlaylist(ctx, playlistID, batch...); err != nil {
-			return fmt.Errorf("failed to remove tracks from playlist: %w", err)
+// groupTracksByPeriod categorizes tracks into a map where the key is the period.
+func (p *playlistSorter) groupTracksByPeriod(tracks []spotify.SavedTrack) map[period][]spotify.SavedTrack {
+	grouped := make(map[period][]spotify.SavedTrack)
+	for _, item := range tracks {
+		pd, err := p.periodOf(item)
+		if err != nil {
+			p.logger.Printf("Error parsing track date for '%s': %v", item.Name, err)
+			continue
 		}
+		grouped[pd] = append(grouped[pd], item)
 	}
-	p.logger.Printf("✅ Finished removing all %d old tracks.", len(trackIDs))
-	return nil
+	return grouped
+}
+
+// periodOf returns the period a track was liked in, or its release year when grouping by
+// release.
+func (p *playlistSorter) periodOf(item spotify.SavedTrack) (period, error) {
+	if p.cfg.GroupBy == "release" {
+		year, err := releaseYear(item.Album)
+		return period{Year: year}, err
+	}
+	t, err := time.Parse(time.RFC3339, item.AddedAt)
+	if err != nil {
+		return period{}, err
+	}
+	return p.periods.Period(t), nil
+}
+
+// yearOf returns the yearly period a track was liked in, or its release year when
+// grouping by release.
+func (p *playlistSorter) yearOf(item spotify.SavedTrack) (int, error) {
+	if p.cfg.GroupBy == "release" {
+		return releaseYear(item.Album)
+	}
+	t, err := time.Parse(time.RFC3339, item.AddedAt)
+	if err != nil {
+		return 0, err
+	}
+	return p.periods.Year(t), nil
 }
 
-// addTracksInBatches adds tracks to a playlist in batches of 100.
-func (p *playlistSorter) addTracksInBatches(ctx context.Context, playlistID spotify.ID, trackIDs []spotify.ID) error {
-	batchSize := 100
-	for i := 0; i < len(trackIDs); i += batchSize {
-		end := i + batchSize
-		if end > len(trackIDs) {
-			end = len(trackIDs)
+// wantsYear reports whether the year filter selects year.
+func (p *playlistSorter) wantsYear(year int) bool {
+	if len(p.opts.Years) > 0 {
+		return slices.Contains(p.opts.Years, year)
+	}
+	return year >= p.opts.Since
+}
+
+// accessOf returns the visibility configured for the playlist called name.
+func (p *playlistSorter) accessOf(name string) playlistAccess {
+	for _, pattern := range slices.Sorted(maps.Keys(p.visibilities)) {
+		if ok, _ := path.Match(pattern, name); ok {
+			return p.visibilities[pattern]
 		}
-		batch := trackIDs[i:end]
-		p.logger.Printf("  Adding batch of %d tracks...", len(batch))
-		if _, err := p.client.AddTracksToPlaylist(ctx, playlistID, batch...); err != nil {
-			return fmt.Errorf("failed to add tracks to playlist: %w", err)
+	}
+	return p.visibility
+}
+
+// label names the playlist period pd.
+func (p *playlistSorter) label(pd period) string {
+	if p.cfg.GroupBy == "release" {
+		return strconv.Itoa(pd.Year)
+	}
+	return p.periods.PeriodLabel(pd)
+}
+
+// describe names the playlist of pd in the log, e.g. "Year 2024" or "2024 Q3".
+func (p *playlistSorter) describe(pd period) string {
+	if pd.Part == 0 {
+		return "Year " + p.label(pd)
+	}
+	return p.label(pd)
+}
+
+// fetchCutoff returns the year at which fetching liked songs can stop, or 0 if the whole
+// library is needed. Only like dates come in order, so release grouping needs it all.
+func (p *playlistSorter) fetchCutoff() int {
+	if p.cfg.GroupBy == "release" {
+		return 0
+	}
+	return p.oldestYear()
+}
+
+// releaseYear returns the year an album was released. Release dates are "1994",
+// "1994-03" or "1994-03-01" depending on their precision.
+func releaseYear(album spotify.SimpleAlbum) (int, error) {
+	if len(album.ReleaseDate) < 4 {
+		return 0, fmt.Errorf("unknown release date '%s'", album.ReleaseDate)
+	}
+	year, err := strconv.Atoi(album.ReleaseDate[:4])
+	if err != nil || year == 0 {
+		return 0, fmt.Errorf("unknown release date '%s'", album.ReleaseDate)
+	}
+	return year, nil
+}
+
+// oldestYear returns the oldest year the filter selects, or 0 if it selects every year.
+func (p *playlistSorter) oldestYear() int {
+	if len(p.opts.Years) > 0 {
+		return slices.Min(p.opts.Years)
+	}
+	return p.opts.Since
+}
+
+// uniqueSavedTracks drops repeated tracks, keeping the first occurrence.
+func uniqueSavedTracks(tracks []spotify.SavedTrack) []spotify.SavedTrack {
+	seen := make(map[spotify.ID]struct{}, len(tracks))
+	unique := tracks[:0]
+	for _, t := range tracks {
+		if _, dup := seen[t.ID]; !dup {
+			seen[t.ID] = struct{}{}
+			unique = append(unique, t)
 		}
 	}
-	p.logger.Printf("✅ Finished adding all %d tracks.", len(trackIDs))
-	return nil
+	return unique
+}
+
+// savedTrackIDs returns the IDs of tracks, preserving order.
+func savedTrackIDs(tracks []spotify.SavedTrack) []spotify.ID {
+	ids := make([]spotify.ID, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// fullTracks unwraps saved tracks, preserving order.
+func fullTracks(tracks []spotify.SavedTrack) []spotify.FullTrack {
+	full := make([]spotify.FullTrack, len(tracks))
+	for i, t := range tracks {
+		full[i] = t.FullTrack
+	}
+	return full
+}
+
+// totalDuration sums the playing time of tracks.
+func totalDuration(tracks []spotify.SavedTrack) time.Duration {
+	var total time.Duration
+	for _, t := range tracks {
+		total += time.Duration(t.Duration) * time.Millisecond
+	}
+	return total
 }
@@ -0,0 +1,71 @@
+package processor_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"spotify/internal/config"
+	"spotify/internal/processor"
+	"spotify/internal/spotifytest"
+	"spotify/internal/store"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// TestSorterKeepsSelectedYears checks that paging through the library keeps only the
+// songs of the selected years, and the IDs of all of them.
+func TestSorterKeepsSelectedYears(t *testing.T) {
+	f := spotifytest.Fixture{User: spotify.PrivateUser{User: spotify.User{ID: "me"}}}
+	added := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	var want []spotify.ID
+	for i := range 120 {
+		id := spotify.ID(fmt.Sprintf("track%03d", i))
+		release := "2019-03-01"
+		if i%3 == 0 {
+			release = "2024"
+			want = append(want, id)
+		}
+		track := spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: id, Name: string(id)}}
+		track.Album.ReleaseDate = release
+		track.AvailableMarkets = []string{"IT", "SE"}
+		f.Tracks = append(f.Tracks, track)
+		f.Liked = append(f.Liked, spotifytest.Saved{ID: id, AddedAt: added.Add(-time.Duration(i) * time.Hour).Format(time.RFC3339)})
+	}
+	client, err := spotifytest.New(f)
+	if err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	st, err := store.Open(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default().Sorter
+	cfg.GroupBy = "release"
+	sorter, err := processor.NewPlaylistSorter(client, st, log.New(io.Discard, "", 0), nil, cfg, config.Default().Playlists, processor.SorterOptions{Years: []int{2024}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	liked, ids, err := processor.FetchSorterLibrary(context.Background(), sorter)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(ids) != 120 {
+		t.Errorf("got %d liked IDs, want all 120", len(ids))
+	}
+	if len(liked) != len(want) {
+		t.Fatalf("kept %d songs, want the %d released in 2024", len(liked), len(want))
+	}
+	for i, s := range liked {
+		if s.ID != want[i] {
+			t.Errorf("kept song %d = %s, want %s", i, s.ID, want[i])
+		}
+		if s.AvailableMarkets != nil {
+			t.Errorf("%s kept its markets", s.ID)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"spotify/internal/folders"
+	"strconv"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// partName matches the names of split parts, e.g. "Road Trip (2/3)".
+var partName = regexp.MustCompile(`^(.*) \((\d+)/(\d+)\)$`)
+
+// SplitOptions selects what the playlist splitter does.
+type SplitOptions struct {
+	// Name is the playlist to split, or the base name of the parts to join.
+	Name string
+	// PartSize is the number of tracks per part when splitting.
+	PartSize int
+	// Join concatenates the parts back into a playlist called Name instead of splitting.
+	Join bool
+}
+
+type playlistSplitter struct {
+	client SpotifyClient
+	logger *log.Logger
+	writer *playlistWriter
+	opts   SplitOptions
+}
+
+// NewPlaylistSplitter returns a Processor that splits a playlist into parts named
+// "Name (1/3)", "Name (2/3)", ... of at most opts.PartSize tracks, preserving order, or
+// with opts.Join joins such parts back together.
+func NewPlaylistSplitter(client SpotifyClient, logger *log.Logger, opts SplitOptions) (Processor, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("no playlist name given")
+	}
+	if !opts.Join && (opts.PartSize <= 0 || opts.PartSize > 10000) {
+		return nil, fmt.Errorf("part size must be between 1 and 10000, got %d", opts.PartSize)
+	}
+	return &playlistSplitter{client: client, logger: logger, writer: newPlaylistWriter(client, logger), opts: opts}, nil
+}
+
+// Run splits or joins.
+func (p *playlistSplitter) Run(ctx context.Context) error {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	owned, err := fetchOwnedPlaylists(ctx, p.client, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list playlists: %w", err)
+	}
+	if p.opts.Join {
+		return p.join(ctx, user.ID, owned)
+	}
+	return p.split(ctx, user.ID, owned)
+}
+
+func (p *playlistSplitter) split(ctx context.Context, userID string, owned []spotify.SimplePlaylist) error {
+	i := slices.IndexFunc(owned, func(pl spotify.SimplePlaylist) bool { return folders.StripPrefix(pl.Name) == p.opts.Name })
+	if i < 0 {
+		return fmt.Errorf("no playlist of yours is called '%s'", p.opts.Name)
+	}
+	source := owned[i]
+	trackIDs, err := fetchPlaylistTrackIDs(ctx, p.client, source.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", source.Name, err)
+	}
+	parts := max(1, ceilDiv(len(trackIDs), p.opts.PartSize))
+	p.logger.Printf("Splitting '%s' (%d tracks) into %d parts...", source.Name, len(trackIDs), parts)
+
+	err = forEachPlaylist(ctx, p.logger, makeRange(1, parts), func(n int) string { return fmt.Sprintf("Part %d/%d", n, parts) }, func(n int) error {
+		name := fmt.Sprintf("%s (%d/%d)", p.opts.Name, n, parts)
+		chunk := trackIDs[(n-1)*p.opts.PartSize : min(n*p.opts.PartSize, len(trackIDs))]
+		description := fmt.Sprintf("Part %d of %d of %s.", n, parts, p.opts.Name)
+		playlistID, err := p.writer.Ensure(ctx, userID, name, description)
+		if err != nil {
+			return err
+		}
+		if err := p.writer.Replace(ctx, playlistID, chunk); err != nil {
+			return fmt.Errorf("could not write playlist '%s': %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Parts of an earlier split into a different number of parts are now stale.
+	for _, pl := range p.parts(owned) {
+		if partTotal(pl) != parts {
+			if err := p.client.UnfollowPlaylist(ctx, pl.ID); err != nil {
+				p.logger.Printf("⚠️  Could not remove stale part '%s': %v", pl.Name, err)
+				continue
+			}
+			p.logger.Printf("Removed stale part '%s'.", pl.Name)
+		}
+	}
+	p.logger.Printf("✅ Split '%s' into %d parts. The original playlist is unchanged.", source.Name, parts)
+	return nil
+}
+
+func (p *playlistSplitter) join(ctx context.Context, userID string, owned []spotify.SimplePlaylist) error {
+	parts := p.parts(owned)
+	if len(parts) == 0 {
+		return fmt.Errorf("no parts of '%s' found, expected playlists like '%s (1/2)'", p.opts.Name, p.opts.Name)
+	}
+	total := partTotal(parts[0])
+	for _, pl := range parts {
+		if partTotal(pl) != total {
+			return fmt.Errorf("'%s' and '%s' come from different splits; remove one set first", parts[0].Name, pl.Name)
+		}
+	}
+	if len(parts) != total {
+		p.logger.Printf("⚠️  Only %d of %d parts were found; joining those.", len(parts), total)
+	}
+	var trackIDs []spotify.ID
+	for _, pl := range parts {
+		ids, err := fetchPlaylistTrackIDs(ctx, p.client, pl.ID)
+		if err != nil {
+			return fmt.Errorf("failed to read '%s': %w", pl.Name, err)
+		}
+		trackIDs = append(trackIDs, ids...)
+	}
+	if len(trackIDs) > 10000 {
+		return fmt.Errorf("the parts hold %d tracks, more than a playlist can", len(trackIDs))
+	}
+	p.logger.Printf("Joining %d parts (%d tracks) into '%s'...", len(parts), len(trackIDs), p.opts.Name)
+	playlistID, err := p.writer.Ensure(ctx, userID, p.opts.Name, "")
+	if err != nil {
+		return err
+	}
+	if err := p.writer.Replace(ctx, playlistID, trackIDs); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", p.opts.Name, err)
+	}
+	p.logger.Printf("✅ Joined the parts into '%s'. The parts are unchanged.", p.opts.Name)
+	return nil
+}
+
+// parts returns the owned playlists that are parts of opts.Name, in part order.
+func (p *playlistSplitter) parts(owned []spotify.SimplePlaylist) []spotify.SimplePlaylist {
+	var parts []spotify.SimplePlaylist
+	for _, pl := range owned {
+		if m := partName.FindStringSubmatch(folders.StripPrefix(pl.Name)); m != nil && m[1] == p.opts.Name {
+			parts = append(parts, pl)
+		}
+	}
+	slices.SortStableFunc(parts, func(a, b spotify.SimplePlaylist) int { return partNumber(a) - partNumber(b) })
+	return parts
+}
+
+// partNumber and partTotal read "n" and "m" from the name of a part "Name (n/m)".
+func partNumber(pl spotify.SimplePlaylist) int {
+	n, _ := strconv.Atoi(partName.FindStringSubmatch(folders.StripPrefix(pl.Name))[2])
+	return n
+}
+
+func partTotal(pl spotify.SimplePlaylist) int {
+	m, _ := strconv.Atoi(partName.FindStringSubmatch(folders.StripPrefix(pl.Name))[3])
+	return m
+}
+
+// makeRange returns the integers from first to last, inclusive.
+func makeRange(first, last int) []int {
+	var r []int
+	for i := first; i <= last; i++ {
+		r = append(r, i)
+	}
+	return r
+}
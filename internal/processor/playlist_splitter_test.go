@@ -0,0 +1,115 @@
+package processor_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"spotify/internal/processor"
+	"spotify/internal/spotifytest"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// playlistFixture returns an account with one playlist called name holding n tracks.
+func playlistFixture(t *testing.T, name string, n int) (*spotifytest.Client, []spotify.ID) {
+	t.Helper()
+	f := spotifytest.Fixture{User: spotify.PrivateUser{User: spotify.User{ID: "me"}}}
+	var ids []spotify.ID
+	for i := range n {
+		id := spotify.ID(fmt.Sprintf("track%03d", i))
+		ids = append(ids, id)
+		f.Tracks = append(f.Tracks, spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: id, Name: string(id)}})
+	}
+	f.Playlists = []spotifytest.Playlist{{ID: "source", Name: name, Tracks: ids}}
+	client, err := spotifytest.New(f)
+	if err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return client, ids
+}
+
+// playlistTracks returns the tracks of the playlists of client by name.
+func playlistTracks(client *spotifytest.Client) map[string][]spotify.ID {
+	out := make(map[string][]spotify.ID)
+	for _, p := range client.Fixture().Playlists {
+		out[p.Name] = p.Tracks
+	}
+	return out
+}
+
+func TestPlaylistSplitter(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	client, ids := playlistFixture(t, "Road Trip", 250)
+
+	split, err := processor.NewPlaylistSplitter(client, logger, processor.SplitOptions{Name: "Road Trip", PartSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := split.Run(context.Background()); err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	got := playlistTracks(client)
+	want := map[string][]spotify.ID{
+		"Road Trip":       ids,
+		"Road Trip (1/3)": ids[:100],
+		"Road Trip (2/3)": ids[100:200],
+		"Road Trip (3/3)": ids[200:],
+	}
+	for name, tracks := range want {
+		if !slices.Equal(got[name], tracks) {
+			t.Errorf("'%s' after splitting holds %d tracks, want %d in order", name, len(got[name]), len(tracks))
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("playlists after splitting = %d, want %d", len(got), len(want))
+	}
+
+	// Splitting again into fewer parts removes the parts left over.
+	split, err = processor.NewPlaylistSplitter(client, logger, processor.SplitOptions{Name: "Road Trip", PartSize: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := split.Run(context.Background()); err != nil {
+		t.Fatalf("second split: %v", err)
+	}
+	got = playlistTracks(client)
+	if _, ok := got["Road Trip (3/3)"]; ok || len(got) != 3 {
+		t.Errorf("playlists after splitting in two = %v, want the parts of three removed", slices.Sorted(maps.Keys(got)))
+	}
+
+	join, err := processor.NewPlaylistSplitter(client, logger, processor.SplitOptions{Name: "Road Trip", Join: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := join.Run(context.Background()); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if got := playlistTracks(client)["Road Trip"]; !slices.Equal(got, ids) {
+		t.Errorf("joined playlist holds %d tracks, want the original %d in order", len(got), len(ids))
+	}
+}
+
+func TestPlaylistSplitterOptions(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	client, _ := playlistFixture(t, "Road Trip", 1)
+	for _, opts := range []processor.SplitOptions{
+		{PartSize: 100},
+		{Name: "Road Trip"},
+		{Name: "Road Trip", PartSize: 10001},
+	} {
+		if _, err := processor.NewPlaylistSplitter(client, logger, opts); err == nil {
+			t.Errorf("NewPlaylistSplitter(%+v) didn't fail", opts)
+		}
+	}
+	missing, err := processor.NewPlaylistSplitter(client, logger, processor.SplitOptions{Name: "Gym", PartSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := missing.Run(context.Background()); err == nil {
+		t.Error("splitting a playlist that doesn't exist didn't fail")
+	}
+}
@@ -0,0 +1,194 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// statsTop is the length of the artist and genre rankings.
+const statsTop = 10
+
+type playlistStats struct {
+	client     SpotifyClient
+	out        io.Writer
+	logger     *log.Logger
+	playlistID spotify.ID
+}
+
+// NewPlaylistStats returns a Processor that describes any playlist the user can see:
+// its length, top artists and genres, average audio features, release years and share
+// of explicit tracks.
+func NewPlaylistStats(client SpotifyClient, out io.Writer, logger *log.Logger, playlistID spotify.ID) Processor {
+	return &playlistStats{client: client, out: out, logger: logger, playlistID: playlistID}
+}
+
+// statsCount is a name and how many tracks it covers.
+type statsCount struct {
+	name  string
+	count int
+}
+
+// Run fetches the playlist and prints the report.
+func (p *playlistStats) Run(ctx context.Context) error {
+	playlist, err := p.client.GetPlaylist(ctx, p.playlistID, spotify.Fields("name,owner(display_name,id)"))
+	if err != nil {
+		return fmt.Errorf("failed to get playlist: %w", err)
+	}
+	tracks, err := fetchPlaylistTracks(ctx, p.client, p.playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch tracks of '%s': %w", playlist.Name, err)
+	}
+	owner := playlist.Owner.DisplayName
+	if owner == "" {
+		owner = playlist.Owner.ID
+	}
+	fmt.Fprintf(p.out, "\n📊 '%s' by %s\n\n", playlist.Name, owner)
+	if len(tracks) == 0 {
+		fmt.Fprintln(p.out, "  The playlist has no Spotify tracks.")
+		return nil
+	}
+
+	var duration time.Duration
+	explicit := 0
+	artists := make(map[string]int)
+	years := make(map[int]int)
+	for _, t := range tracks {
+		duration += time.Duration(t.Duration) * time.Millisecond
+		if t.Explicit {
+			explicit++
+		}
+		for _, a := range t.Artists {
+			artists[a.Name]++
+		}
+		if year, err := releaseYear(t.Album); err == nil {
+			years[year]++
+		}
+	}
+	fmt.Fprintf(p.out, "  %d tracks, %s\n", len(tracks), formatDuration(duration))
+	fmt.Fprintf(p.out, "  %d explicit (%.0f%%)\n", explicit, 100*float64(explicit)/float64(len(tracks)))
+	p.printRanking("Top artists", artists)
+
+	genres, err := fetchArtistGenres(ctx, p.client, uniqueArtistIDs(tracks))
+	if err != nil {
+		p.logger.Printf("⚠️  Skipping genres: %v", err)
+	} else {
+		byGenre := make(map[string]int)
+		for _, t := range tracks {
+			if len(t.Artists) > 0 {
+				for _, g := range genres[t.Artists[0].ID] {
+					byGenre[g]++
+				}
+			}
+		}
+		p.printRanking("Top genres", byGenre)
+	}
+
+	// Spotify no longer serves audio features to every app; the rest of the report stands
+	// without them.
+	features, err := fetchAudioFeatures(ctx, p.client, trackIDs(tracks))
+	if err != nil {
+		p.logger.Printf("⚠️  Skipping audio features: %v", err)
+	} else {
+		p.printFeatures(features)
+	}
+	p.printYears(years)
+	return nil
+}
+
+// printRanking prints the names with the most tracks.
+func (p *playlistStats) printRanking(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	ranked := make([]statsCount, 0, len(counts))
+	for name, n := range counts {
+		ranked = append(ranked, statsCount{name, n})
+	}
+	slices.SortFunc(ranked, func(a, b statsCount) int {
+		if a.count != b.count {
+			return b.count - a.count
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	fmt.Fprintf(p.out, "\n%s\n", title)
+	for i, c := range ranked[:min(statsTop, len(ranked))] {
+		fmt.Fprintf(p.out, "  %2d. %-40s %4d\n", i+1, c.name, c.count)
+	}
+}
+
+// printFeatures prints the average of each audio feature.
+func (p *playlistStats) printFeatures(features map[spotify.ID]*spotify.AudioFeatures) {
+	if len(features) == 0 {
+		return
+	}
+	var sum spotify.AudioFeatures
+	for _, f := range features {
+		sum.Danceability += f.Danceability
+		sum.Energy += f.Energy
+		sum.Valence += f.Valence
+		sum.Acousticness += f.Acousticness
+		sum.Instrumentalness += f.Instrumentalness
+		sum.Speechiness += f.Speechiness
+		sum.Liveness += f.Liveness
+		sum.Tempo += f.Tempo
+		sum.Loudness += f.Loudness
+	}
+	n := float32(len(features))
+	fmt.Fprintf(p.out, "\nAverage audio features (%d tracks)\n", len(features))
+	for _, f := range []struct {
+		name  string
+		value float32
+	}{
+		{"danceability", sum.Danceability / n},
+		{"energy", sum.Energy / n},
+		{"valence", sum.Valence / n},
+		{"acousticness", sum.Acousticness / n},
+		{"instrumentalness", sum.Instrumentalness / n},
+		{"speechiness", sum.Speechiness / n},
+		{"liveness", sum.Liveness / n},
+	} {
+		fmt.Fprintf(p.out, "  %-17s %.2f %s\n", f.name, f.value, strings.Repeat("█", int(f.value*20+0.5)))
+	}
+	fmt.Fprintf(p.out, "  %-17s %.0f BPM\n", "tempo", sum.Tempo/n)
+	fmt.Fprintf(p.out, "  %-17s %.1f dB\n", "loudness", sum.Loudness/n)
+}
+
+// printYears prints a histogram of release years, by decade when they span more than
+// 30 years.
+func (p *playlistStats) printYears(years map[int]int) {
+	if len(years) == 0 {
+		return
+	}
+	first, last := 9999, 0
+	for y := range years {
+		first, last = min(first, y), max(last, y)
+	}
+	step, title := 1, "Release years"
+	if last-first > 30 {
+		step, title = 10, "Release decades"
+	}
+	first -= first % step
+	buckets := make(map[int]int)
+	most := 0
+	for y, n := range years {
+		b := y - y%step
+		buckets[b] += n
+		most = max(most, buckets[b])
+	}
+	fmt.Fprintf(p.out, "\n%s\n", title)
+	for b := first; b <= last; b += step {
+		label := fmt.Sprint(b)
+		if step > 1 {
+			label += "s"
+		}
+		line := fmt.Sprintf("  %-6s %4d %s", label, buckets[b], strings.Repeat("█", (buckets[b]*40+most-1)/most))
+		fmt.Fprintln(p.out, strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"fmt"
+	"spotify/internal/config"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// stampSignature ends every stats stamp, marking the playlist as generated by this tool.
+const stampSignature = "by spotify-manager"
+
+// playlistSignature ends the description of every playlist this tool generates, so later
+// runs can tell them from hand-made playlists of the same name.
+const playlistSignature = "[spotify-manager]"
+
+// maxDescriptionLength is the longest playlist description Spotify accepts.
+const maxDescriptionLength = 300
+
+// signed returns description ending with the signature, shortened to fit if needed.
+func signed(description string) string {
+	if strings.HasSuffix(description, playlistSignature) {
+		return description
+	}
+	room := maxDescriptionLength - len(playlistSignature) - 1
+	if len(description) > room {
+		description = strings.ToValidUTF8(description[:room], "")
+	}
+	return strings.TrimSpace(description + " " + playlistSignature)
+}
+
+// isGenerated reports whether a playlist description marks it as generated by this tool,
+// by the signature or by the stats stamp of playlists written before signatures.
+func isGenerated(description string) bool {
+	return strings.Contains(description, playlistSignature) || strings.Contains(description, stampSignature)
+}
+
+// PlaylistTemplateData is the data available to playlist name and description templates.
+type PlaylistTemplateData struct {
+	Name       string // source name, for processors that derive playlists from another one
+	Year       int
+	Period     string // label of the period the playlist covers, e.g. "2021", "2021/22" or "2021 Q3"
+	From, To   string // first and last day of a date-range playlist
+	Days       int    // window length of rolling playlists
+	Query      string // filter expression of query playlists
+	Event      string // concert of setlist playlists, e.g. "Radiohead at Glastonbury, Pilton"
+	TrackCount int
+	Duration   string // e.g. "14h 32m"
+	Date       string // generation date, e.g. "2025-03-02"
+}
+
+// playlistTemplates renders the name and description of a generated playlist.
+type playlistTemplates struct {
+	name        *template.Template
+	description *template.Template
+	statsStamp  bool
+}
+
+func newPlaylistTemplates(name, description string, shared config.Playlists) (*playlistTemplates, error) {
+	nameTmpl, err := template.New("name").Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid playlist name template: %w", err)
+	}
+	descTmpl, err := template.New("description").Parse(description)
+	if err != nil {
+		return nil, fmt.Errorf("invalid playlist description template: %w", err)
+	}
+	return &playlistTemplates{name: nameTmpl, description: descTmpl, statsStamp: shared.StatsStamp}, nil
+}
+
+// Render returns the playlist name and description for data, with the stats stamp
+// appended to the description when enabled.
+func (t *playlistTemplates) Render(data PlaylistTemplateData) (string, string, error) {
+	var name, description strings.Builder
+	if err := t.name.Execute(&name, data); err != nil {
+		return "", "", fmt.Errorf("could not render playlist name: %w", err)
+	}
+	if err := t.description.Execute(&description, data); err != nil {
+		return "", "", fmt.Errorf("could not render playlist description: %w", err)
+	}
+	if t.statsStamp {
+		fmt.Fprintf(&description, " · %d tracks · %s · updated %s %s", data.TrackCount, data.Duration, data.Date, stampSignature)
+	}
+	return name.String(), strings.TrimSpace(description.String()), nil
+}
+
+// formatDuration renders a duration as hours and minutes, e.g. "14h 32m".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"spotify/internal/folders"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// PlaylistUnfollowOptions selects the playlists to unfollow.
+type PlaylistUnfollowOptions struct {
+	// Pattern is a glob matched against playlist names, or a regular expression with
+	// Regex. Folder prefixes may be left out.
+	Pattern string
+	Regex   bool
+	// Generated keeps only the playlists whose description marks them as generated by
+	// this tool.
+	Generated bool
+	// DryRun lists the playlists without unfollowing them.
+	DryRun bool
+	// Yes skips the confirmation.
+	Yes bool
+}
+
+type playlistUnfollower struct {
+	client SpotifyClient
+	in     *bufio.Reader
+	out    io.Writer
+	logger *log.Logger
+	opts   PlaylistUnfollowOptions
+	match  func(name string) bool
+}
+
+// NewPlaylistUnfollower returns a Processor that unfollows the playlists in the library
+// whose name matches a pattern. Unfollowing a playlist the user owns deletes it. The
+// matches are listed and confirmed once on in, unless opts.Yes is set.
+func NewPlaylistUnfollower(client SpotifyClient, in io.Reader, out io.Writer, logger *log.Logger, opts PlaylistUnfollowOptions) (Processor, error) {
+	if opts.Pattern == "" {
+		return nil, fmt.Errorf("a name pattern is needed")
+	}
+	var match func(name string) bool
+	if opts.Regex {
+		re, err := regexp.Compile(opts.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		match = re.MatchString
+	} else {
+		if _, err := path.Match(opts.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", opts.Pattern, err)
+		}
+		match = func(name string) bool {
+			ok, _ := path.Match(opts.Pattern, name)
+			return ok
+		}
+	}
+	return &playlistUnfollower{client: client, in: bufio.NewReader(in), out: out, logger: logger, opts: opts, match: match}, nil
+}
+
+// Run lists the matching playlists and unfollows them once confirmed.
+func (p *playlistUnfollower) Run(ctx context.Context) error {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	var matched []spotify.SimplePlaylist
+	offset := 0
+	for {
+		page, err := p.client.GetPlaylistsForUser(ctx, user.ID, spotify.Limit(50), spotify.Offset(offset))
+		if err != nil {
+			return fmt.Errorf("failed to get user playlists: %w", err)
+		}
+		if len(page.Playlists) == 0 {
+			break
+		}
+		for _, pl := range page.Playlists {
+			if p.selects(pl) {
+				matched = append(matched, pl)
+			}
+		}
+		offset += len(page.Playlists)
+	}
+	if len(matched) == 0 {
+		fmt.Fprintf(p.out, "No playlists match '%s'.\n", p.opts.Pattern)
+		return nil
+	}
+
+	fmt.Fprintf(p.out, "\n%d playlists match '%s':\n", len(matched), p.opts.Pattern)
+	for _, pl := range matched {
+		action := "unfollow"
+		if pl.Owner.ID == user.ID {
+			action = "delete"
+		}
+		fmt.Fprintf(p.out, "   - %s (%d tracks, %s) — %s\n", pl.Name, pl.Tracks.Total, pl.URI, action)
+	}
+	if p.opts.DryRun {
+		fmt.Fprintln(p.out, "\n🧪 Dry run: nothing was changed.")
+		return nil
+	}
+	if !p.opts.Yes && !p.confirm(len(matched)) {
+		p.logger.Println("Nothing was changed.")
+		return nil
+	}
+	for _, pl := range matched {
+		if err := p.client.UnfollowPlaylist(ctx, pl.ID); err != nil {
+			return fmt.Errorf("failed to unfollow '%s': %w", pl.Name, err)
+		}
+		p.logger.Printf("✅ Unfollowed '%s'.", pl.Name)
+	}
+	return nil
+}
+
+// selects reports whether pl matches the pattern and, with Generated, was generated.
+func (p *playlistUnfollower) selects(pl spotify.SimplePlaylist) bool {
+	if p.opts.Generated && !isGenerated(pl.Description) {
+		return false
+	}
+	return p.match(pl.Name) || p.match(folders.StripPrefix(pl.Name))
+}
+
+// confirm asks once for all the playlists.
+func (p *playlistUnfollower) confirm(n int) bool {
+	fmt.Fprintf(p.out, "\n⚠️  Unfollow these %d playlists? Your own are deleted. [y/N] ", n)
+	answer, _ := p.in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
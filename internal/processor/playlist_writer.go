@@ -0,0 +1,348 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"spotify/internal/folders"
+	"spotify/internal/store"
+	"sync"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// ErrPlaylistChanged is returned when a playlist's snapshot moves underneath a rewrite,
+// meaning someone else modified it while we were writing.
+var ErrPlaylistChanged = errors.New("playlist was modified concurrently")
+
+// playlistWriter rewrites playlist contents using snapshot IDs to detect concurrent edits.
+type playlistWriter struct {
+	client SpotifyClient
+	logger *log.Logger
+	// unsigned leaves the signature out of descriptions, for copies of hand-made playlists.
+	unsigned bool
+	// appenders is how many batches are appended at once after the first. Above 1 the
+	// batches end up in the order their requests finish.
+	appenders int
+}
+
+// writeBatchSize is the most tracks a playlist write may carry.
+const writeBatchSize = 100
+
+func newPlaylistWriter(client SpotifyClient, logger *log.Logger) *playlistWriter {
+	return &playlistWriter{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Find searches the user's own playlists for a playlist by name using manual pagination.
+// A playlist generated by this tool is preferred over hand-made ones of the same name.
+func (w *playlistWriter) Find(ctx context.Context, userID, name string) (*spotify.SimplePlaylist, error) {
+	w.logger.Printf("Searching for existing playlist named '%s'...", name)
+	limit := 50
+	offset := 0
+
+	var found *spotify.SimplePlaylist
+	for {
+		page, err := w.client.GetPlaylistsForUser(ctx, userID, spotify.Limit(limit), spotify.Offset(offset))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user playlists: %w", err)
+		}
+
+		for _, pl := range page.Playlists {
+			// Folder prefixes are ignored so renamed playlists keep being found.
+			if folders.StripPrefix(pl.Name) != name || pl.Owner.ID != userID {
+				continue
+			}
+			if isGenerated(pl.Description) {
+				w.logger.Printf("Found existing playlist: '%s' (ID: %s)", pl.Name, pl.ID)
+				return &pl, nil
+			}
+			if found == nil {
+				found = &pl
+			}
+		}
+		if len(page.Playlists) == 0 {
+			break
+		}
+		offset += len(page.Playlists)
+	}
+
+	if found == nil {
+		w.logger.Println("No existing playlist found.")
+		return nil, nil
+	}
+	w.logger.Printf("Found existing playlist: '%s' (ID: %s), not generated by this tool.", found.Name, found.ID)
+	return found, nil
+}
+
+// playlistAccess is who can see and edit a playlist.
+type playlistAccess struct {
+	public, collaborative bool
+}
+
+// parseVisibility parses a configured visibility: "private" (or ""), "public" or
+// "collaborative". Collaborative playlists are private, as Spotify requires.
+func parseVisibility(visibility string) (playlistAccess, error) {
+	switch visibility {
+	case "", "private":
+		return playlistAccess{}, nil
+	case "public":
+		return playlistAccess{public: true}, nil
+	case "collaborative":
+		return playlistAccess{collaborative: true}, nil
+	default:
+		return playlistAccess{}, fmt.Errorf("unknown visibility '%s' (available: private, public, collaborative)", visibility)
+	}
+}
+
+// Ensure returns the ID of the user's playlist called name, creating it as a private
+// playlist if needed. An existing playlist gets its description updated to match. The
+// description is signed, marking the playlist as generated by this tool.
+func (w *playlistWriter) Ensure(ctx context.Context, userID, name, description string) (spotify.ID, error) {
+	return w.ensure(ctx, userID, name, description, nil)
+}
+
+// EnsureAccess is Ensure for playlists with a configured visibility: new playlists are
+// created with it, and existing ones made public or private to match.
+func (w *playlistWriter) EnsureAccess(ctx context.Context, userID, name, description string, access playlistAccess) (spotify.ID, error) {
+	return w.ensure(ctx, userID, name, description, &access)
+}
+
+// ensure implements Ensure, leaving the visibility of existing playlists alone when
+// access is nil.
+func (w *playlistWriter) ensure(ctx context.Context, userID, name, description string, access *playlistAccess) (spotify.ID, error) {
+	existing, err := w.Find(ctx, userID, name)
+	if err != nil {
+		return "", err
+	}
+	if !w.unsigned {
+		description = signed(description)
+	}
+
+	if existing != nil {
+		w.logger.Printf("Found existing playlist: '%s'. Its contents will be replaced.", existing.Name)
+		if existing.Description != description {
+			if err := w.client.ChangePlaylistDescription(ctx, existing.ID, description); err != nil {
+				w.logger.Printf("⚠️  Could not update description for '%s': %v", name, err)
+			}
+		}
+		if access != nil {
+			w.updateAccess(ctx, existing, *access)
+		}
+		return existing.ID, nil
+	}
+
+	create := playlistAccess{}
+	if access != nil {
+		create = *access
+	}
+	created, err := w.client.CreatePlaylistForUser(ctx, userID, name, description, create.public, create.collaborative)
+	if err != nil {
+		return "", fmt.Errorf("failed to create playlist '%s': %w", name, err)
+	}
+	w.logger.Printf("✅ Created new playlist: '%s'", created.Name)
+	return created.ID, nil
+}
+
+// updateAccess makes an existing playlist public or private to match access. A failure
+// is only logged, since the playlist can still be written.
+func (w *playlistWriter) updateAccess(ctx context.Context, existing *spotify.SimplePlaylist, access playlistAccess) {
+	if existing.Collaborative != access.collaborative {
+		w.logger.Printf("⚠️  Spotify's API can't change whether '%s' is collaborative; change it in the app.", existing.Name)
+		return
+	}
+	if existing.IsPublic == access.public {
+		return
+	}
+	if err := w.client.ChangePlaylistAccess(ctx, existing.ID, access.public); err != nil {
+		w.logger.Printf("⚠️  Could not change the visibility of '%s': %v", existing.Name, err)
+		return
+	}
+	visibility := "private"
+	if access.public {
+		visibility = "public"
+	}
+	w.logger.Printf("✅ Made '%s' %s.", existing.Name, visibility)
+}
+
+// Replace sets the playlist's items to exactly trackIDs. The first batch replaces the
+// playlist in a single call, so the playlist is never left empty, and the rest are
+// appended in order. Before each append the playlist snapshot is compared with the one
+// returned by our previous write, and the rewrite aborts with ErrPlaylistChanged on mismatch.
+func (w *playlistWriter) Replace(ctx context.Context, playlistID spotify.ID, trackIDs []spotify.ID) error {
+	return w.ReplaceResumable(ctx, playlistID, trackIDs, store.PartialWrite{}, nil)
+}
+
+// ReplaceResumable is Replace for rewrites that may be interrupted. If from describes an
+// earlier rewrite of the same tracks and the playlist is still at its snapshot, writing
+// continues with the next batch; otherwise it starts over. progress, if non-nil, is
+// called after every batch. With appenders above 1 the batches after the first are
+// appended at once and progress is only reported for the first: the snapshot moves with
+// every append, so an interrupted write starts over.
+func (w *playlistWriter) ReplaceResumable(ctx context.Context, playlistID spotify.ID, trackIDs []spotify.ID, from store.PartialWrite, progress func(store.PartialWrite)) error {
+	batchSize := writeBatchSize
+	report := func(written int, snapshotID string) {
+		if progress != nil {
+			progress(store.PartialWrite{Total: len(trackIDs), Written: written, SnapshotID: snapshotID})
+		}
+	}
+
+	end := 0
+	var snapshotID string
+	if from.Total == len(trackIDs) && from.Written > 0 && from.Written < len(trackIDs) {
+		err := w.checkSnapshot(ctx, playlistID, from.SnapshotID)
+		switch {
+		case err == nil:
+			w.logger.Printf("  Resuming after %d tracks already written...", from.Written)
+			end, snapshotID = from.Written, from.SnapshotID
+		case errors.Is(err, ErrPlaylistChanged):
+			w.logger.Println("  Playlist changed since the checkpoint, rewriting it from the start...")
+		default:
+			return err
+		}
+	}
+	if end == 0 {
+		end = min(batchSize, len(trackIDs))
+		w.logger.Printf("  Replacing playlist contents with first %d tracks...", end)
+		var err error
+		snapshotID, err = w.client.ReplacePlaylistItems(ctx, playlistID, trackURIs(trackIDs[:end])...)
+		if err != nil {
+			return fmt.Errorf("failed to replace playlist items: %w", err)
+		}
+		report(end, snapshotID)
+	}
+
+	if w.appenders > 1 && len(trackIDs)-end > batchSize {
+		if err := w.appendUnordered(ctx, playlistID, trackIDs[end:], snapshotID); err != nil {
+			return err
+		}
+		end = len(trackIDs)
+	}
+	for i := end; i < len(trackIDs); i += batchSize {
+		end := min(i+batchSize, len(trackIDs))
+		if err := w.checkSnapshot(ctx, playlistID, snapshotID); err != nil {
+			return err
+		}
+		batch := trackIDs[i:end]
+		w.logger.Printf("  Adding batch of %d tracks...", len(batch))
+		var err error
+		snapshotID, err = w.client.AddTracksToPlaylist(ctx, playlistID, batch...)
+		if err != nil {
+			return fmt.Errorf("failed to add tracks to playlist: %w", err)
+		}
+		report(end, snapshotID)
+	}
+	w.logger.Printf("✅ Finished writing all %d tracks.", len(trackIDs))
+	return nil
+}
+
+// appendUnordered adds trackIDs in batches, w.appenders at a time. Every append moves
+// the snapshot, so it's only checked before starting; the first failing batch stops the
+// ones not yet sent.
+func (w *playlistWriter) appendUnordered(ctx context.Context, playlistID spotify.ID, trackIDs []spotify.ID, snapshotID string) error {
+	if err := w.checkSnapshot(ctx, playlistID, snapshotID); err != nil {
+		return err
+	}
+	var batches [][]spotify.ID
+	for i := 0; i < len(trackIDs); i += writeBatchSize {
+		batches = append(batches, trackIDs[i:min(i+writeBatchSize, len(trackIDs))])
+	}
+	w.logger.Printf("  Adding %d tracks in %d batches, %d at a time...", len(trackIDs), len(batches), w.appenders)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(batches))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(w.appenders, len(batches)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if _, errs[i] = w.client.AddTracksToPlaylist(ctx, playlistID, batches[i]...); errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range batches {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to add tracks to playlist: %w", err)
+	}
+	return ctx.Err()
+}
+
+// checkSnapshot verifies the playlist is still at the snapshot produced by our last write.
+func (w *playlistWriter) checkSnapshot(ctx context.Context, playlistID spotify.ID, expected string) error {
+	playlist, err := w.client.GetPlaylist(ctx, playlistID, spotify.Fields("snapshot_id"))
+	if err != nil {
+		return fmt.Errorf("failed to get playlist snapshot: %w", err)
+	}
+	if playlist.SnapshotID != expected {
+		return fmt.Errorf("%w: expected snapshot %s, found %s", ErrPlaylistChanged, expected, playlist.SnapshotID)
+	}
+	return nil
+}
+
+// trackURIs converts track IDs into the URIs expected by the playlist items endpoints.
+func trackURIs(ids []spotify.ID) []spotify.URI {
+	uris := make([]spotify.URI, len(ids))
+	for i, id := range ids {
+		uris[i] = spotify.URI("spotify:track:" + id)
+	}
+	return uris
+}
+
+// playlistMismatch describes how a playlist differs from what was written to it.
+type playlistMismatch struct {
+	Expected, Found int
+	// Missing were written but aren't in the playlist; Unexpected are in the playlist
+	// but weren't written, or more often than written.
+	Missing, Unexpected []spotify.ID
+}
+
+func (m *playlistMismatch) String() string {
+	return fmt.Sprintf("expected %d tracks, found %d (%d missing, %d unexpected)", m.Expected, m.Found, len(m.Missing), len(m.Unexpected))
+}
+
+// Verify re-fetches the playlist and compares it with trackIDs, the tracks just written.
+// Spotify can accept a write and still drop tracks, e.g. ones no longer available. It
+// returns nil if the playlist holds exactly trackIDs; order isn't checked.
+func (w *playlistWriter) Verify(ctx context.Context, playlistID spotify.ID, trackIDs []spotify.ID) (*playlistMismatch, error) {
+	found, err := fetchPlaylistTrackIDs(ctx, w.client, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-fetch playlist for verification: %w", err)
+	}
+	counts := make(map[spotify.ID]int, len(trackIDs))
+	for _, id := range trackIDs {
+		counts[id]++
+	}
+	m := &playlistMismatch{Expected: len(trackIDs), Found: len(found)}
+	for _, id := range found {
+		if counts[id] == 0 {
+			m.Unexpected = append(m.Unexpected, id)
+			continue
+		}
+		counts[id]--
+	}
+	for _, id := range trackIDs {
+		if counts[id] > 0 {
+			m.Missing = append(m.Missing, id)
+			counts[id]--
+		}
+	}
+	if len(m.Missing) == 0 && len(m.Unexpected) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
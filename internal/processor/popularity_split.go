@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// likedSongsName stands for the liked songs in the templates of playlists derived from
+// a source.
+const likedSongsName = "Liked Songs"
+
+type popularitySplitter struct {
+	client    SpotifyClient
+	registry  CoverRegistry
+	logger    *log.Logger
+	imgGen    ImageGenerator
+	writer    *playlistWriter
+	hits      *playlistTemplates
+	deepCuts  *playlistTemplates
+	cfg       config.Popularity
+	sourceRef string
+}
+
+// NewPopularitySplitter returns a Processor that splits the liked songs, or the playlist
+// sourceRef refers to by URL, URI, ID or name, into a playlist of hits, with a Spotify
+// popularity of at least cfg.Threshold, and one of deep cuts below it.
+func NewPopularitySplitter(client SpotifyClient, registry CoverRegistry, logger *log.Logger, imgGen ImageGenerator, cfg config.Popularity, shared config.Playlists, sourceRef string) (Processor, error) {
+	if cfg.Threshold < 1 || cfg.Threshold > 100 {
+		return nil, fmt.Errorf("threshold must be between 1 and 100, got %d", cfg.Threshold)
+	}
+	hits, err := newPlaylistTemplates(cfg.HitsNameTemplate, cfg.HitsDescriptionTemplate, shared)
+	if err != nil {
+		return nil, fmt.Errorf("hits playlist: %w", err)
+	}
+	deepCuts, err := newPlaylistTemplates(cfg.DeepCutsNameTemplate, cfg.DeepCutsDescriptionTemplate, shared)
+	if err != nil {
+		return nil, fmt.Errorf("deep cuts playlist: %w", err)
+	}
+	return &popularitySplitter{
+		client:    client,
+		registry:  registry,
+		logger:    logger,
+		imgGen:    imgGen,
+		writer:    newPlaylistWriter(client, logger),
+		hits:      hits,
+		deepCuts:  deepCuts,
+		cfg:       cfg,
+		sourceRef: sourceRef,
+	}, nil
+}
+
+// Run reads the source and writes both playlists, keeping the source's order.
+func (p *popularitySplitter) Run(ctx context.Context) error {
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	name, tracks, err := p.source(ctx)
+	if err != nil {
+		return err
+	}
+	var hits, deepCuts []spotify.FullTrack
+	for _, t := range tracks {
+		if int(t.Popularity) >= p.cfg.Threshold {
+			hits = append(hits, t)
+		} else {
+			deepCuts = append(deepCuts, t)
+		}
+	}
+	p.logger.Printf("%d of %d songs of %s have a popularity of at least %d.", len(hits), len(tracks), name, p.cfg.Threshold)
+	covers := newCoverUploader(p.client, p.imgGen, p.registry, p.logger)
+	today := time.Now().Format(time.DateOnly)
+	for _, part := range []struct {
+		templates *playlistTemplates
+		label     string
+		tracks    []spotify.FullTrack
+	}{
+		{p.hits, "Hits", hits},
+		{p.deepCuts, "Deep Cuts", deepCuts},
+	} {
+		var length time.Duration
+		for _, t := range part.tracks {
+			length += time.Duration(t.Duration) * time.Millisecond
+		}
+		playlistName, description, err := part.templates.Render(PlaylistTemplateData{
+			Name:       name,
+			TrackCount: len(part.tracks),
+			Duration:   formatDuration(length),
+			Date:       today,
+		})
+		if err != nil {
+			return err
+		}
+		playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+		if err != nil {
+			return err
+		}
+		covers.Upload(ctx, playlistID, CoverSpec{Name: playlistName, Label: part.label, Subtitle: p.cfg.CoverSubtitle, Tracks: part.tracks})
+		if err := p.writer.Replace(ctx, playlistID, trackIDs(part.tracks)); err != nil {
+			return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+		}
+		p.logger.Printf("✅ Wrote %d songs to '%s'.", len(part.tracks), playlistName)
+	}
+	return nil
+}
+
+// source returns the name and tracks of the liked songs or the chosen playlist.
+func (p *popularitySplitter) source(ctx context.Context) (string, []spotify.FullTrack, error) {
+	if p.sourceRef == "" {
+		liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to fetch liked tracks: %w", err)
+		}
+		return likedSongsName, fullTracks(uniqueSavedTracks(liked)), nil
+	}
+	playlistID, name, err := resolvePlaylist(ctx, p.client, p.writer, p.sourceRef)
+	if err != nil {
+		return "", nil, err
+	}
+	tracks, err := fetchPlaylistTracks(ctx, p.client, playlistID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read '%s': %w", name, err)
+	}
+	return name, tracks, nil
+}
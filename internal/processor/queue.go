@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// QueueOptions selects the tracks the queue command adds to the playback queue.
+type QueueOptions struct {
+	// Playlist is the URL, URI or ID of a playlist, or the name of one of the user's own.
+	Playlist string
+	// Query is a filter expression over the liked songs, used instead of Playlist.
+	Query string
+	// Shuffle queues the tracks in random order.
+	Shuffle bool
+	// Limit caps the number of tracks queued; 0 queues them all.
+	Limit int
+	// Device is part of the name of the device to queue on; empty means the active one.
+	Device string
+}
+
+type queuer struct {
+	client SpotifyClient
+	logger *log.Logger
+	writer *playlistWriter
+	query  *trackQuery
+	opts   QueueOptions
+}
+
+// NewQueuer returns a Processor that adds the tracks of a playlist, or the liked songs
+// matching a query, to the playback queue one by one, which Spotify's apps can't do in
+// bulk. Dates in the query are read in loc.
+func NewQueuer(client SpotifyClient, tags TagSource, logger *log.Logger, loc *time.Location, opts QueueOptions) (Processor, error) {
+	if (opts.Playlist == "") == (opts.Query == "") {
+		return nil, fmt.Errorf("give either a playlist or a query")
+	}
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("the limit can't be negative")
+	}
+	q := &queuer{client: client, logger: logger, writer: newPlaylistWriter(client, logger), opts: opts}
+	if opts.Query != "" {
+		query, err := newTrackQuery(opts.Query, loc, tags)
+		if err != nil {
+			return nil, err
+		}
+		q.query = query
+	}
+	return q, nil
+}
+
+// Run selects the tracks and queues them on the chosen device.
+func (p *queuer) Run(ctx context.Context) error {
+	source, tracks, err := p.tracks(ctx)
+	if err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		p.logger.Printf("✅ Nothing to queue from %s.", source)
+		return ErrNothingToDo
+	}
+	if p.opts.Shuffle {
+		rand.Shuffle(len(tracks), func(i, j int) { tracks[i], tracks[j] = tracks[j], tracks[i] })
+	}
+	if p.opts.Limit > 0 && len(tracks) > p.opts.Limit {
+		tracks = tracks[:p.opts.Limit]
+	}
+
+	devices, err := p.client.PlayerDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	device, err := chooseDevice(devices, p.opts.Device)
+	if err != nil {
+		return err
+	}
+	opt := &spotify.PlayOptions{DeviceID: &device.ID}
+	for i, t := range tracks {
+		if err := p.client.QueueSongOpt(ctx, t.ID, opt); err != nil {
+			return fmt.Errorf("queued %d of %d tracks, then failed on '%s': %w", i, len(tracks), t.Name, err)
+		}
+		if (i+1)%25 == 0 {
+			p.logger.Printf("Queued %d/%d tracks...", i+1, len(tracks))
+		}
+	}
+	p.logger.Printf("✅ Queued %d tracks of %s on %s.", len(tracks), source, device.Name)
+	return nil
+}
+
+// tracks returns a description of the source and its tracks, in order.
+func (p *queuer) tracks(ctx context.Context) (string, []spotify.FullTrack, error) {
+	if p.query != nil {
+		matched, err := p.query.selectTracks(ctx, p.client, p.logger)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("the songs matching '%s'", p.opts.Query), fullTracks(matched), nil
+	}
+	playlistID, name, err := resolvePlaylist(ctx, p.client, p.writer, p.opts.Playlist)
+	if err != nil {
+		return "", nil, err
+	}
+	tracks, err := fetchPlaylistTracks(ctx, p.client, playlistID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read '%s': %w", name, err)
+	}
+	return fmt.Sprintf("'%s'", name), tracks, nil
+}
@@ -0,0 +1,46 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rough API costs used to estimate how long a run will take.
+const (
+	likedPageSize = 50
+	writeBatch    = 100
+	// requestsPerPlaylist covers finding or creating a playlist, updating its description
+	// and uploading its cover.
+	requestsPerPlaylist = 4
+	// sustainedRequestRate is a request rate Spotify tolerates over a long run without
+	// answering with 429s.
+	sustainedRequestRate = 2.0 // per second
+)
+
+// quotaEstimate is the expected cost of a run.
+type quotaEstimate struct {
+	Requests int
+	Duration time.Duration
+}
+
+// String renders the estimate, e.g. "~1200 requests, about 10m".
+func (e quotaEstimate) String() string {
+	return fmt.Sprintf("~%d requests, about %s", e.Requests, e.Duration.Round(time.Minute))
+}
+
+// estimateSort predicts the cost of sorting a library of tracks liked songs into
+// playlists. Every write batch after the first also checks the playlist snapshot.
+func estimateSort(tracks, playlists int) quotaEstimate {
+	fetch := ceilDiv(tracks, likedPageSize)
+	batches := ceilDiv(tracks, writeBatch)
+	snapshots := max(batches-playlists, 0)
+	requests := fetch + batches + snapshots + playlists*requestsPerPlaylist
+	return quotaEstimate{
+		Requests: requests,
+		Duration: time.Duration(float64(requests) / sustainedRequestRate * float64(time.Second)),
+	}
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type rollingPlaylist struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	cfg       config.Rolling
+}
+
+// NewRollingPlaylist returns a Processor that maintains a single playlist of the songs
+// liked within the last cfg.Days days. Each run appends new likes and removes expired
+// ones instead of rewriting the playlist, so it suits frequent scheduled runs.
+func NewRollingPlaylist(client SpotifyClient, registry CoverRegistry, logger *log.Logger, imgGen ImageGenerator, cfg config.Rolling, shared config.Playlists) (Processor, error) {
+	if cfg.Days <= 0 {
+		return nil, fmt.Errorf("days must be positive, got %d", cfg.Days)
+	}
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &rollingPlaylist{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, registry, logger),
+		templates: templates,
+		cfg:       cfg,
+	}, nil
+}
+
+// Run brings the playlist in line with the current window.
+func (p *rollingPlaylist) Run(ctx context.Context) error {
+	cutoff := time.Now().AddDate(0, 0, -p.cfg.Days)
+	var recent []spotify.SavedTrack
+	err := pageLikedTracks(ctx, p.client, p.logger, 0, func(page []spotify.SavedTrack, next int) bool {
+		for _, t := range page {
+			addedAt, err := time.Parse(time.RFC3339, t.AddedAt)
+			if err != nil || addedAt.Before(cutoff) {
+				// Liked songs come newest first, so everything after this is older.
+				return err != nil
+			}
+			recent = append(recent, t)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	recent = sortByAdded(recent, false)
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		Days:       p.cfg.Days,
+		TrackCount: len(recent),
+		Duration:   formatDuration(totalDuration(recent)),
+		Date:       time.Now().Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{
+		Name:     playlistName,
+		Label:    fmt.Sprintf("%d days", p.cfg.Days),
+		Subtitle: p.cfg.CoverSubtitle,
+		Tracks:   fullTracks(recent),
+	})
+
+	current, err := fetchPlaylistTrackIDs(ctx, p.client, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to read playlist '%s': %w", playlistName, err)
+	}
+	wanted := make(map[spotify.ID]struct{}, len(recent))
+	for _, t := range recent {
+		wanted[t.ID] = struct{}{}
+	}
+	present := make(map[spotify.ID]struct{}, len(current))
+	var expired []spotify.ID
+	for _, id := range current {
+		present[id] = struct{}{}
+		if _, ok := wanted[id]; !ok {
+			expired = append(expired, id)
+		}
+	}
+	var added []spotify.ID
+	for _, t := range recent {
+		if _, ok := present[t.ID]; !ok {
+			added = append(added, t.ID)
+		}
+	}
+
+	err = inBatches(expired, 100, func(batch []spotify.ID) error {
+		_, err := p.client.RemoveTracksFromPlaylist(ctx, playlistID, batch...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove expired tracks: %w", err)
+	}
+	err = inBatches(added, 100, func(batch []spotify.ID) error {
+		_, err := p.client.AddTracksToPlaylist(ctx, playlistID, batch...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add new tracks: %w", err)
+	}
+	p.logger.Printf("✅ '%s': %d new, %d expired, %d tracks in total.", playlistName, len(added), len(expired), len(recent))
+	return nil
+}
@@ -0,0 +1,271 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// SearchOptions selects what the search command looks for and does with the results.
+type SearchOptions struct {
+	// Types are "track", "artist" and "album".
+	Types []string
+	// Limit is how many results of each type are shown.
+	Limit int
+	// Pick chooses the results acted on, as row numbers like "1,3" or "all".
+	Pick string
+	// AddTo is the playlist the picked tracks are added to, created if needed; empty
+	// skips it.
+	AddTo string
+	// Like likes the picked tracks, saves the picked albums and follows the picked
+	// artists.
+	Like bool
+}
+
+var searchTypes = map[string]spotify.SearchType{
+	"track":  spotify.SearchTypeTrack,
+	"artist": spotify.SearchTypeArtist,
+	"album":  spotify.SearchTypeAlbum,
+}
+
+type searcher struct {
+	client SpotifyClient
+	out    io.Writer
+	logger *log.Logger
+	writer *playlistWriter
+	query  string
+	types  spotify.SearchType
+	opts   SearchOptions
+}
+
+// NewSearcher returns a Processor that searches the Spotify catalog for query and prints
+// the results as a table per type, optionally adding the picked tracks to a playlist or
+// saving the picked results to the library.
+func NewSearcher(client SpotifyClient, out io.Writer, logger *log.Logger, query string, opts SearchOptions) (Processor, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("nothing to search for")
+	}
+	if opts.Limit < 1 || opts.Limit > 50 {
+		return nil, fmt.Errorf("limit must be between 1 and 50, got %d", opts.Limit)
+	}
+	var types spotify.SearchType
+	for _, name := range opts.Types {
+		t, ok := searchTypes[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown search type '%s' (available: track, artist, album)", name)
+		}
+		types |= t
+	}
+	if opts.AddTo != "" && types&spotify.SearchTypeTrack == 0 {
+		return nil, fmt.Errorf("--add-to adds tracks; search for the track type too")
+	}
+	return &searcher{client: client, out: out, logger: logger, writer: newPlaylistWriter(client, logger), query: query, types: types, opts: opts}, nil
+}
+
+// Run searches, prints the results and applies the follow-up actions.
+func (p *searcher) Run(ctx context.Context) error {
+	result, err := p.client.Search(ctx, p.query, p.types, spotify.Limit(p.opts.Limit))
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	var tracks []spotify.FullTrack
+	var artists []spotify.FullArtist
+	var albums []spotify.SimpleAlbum
+	if result.Tracks != nil {
+		tracks = result.Tracks.Tracks
+	}
+	if result.Artists != nil {
+		artists = result.Artists.Artists
+	}
+	if result.Albums != nil {
+		albums = result.Albums.Albums
+	}
+	if p.types&spotify.SearchTypeTrack != 0 {
+		p.printTracks(tracks)
+	}
+	if p.types&spotify.SearchTypeArtist != 0 {
+		p.printArtists(artists)
+	}
+	if p.types&spotify.SearchTypeAlbum != 0 {
+		p.printAlbums(albums)
+	}
+	if p.opts.AddTo == "" && !p.opts.Like {
+		return nil
+	}
+
+	if tracks, err = pickResults(tracks, p.opts.Pick); err != nil {
+		return err
+	}
+	if artists, err = pickResults(artists, p.opts.Pick); err != nil {
+		return err
+	}
+	if albums, err = pickResults(albums, p.opts.Pick); err != nil {
+		return err
+	}
+	if p.opts.AddTo != "" {
+		if err := p.addTo(ctx, tracks); err != nil {
+			return err
+		}
+	}
+	if p.opts.Like {
+		return p.like(ctx, tracks, artists, albums)
+	}
+	return nil
+}
+
+func (p *searcher) printTracks(tracks []spotify.FullTrack) {
+	fmt.Fprintf(p.out, "\nTracks (%d)\n", len(tracks))
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTITLE\tARTISTS\tALBUM\tPOPULARITY\tURI")
+	for i, t := range tracks {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", i+1, t.Name, artistNames(t.Artists), albumLabel(t.Album), t.Popularity, t.URI)
+	}
+	w.Flush()
+}
+
+func (p *searcher) printArtists(artists []spotify.FullArtist) {
+	fmt.Fprintf(p.out, "\nArtists (%d)\n", len(artists))
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tNAME\tGENRES\tFOLLOWERS\tPOPULARITY\tURI")
+	for i, a := range artists {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\n", i+1, a.Name, strings.Join(a.Genres, ", "), a.Followers.Count, a.Popularity, a.URI)
+	}
+	w.Flush()
+}
+
+func (p *searcher) printAlbums(albums []spotify.SimpleAlbum) {
+	fmt.Fprintf(p.out, "\nAlbums (%d)\n", len(albums))
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tALBUM\tARTISTS\tTYPE\tTRACKS\tURI")
+	for i, a := range albums {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", i+1, albumLabel(a), artistNames(a.Artists), a.AlbumType, a.TotalTracks, a.URI)
+	}
+	w.Flush()
+}
+
+// albumLabel names an album with its release year.
+func albumLabel(album spotify.SimpleAlbum) string {
+	if len(album.ReleaseDate) < 4 {
+		return album.Name
+	}
+	return fmt.Sprintf("%s (%s)", album.Name, album.ReleaseDate[:4])
+}
+
+// pickResults returns the results chosen by spec, as row numbers or "all".
+func pickResults[T any](results []T, spec string) ([]T, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+	rows, err := parseRows(spec, len(results))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --pick: %w", err)
+	}
+	picked := make([]T, len(rows))
+	for i, row := range rows {
+		picked[i] = results[row]
+	}
+	return picked, nil
+}
+
+// addTo adds tracks to the playlist named by --add-to, creating it if needed.
+func (p *searcher) addTo(ctx context.Context, tracks []spotify.FullTrack) error {
+	if len(tracks) == 0 {
+		p.logger.Println("No tracks found to add.")
+		return nil
+	}
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlist, err := p.writer.Find(ctx, user.ID, p.opts.AddTo)
+	if err != nil {
+		return err
+	}
+	var playlistID spotify.ID
+	if playlist != nil {
+		playlistID = playlist.ID
+	} else {
+		created, err := p.client.CreatePlaylistForUser(ctx, user.ID, p.opts.AddTo, "", false, false)
+		if err != nil {
+			return fmt.Errorf("failed to create playlist '%s': %w", p.opts.AddTo, err)
+		}
+		p.logger.Printf("✅ Created new playlist: '%s'", created.Name)
+		playlistID = created.ID
+	}
+	if _, err := p.client.AddTracksToPlaylist(ctx, playlistID, trackIDs(tracks)...); err != nil {
+		return fmt.Errorf("failed to add tracks to '%s': %w", p.opts.AddTo, err)
+	}
+	for _, t := range tracks {
+		p.logger.Printf("✅ Added '%s' by %s to '%s'.", t.Name, artistNames(t.Artists), p.opts.AddTo)
+	}
+	return nil
+}
+
+// like saves the picked results to the library.
+func (p *searcher) like(ctx context.Context, tracks []spotify.FullTrack, artists []spotify.FullArtist, albums []spotify.SimpleAlbum) error {
+	if len(tracks) > 0 {
+		if err := p.client.AddTracksToLibrary(ctx, trackIDs(tracks)...); err != nil {
+			return fmt.Errorf("failed to like tracks: %w", err)
+		}
+		for _, t := range tracks {
+			p.logger.Printf("✅ Liked '%s' by %s.", t.Name, artistNames(t.Artists))
+		}
+	}
+	if len(artists) > 0 {
+		ids := make([]spotify.ID, len(artists))
+		for i, a := range artists {
+			ids[i] = a.ID
+		}
+		if err := p.client.FollowArtist(ctx, ids...); err != nil {
+			return fmt.Errorf("failed to follow artists: %w", err)
+		}
+		for _, a := range artists {
+			p.logger.Printf("✅ Followed %s.", a.Name)
+		}
+	}
+	if len(albums) > 0 {
+		ids := make([]spotify.ID, len(albums))
+		for i, a := range albums {
+			ids[i] = a.ID
+		}
+		if err := p.client.AddAlbumsToLibrary(ctx, ids...); err != nil {
+			return fmt.Errorf("failed to save albums: %w", err)
+		}
+		for _, a := range albums {
+			p.logger.Printf("✅ Saved '%s' by %s.", a.Name, artistNames(a.Artists))
+		}
+	}
+	return nil
+}
+
+// parseRows returns the 0-based indexes of the rows given as "1,4-7" or "all", out of n.
+func parseRows(spec string, n int) ([]int, error) {
+	var picked []int
+	if spec == "all" {
+		for i := range n {
+			picked = append(picked, i)
+		}
+		return picked, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err1 := strconv.Atoi(from)
+		last, err2 := first, error(nil)
+		if isRange {
+			last, err2 = strconv.Atoi(to)
+		}
+		if err1 != nil || err2 != nil || first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("invalid rows '%s': use numbers between 1 and %d, e.g. 1,4-7", part, n)
+		}
+		for i := first; i <= last; i++ {
+			picked = append(picked, i-1)
+		}
+	}
+	return picked, nil
+}
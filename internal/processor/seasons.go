@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type seasonPlaylistBuilder struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	cfg       config.Seasons
+	loc       *time.Location
+}
+
+// NewSeasonPlaylistBuilder returns a Processor that groups liked songs by the
+// meteorological season they were liked in, whatever the year, e.g. "Summer Songs".
+// Like dates are read in loc.
+func NewSeasonPlaylistBuilder(client SpotifyClient, registry CoverRegistry, logger *log.Logger, imgGen ImageGenerator, cfg config.Seasons, shared config.Playlists, loc *time.Location) (Processor, error) {
+	switch cfg.Hemisphere {
+	case "", "north", "south":
+	default:
+		return nil, fmt.Errorf("unknown hemisphere '%s' (available: north, south)", cfg.Hemisphere)
+	}
+	if len(cfg.Names) != 4 {
+		return nil, fmt.Errorf("names needs the four seasons, spring first; got %d", len(cfg.Names))
+	}
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &seasonPlaylistBuilder{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, registry, logger),
+		templates: templates,
+		cfg:       cfg,
+		loc:       loc,
+	}, nil
+}
+
+// Run sorts the liked songs into seasons and writes a playlist per season, in the order
+// the songs were liked.
+func (p *seasonPlaylistBuilder) Run(ctx context.Context) error {
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	var bySeason [4][]spotify.SavedTrack
+	for _, t := range liked {
+		addedAt, err := time.Parse(time.RFC3339, t.AddedAt)
+		if err != nil {
+			continue
+		}
+		season := p.seasonOf(addedAt.In(p.loc).Month())
+		bySeason[season] = append(bySeason[season], t)
+	}
+	var seasons []int
+	for season, tracks := range bySeason {
+		if len(tracks) > 0 {
+			seasons = append(seasons, season)
+		}
+	}
+	if len(seasons) == 0 {
+		p.logger.Println("No liked tracks found. Nothing to do.")
+		return ErrNothingToDo
+	}
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	// Covers render in the background while the tracks are written.
+	defer p.covers.Wait()
+	today := time.Now().Format(time.DateOnly)
+	// A failing season doesn't stop the others; failures are reported together at the end.
+	return forEachPlaylist(ctx, p.logger, seasons, func(season int) string { return p.cfg.Names[season] }, func(season int) error {
+		tracks := sortByAdded(bySeason[season], false)
+		name := p.cfg.Names[season]
+		playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+			Name:       name,
+			TrackCount: len(tracks),
+			Duration:   formatDuration(totalDuration(tracks)),
+			Date:       today,
+		})
+		if err != nil {
+			return err
+		}
+		p.logger.Printf("--- Processing %s (%d tracks) ---", name, len(tracks))
+
+		playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+		if err != nil {
+			return err
+		}
+		p.covers.Start(ctx, playlistID, CoverSpec{
+			Name:     playlistName,
+			Label:    name,
+			Subtitle: p.cfg.CoverSubtitle,
+			Tracks:   fullTracks(tracks),
+		})
+		if err := p.writer.Replace(ctx, playlistID, savedTrackIDs(tracks)); err != nil {
+			return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+		}
+		return nil
+	})
+}
+
+// seasonOf returns the meteorological season of month: 0 for spring (March to May in the
+// north), then summer, autumn and winter. The south's seasons are half a year off.
+func (p *seasonPlaylistBuilder) seasonOf(month time.Month) int {
+	season := int(month) % 12 / 3 // December to February is 0
+	season = (season + 3) % 4     // shift so spring is 0 and winter 3
+	if p.cfg.Hemisphere == "south" {
+		season = (season + 2) % 4
+	}
+	return season
+}
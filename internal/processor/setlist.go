@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"spotify/internal/setlistfm"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// SetlistOptions selects the concert a setlist playlist is built from: either a setlist
+// ID, or an artist and the concert's date.
+type SetlistOptions struct {
+	ID     string
+	Artist string
+	Date   time.Time
+	// Name overrides the playlist name, which defaults to "<artist> @ <venue> (<date>)".
+	Name string
+}
+
+type setlistPlaylistBuilder struct {
+	client    SpotifyClient
+	setlists  *setlistfm.Client
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	resolver  *trackResolver
+	opts      SetlistOptions
+}
+
+// NewSetlistPlaylistBuilder returns a Processor that fetches a concert's setlist from
+// setlist.fm and writes its songs, in order, into a playlist. Songs are looked up on
+// Spotify by name, with matches scored by matchCfg.
+func NewSetlistPlaylistBuilder(client SpotifyClient, setlists *setlistfm.Client, st *store.Store, logger *log.Logger, imgGen ImageGenerator, matchCfg config.Matching, shared config.Playlists, opts SetlistOptions) (Processor, error) {
+	if opts.ID == "" && (opts.Artist == "" || opts.Date.IsZero()) {
+		return nil, fmt.Errorf("a setlist needs either a setlist.fm URL or an artist and a date")
+	}
+	// The name is used verbatim, so it can't be mistaken for a template.
+	templates, err := newPlaylistTemplates("{{.Name}}", "Setlist of {{.Event}} on {{.From}}, via setlist.fm.", shared)
+	if err != nil {
+		return nil, err
+	}
+	return &setlistPlaylistBuilder{
+		client:    client,
+		setlists:  setlists,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, st, logger),
+		templates: templates,
+		resolver:  newTrackResolver(client, matchCfg, logger),
+		opts:      opts,
+	}, nil
+}
+
+// Run fetches the setlist, resolves its songs and writes the playlist.
+func (p *setlistPlaylistBuilder) Run(ctx context.Context) error {
+	var set setlistfm.Setlist
+	var err error
+	if p.opts.ID != "" {
+		set, err = p.setlists.Get(ctx, p.opts.ID)
+	} else {
+		set, err = p.setlists.Find(ctx, p.opts.Artist, p.opts.Date)
+	}
+	if err != nil {
+		return err
+	}
+	if len(set.Songs) == 0 {
+		p.logger.Printf("The setlist of %s on %s has no songs yet. Nothing to do.", set.Artist, set.Date.Format(time.DateOnly))
+		return ErrNothingToDo
+	}
+	p.logger.Printf("Found %d songs played by %s at %s on %s.", len(set.Songs), set.Artist, set.Venue, set.Date.Format(time.DateOnly))
+
+	var trackIDs []spotify.ID
+	seen := make(map[spotify.ID]struct{})
+	for _, song := range set.Songs {
+		id, err := p.resolver.Resolve(ctx, song.Title, song.Artist)
+		if err != nil {
+			return err
+		}
+		// Songs played twice, e.g. again in the encore, are only added once.
+		if _, dup := seen[id]; id == "" || dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		trackIDs = append(trackIDs, id)
+	}
+	if len(trackIDs) == 0 {
+		return fmt.Errorf("none of the %d songs were found on Spotify", len(set.Songs))
+	}
+	tracks, err := getTracks(ctx, p.client, trackIDs)
+	if err != nil {
+		return fmt.Errorf("failed to fetch tracks: %w", err)
+	}
+	var length time.Duration
+	for _, t := range tracks {
+		length += time.Duration(t.Duration) * time.Millisecond
+	}
+
+	name := p.opts.Name
+	if name == "" {
+		name = fmt.Sprintf("%s @ %s (%s)", set.Artist, set.Venue, set.Date.Format(time.DateOnly))
+	}
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		Name:       name,
+		Event:      fmt.Sprintf("%s at %s, %s", set.Artist, set.Venue, set.City),
+		From:       set.Date.Format(time.DateOnly),
+		TrackCount: len(trackIDs),
+		Duration:   formatDuration(length),
+		Date:       time.Now().Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{Name: playlistName, Label: set.Artist, Subtitle: set.Date.Format("2 Jan 2006"), Tracks: tracks})
+	if err := p.writer.Replace(ctx, playlistID, trackIDs); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	p.logger.Printf("✅ Wrote %d of %d songs to '%s'.", len(trackIDs), len(set.Songs), playlistName)
+	return nil
+}
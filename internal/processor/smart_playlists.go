@@ -0,0 +1,231 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"spotify/internal/config"
+	"spotify/internal/rules"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// smartPlaylist is a configured smart playlist with its compiled rule.
+type smartPlaylist struct {
+	cfg       config.SmartPlaylist
+	match     rules.Condition
+	templates *playlistTemplates
+}
+
+type smartPlaylistSyncer struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	tags      TagSource
+	playlists []smartPlaylist
+	needs     rules.Needs
+}
+
+// NewSmartPlaylistSyncer returns a Processor that keeps every playlist declared under
+// smart_playlists in sync with the liked songs matching its rule. Rule dates are read in
+// loc. It fails if a rule doesn't compile or two playlists share a name.
+func NewSmartPlaylistSyncer(client SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfgs []config.SmartPlaylist, shared config.Playlists, loc *time.Location) (Processor, error) {
+	s := &smartPlaylistSyncer{
+		client: client,
+		logger: logger,
+		writer: newPlaylistWriter(client, logger),
+		covers: newCoverUploader(client, imgGen, st, logger),
+		tags:   st,
+	}
+	now := time.Now()
+	seen := make(map[string]struct{}, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("every smart playlist needs a name")
+		}
+		if _, dup := seen[cfg.Name]; dup {
+			return nil, fmt.Errorf("smart playlist '%s' is declared twice", cfg.Name)
+		}
+		seen[cfg.Name] = struct{}{}
+		if err := validateOrdering(cfg.Order); err != nil {
+			return nil, fmt.Errorf("smart playlist '%s': %w", cfg.Name, err)
+		}
+		match, needs, err := rules.Compile(cfg.Rule, loc, now)
+		if err != nil {
+			return nil, fmt.Errorf("smart playlist '%s': invalid rule: %w", cfg.Name, err)
+		}
+		// The name is used verbatim, so it can't be mistaken for a template.
+		templates, err := newPlaylistTemplates("{{.Name}}", cfg.Description, shared)
+		if err != nil {
+			return nil, fmt.Errorf("smart playlist '%s': %w", cfg.Name, err)
+		}
+		s.playlists = append(s.playlists, smartPlaylist{cfg: cfg, match: match, templates: templates})
+		s.needs.Genres = s.needs.Genres || needs.Genres || (cfg.Order.Strategy == "diverse" && cfg.Order.GenreSpacing > 0)
+		s.needs.Features = s.needs.Features || needs.Features || cfg.Order.Strategy == "harmonic"
+		s.needs.Tags = s.needs.Tags || needs.Tags
+	}
+	return s, nil
+}
+
+// Run fetches the liked songs once, with genres and audio features if any rule needs
+// them, and rewrites every smart playlist.
+func (s *smartPlaylistSyncer) Run(ctx context.Context) error {
+	if len(s.playlists) == 0 {
+		s.logger.Println("No smart playlists configured under smart_playlists.")
+		return nil
+	}
+	liked, err := fetchLikedTracks(ctx, s.client, s.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	candidates, genres, err := ruleCandidates(ctx, s.client, uniqueSavedTracks(liked), s.needs, s.tags)
+	if err != nil {
+		return err
+	}
+
+	features := make(map[spotify.ID]*spotify.AudioFeatures)
+	for _, c := range candidates {
+		if c.Features != nil {
+			features[c.ID] = c.Features
+		}
+	}
+
+	user, err := s.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	// Covers render in the background while the tracks are written.
+	defer s.covers.Wait()
+	today := time.Now().Format(time.DateOnly)
+	return forEachPlaylist(ctx, s.logger, s.playlists, func(sp smartPlaylist) string { return fmt.Sprintf("Smart playlist '%s'", sp.cfg.Name) }, func(sp smartPlaylist) error {
+		var tracks []spotify.SavedTrack
+		for _, c := range candidates {
+			if sp.match(c) {
+				tracks = append(tracks, c.SavedTrack)
+			}
+		}
+		tracks = sortByAdded(capPerArtist(tracks, sp.cfg.Order, sp.cfg.Name), sp.cfg.Order.Descending)
+		switch sp.cfg.Order.Strategy {
+		case "diverse":
+			tracks = diversify(tracks, genres, sp.cfg.Order)
+		case "harmonic":
+			tracks = harmonize(tracks, features)
+		}
+		if sp.cfg.Limit > 0 && len(tracks) > sp.cfg.Limit {
+			tracks = tracks[:sp.cfg.Limit]
+		}
+
+		playlistName, description, err := sp.templates.Render(PlaylistTemplateData{
+			Name:       sp.cfg.Name,
+			TrackCount: len(tracks),
+			Duration:   formatDuration(totalDuration(tracks)),
+			Date:       today,
+		})
+		if err != nil {
+			return err
+		}
+		s.logger.Printf("--- Processing '%s' (%d tracks) ---", playlistName, len(tracks))
+		playlistID, err := s.writer.Ensure(ctx, user.ID, playlistName, description)
+		if err != nil {
+			return err
+		}
+		s.covers.Start(ctx, playlistID, CoverSpec{Name: playlistName, Subtitle: sp.cfg.CoverSubtitle, Tracks: fullTracks(tracks)})
+		if err := s.writer.Replace(ctx, playlistID, savedTrackIDs(tracks)); err != nil {
+			return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+		}
+		return nil
+	})
+}
+
+// ruleCandidates prepares liked songs for rule matching, fetching the genres and audio
+// features the rules need. When rules look at tags, tagged songs that aren't liked are
+// added too, with an empty AddedAt. The genres of primary artists are also returned for
+// ordering.
+func ruleCandidates(ctx context.Context, client SpotifyClient, liked []spotify.SavedTrack, needs rules.Needs, tags TagSource) ([]rules.Track, map[spotify.ID][]string, error) {
+	var tagged map[spotify.ID][]string
+	var err error
+	if needs.Tags {
+		tagged = tags.AllTags()
+		if liked, err = addTaggedTracks(ctx, client, liked, tagged); err != nil {
+			return nil, nil, err
+		}
+	}
+	var genres map[spotify.ID][]string
+	if needs.Genres {
+		if genres, err = fetchArtistGenres(ctx, client, uniqueArtistIDs(fullTracks(liked))); err != nil {
+			return nil, nil, err
+		}
+	}
+	var features map[spotify.ID]*spotify.AudioFeatures
+	if needs.Features {
+		if features, err = fetchAudioFeatures(ctx, client, savedTrackIDs(liked)); err != nil {
+			return nil, nil, err
+		}
+	}
+	candidates := make([]rules.Track, len(liked))
+	for i, t := range liked {
+		addedAt, _ := time.Parse(time.RFC3339, t.AddedAt)
+		candidates[i] = rules.Track{SavedTrack: t, AddedAt: addedAt, Tags: tagged[t.ID], Features: features[t.ID]}
+		if len(t.Artists) > 0 {
+			candidates[i].Genres = genres[t.Artists[0].ID]
+		}
+	}
+	return candidates, genres, nil
+}
+
+// addTaggedTracks appends the tagged tracks missing from liked.
+func addTaggedTracks(ctx context.Context, client SpotifyClient, liked []spotify.SavedTrack, tagged map[spotify.ID][]string) ([]spotify.SavedTrack, error) {
+	have := make(map[spotify.ID]struct{}, len(liked))
+	for _, t := range liked {
+		have[t.ID] = struct{}{}
+	}
+	var missing []spotify.ID
+	for _, id := range slices.Sorted(maps.Keys(tagged)) {
+		if _, ok := have[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	err := inBatches(missing, 50, func(batch []spotify.ID) error {
+		tracks, err := client.GetTracks(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, t := range tracks {
+			if t != nil {
+				liked = append(liked, spotify.SavedTrack{FullTrack: *t})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tagged tracks: %w", err)
+	}
+	return liked, nil
+}
+
+// fetchAudioFeatures looks up the audio features of tracks. Tracks Spotify has no
+// features for are missing from the result.
+func fetchAudioFeatures(ctx context.Context, client SpotifyClient, trackIDs []spotify.ID) (map[spotify.ID]*spotify.AudioFeatures, error) {
+	features := make(map[spotify.ID]*spotify.AudioFeatures, len(trackIDs))
+	err := inBatches(trackIDs, 100, func(batch []spotify.ID) error {
+		found, err := client.GetAudioFeatures(ctx, batch...)
+		if err != nil {
+			return err
+		}
+		for _, f := range found {
+			if f != nil {
+				features[f.ID] = f
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audio features: %w", err)
+	}
+	return features, nil
+}
@@ -0,0 +1,168 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"spotify/internal/store"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// tagExportVersion is the version of the tag export format written by the tag exporter.
+const tagExportVersion = 1
+
+// tagExport is the file format of exported tags.
+type tagExport struct {
+	Version int                     `json:"version"`
+	Tags    map[spotify.ID][]string `json:"tags"`
+}
+
+// normalizeTag lowercases and trims a tag so "Chill " and "chill" are the same tag.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+type tagEditor struct {
+	store   *store.Store
+	trackID spotify.ID
+	add     []string
+	remove  []string
+	logger  *log.Logger
+}
+
+// NewTagEditor returns a Processor that adds and removes local tags of a track.
+func NewTagEditor(st *store.Store, trackID spotify.ID, add, remove []string, logger *log.Logger) Processor {
+	return &tagEditor{store: st, trackID: trackID, add: add, remove: remove, logger: logger}
+}
+
+// Run updates the track's tags in the store.
+func (p *tagEditor) Run(ctx context.Context) error {
+	tags := p.store.Tags(p.trackID)
+	for _, tag := range p.add {
+		if tag = normalizeTag(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range p.remove {
+		tags = slices.DeleteFunc(tags, func(t string) bool { return t == normalizeTag(tag) })
+	}
+	p.store.SetTags(p.trackID, tags)
+	tags = p.store.Tags(p.trackID)
+	if len(tags) == 0 {
+		p.logger.Printf("✅ %s has no tags.", p.trackID)
+		return nil
+	}
+	p.logger.Printf("✅ %s is tagged %s.", p.trackID, strings.Join(tags, ", "))
+	return nil
+}
+
+type tagLister struct {
+	store  *store.Store
+	logger *log.Logger
+}
+
+// NewTagLister returns a Processor that prints every tag with the number of tracks
+// carrying it.
+func NewTagLister(st *store.Store, logger *log.Logger) Processor {
+	return &tagLister{store: st, logger: logger}
+}
+
+// Run prints the tags, most used first.
+func (p *tagLister) Run(ctx context.Context) error {
+	counts := make(map[string]int)
+	for _, tags := range p.store.AllTags() {
+		for _, tag := range tags {
+			counts[tag]++
+		}
+	}
+	if len(counts) == 0 {
+		p.logger.Println("No tracks are tagged yet.")
+		return nil
+	}
+	names := slices.Sorted(maps.Keys(counts))
+	slices.SortStableFunc(names, func(a, b string) int { return counts[b] - counts[a] })
+	for _, tag := range names {
+		fmt.Printf("%-24s %d\n", tag, counts[tag])
+	}
+	return nil
+}
+
+type tagExporter struct {
+	store  *store.Store
+	path   string
+	logger *log.Logger
+}
+
+// NewTagExporter returns a Processor that writes every tag as JSON to path, or to
+// standard output if path is empty or "-".
+func NewTagExporter(st *store.Store, path string, logger *log.Logger) Processor {
+	return &tagExporter{store: st, path: path, logger: logger}
+}
+
+// Run writes the export.
+func (p *tagExporter) Run(ctx context.Context) error {
+	var out io.Writer = os.Stdout
+	if p.path != "" && p.path != "-" {
+		f, err := os.Create(p.path)
+		if err != nil {
+			return fmt.Errorf("could not create '%s': %w", p.path, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	all := p.store.AllTags()
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(tagExport{Version: tagExportVersion, Tags: all}); err != nil {
+		return fmt.Errorf("could not write tags: %w", err)
+	}
+	if out != os.Stdout {
+		p.logger.Printf("✅ Exported the tags of %d tracks to %s.", len(all), p.path)
+	}
+	return nil
+}
+
+type tagImporter struct {
+	store  *store.Store
+	path   string
+	logger *log.Logger
+}
+
+// NewTagImporter returns a Processor that merges the tags exported to path into the
+// store. Existing tags are kept.
+func NewTagImporter(st *store.Store, path string, logger *log.Logger) Processor {
+	return &tagImporter{store: st, path: path, logger: logger}
+}
+
+// Run reads the export and merges it.
+func (p *tagImporter) Run(ctx context.Context) error {
+	raw, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("could not read '%s': %w", p.path, err)
+	}
+	var export tagExport
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return fmt.Errorf("could not decode '%s': %w", p.path, err)
+	}
+	if export.Version != tagExportVersion {
+		return fmt.Errorf("unsupported tag export version %d", export.Version)
+	}
+	for id, tags := range export.Tags {
+		merged := p.store.Tags(id)
+		for _, tag := range tags {
+			if tag = normalizeTag(tag); tag != "" {
+				merged = append(merged, tag)
+			}
+		}
+		p.store.SetTags(id, merged)
+	}
+	p.logger.Printf("✅ Imported the tags of %d tracks.", len(export.Tags))
+	return nil
+}
@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"spotify/internal/history"
+	"spotify/internal/store"
+	"strconv"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type topPlayedBuilder struct {
+	client    SpotifyClient
+	store     *store.Store
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	resolver  *trackResolver
+	cfg       config.TopPlayed
+
+	// resolved is set once plays were given track IDs, which need saving.
+	resolved bool
+}
+
+// NewTopPlayedBuilder returns a Processor that builds a "Most Played" playlist for every
+// year in the imported streaming history, ranked by actual listening time. Tracks from
+// exports without IDs are looked up by name, with matches scored by matchCfg, when they
+// make it into a playlist.
+func NewTopPlayedBuilder(client SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, cfg config.TopPlayed, matchCfg config.Matching, shared config.Playlists) (Processor, error) {
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &topPlayedBuilder{
+		client:    client,
+		store:     st,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, st, logger),
+		templates: templates,
+		resolver:  newTrackResolver(client, matchCfg, logger),
+		cfg:       cfg,
+	}, nil
+}
+
+// Run ranks each year's plays and writes the top tracks into that year's playlist.
+func (p *topPlayedBuilder) Run(ctx context.Context) error {
+	plays := p.store.Plays()
+	if len(plays) == 0 {
+		p.logger.Println("No streaming history in the local store. Run import-history first.")
+		return nil
+	}
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	minPlay := time.Duration(p.cfg.MinPlaySeconds) * time.Second
+	// Covers render in the background while the tracks are written.
+	defer p.covers.Wait()
+	defer p.saveResolved()
+	today := time.Now().Format(time.DateOnly)
+	// A failing year doesn't stop the others; failures are reported together at the end.
+	return forEachPlaylist(ctx, p.logger, history.Years(plays), func(year int) string { return fmt.Sprintf("Year %d", year) }, func(year int) error {
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		ranked := history.RankTracks(plays, from, from.AddDate(1, 0, 0), minPlay)
+
+		var trackIDs []spotify.ID
+		var listened time.Duration
+		seen := make(map[spotify.ID]struct{})
+		for _, stat := range ranked {
+			if p.cfg.Limit > 0 && len(trackIDs) == p.cfg.Limit {
+				break
+			}
+			if stat.Plays < p.cfg.MinPlays {
+				continue
+			}
+			if stat.TrackID == "" {
+				if stat.TrackID, err = p.resolve(ctx, stat); err != nil {
+					return err
+				}
+				if stat.TrackID == "" {
+					continue
+				}
+			}
+			// Plays of a track from both kinds of export rank separately until resolved.
+			if _, dup := seen[stat.TrackID]; dup {
+				continue
+			}
+			seen[stat.TrackID] = struct{}{}
+			trackIDs = append(trackIDs, stat.TrackID)
+			listened += time.Duration(stat.MsPlayed) * time.Millisecond
+		}
+		if len(trackIDs) == 0 {
+			p.logger.Printf("No tracks in %d pass the play thresholds. Skipping.", year)
+			return nil
+		}
+
+		playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+			Year:       year,
+			TrackCount: len(trackIDs),
+			Duration:   formatDuration(listened),
+			Date:       today,
+		})
+		if err != nil {
+			return err
+		}
+		p.logger.Printf("--- Processing year %d (%d tracks) ---", year, len(trackIDs))
+
+		playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+		if err != nil {
+			return err
+		}
+		p.covers.Start(ctx, playlistID, CoverSpec{Name: playlistName, Label: strconv.Itoa(year), Subtitle: p.cfg.CoverSubtitle})
+		if err := p.writer.Replace(ctx, playlistID, trackIDs); err != nil {
+			return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+		}
+		return nil
+	})
+}
+
+// resolve looks up a track known only by name and records the match on its plays, so
+// it's looked up once. It returns "" if nothing matches well enough.
+func (p *topPlayedBuilder) resolve(ctx context.Context, stat history.TrackStat) (spotify.ID, error) {
+	id, err := p.resolver.Resolve(ctx, stat.TrackName, stat.ArtistName)
+	if err != nil || id == "" {
+		return "", err
+	}
+	if p.store.ResolvePlays(stat.TrackName, stat.ArtistName, id) > 0 {
+		p.resolved = true
+	}
+	return id, nil
+}
+
+// saveResolved writes the track IDs found by resolve to the store.
+func (p *topPlayedBuilder) saveResolved() {
+	if !p.resolved {
+		return
+	}
+	if err := p.store.Save(); err != nil {
+		p.logger.Printf("⚠️  Could not save resolved tracks: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package processor
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"spotify/internal/config"
+	"spotify/internal/query"
+	"spotify/internal/rules"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// trackQuery is a compiled query expression over liked and tagged songs.
+type trackQuery struct {
+	expr  string
+	match rules.Condition
+	needs rules.Needs
+	tags  TagSource
+}
+
+// newTrackQuery parses expr; dates in it are read in loc. An empty expression matches
+// every liked song.
+func newTrackQuery(expr string, loc *time.Location, tags TagSource) (*trackQuery, error) {
+	if expr == "" {
+		return &trackQuery{match: func(rules.Track) bool { return true }, tags: tags}, nil
+	}
+	rule, err := query.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	match, needs, err := rules.Compile(rule, loc, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return &trackQuery{expr: expr, match: match, needs: needs, tags: tags}, nil
+}
+
+// selectTracks returns the liked songs matching the query, oldest like first. Queries on
+// tags also match tagged songs that aren't liked, which come last.
+func (q *trackQuery) selectTracks(ctx context.Context, client SpotifyClient, logger *log.Logger) ([]spotify.SavedTrack, error) {
+	liked, err := fetchLikedTracks(ctx, client, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	candidates, _, err := ruleCandidates(ctx, client, uniqueSavedTracks(liked), q.needs, q.tags)
+	if err != nil {
+		return nil, err
+	}
+	var matched []spotify.SavedTrack
+	for _, c := range candidates {
+		if q.match(c) {
+			matched = append(matched, c.SavedTrack)
+		}
+	}
+	if q.expr != "" {
+		logger.Printf("%d songs match '%s'.", len(matched), q.expr)
+	}
+	return sortByAdded(matched, false), nil
+}
+
+type queryExporter struct {
+	client SpotifyClient
+	logger *log.Logger
+	query  *trackQuery
+	path   string
+	format exportFormat
+}
+
+// NewQueryExporter returns a Processor that writes the liked songs matching a query, or
+// all of them for an empty query, as CSV to path, or to standard output if path is empty
+// or "-". format names the column layout: "default", or the preset of a service to
+// import the file into.
+func NewQueryExporter(client SpotifyClient, tags TagSource, logger *log.Logger, expr string, loc *time.Location, path, format string) (Processor, error) {
+	layout, ok := exportFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format '%s' (available: %s)", format, exportFormatNames())
+	}
+	q, err := newTrackQuery(expr, loc, tags)
+	if err != nil {
+		return nil, err
+	}
+	return &queryExporter{client: client, logger: logger, query: q, path: path, format: layout}, nil
+}
+
+// Run selects the songs and writes one CSV row per song.
+func (p *queryExporter) Run(ctx context.Context) error {
+	tracks, err := p.query.selectTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = os.Stdout
+	if p.path != "" && p.path != "-" {
+		f, err := os.Create(p.path)
+		if err != nil {
+			return fmt.Errorf("could not create '%s': %w", p.path, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := csv.NewWriter(out)
+	w.Write(p.format.header)
+	for _, t := range tracks {
+		w.Write(p.format.row(t))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("could not write export: %w", err)
+	}
+	if out != os.Stdout {
+		p.logger.Printf("✅ Exported %d songs to %s.", len(tracks), p.path)
+	}
+	return nil
+}
+
+type queryPlaylistBuilder struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	query     *trackQuery
+	name      string
+}
+
+// NewQueryPlaylistBuilder returns a Processor that writes the liked songs matching a
+// query into the playlist called name.
+func NewQueryPlaylistBuilder(client SpotifyClient, st *store.Store, logger *log.Logger, imgGen ImageGenerator, expr string, loc *time.Location, name string, shared config.Playlists) (Processor, error) {
+	if name == "" {
+		return nil, fmt.Errorf("a query playlist needs a name")
+	}
+	q, err := newTrackQuery(expr, loc, st)
+	if err != nil {
+		return nil, err
+	}
+	// The name and query are used verbatim, so they can't be mistaken for templates.
+	templates, err := newPlaylistTemplates("{{.Name}}", "Songs I liked matching {{.Query}}", shared)
+	if err != nil {
+		return nil, err
+	}
+	return &queryPlaylistBuilder{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, st, logger),
+		templates: templates,
+		query:     q,
+		name:      name,
+	}, nil
+}
+
+// Run selects the songs and rewrites the playlist with them.
+func (p *queryPlaylistBuilder) Run(ctx context.Context) error {
+	tracks, err := p.query.selectTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return err
+	}
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		Name:       p.name,
+		Query:      p.query.expr,
+		TrackCount: len(tracks),
+		Duration:   formatDuration(totalDuration(tracks)),
+		Date:       time.Now().Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{Name: playlistName, Tracks: fullTracks(tracks)})
+	if err := p.writer.Replace(ctx, playlistID, savedTrackIDs(tracks)); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	return nil
+}
+
+type queryRemover struct {
+	client  SpotifyClient
+	logger  *log.Logger
+	query   *trackQuery
+	confirm bool
+}
+
+// NewQueryRemover returns a Processor that removes the liked songs matching a query from
+// the library. Without confirm it only lists them.
+func NewQueryRemover(client SpotifyClient, tags TagSource, logger *log.Logger, expr string, loc *time.Location, confirm bool) (Processor, error) {
+	q, err := newTrackQuery(expr, loc, tags)
+	if err != nil {
+		return nil, err
+	}
+	return &queryRemover{client: client, logger: logger, query: q, confirm: confirm}, nil
+}
+
+// Run selects the songs and unlikes them. Tagged songs that aren't liked are ignored.
+func (p *queryRemover) Run(ctx context.Context) error {
+	tracks, err := p.query.selectTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return err
+	}
+	tracks = slices.DeleteFunc(tracks, func(t spotify.SavedTrack) bool { return t.AddedAt == "" })
+	if len(tracks) == 0 {
+		return nil
+	}
+	if !p.confirm {
+		for _, t := range tracks {
+			p.logger.Printf("  would remove '%s' by %s", t.Name, artistNames(t.Artists))
+		}
+		p.logger.Println("Dry run: nothing was removed. Run again with --yes to remove these songs.")
+		return nil
+	}
+	err = inBatches(savedTrackIDs(tracks), 50, func(batch []spotify.ID) error {
+		return p.client.RemoveTracksFromLibrary(ctx, batch...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove tracks: %w", err)
+	}
+	p.logger.Printf("✅ Removed %d songs from your library.", len(tracks))
+	return nil
+}
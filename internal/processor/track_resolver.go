@@ -0,0 +1,50 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"spotify/internal/config"
+	"spotify/internal/matching"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// trackResolver finds Spotify tracks known only by title and artist, such as plays from
+// exports without IDs or Last.fm scrobbles. Lookups are cached for the run, misses too.
+type trackResolver struct {
+	client  SpotifyClient
+	matcher *matching.Matcher
+	logger  *log.Logger
+	cache   map[string]spotify.ID
+}
+
+func newTrackResolver(client SpotifyClient, cfg config.Matching, logger *log.Logger) *trackResolver {
+	return &trackResolver{client: client, matcher: matching.New(cfg), logger: logger, cache: make(map[string]spotify.ID)}
+}
+
+// Resolve searches for the track and returns the best match scoring above the matching
+// threshold, or "" if there's none.
+func (r *trackResolver) Resolve(ctx context.Context, title, artist string) (spotify.ID, error) {
+	key := matching.Key(title, artist)
+	if id, ok := r.cache[key]; ok {
+		return id, nil
+	}
+	q := fmt.Sprintf("track:%q artist:%q", title, artist)
+	result, err := r.client.Search(ctx, q, spotify.SearchTypeTrack, spotify.Limit(10))
+	if err != nil {
+		return "", fmt.Errorf("could not look up '%s' by %s: %w", title, artist, err)
+	}
+	var candidates []matching.Track
+	if result.Tracks != nil {
+		for _, t := range result.Tracks.Tracks {
+			candidates = append(candidates, matching.FromFull(t))
+		}
+	}
+	match, _, ok := r.matcher.Best(matching.Track{Title: title, Artist: artist}, candidates)
+	if !ok {
+		r.logger.Printf("⚠️  Could not find '%s' by %s on Spotify.", title, artist)
+	}
+	r.cache[key] = match.ID
+	return match.ID, nil
+}
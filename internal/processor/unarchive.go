@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"spotify/internal/config"
+	"spotify/internal/store"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type unarchiver struct {
+	client  SpotifyClient
+	store   *store.Store
+	logger  *log.Logger
+	periods yearPeriods
+	years   []int
+}
+
+// NewUnarchiver returns a Processor that likes the songs archived by "sort --archive"
+// again, those liked in years if any are given. Years are counted like the sorter's.
+func NewUnarchiver(client SpotifyClient, st *store.Store, logger *log.Logger, cfg config.Sorter, years []int) (Processor, error) {
+	periods, err := newYearPeriods(cfg.Timezone, cfg.YearStart)
+	if err != nil {
+		return nil, err
+	}
+	return &unarchiver{client: client, store: st, logger: logger, periods: periods, years: years}, nil
+}
+
+// Run likes the archived songs again, oldest first so Liked Songs roughly keeps their
+// original order. The sorter keeps them in the playlists of their original like dates.
+func (p *unarchiver) Run(ctx context.Context) error {
+	var tracks []spotify.SavedTrack
+	for _, a := range p.store.Archived() {
+		if !a.RestoredAt.IsZero() {
+			continue
+		}
+		likedAt, err := time.Parse(time.RFC3339, a.Track.AddedAt)
+		if err != nil {
+			continue
+		}
+		if len(p.years) == 0 || slices.Contains(p.years, p.periods.Year(likedAt)) {
+			tracks = append(tracks, a.Track)
+		}
+	}
+	if len(tracks) == 0 {
+		p.logger.Println("No archived songs to restore. Nothing to do.")
+		return ErrNothingToDo
+	}
+	slices.SortFunc(tracks, func(a, b spotify.SavedTrack) int { return strings.Compare(a.AddedAt, b.AddedAt) })
+	restored := 0
+	err := inBatches(savedTrackIDs(tracks), 50, func(batch []spotify.ID) error {
+		if err := p.client.AddTracksToLibrary(ctx, batch...); err != nil {
+			return err
+		}
+		p.store.Restore(batch, time.Now())
+		restored += len(batch)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("restored %d of %d archived songs: %w", restored, len(tracks), err)
+	}
+	p.logger.Printf("✅ Liked %d archived songs again.", restored)
+	return nil
+}
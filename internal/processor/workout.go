@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"spotify/internal/config"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+type workoutBuilder struct {
+	client    SpotifyClient
+	logger    *log.Logger
+	writer    *playlistWriter
+	covers    *coverUploader
+	templates *playlistTemplates
+	cfg       config.Workout
+}
+
+// NewWorkoutBuilder returns a Processor that builds a workout playlist from the liked
+// songs, filling each of cfg.Phases with songs in its tempo range so the playlist follows
+// the BPM curve of the workout.
+func NewWorkoutBuilder(client SpotifyClient, registry CoverRegistry, logger *log.Logger, imgGen ImageGenerator, cfg config.Workout, shared config.Playlists) (Processor, error) {
+	if len(cfg.Phases) == 0 {
+		return nil, fmt.Errorf("at least one phase is needed")
+	}
+	for i, phase := range cfg.Phases {
+		if phase.MinBPM < 0 || phase.MaxBPM <= phase.MinBPM {
+			return nil, fmt.Errorf("phase %d (%s): invalid BPM range %.0f-%.0f", i+1, phase.Name, phase.MinBPM, phase.MaxBPM)
+		}
+		if phase.Minutes <= 0 {
+			return nil, fmt.Errorf("phase %d (%s): minutes must be positive, got %d", i+1, phase.Name, phase.Minutes)
+		}
+	}
+	templates, err := newPlaylistTemplates(cfg.NameTemplate, cfg.DescriptionTemplate, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &workoutBuilder{
+		client:    client,
+		logger:    logger,
+		writer:    newPlaylistWriter(client, logger),
+		covers:    newCoverUploader(client, imgGen, registry, logger),
+		templates: templates,
+		cfg:       cfg,
+	}, nil
+}
+
+// workoutTrack is a liked song and its tempo.
+type workoutTrack struct {
+	track spotify.FullTrack
+	bpm   float64
+}
+
+// Run picks the songs of each phase and writes the playlist.
+func (p *workoutBuilder) Run(ctx context.Context) error {
+	liked, err := fetchLikedTracks(ctx, p.client, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch liked tracks: %w", err)
+	}
+	tracks := fullTracks(uniqueSavedTracks(liked))
+	features, err := fetchAudioFeatures(ctx, p.client, trackIDs(tracks))
+	if err != nil {
+		return err
+	}
+	var pool []workoutTrack
+	for _, t := range tracks {
+		if f, ok := features[t.ID]; ok && f.Tempo > 0 {
+			pool = append(pool, workoutTrack{track: t, bpm: float64(f.Tempo)})
+		}
+	}
+	if len(pool) == 0 {
+		return fmt.Errorf("none of your liked songs has a known tempo")
+	}
+	// Shuffle so each run makes a different workout out of the same library.
+	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	used := make(map[spotify.ID]bool)
+	var playlist []spotify.FullTrack
+	for i, phase := range p.cfg.Phases {
+		target := time.Duration(phase.Minutes) * time.Minute
+		var picked []workoutTrack
+		var length time.Duration
+		for _, t := range pool {
+			if length >= target {
+				break
+			}
+			if used[t.track.ID] || t.bpm < phase.MinBPM || t.bpm > phase.MaxBPM {
+				continue
+			}
+			used[t.track.ID] = true
+			picked = append(picked, t)
+			length += time.Duration(t.track.Duration) * time.Millisecond
+		}
+		// The tempo rises through each phase, except in one slower than the phase before,
+		// where it falls.
+		slowing := i > 0 && phase.MaxBPM < p.cfg.Phases[i-1].MaxBPM
+		slices.SortFunc(picked, func(a, b workoutTrack) int {
+			if slowing {
+				return cmp.Compare(b.bpm, a.bpm)
+			}
+			return cmp.Compare(a.bpm, b.bpm)
+		})
+		if length < target {
+			p.logger.Printf("⚠️  Only %s of songs at %.0f-%.0f BPM for the %s phase of %d minutes.", formatLength(length), phase.MinBPM, phase.MaxBPM, phase.Name, phase.Minutes)
+		}
+		p.logger.Printf("%s: %d songs, %s.", phase.Name, len(picked), formatLength(length))
+		for _, t := range picked {
+			playlist = append(playlist, t.track)
+		}
+	}
+	if len(playlist) == 0 {
+		return fmt.Errorf("no liked songs fit the workout's BPM ranges")
+	}
+
+	user, err := p.client.CurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	var length time.Duration
+	for _, t := range playlist {
+		length += time.Duration(t.Duration) * time.Millisecond
+	}
+	playlistName, description, err := p.templates.Render(PlaylistTemplateData{
+		TrackCount: len(playlist),
+		Duration:   formatDuration(length),
+		Date:       time.Now().Format(time.DateOnly),
+	})
+	if err != nil {
+		return err
+	}
+	playlistID, err := p.writer.Ensure(ctx, user.ID, playlistName, description)
+	if err != nil {
+		return err
+	}
+	p.covers.Upload(ctx, playlistID, CoverSpec{Name: playlistName, Label: "BPM", Subtitle: p.cfg.CoverSubtitle, Tracks: playlist})
+	if err := p.writer.Replace(ctx, playlistID, trackIDs(playlist)); err != nil {
+		return fmt.Errorf("could not write playlist '%s': %w", playlistName, err)
+	}
+	p.logger.Printf("✅ Wrote %d songs (%s) to '%s'.", len(playlist), formatDuration(length), playlistName)
+	return nil
+}
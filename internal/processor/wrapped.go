@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"spotify/internal/history"
+	"spotify/internal/report"
+	"spotify/internal/store"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// genreSampleSize is how many of the year's most played tracks genres are looked up for.
+// They cover most of the listening time at a fraction of the requests.
+const genreSampleSize = 500
+
+// WrappedOptions selects the year and outputs of the year-in-review report.
+type WrappedOptions struct {
+	// Year defaults to the year of the latest play.
+	Year int
+	// HTML is where the shareable page is written; empty skips it.
+	HTML string
+	// Top is the length of each ranking.
+	Top int
+	// MinPlay is how long a play must last to count.
+	MinPlay time.Duration
+}
+
+type wrappedReport struct {
+	client SpotifyClient
+	store  *store.Store
+	out    io.Writer
+	logger *log.Logger
+	opts   WrappedOptions
+}
+
+// NewWrappedReport returns a Processor that summarizes a year of the imported streaming
+// history: top tracks, artists and genres, listening hours and how much of it was new.
+// The report is printed to out and optionally written as an HTML page.
+func NewWrappedReport(client SpotifyClient, st *store.Store, out io.Writer, logger *log.Logger, opts WrappedOptions) Processor {
+	return &wrappedReport{client: client, store: st, out: out, logger: logger, opts: opts}
+}
+
+// Run builds the report. Only the genre ranking needs Spotify.
+func (p *wrappedReport) Run(ctx context.Context) error {
+	plays := p.store.Plays()
+	if len(plays) == 0 {
+		p.logger.Println("No streaming history in the local store. Run import-history first.")
+		return nil
+	}
+	year := p.opts.Year
+	if year == 0 {
+		year = history.Latest(plays).UTC().Year()
+	}
+	w := report.BuildWrapped(plays, year, p.opts.MinPlay, p.opts.Top)
+	if w.Plays == 0 {
+		p.logger.Printf("No plays in %d.", year)
+		return nil
+	}
+	genres, err := p.topGenres(ctx, plays, year)
+	if err != nil {
+		return err
+	}
+	w.TopGenres = genres
+
+	w.WriteText(p.out)
+	if p.opts.HTML == "" {
+		return nil
+	}
+	f, err := os.Create(p.opts.HTML)
+	if err != nil {
+		return fmt.Errorf("could not create '%s': %w", p.opts.HTML, err)
+	}
+	defer f.Close()
+	if err := w.WriteHTML(f); err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+	p.logger.Printf("✅ Wrote your %d in review to %s.", year, p.opts.HTML)
+	return nil
+}
+
+// topGenres ranks genres by the time spent on tracks by artists of that genre, sampling
+// the most played tracks. Tracks from exports without IDs are left out.
+func (p *wrappedReport) topGenres(ctx context.Context, plays []store.Play, year int) ([]report.Entry, error) {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var ids []spotify.ID
+	stats := make(map[spotify.ID]history.TrackStat)
+	for _, stat := range history.RankTracks(plays, from, from.AddDate(1, 0, 0), p.opts.MinPlay) {
+		if stat.TrackID == "" {
+			continue
+		}
+		if len(ids) == genreSampleSize {
+			break
+		}
+		ids = append(ids, stat.TrackID)
+		stats[stat.TrackID] = stat
+	}
+
+	tracks, err := getTracks(ctx, p.client, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tracks: %w", err)
+	}
+	artistGenres, err := fetchArtistGenres(ctx, p.client, uniqueArtistIDs(tracks))
+	if err != nil {
+		return nil, err
+	}
+
+	byGenre := make(map[string]*report.Entry)
+	for _, t := range tracks {
+		if len(t.Artists) == 0 {
+			continue
+		}
+		stat := stats[t.ID]
+		for _, genre := range artistGenres[t.Artists[0].ID] {
+			e, ok := byGenre[genre]
+			if !ok {
+				e = &report.Entry{Name: genre}
+				byGenre[genre] = e
+			}
+			e.Plays += stat.Plays
+			e.Listened += time.Duration(stat.MsPlayed) * time.Millisecond
+		}
+	}
+	entries := make([]report.Entry, 0, len(byGenre))
+	for _, e := range byGenre {
+		entries = append(entries, *e)
+	}
+	return report.Rank(entries, p.opts.Top), nil
+}
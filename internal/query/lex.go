@@ -0,0 +1,84 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // byte offset in the expression, for error messages
+}
+
+// lex splits expr into tokens. Words run until whitespace, a parenthesis or an operator;
+// quoted strings may contain anything but an unescaped quote.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		r, size := utf8.DecodeRuneInString(expr[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(expr) && expr[i+1] == '=' {
+				op += "="
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		case c == ':' || c == '=':
+			tokens = append(tokens, token{tokOp, string(c), i})
+			i++
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != '"'; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				b.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{tokString, b.String(), i})
+			i = j + 1
+		default:
+			j := i
+			for j < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[j:])
+				if unicode.IsSpace(r) || strings.ContainsRune(`()<>:="`, r) {
+					break
+				}
+				j += size
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", r, i)
+			}
+			tokens = append(tokens, token{tokWord, expr[i:j], i})
+			i = j
+		}
+	}
+	return append(tokens, token{tokEOF, "end of query", len(expr)}), nil
+}
@@ -0,0 +1,245 @@
+// Package query parses filter expressions given on the command line, such as
+//
+//	artist:"Radiohead" AND year<2000 AND energy>0.6
+//
+// into the same rules smart playlists are declared with.
+package query
+
+import (
+	"fmt"
+	"math"
+	"spotify/internal/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse turns expr into a rule. Terms are field:value or field<op>value with <, <=, >,
+// >= or =, combined with AND, OR, NOT and parentheses; AND binds tighter than OR and
+// terms next to each other are ANDed. Fields:
+//
+//	title, artist, genre   glob patterns, e.g. artist:"radio*"
+//	tag                    glob pattern over local tags
+//	added                  like date, e.g. added>=2024-01-01
+//	year, popularity       release year and popularity
+//	explicit               true or false
+//	energy, tempo, ...     audio features
+func Parse(expr string) (config.Rule, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return config.Rule{}, err
+	}
+	p := parser{tokens: tokens}
+	rule, err := p.or()
+	if err != nil {
+		return config.Rule{}, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return config.Rule{}, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos)
+	}
+	return rule, nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token { return p.tokens[p.next] }
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the bare word kw, in any case.
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) or() (config.Rule, error) {
+	rule, err := p.and()
+	if err != nil {
+		return config.Rule{}, err
+	}
+	anyOf := []config.Rule{rule}
+	for p.keyword("or") {
+		p.take()
+		if rule, err = p.and(); err != nil {
+			return config.Rule{}, err
+		}
+		anyOf = append(anyOf, rule)
+	}
+	if len(anyOf) == 1 {
+		return anyOf[0], nil
+	}
+	return config.Rule{Any: anyOf}, nil
+}
+
+func (p *parser) and() (config.Rule, error) {
+	rule, err := p.unary()
+	if err != nil {
+		return config.Rule{}, err
+	}
+	all := []config.Rule{rule}
+	for {
+		if p.keyword("and") {
+			p.take()
+		} else if t := p.peek(); t.kind == tokEOF || t.kind == tokRParen || p.keyword("or") {
+			break
+		}
+		if rule, err = p.unary(); err != nil {
+			return config.Rule{}, err
+		}
+		all = append(all, rule)
+	}
+	if len(all) == 1 {
+		return all[0], nil
+	}
+	return config.Rule{All: all}, nil
+}
+
+func (p *parser) unary() (config.Rule, error) {
+	if p.keyword("not") {
+		p.take()
+		rule, err := p.unary()
+		if err != nil {
+			return config.Rule{}, err
+		}
+		return config.Rule{Not: &rule}, nil
+	}
+	if p.peek().kind == tokLParen {
+		open := p.take()
+		rule, err := p.or()
+		if err != nil {
+			return config.Rule{}, err
+		}
+		if p.take().kind != tokRParen {
+			return config.Rule{}, fmt.Errorf("unclosed '(' at position %d", open.pos)
+		}
+		return rule, nil
+	}
+	return p.term()
+}
+
+func (p *parser) term() (config.Rule, error) {
+	field := p.take()
+	if field.kind != tokWord {
+		return config.Rule{}, fmt.Errorf("expected a field at position %d, got '%s'", field.pos, field.text)
+	}
+	op := p.take()
+	if op.kind != tokOp {
+		return config.Rule{}, fmt.Errorf("expected an operator after '%s' at position %d", field.text, op.pos)
+	}
+	value := p.take()
+	if value.kind != tokWord && value.kind != tokString {
+		return config.Rule{}, fmt.Errorf("expected a value after '%s%s' at position %d", field.text, op.text, value.pos)
+	}
+	rule, err := buildTerm(strings.ToLower(field.text), op.text, value.text)
+	if err != nil {
+		return config.Rule{}, fmt.Errorf("%s%s%s: %w", field.text, op.text, value.text, err)
+	}
+	return rule, nil
+}
+
+// features are the audio features that can be filtered on.
+var features = []string{"acousticness", "danceability", "energy", "instrumentalness", "liveness", "loudness", "speechiness", "tempo", "valence"}
+
+func buildTerm(field, op, value string) (config.Rule, error) {
+	equality := op == ":" || op == "="
+	switch field {
+	case "title", "artist", "genre", "tag":
+		if !equality {
+			return config.Rule{}, fmt.Errorf("%s only supports ':'", field)
+		}
+		switch field {
+		case "title":
+			return config.Rule{Title: value}, nil
+		case "artist":
+			return config.Rule{Artist: value}, nil
+		case "tag":
+			return config.Rule{Tag: value}, nil
+		}
+		return config.Rule{Genre: value}, nil
+	case "explicit":
+		explicit, err := strconv.ParseBool(value)
+		if err != nil || !equality {
+			return config.Rule{}, fmt.Errorf("explicit takes true or false")
+		}
+		return config.Rule{Explicit: &explicit}, nil
+	case "added":
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return config.Rule{}, fmt.Errorf("dates look like 2024-01-31")
+		}
+		after, before := day.Format(time.DateOnly), day.AddDate(0, 0, 1).Format(time.DateOnly)
+		switch op {
+		case ">":
+			return config.Rule{AddedAfter: before}, nil
+		case ">=":
+			return config.Rule{AddedAfter: after}, nil
+		case "<":
+			return config.Rule{AddedBefore: after}, nil
+		case "<=":
+			return config.Rule{AddedBefore: before}, nil
+		}
+		return config.Rule{AddedAfter: after, AddedBefore: before}, nil
+	case "year", "popularity":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return config.Rule{}, fmt.Errorf("%s takes a whole number", field)
+		}
+		r := intRange(op, n)
+		if field == "year" {
+			return config.Rule{ReleaseYear: &r}, nil
+		}
+		return config.Rule{Popularity: &r}, nil
+	}
+	for _, name := range features {
+		if field == name {
+			v, err := strconv.ParseFloat(value, 32)
+			if err != nil {
+				return config.Rule{}, fmt.Errorf("%s takes a number", field)
+			}
+			return config.Rule{Features: map[string]config.Range{name: featureRange(op, float32(v))}}, nil
+		}
+	}
+	return config.Rule{}, fmt.Errorf("unknown field '%s' (available: title, artist, genre, tag, added, year, popularity, explicit, %s)", field, strings.Join(features, ", "))
+}
+
+// intRange turns a comparison into an inclusive range of whole numbers.
+func intRange(op string, n int) config.Range {
+	bound := func(n int) *float64 { v := float64(n); return &v }
+	switch op {
+	case ">":
+		return config.Range{Min: bound(n + 1)}
+	case ">=":
+		return config.Range{Min: bound(n)}
+	case "<":
+		return config.Range{Max: bound(n - 1)}
+	case "<=":
+		return config.Range{Max: bound(n)}
+	}
+	return config.Range{Min: bound(n), Max: bound(n)}
+}
+
+// featureRange turns a comparison into an inclusive range. Audio features are float32,
+// so strict bounds step to the neighbouring float32.
+func featureRange(op string, v float32) config.Range {
+	bound := func(v float32) *float64 { f := float64(v); return &f }
+	switch op {
+	case ">":
+		return config.Range{Min: bound(math.Nextafter32(v, float32(math.Inf(1))))}
+	case ">=":
+		return config.Range{Min: bound(v)}
+	case "<":
+		return config.Range{Max: bound(math.Nextafter32(v, float32(math.Inf(-1))))}
+	case "<=":
+		return config.Range{Max: bound(v)}
+	}
+	return config.Range{Min: bound(v), Max: bound(v)}
+}
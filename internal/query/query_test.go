@@ -0,0 +1,116 @@
+package query
+
+import (
+	"reflect"
+	"spotify/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func num(v float64) *float64 { return &v }
+
+func TestParse(t *testing.T) {
+	explicit := true
+	tests := []struct {
+		expr string
+		want config.Rule
+	}{
+		{`artist:radiohead`, config.Rule{Artist: "radiohead"}},
+		{`ARTIST = "Radio*"`, config.Rule{Artist: "Radio*"}},
+		{`title:"Say \"Hi\" (Live)"`, config.Rule{Title: `Say "Hi" (Live)`}},
+		{`artist:Beyoncé`, config.Rule{Artist: "Beyoncé"}},
+		{`artist:"Åsa" genre:à`, config.Rule{All: []config.Rule{{Artist: "Åsa"}, {Genre: "à"}}}},
+		{"artist:x\r\n", config.Rule{Artist: "x"}},
+		{" artist:x ", config.Rule{Artist: "x"}},
+		{`explicit:true`, config.Rule{Explicit: &explicit}},
+		{`year>=1990 year<2000`, config.Rule{All: []config.Rule{
+			{ReleaseYear: &config.Range{Min: num(1990)}},
+			{ReleaseYear: &config.Range{Max: num(1999)}},
+		}}},
+		{`popularity>50`, config.Rule{Popularity: &config.Range{Min: num(51)}}},
+		{`added>=2024-01-01`, config.Rule{AddedAfter: "2024-01-01"}},
+		{`added<=2024-01-31`, config.Rule{AddedBefore: "2024-02-01"}},
+		{`added:2024-02-29`, config.Rule{AddedAfter: "2024-02-29", AddedBefore: "2024-03-01"}},
+		{`tempo>=120`, config.Rule{Features: map[string]config.Range{"tempo": {Min: num(120)}}}},
+		// AND binds tighter than OR.
+		{`artist:a OR artist:b AND artist:c`, config.Rule{Any: []config.Rule{
+			{Artist: "a"},
+			{All: []config.Rule{{Artist: "b"}, {Artist: "c"}}},
+		}}},
+		{`(artist:a OR artist:b) and artist:c`, config.Rule{All: []config.Rule{
+			{Any: []config.Rule{{Artist: "a"}, {Artist: "b"}}},
+			{Artist: "c"},
+		}}},
+		{`NOT artist:a artist:b`, config.Rule{All: []config.Rule{
+			{Not: &config.Rule{Artist: "a"}},
+			{Artist: "b"},
+		}}},
+		{`not not artist:a`, config.Rule{Not: &config.Rule{Not: &config.Rule{Artist: "a"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeatureBoundsAreStrict(t *testing.T) {
+	rule, err := Parse(`energy>0.5 energy<0.5`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	above, below := rule.All[0].Features["energy"].Min, rule.All[1].Features["energy"].Max
+	if float32(*above) <= 0.5 || float32(*below) >= 0.5 {
+		t.Errorf("strict bounds = %v and %v, want just above and below 0.5", *above, *below)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{`artist:"radio`, "unterminated string at position 7"},
+		{`(artist:a`, "unclosed '(' at position 0"},
+		{`artist:a)`, "unexpected ')' at position 8"},
+		{`artist`, "expected an operator after 'artist' at position 6"},
+		{`artist:`, "expected a value after 'artist:' at position 7"},
+		{`:x`, "expected a field at position 0, got ':'"},
+		{`mood:happy`, "unknown field 'mood'"},
+		{`year:nineties`, "year:nineties: year takes a whole number"},
+		{`added>yesterday`, "dates look like 2024-01-31"},
+		{`artist>a`, "artist only supports ':'"},
+		{`explicit:maybe`, "explicit takes true or false"},
+		{`energy:high`, "energy takes a number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseTerminates guards against the lexer not advancing, which hung query tasks.
+func TestParseTerminates(t *testing.T) {
+	for _, expr := range []string{"artist:à", "artist:x\r", "\x85", "artist:\xa0", "title:\xff\xfe", "Åsa\v"} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Parse(expr)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Parse(%q) didn't return", expr)
+		}
+	}
+}
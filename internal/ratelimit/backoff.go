@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// backoffRetries is how many times a rate-limited request is sent again.
+	backoffRetries = 3
+	// maxRetryAfter is the longest wait worth sitting out; Spotify asks for hours when
+	// an app is banned for a while, and the run had better stop with its exit code.
+	maxRetryAfter = 2 * time.Minute
+	// defaultRetryAfter is waited when a 429 doesn't say how long to wait.
+	defaultRetryAfter = 5 * time.Second
+)
+
+// Backoff waits out Spotify's 429 Too Many Requests answers and sends the request again.
+// The wait holds back every request sent through it, so parallel workers back off
+// together instead of each running into the limit again.
+type Backoff struct {
+	logger *log.Logger
+
+	mu sync.Mutex
+	// until is when requests may be sent again.
+	until time.Time
+}
+
+// NewBackoff returns a Backoff that logs its pauses to logger.
+func NewBackoff(logger *log.Logger) *Backoff {
+	return &Backoff{logger: logger}
+}
+
+// Wrap returns a transport that sends requests through next and retries the rate-limited
+// ones. A request still refused after a few retries, one Spotify asks to hold for too
+// long, and one whose body can't be sent again get the 429, which the client reports as
+// a spotify.Error.
+func (b *Backoff) Wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		for attempt := 0; ; attempt++ {
+			if err := b.wait(req.Context()); err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == backoffRetries {
+				return resp, err
+			}
+			delay := retryAfter(resp)
+			if delay > maxRetryAfter {
+				return resp, nil
+			}
+			retry := req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return resp, nil
+				}
+				if retry.Body, err = req.GetBody(); err != nil {
+					return resp, nil
+				}
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			b.logger.Printf("⚠️  Hit Spotify's rate limit, pausing every request for %s before retrying...", delay)
+			b.pause(delay)
+			req = retry
+		}
+	})
+}
+
+// retryAfter returns how long a 429 response asks to wait.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return defaultRetryAfter
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// wait blocks until requests may be sent or ctx is done.
+func (b *Backoff) wait(ctx context.Context) error {
+	b.mu.Lock()
+	d := time.Until(b.until)
+	b.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause holds requests back for d, unless they're already held back for longer.
+func (b *Backoff) pause(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.until) {
+		b.until = until
+	}
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// limitedServer answers the first limited requests with a 429 asking to wait retryAfter
+// seconds, and records the bodies it was sent.
+type limitedServer struct {
+	limited    int
+	retryAfter string
+	bodies     []string
+}
+
+func (s *limitedServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+	}
+	s.bodies = append(s.bodies, body)
+	if len(s.bodies) <= s.limited {
+		header := http.Header{}
+		header.Set("Retry-After", s.retryAfter)
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header, Body: http.NoBody}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func TestBackoffRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		limited    int
+		retryAfter string
+		wantStatus int
+		wantSent   int
+	}{
+		{"not limited", 0, "0", http.StatusOK, 1},
+		{"limited once", 1, "0", http.StatusOK, 2},
+		{"limited until the last retry", backoffRetries, "0", http.StatusOK, backoffRetries + 1},
+		{"still limited after the retries", backoffRetries + 1, "0", http.StatusTooManyRequests, backoffRetries + 1},
+		{"asked to wait too long", 1, "3600", http.StatusTooManyRequests, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &limitedServer{limited: tt.limited, retryAfter: tt.retryAfter}
+			rt := NewBackoff(log.New(io.Discard, "", 0)).Wrap(server)
+			req, _ := http.NewRequest(http.MethodPut, "https://api.spotify.com/v1/me/tracks", strings.NewReader(`{"ids":["a"]}`))
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(server.bodies) != tt.wantSent {
+				t.Errorf("sent %d times, want %d", len(server.bodies), tt.wantSent)
+			}
+			for i, body := range server.bodies {
+				if body != `{"ids":["a"]}` {
+					t.Errorf("body of attempt %d = %q, want it sent again", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffBodyNotResendable(t *testing.T) {
+	server := &limitedServer{limited: 1, retryAfter: "0"}
+	rt := NewBackoff(log.New(io.Discard, "", 0)).Wrap(server)
+	req, _ := http.NewRequest(http.MethodPut, "https://api.spotify.com/v1/me/tracks", &closeRecorder{Reader: strings.NewReader("{}")})
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests || len(server.bodies) != 1 {
+		t.Errorf("got status %v, error %v after %d attempts; want the 429 without a retry", resp, err, len(server.bodies))
+	}
+}
+
+func TestBackoffHoldsBackEveryRequest(t *testing.T) {
+	b := NewBackoff(log.New(io.Discard, "", 0))
+	server := &limitedServer{}
+	rt := b.Wrap(server)
+	b.pause(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	body := &closeRecorder{Reader: strings.NewReader("{}")}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.spotify.com/v1/me/tracks", body)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if len(server.bodies) != 0 {
+		t.Error("a request was sent while the rate limit was waited out")
+	}
+	if !body.closed {
+		t.Error("the body of a request that wasn't sent was left open")
+	}
+
+	// A shorter pause doesn't cut the longer one short.
+	b.pause(time.Millisecond)
+	if d := time.Until(b.until); d < 59*time.Minute {
+		t.Errorf("pause left %s, want about an hour", d)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"7", 7 * time.Second},
+		{"0", 0},
+		{"", defaultRetryAfter},
+		{"soon", defaultRetryAfter},
+		{"-1", defaultRetryAfter},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{"Retry-After": {tt.header}}}
+		if got := retryAfter(resp); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"spotify/internal/processor"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxBatch is the most items Spotify takes in one change of the library or a playlist.
+const maxBatch = 100
+
+// Batches is a SpotifyClient that splits changes to the library and to playlists into
+// batches of at most size items, and waits delay between batches: bursts of large writes
+// are what trip Spotify's rate limit on big libraries. Other calls pass straight through.
+type Batches struct {
+	processor.SpotifyClient
+	size  int
+	delay time.Duration
+
+	mu sync.Mutex
+	// next is when the next batch may be sent.
+	next time.Time
+}
+
+// NewBatches wraps client. A size of 0 keeps the batches processors send.
+func NewBatches(client processor.SpotifyClient, size int, delay time.Duration) (*Batches, error) {
+	if size < 0 || size > maxBatch {
+		return nil, fmt.Errorf("batch size must be between 1 and %d, or 0 to keep the default, got %d", maxBatch, size)
+	}
+	if delay < 0 {
+		return nil, fmt.Errorf("batch delay must not be negative, got %s", delay)
+	}
+	return &Batches{SpotifyClient: client, size: size, delay: delay}, nil
+}
+
+// wait blocks until delay has passed since the previous batch, or ctx ends.
+func (b *Batches) wait(ctx context.Context) error {
+	b.mu.Lock()
+	// Reserving the slot now queues concurrent callers in order.
+	at := time.Now()
+	if b.next.After(at) {
+		at = b.next
+	}
+	b.next = at.Add(b.delay)
+	b.mu.Unlock()
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// split calls fn with ids in batches, waiting before each. Nothing is sent for no ids.
+func (b *Batches) split(ctx context.Context, ids []spotify.ID, fn func(batch []spotify.ID) error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	size := len(ids)
+	if b.size > 0 && b.size < size {
+		size = b.size
+	}
+	i := 0
+	for {
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+		end := min(i+size, len(ids))
+		if err := fn(ids[i:end]); err != nil {
+			return err
+		}
+		if i = end; i >= len(ids) {
+			return nil
+		}
+	}
+}
+
+func (b *Batches) AddTracksToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	return b.split(ctx, ids, func(batch []spotify.ID) error {
+		return b.SpotifyClient.AddTracksToLibrary(ctx, batch...)
+	})
+}
+
+func (b *Batches) RemoveTracksFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	return b.split(ctx, ids, func(batch []spotify.ID) error {
+		return b.SpotifyClient.RemoveTracksFromLibrary(ctx, batch...)
+	})
+}
+
+func (b *Batches) AddAlbumsToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	return b.split(ctx, ids, func(batch []spotify.ID) error {
+		return b.SpotifyClient.AddAlbumsToLibrary(ctx, batch...)
+	})
+}
+
+func (b *Batches) RemoveAlbumsFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	return b.split(ctx, ids, func(batch []spotify.ID) error {
+		return b.SpotifyClient.RemoveAlbumsFromLibrary(ctx, batch...)
+	})
+}
+
+func (b *Batches) FollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	return b.split(ctx, ids, func(batch []spotify.ID) error {
+		return b.SpotifyClient.FollowArtist(ctx, batch...)
+	})
+}
+
+func (b *Batches) UnfollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	return b.split(ctx, ids, func(batch []spotify.ID) error {
+		return b.SpotifyClient.UnfollowArtist(ctx, batch...)
+	})
+}
+
+// AddTracksToPlaylist returns the snapshot of the last batch.
+func (b *Batches) AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	var snapshotID string
+	err := b.split(ctx, trackIDs, func(batch []spotify.ID) (err error) {
+		snapshotID, err = b.SpotifyClient.AddTracksToPlaylist(ctx, playlistID, batch...)
+		return err
+	})
+	return snapshotID, err
+}
+
+// RemoveTracksFromPlaylist returns the snapshot of the last batch.
+func (b *Batches) RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	var snapshotID string
+	err := b.split(ctx, trackIDs, func(batch []spotify.ID) (err error) {
+		snapshotID, err = b.SpotifyClient.RemoveTracksFromPlaylist(ctx, playlistID, batch...)
+		return err
+	})
+	return snapshotID, err
+}
+
+// ReplacePlaylistItems replaces the playlist with the first batch and appends the rest,
+// returning the snapshot of the last batch. Only songs can be appended, so items that
+// include episodes are replaced in a single call.
+func (b *Batches) ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error) {
+	ids := make([]spotify.ID, 0, len(items))
+	for _, uri := range items {
+		id, ok := strings.CutPrefix(string(uri), "spotify:track:")
+		if !ok {
+			ids = nil
+			break
+		}
+		ids = append(ids, spotify.ID(id))
+	}
+	first := len(items)
+	if ids != nil && b.size > 0 {
+		first = min(b.size, len(items))
+	}
+	if err := b.wait(ctx); err != nil {
+		return "", err
+	}
+	snapshotID, err := b.SpotifyClient.ReplacePlaylistItems(ctx, playlistID, items[:first]...)
+	if err != nil || first == len(items) {
+		return snapshotID, err
+	}
+	err = b.split(ctx, ids[first:], func(batch []spotify.ID) (err error) {
+		snapshotID, err = b.SpotifyClient.AddTracksToPlaylist(ctx, playlistID, batch...)
+		return err
+	})
+	return snapshotID, err
+}
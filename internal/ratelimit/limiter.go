@@ -0,0 +1,77 @@
+// Package ratelimit caps the rate of Spotify API requests on the client side, so long
+// scheduled runs stay clear of Spotify's own limits instead of recovering from them.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket: it allows burst requests at once and perSecond on average.
+type Limiter struct {
+	perSecond float64
+	burst     float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter allowing perSecond requests a second, in bursts of up to burst.
+func New(perSecond float64, burst int) (*Limiter, error) {
+	if perSecond <= 0 {
+		return nil, fmt.Errorf("requests per second must be positive, got %g", perSecond)
+	}
+	if burst < 1 {
+		return nil, fmt.Errorf("burst must be at least 1, got %d", burst)
+	}
+	return &Limiter{perSecond: perSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}, nil
+}
+
+// Wait blocks until a request may be made or ctx ends.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.perSecond)
+	l.last = now
+	// Taking the token now, even into debt, queues concurrent callers in order.
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.perSecond * float64(time.Second))
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the token back: the request won't be made.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Wrap returns a transport that waits for the limiter before each request it sends
+// through next. Wrapped in a Backoff, retries of rate-limited requests wait for it too.
+func (l *Limiter) Wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := l.Wait(req.Context()); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
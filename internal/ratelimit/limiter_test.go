@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tryWait reports whether l lets a request through within 50ms. The limiters under test
+// refill one token a second, so a request that has to wait never makes it.
+func tryWait(t *testing.T, l *Limiter) bool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := l.Wait(ctx)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait: %v", err)
+	}
+	return err == nil
+}
+
+func TestNewRejectsInvalidRates(t *testing.T) {
+	if _, err := New(0, 1); err == nil {
+		t.Error("New(0, 1) didn't fail")
+	}
+	if _, err := New(1, 0); err == nil {
+		t.Error("New(1, 0) didn't fail")
+	}
+}
+
+func TestBurst(t *testing.T) {
+	l, err := New(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if !tryWait(t, l) {
+			t.Fatalf("request %d of a burst of 3 was held back", i+1)
+		}
+	}
+	if tryWait(t, l) {
+		t.Error("a fourth request went through a burst of 3")
+	}
+}
+
+func TestRefill(t *testing.T) {
+	l, err := New(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		tryWait(t, l)
+	}
+
+	// Two seconds at one request a second make room for two more.
+	l.mu.Lock()
+	l.last = l.last.Add(-2 * time.Second)
+	l.mu.Unlock()
+	if !tryWait(t, l) || !tryWait(t, l) {
+		t.Fatal("requests were held back after the bucket refilled")
+	}
+	if tryWait(t, l) {
+		t.Error("a third request went through two refilled tokens")
+	}
+
+	// A long pause refills the bucket up to the burst only.
+	l.mu.Lock()
+	l.last = l.last.Add(-time.Hour)
+	l.mu.Unlock()
+	for i := range 3 {
+		if !tryWait(t, l) {
+			t.Fatalf("request %d was held back after a long pause", i+1)
+		}
+	}
+	if tryWait(t, l) {
+		t.Error("a long pause refilled the bucket past its burst")
+	}
+}
+
+func TestWaitCancelled(t *testing.T) {
+	l, err := New(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tryWait(t, l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait = %v, want context.Canceled", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Wait didn't return when its context was cancelled")
+	}
+
+	// The cancelled request gave its token back instead of pushing the next one further.
+	l.mu.Lock()
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens < 0 {
+		t.Errorf("tokens = %g after a cancelled wait, want the token given back", tokens)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	l, err := New(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := 0
+	rt := l.Wrap(roundTripFunc(func(*http.Request) (*http.Response, error) {
+		sent++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.spotify.com/v1/me", nil)
+	if _, err := rt.RoundTrip(req); err != nil || sent != 1 {
+		t.Fatalf("first request: sent %d, error %v", sent, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	body := &closeRecorder{Reader: strings.NewReader("{}")}
+	req, _ = http.NewRequestWithContext(ctx, http.MethodPost, "https://api.spotify.com/v1/me/tracks", body)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("held back request error = %v, want context.DeadlineExceeded", err)
+	}
+	if sent != 1 {
+		t.Errorf("a request held back past its deadline was sent")
+	}
+	if !body.closed {
+		t.Error("the body of a request that wasn't sent was left open")
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
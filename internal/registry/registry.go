@@ -0,0 +1,96 @@
+// Package registry holds the commands of the CLI. Each command registers itself with a
+// name, a description, the Spotify scopes it needs and the configuration it reads, so
+// the CLI can list and validate them, and new commands can be added in their own file
+// without touching the dispatcher.
+package registry
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"spotify/internal/assets"
+	"spotify/internal/config"
+	"spotify/internal/processor"
+	"spotify/internal/store"
+	"sync"
+)
+
+// Env is what a command is built from: the state shared by every command of a run.
+type Env interface {
+	// Client logs in on first use and returns the Spotify client.
+	Client() processor.SpotifyClient
+	Store() *store.Store
+	Logger() *log.Logger
+	Config() config.Config
+	ImageGenerator() (processor.ImageGenerator, error)
+	AssetCache() *assets.Cache
+}
+
+// Command is a command of the CLI.
+type Command struct {
+	Name        string
+	Description string
+	// Scopes are the Spotify authorization scopes the command needs. The login asks for
+	// those of the command being run, and no more.
+	Scopes []string
+	// Public commands can run on an app token, with --public, when they're given only
+	// public data to read.
+	Public bool
+	// Quiet commands print only their output, without the start and finish messages, so
+	// it can be piped or evaluated.
+	Quiet bool
+	// ConfigSection names the section of config.yaml the command reads, or "".
+	ConfigSection string
+	// Validate checks the command's configuration without logging in. May be nil.
+	Validate func(cfg config.Config) error
+	// Build parses the command's arguments and returns its processor.
+	Build func(env Env, args []string) (processor.Processor, error)
+}
+
+var (
+	mu       sync.Mutex
+	commands = make(map[string]Command)
+)
+
+// Register adds a command. It panics if the name is taken or Build is missing, since
+// either is a programming error.
+func Register(c Command) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c.Build == nil {
+		panic(fmt.Sprintf("registry: command '%s' has no Build function", c.Name))
+	}
+	if _, dup := commands[c.Name]; dup {
+		panic(fmt.Sprintf("registry: command '%s' registered twice", c.Name))
+	}
+	commands[c.Name] = c
+}
+
+// Lookup returns the command called name.
+func Lookup(name string) (Command, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := commands[name]
+	return c, ok
+}
+
+// All returns every registered command, sorted by name.
+func All() []Command {
+	mu.Lock()
+	defer mu.Unlock()
+	all := make([]Command, 0, len(commands))
+	for _, c := range commands {
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Names returns the names of every registered command, sorted.
+func Names() []string {
+	var names []string
+	for _, c := range All() {
+		names = append(names, c.Name)
+	}
+	return names
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+var wrappedPage = template.Must(template.New("wrapped").Funcs(template.FuncMap{
+	"hours":   func(e Entry) string { return fmt.Sprintf("%.1fh", e.Listened.Hours()) },
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", 100*f) },
+	"inc":     func(i int) int { return i + 1 },
+	"section": func(title string, entries []Entry) any {
+		return struct {
+			Title   string
+			Entries []Entry
+		}{title, entries}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>My {{.Year}} in Music</title>
+<style>
+  body { margin: 0; background: linear-gradient(160deg, #1db954, #191414 60%); color: #fff; font-family: system-ui, sans-serif; min-height: 100vh; }
+  main { max-width: 720px; margin: 0 auto; padding: 48px 24px; }
+  h1 { font-size: 3em; margin: 0 0 32px; }
+  .stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(150px, 1fr)); gap: 16px; margin-bottom: 40px; }
+  .stat { background: rgba(255,255,255,.08); border-radius: 12px; padding: 16px; }
+  .stat b { display: block; font-size: 2em; }
+  h2 { margin: 32px 0 12px; }
+  ol { list-style: none; padding: 0; margin: 0; }
+  li { position: relative; padding: 8px 12px; margin: 4px 0; border-radius: 6px; overflow: hidden; isolation: isolate; }
+  li .bar { position: absolute; inset: 0 auto 0 0; background: rgba(29,185,84,.35); z-index: -1; }
+  li span { opacity: .7; }
+  li em { float: right; font-style: normal; opacity: .7; }
+  footer { margin-top: 48px; opacity: .5; font-size: .85em; }
+</style>
+</head>
+<body>
+<main>
+<h1>My {{.Year}} in Music</h1>
+<div class="stats">
+  <div class="stat"><b>{{printf "%.0f" .Hours}}</b>hours listened</div>
+  <div class="stat"><b>{{.Plays}}</b>plays</div>
+  <div class="stat"><b>{{.Tracks}}</b>tracks</div>
+  <div class="stat"><b>{{.Artists}}</b>artists</div>
+  <div class="stat"><b>{{percent .DiscoveryRate}}</b>new discoveries</div>
+</div>
+{{define "ranking"}}{{if .Entries}}
+<h2>{{.Title}}</h2>
+<ol>
+{{range $i, $e := .Entries}}  <li><div class="bar" style="width: {{percent $e.Share}}"></div>{{inc $i}}. {{$e.Name}}{{if $e.Detail}} <span>· {{$e.Detail}}</span>{{end}}<em>{{hours $e}}</em></li>
+{{end}}</ol>
+{{end}}{{end}}
+{{template "ranking" (section "Top Tracks" .TopTracks)}}
+{{template "ranking" (section "Top Artists" .TopArtists)}}
+{{template "ranking" (section "Top Genres" .TopGenres)}}
+<footer>Made with spotify-manager from my streaming history.</footer>
+</main>
+</body>
+</html>
+`))
+
+// WriteHTML renders the report as a standalone page to share.
+func (w Wrapped) WriteHTML(out io.Writer) error {
+	return wrappedPage.Execute(out, w)
+}
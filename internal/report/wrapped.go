@@ -0,0 +1,157 @@
+// Package report renders summaries of the listening history, such as the year in review.
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"spotify/internal/history"
+	"spotify/internal/store"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Entry is one line of a ranking.
+type Entry struct {
+	Name string
+	// Detail is shown next to the name, e.g. a track's artist.
+	Detail   string
+	Plays    int
+	Listened time.Duration
+	// Share is Listened relative to the first entry of the ranking, for drawing bars.
+	Share float64
+}
+
+// Wrapped is a year in review built from the imported streaming history.
+type Wrapped struct {
+	Year     int
+	Listened time.Duration
+	Plays    int
+	Tracks   int
+	Artists  int
+	// NewTracks counts the tracks first played this year. DiscoveryRate is their share of
+	// Tracks; it's only meaningful when the history covers the years before.
+	NewTracks     int
+	DiscoveryRate float64
+	TopTracks     []Entry
+	TopArtists    []Entry
+	TopGenres     []Entry
+}
+
+// BuildWrapped summarizes the plays of year (in UTC), keeping the top entries of each
+// ranking. Plays shorter than minPlay don't count. Genres aren't part of the history, so
+// TopGenres is left for the caller.
+func BuildWrapped(plays []store.Play, year int, minPlay time.Duration, top int) Wrapped {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+	w := Wrapped{Year: year}
+
+	heardBefore := make(map[string]struct{})
+	artists := make(map[string]*Entry)
+	for _, p := range plays {
+		if time.Duration(p.MsPlayed)*time.Millisecond < minPlay || !p.PlayedAt.Before(to) {
+			continue
+		}
+		if p.PlayedAt.Before(from) {
+			heardBefore[trackKey(p.TrackID, p.TrackName, p.ArtistName)] = struct{}{}
+			continue
+		}
+		w.Plays++
+		w.Listened += time.Duration(p.MsPlayed) * time.Millisecond
+		a, ok := artists[p.ArtistName]
+		if !ok {
+			a = &Entry{Name: p.ArtistName}
+			artists[p.ArtistName] = a
+		}
+		a.Plays++
+		a.Listened += time.Duration(p.MsPlayed) * time.Millisecond
+	}
+
+	ranked := history.RankTracks(plays, from, to, minPlay)
+	w.Tracks = len(ranked)
+	w.Artists = len(artists)
+	for _, stat := range ranked {
+		if _, ok := heardBefore[trackKey(stat.TrackID, stat.TrackName, stat.ArtistName)]; !ok {
+			w.NewTracks++
+		}
+		if len(w.TopTracks) < top {
+			w.TopTracks = append(w.TopTracks, Entry{
+				Name:     stat.TrackName,
+				Detail:   stat.ArtistName,
+				Plays:    stat.Plays,
+				Listened: time.Duration(stat.MsPlayed) * time.Millisecond,
+			})
+		}
+	}
+	if w.Tracks > 0 {
+		w.DiscoveryRate = float64(w.NewTracks) / float64(w.Tracks)
+	}
+	w.TopTracks = withShares(w.TopTracks)
+
+	byArtist := make([]Entry, 0, len(artists))
+	for _, a := range artists {
+		byArtist = append(byArtist, *a)
+	}
+	w.TopArtists = Rank(byArtist, top)
+	return w
+}
+
+// Rank orders entries by listening time, most first, and keeps the top ones.
+func Rank(entries []Entry, top int) []Entry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Listened != entries[j].Listened {
+			return entries[i].Listened > entries[j].Listened
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return withShares(entries[:min(top, len(entries))])
+}
+
+// withShares fills in each entry's share of the first.
+func withShares(entries []Entry) []Entry {
+	for i := range entries {
+		if entries[0].Listened > 0 {
+			entries[i].Share = float64(entries[i].Listened) / float64(entries[0].Listened)
+		}
+	}
+	return entries
+}
+
+// trackKey identifies a track by ID, or by name for plays from exports without IDs.
+func trackKey(id spotify.ID, name, artist string) string {
+	if id != "" {
+		return string(id)
+	}
+	return strings.ToLower(name) + "|" + strings.ToLower(artist)
+}
+
+// Hours returns the listening time in hours.
+func (w Wrapped) Hours() float64 {
+	return w.Listened.Hours()
+}
+
+// WriteText prints the report for the terminal.
+func (w Wrapped) WriteText(out io.Writer) {
+	fmt.Fprintf(out, "🎧 Your %d in music\n\n", w.Year)
+	fmt.Fprintf(out, "  %.0f hours listened over %d plays\n", w.Hours(), w.Plays)
+	fmt.Fprintf(out, "  %d different tracks by %d artists\n", w.Tracks, w.Artists)
+	fmt.Fprintf(out, "  %d tracks discovered (%.0f%% of what you played)\n", w.NewTracks, 100*w.DiscoveryRate)
+	section := func(title string, entries []Entry) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintf(out, "\n%s\n", title)
+		for i, e := range entries {
+			name := e.Name
+			if e.Detail != "" {
+				name += " · " + e.Detail
+			}
+			fmt.Fprintf(out, "  %2d. %-50s %6.1fh\n", i+1, name, e.Listened.Hours())
+		}
+	}
+	section("Top tracks", w.TopTracks)
+	section("Top artists", w.TopArtists)
+	section("Top genres", w.TopGenres)
+}
@@ -0,0 +1,240 @@
+// Package rules compiles the smart playlist rules declared in the config into conditions
+// on liked songs.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"spotify/internal/config"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Track is a liked song with the data conditions look at.
+type Track struct {
+	spotify.SavedTrack
+	AddedAt time.Time
+	// Genres are the genres of the primary artist, if Needs.Genres asked for them.
+	Genres []string
+	// Tags are the song's local tags, if Needs.Tags asked for them.
+	Tags []string
+	// Features are the song's audio features, if Needs.Features asked for them. nil when
+	// Spotify has none.
+	Features *spotify.AudioFeatures
+}
+
+// Condition reports whether a track matches a rule.
+type Condition func(t Track) bool
+
+// Needs lists the data a rule reads that isn't part of a liked song, so callers only
+// fetch what's used.
+type Needs struct {
+	Genres   bool
+	Features bool
+	Tags     bool
+}
+
+// features reads each supported audio feature.
+var features = map[string]func(f *spotify.AudioFeatures) float32{
+	"acousticness":     func(f *spotify.AudioFeatures) float32 { return f.Acousticness },
+	"danceability":     func(f *spotify.AudioFeatures) float32 { return f.Danceability },
+	"energy":           func(f *spotify.AudioFeatures) float32 { return f.Energy },
+	"instrumentalness": func(f *spotify.AudioFeatures) float32 { return f.Instrumentalness },
+	"liveness":         func(f *spotify.AudioFeatures) float32 { return f.Liveness },
+	"loudness":         func(f *spotify.AudioFeatures) float32 { return f.Loudness },
+	"speechiness":      func(f *spotify.AudioFeatures) float32 { return f.Speechiness },
+	"tempo":            func(f *spotify.AudioFeatures) float32 { return f.Tempo },
+	"valence":          func(f *spotify.AudioFeatures) float32 { return f.Valence },
+}
+
+// Compile turns r into a Condition. Dates are read in loc and AddedWithinDays counts back
+// from now. It fails on empty rules, bad patterns or dates, and unknown features.
+func Compile(r config.Rule, loc *time.Location, now time.Time) (Condition, Needs, error) {
+	c := compiler{loc: loc, now: now}
+	cond, err := c.compile(r)
+	return cond, c.needs, err
+}
+
+type compiler struct {
+	loc   *time.Location
+	now   time.Time
+	needs Needs
+}
+
+func (c *compiler) compile(r config.Rule) (Condition, error) {
+	var conds []Condition
+	add := func(cond Condition) { conds = append(conds, cond) }
+
+	if len(r.All) > 0 {
+		all, err := c.compileEach(r.All)
+		if err != nil {
+			return nil, fmt.Errorf("all: %w", err)
+		}
+		add(func(t Track) bool {
+			for _, cond := range all {
+				if !cond(t) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	if len(r.Any) > 0 {
+		anyOf, err := c.compileEach(r.Any)
+		if err != nil {
+			return nil, fmt.Errorf("any: %w", err)
+		}
+		add(func(t Track) bool {
+			for _, cond := range anyOf {
+				if cond(t) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if r.Not != nil {
+		not, err := c.compile(*r.Not)
+		if err != nil {
+			return nil, fmt.Errorf("not: %w", err)
+		}
+		add(func(t Track) bool { return !not(t) })
+	}
+	if r.Title != "" {
+		pattern, err := globPattern(r.Title)
+		if err != nil {
+			return nil, fmt.Errorf("title: %w", err)
+		}
+		add(func(t Track) bool { return matchGlob(pattern, t.Name) })
+	}
+	if r.Artist != "" {
+		pattern, err := globPattern(r.Artist)
+		if err != nil {
+			return nil, fmt.Errorf("artist: %w", err)
+		}
+		add(func(t Track) bool {
+			return slices.ContainsFunc(t.Artists, func(a spotify.SimpleArtist) bool { return matchGlob(pattern, a.Name) })
+		})
+	}
+	if r.Genre != "" {
+		pattern, err := globPattern(r.Genre)
+		if err != nil {
+			return nil, fmt.Errorf("genre: %w", err)
+		}
+		c.needs.Genres = true
+		add(func(t Track) bool {
+			return slices.ContainsFunc(t.Genres, func(g string) bool { return matchGlob(pattern, g) })
+		})
+	}
+	if r.Tag != "" {
+		pattern, err := globPattern(r.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("tag: %w", err)
+		}
+		c.needs.Tags = true
+		add(func(t Track) bool {
+			return slices.ContainsFunc(t.Tags, func(tag string) bool { return matchGlob(pattern, tag) })
+		})
+	}
+	if r.AddedAfter != "" {
+		after, err := time.ParseInLocation(time.DateOnly, r.AddedAfter, c.loc)
+		if err != nil {
+			return nil, fmt.Errorf("added_after: %w", err)
+		}
+		add(func(t Track) bool { return !t.AddedAt.Before(after) })
+	}
+	if r.AddedBefore != "" {
+		before, err := time.ParseInLocation(time.DateOnly, r.AddedBefore, c.loc)
+		if err != nil {
+			return nil, fmt.Errorf("added_before: %w", err)
+		}
+		add(func(t Track) bool { return t.AddedAt.Before(before) })
+	}
+	if r.AddedWithinDays > 0 {
+		since := c.now.AddDate(0, 0, -r.AddedWithinDays)
+		add(func(t Track) bool { return t.AddedAt.After(since) })
+	}
+	if r.Explicit != nil {
+		explicit := *r.Explicit
+		add(func(t Track) bool { return t.Explicit == explicit })
+	}
+	if r.Popularity != nil {
+		rng := *r.Popularity
+		add(func(t Track) bool { return inRange(rng, float64(t.Popularity)) })
+	}
+	if r.ReleaseYear != nil {
+		rng := *r.ReleaseYear
+		add(func(t Track) bool {
+			year, err := strconv.Atoi(strings.SplitN(t.Album.ReleaseDate, "-", 2)[0])
+			return err == nil && inRange(rng, float64(year))
+		})
+	}
+	for _, name := range sortedKeys(r.Features) {
+		get, ok := features[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown audio feature '%s' (available: %s)", name, strings.Join(sortedKeys(features), ", "))
+		}
+		rng := r.Features[name]
+		c.needs.Features = true
+		add(func(t Track) bool { return t.Features != nil && inRange(rng, float64(get(t.Features))) })
+	}
+
+	switch len(conds) {
+	case 0:
+		return nil, errors.New("empty rule")
+	case 1:
+		return conds[0], nil
+	}
+	return func(t Track) bool {
+		for _, cond := range conds {
+			if !cond(t) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (c *compiler) compileEach(rules []config.Rule) ([]Condition, error) {
+	conds := make([]Condition, len(rules))
+	for i, r := range rules {
+		cond, err := c.compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		conds[i] = cond
+	}
+	return conds, nil
+}
+
+// globPattern lowercases pattern and checks its syntax.
+func globPattern(pattern string) (string, error) {
+	pattern = strings.ToLower(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	return pattern, nil
+}
+
+func matchGlob(pattern, s string) bool {
+	ok, _ := path.Match(pattern, strings.ToLower(s))
+	return ok
+}
+
+func inRange(r config.Range, v float64) bool {
+	return (r.Min == nil || v >= *r.Min) && (r.Max == nil || v <= *r.Max)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package rules
+
+import (
+	"spotify/internal/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func num(v float64) *float64 { return &v }
+
+// song is a liked song by artists, released in year and liked at added.
+func song(added time.Time, year string, popularity int, explicit bool, artists ...string) Track {
+	t := Track{AddedAt: added}
+	for _, a := range artists {
+		t.Artists = append(t.Artists, spotify.SimpleArtist{Name: a})
+	}
+	t.Album.ReleaseDate = year
+	t.Popularity = spotify.Numeric(popularity)
+	t.Explicit = explicit
+	return t
+}
+
+func TestCompile(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	radiohead := song(time.Date(2024, time.June, 10, 0, 0, 0, 0, time.UTC), "1997-05-21", 80, false, "Radiohead")
+	radiohead.Genres = []string{"alternative rock", "art rock"}
+	radiohead.Features = &spotify.AudioFeatures{Energy: 0.7, Tempo: 120}
+	feature := song(time.Date(2023, time.December, 31, 23, 30, 0, 0, time.UTC), "2021", 40, true, "Kanye West", "Frank Ocean")
+	yes, no := true, false
+
+	tests := []struct {
+		name  string
+		rule  config.Rule
+		track Track
+		want  bool
+		needs Needs
+	}{
+		{"artist glob ignores case", config.Rule{Artist: "RADIO*"}, radiohead, true, Needs{}},
+		{"artist matches any credited artist", config.Rule{Artist: "frank ocean"}, feature, true, Needs{}},
+		{"artist miss", config.Rule{Artist: "radio"}, radiohead, false, Needs{}},
+		{"genre", config.Rule{Genre: "*rock"}, radiohead, true, Needs{Genres: true}},
+		{"genre without genres", config.Rule{Genre: "*rock"}, feature, false, Needs{Genres: true}},
+		{"explicit", config.Rule{Explicit: &yes}, feature, true, Needs{}},
+		{"not explicit", config.Rule{Explicit: &no}, feature, false, Needs{}},
+		{"popularity range includes its ends", config.Rule{Popularity: &config.Range{Min: num(40), Max: num(40)}}, feature, true, Needs{}},
+		{"popularity below min", config.Rule{Popularity: &config.Range{Min: num(50)}}, feature, false, Needs{}},
+		{"release year from a full date", config.Rule{ReleaseYear: &config.Range{Max: num(1999)}}, radiohead, true, Needs{}},
+		{"release year from a bare year", config.Rule{ReleaseYear: &config.Range{Min: num(2021)}}, feature, true, Needs{}},
+		{"feature range", config.Rule{Features: map[string]config.Range{"energy": {Min: num(0.6)}, "tempo": {Max: num(130)}}}, radiohead, true, Needs{Features: true}},
+		{"feature missing from the song", config.Rule{Features: map[string]config.Range{"energy": {Min: num(0)}}}, feature, false, Needs{Features: true}},
+		// 23:30 UTC on New Year's Eve is already 2024 in Rome.
+		{"added after in the rule's zone", config.Rule{AddedAfter: "2024-01-01"}, feature, true, Needs{}},
+		{"added before is exclusive", config.Rule{AddedBefore: "2024-01-01"}, feature, false, Needs{}},
+		{"added within days", config.Rule{AddedWithinDays: 7}, radiohead, true, Needs{}},
+		{"added too long ago", config.Rule{AddedWithinDays: 7}, feature, false, Needs{}},
+		{"fields of one rule are ANDed", config.Rule{Artist: "radiohead", Explicit: &yes}, radiohead, false, Needs{}},
+		{"all", config.Rule{All: []config.Rule{{Artist: "radiohead"}, {Genre: "art rock"}}}, radiohead, true, Needs{Genres: true}},
+		{"all with a miss", config.Rule{All: []config.Rule{{Artist: "radiohead"}, {Explicit: &yes}}}, radiohead, false, Needs{}},
+		{"any", config.Rule{Any: []config.Rule{{Artist: "muse"}, {Artist: "kanye*"}}}, feature, true, Needs{}},
+		{"any without a hit", config.Rule{Any: []config.Rule{{Artist: "muse"}, {Explicit: &no}}}, feature, false, Needs{}},
+		{"not", config.Rule{Not: &config.Rule{Artist: "radiohead"}}, feature, true, Needs{}},
+		{"nested needs", config.Rule{Not: &config.Rule{Any: []config.Rule{{Features: map[string]config.Range{"valence": {}}}}}}, radiohead, false, Needs{Features: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, needs, err := Compile(tt.rule, rome, now)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			if got := cond(tt.track); got != tt.want {
+				t.Errorf("condition = %t, want %t", got, tt.want)
+			}
+			if needs != tt.needs {
+				t.Errorf("needs = %+v, want %+v", needs, tt.needs)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.Rule
+		want string
+	}{
+		{"empty", config.Rule{}, "empty rule"},
+		{"empty nested", config.Rule{Any: []config.Rule{{Artist: "a"}, {}}}, "any: rule 2: empty rule"},
+		{"bad pattern", config.Rule{Artist: "[a"}, "artist: invalid pattern"},
+		{"bad date", config.Rule{Not: &config.Rule{AddedAfter: "01/02/2024"}}, "not: added_after"},
+		{"unknown feature", config.Rule{Features: map[string]config.Range{"mood": {}}}, "unknown audio feature 'mood'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Compile(tt.rule, time.UTC, time.Now())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package setlistfm fetches concert setlists from the setlist.fm API.
+package setlistfm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const apiURL = "https://api.setlist.fm/rest/1.0"
+
+// Song is one song played at a concert. Artist differs from the setlist's for covers.
+type Song struct {
+	Title  string
+	Artist string
+}
+
+// Setlist is the music played at one concert, in order.
+type Setlist struct {
+	ID     string
+	Artist string
+	Date   time.Time
+	Venue  string
+	City   string
+	Songs  []Song
+}
+
+// Client calls the setlist.fm API with an API key.
+type Client struct {
+	apiKey string
+	http   *http.Client
+}
+
+// New returns a client using apiKey.
+func New(apiKey string) *Client {
+	return &Client{apiKey: apiKey, http: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// urlID matches the setlist ID at the end of a setlist page URL, e.g.
+// ".../radiohead/2017/.../radiohead-at-...-63e1b2a7.html".
+var urlID = regexp.MustCompile(`-([0-9a-f]+)\.html$`)
+
+// IDFromURL extracts the setlist ID from a setlist.fm page URL.
+func IDFromURL(pageURL string) (string, error) {
+	m := urlID.FindStringSubmatch(pageURL)
+	if m == nil {
+		return "", fmt.Errorf("'%s' isn't a setlist.fm setlist URL", pageURL)
+	}
+	return m[1], nil
+}
+
+// Get returns the setlist with the given ID.
+func (c *Client) Get(ctx context.Context, id string) (Setlist, error) {
+	var s setlist
+	if err := c.get(ctx, "/setlist/"+url.PathEscape(id), nil, &s); err != nil {
+		return Setlist{}, err
+	}
+	return s.convert()
+}
+
+// Find returns the setlist of artist's concert on date. If several match, e.g. two shows
+// on one day, the first one listed with songs is returned.
+func (c *Client) Find(ctx context.Context, artist string, date time.Time) (Setlist, error) {
+	var body struct {
+		Setlist []setlist `json:"setlist"`
+	}
+	params := url.Values{"artistName": {artist}, "date": {date.Format("02-01-2006")}}
+	if err := c.get(ctx, "/search/setlists", params, &body); err != nil {
+		return Setlist{}, err
+	}
+	for _, s := range body.Setlist {
+		found, err := s.convert()
+		if err == nil && len(found.Songs) > 0 {
+			return found, nil
+		}
+	}
+	return Setlist{}, fmt.Errorf("no setlist found for %s on %s", artist, date.Format(time.DateOnly))
+}
+
+// setlist mirrors the API's JSON.
+type setlist struct {
+	ID        string `json:"id"`
+	EventDate string `json:"eventDate"`
+	Artist    struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Venue struct {
+		Name string `json:"name"`
+		City struct {
+			Name string `json:"name"`
+		} `json:"city"`
+	} `json:"venue"`
+	Sets struct {
+		Set []struct {
+			Song []struct {
+				Name  string `json:"name"`
+				Tape  bool   `json:"tape"`
+				Cover *struct {
+					Name string `json:"name"`
+				} `json:"cover"`
+			} `json:"song"`
+		} `json:"set"`
+	} `json:"sets"`
+}
+
+// convert flattens the sets into one list of songs, leaving out songs played from tape
+// and unnamed ones.
+func (s setlist) convert() (Setlist, error) {
+	date, err := time.Parse("02-01-2006", s.EventDate)
+	if err != nil {
+		return Setlist{}, fmt.Errorf("invalid event date '%s': %w", s.EventDate, err)
+	}
+	out := Setlist{ID: s.ID, Artist: s.Artist.Name, Date: date, Venue: s.Venue.Name, City: s.Venue.City.Name}
+	for _, set := range s.Sets.Set {
+		for _, song := range set.Song {
+			if song.Tape || song.Name == "" {
+				continue
+			}
+			artist := s.Artist.Name
+			if song.Cover != nil && song.Cover.Name != "" {
+				artist = song.Cover.Name
+			}
+			out.Songs = append(out.Songs, Song{Title: song.Name, Artist: artist})
+		}
+	}
+	return out, nil
+}
+
+// get sends a GET request to path and decodes the JSON response into out.
+func (c *Client) get(ctx context.Context, path string, params url.Values, out any) error {
+	if c.apiKey == "" {
+		return fmt.Errorf("SETLISTFM_API_KEY must be set")
+	}
+	u := apiURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("setlist.fm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("setlist.fm has no such setlist")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("setlist.fm returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode setlist.fm response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,616 @@
+package spotifytest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"spotify/internal/cache"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Client is a SpotifyClient over an in-memory account. Writes change the account, and
+// requests Spotify would refuse (unknown IDs, oversized batches) fail with a
+// spotify.Error as they would there. It is safe for concurrent use.
+type Client struct {
+	mu      sync.Mutex
+	initial Fixture
+	state   Fixture
+	tracks  map[spotify.ID]spotify.FullTrack
+	artists map[spotify.ID]spotify.FullArtist
+	albums  map[spotify.ID]spotify.FullAlbum
+	// snapshots counts the writes to each playlist.
+	snapshots map[spotify.ID]int
+	created   int
+}
+
+// New returns a client whose account starts as f.
+func New(f Fixture) (*Client, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		state:     clone(f),
+		tracks:    make(map[spotify.ID]spotify.FullTrack),
+		artists:   make(map[spotify.ID]spotify.FullArtist),
+		albums:    make(map[spotify.ID]spotify.FullAlbum),
+		snapshots: make(map[spotify.ID]int),
+	}
+	for _, t := range f.Tracks {
+		c.tracks[t.ID] = t
+		// Albums only known from their tracks are listed with those tracks.
+		if _, ok := c.albums[t.Album.ID]; !ok {
+			c.albums[t.Album.ID] = spotify.FullAlbum{SimpleAlbum: t.Album}
+		}
+	}
+	for _, a := range f.Albums {
+		c.albums[a.ID] = a
+	}
+	for _, a := range f.Artists {
+		c.artists[a.ID] = a
+	}
+	for i := range c.state.Playlists {
+		if c.state.Playlists[i].Owner == "" {
+			c.state.Playlists[i].Owner = f.User.ID
+		}
+	}
+	c.initial = clone(c.state)
+	return c, nil
+}
+
+// Fixture returns the account as it is now, e.g. to check what a processor changed.
+func (c *Client) Fixture() Fixture {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return clone(c.state)
+}
+
+// clone copies the parts of f that writes change.
+func clone(f Fixture) Fixture {
+	f.Liked = slices.Clone(f.Liked)
+	f.SavedAlbums = slices.Clone(f.SavedAlbums)
+	f.FollowedArtists = slices.Clone(f.FollowedArtists)
+	f.Playlists = slices.Clone(f.Playlists)
+	f.Devices = slices.Clone(f.Devices)
+	f.Queue = slices.Clone(f.Queue)
+	for i := range f.Playlists {
+		f.Playlists[i].Tracks = slices.Clone(f.Playlists[i].Tracks)
+	}
+	return f
+}
+
+func apiError(status int, format string, args ...any) error {
+	return spotify.Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// window returns the range of n items a paged request asks for, with Spotify's default
+// and maximum page sizes.
+func window(opts []spotify.RequestOption, n, defaultLimit, maxLimit int) (start, end, limit int, err error) {
+	query, _ := cache.Query(opts)
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit = defaultLimit
+	if l, err := strconv.Atoi(query.Get("limit")); err == nil {
+		limit = l
+	}
+	if limit < 1 || limit > maxLimit || offset < 0 {
+		return 0, 0, 0, apiError(http.StatusBadRequest, "Invalid limit or offset")
+	}
+	return min(offset, n), min(offset+limit, n), limit, nil
+}
+
+// checkBatch refuses batches larger than Spotify accepts.
+func checkBatch(n, max int) error {
+	if n > max {
+		return apiError(http.StatusBadRequest, "Too many ids requested")
+	}
+	return nil
+}
+
+// playlist returns the index of a playlist. c.mu must be held.
+func (c *Client) playlist(id spotify.ID) (int, error) {
+	i := slices.IndexFunc(c.state.Playlists, func(p Playlist) bool { return p.ID == id })
+	if i < 0 {
+		return 0, apiError(http.StatusNotFound, "Resource not found")
+	}
+	return i, nil
+}
+
+// knownTracks refuses IDs that aren't in the catalog. c.mu must be held.
+func (c *Client) knownTracks(ids []spotify.ID) error {
+	for _, id := range ids {
+		if _, ok := c.tracks[id]; !ok {
+			return apiError(http.StatusBadRequest, "Invalid track id %s", id)
+		}
+	}
+	return nil
+}
+
+func (c *Client) CurrentUser(ctx context.Context) (*spotify.PrivateUser, error) {
+	user := c.state.User
+	return &user, nil
+}
+
+func (c *Client) CurrentUsersTracks(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedTrackPage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	start, end, limit, err := window(opts, len(c.state.Liked), 20, 50)
+	if err != nil {
+		return nil, err
+	}
+	page := &spotify.SavedTrackPage{}
+	page.Total, page.Offset, page.Limit = spotify.Numeric(len(c.state.Liked)), spotify.Numeric(start), spotify.Numeric(limit)
+	for _, s := range c.state.Liked[start:end] {
+		page.Tracks = append(page.Tracks, spotify.SavedTrack{AddedAt: s.AddedAt, FullTrack: c.tracks[s.ID]})
+	}
+	return page, nil
+}
+
+func (c *Client) AddTracksToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 50); err != nil {
+		return err
+	}
+	if err := c.knownTracks(ids); err != nil {
+		return err
+	}
+	c.state.Liked = save(c.state.Liked, ids)
+	return nil
+}
+
+func (c *Client) RemoveTracksFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 50); err != nil {
+		return err
+	}
+	c.state.Liked = unsave(c.state.Liked, ids)
+	return nil
+}
+
+func (c *Client) CurrentUsersAlbums(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SavedAlbumPage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	start, end, limit, err := window(opts, len(c.state.SavedAlbums), 20, 50)
+	if err != nil {
+		return nil, err
+	}
+	page := &spotify.SavedAlbumPage{}
+	page.Total, page.Offset, page.Limit = spotify.Numeric(len(c.state.SavedAlbums)), spotify.Numeric(start), spotify.Numeric(limit)
+	for _, s := range c.state.SavedAlbums[start:end] {
+		page.Albums = append(page.Albums, spotify.SavedAlbum{AddedAt: s.AddedAt, FullAlbum: c.album(s.ID)})
+	}
+	return page, nil
+}
+
+// album returns an album with its tracks. c.mu must be held.
+func (c *Client) album(id spotify.ID) spotify.FullAlbum {
+	album := c.albums[id]
+	if len(album.Tracks.Tracks) == 0 {
+		tracks := c.albumTracks(id)
+		album.Tracks.Tracks = tracks
+		album.Tracks.Total = spotify.Numeric(len(tracks))
+	}
+	return album
+}
+
+// albumTracks returns the catalog tracks of an album in disc and track order. c.mu must
+// be held.
+func (c *Client) albumTracks(id spotify.ID) []spotify.SimpleTrack {
+	if album, ok := c.albums[id]; ok && len(album.Tracks.Tracks) > 0 {
+		return album.Tracks.Tracks
+	}
+	var tracks []spotify.SimpleTrack
+	for _, t := range c.state.Tracks {
+		if t.Album.ID == id {
+			tracks = append(tracks, t.SimpleTrack)
+		}
+	}
+	slices.SortStableFunc(tracks, func(a, b spotify.SimpleTrack) int {
+		if a.DiscNumber != b.DiscNumber {
+			return int(a.DiscNumber - b.DiscNumber)
+		}
+		return int(a.TrackNumber - b.TrackNumber)
+	})
+	return tracks
+}
+
+func (c *Client) AddAlbumsToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 20); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, ok := c.albums[id]; !ok {
+			return apiError(http.StatusBadRequest, "Invalid album id %s", id)
+		}
+	}
+	c.state.SavedAlbums = save(c.state.SavedAlbums, ids)
+	return nil
+}
+
+func (c *Client) RemoveAlbumsFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 20); err != nil {
+		return err
+	}
+	c.state.SavedAlbums = unsave(c.state.SavedAlbums, ids)
+	return nil
+}
+
+// save adds the IDs not yet in saved to its front, as Spotify lists the newest first.
+func save(saved []Saved, ids []spotify.ID) []Saved {
+	now := time.Now().UTC().Format(time.RFC3339)
+	var added []Saved
+	for _, id := range ids {
+		if !slices.ContainsFunc(saved, func(s Saved) bool { return s.ID == id }) &&
+			!slices.ContainsFunc(added, func(s Saved) bool { return s.ID == id }) {
+			added = append(added, Saved{ID: id, AddedAt: now})
+		}
+	}
+	slices.Reverse(added)
+	return append(added, saved...)
+}
+
+func unsave(saved []Saved, ids []spotify.ID) []Saved {
+	return slices.DeleteFunc(saved, func(s Saved) bool { return slices.Contains(ids, s.ID) })
+}
+
+func (c *Client) GetArtists(ctx context.Context, ids ...spotify.ID) ([]*spotify.FullArtist, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 50); err != nil {
+		return nil, err
+	}
+	artists := make([]*spotify.FullArtist, len(ids))
+	for i, id := range ids {
+		if a, ok := c.artists[id]; ok {
+			artists[i] = &a
+		}
+	}
+	return artists, nil
+}
+
+func (c *Client) GetTracks(ctx context.Context, ids []spotify.ID, opts ...spotify.RequestOption) ([]*spotify.FullTrack, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 50); err != nil {
+		return nil, err
+	}
+	tracks := make([]*spotify.FullTrack, len(ids))
+	for i, id := range ids {
+		if t, ok := c.tracks[id]; ok {
+			tracks[i] = &t
+		}
+	}
+	return tracks, nil
+}
+
+func (c *Client) GetAudioFeatures(ctx context.Context, ids ...spotify.ID) ([]*spotify.AudioFeatures, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 100); err != nil {
+		return nil, err
+	}
+	features := make([]*spotify.AudioFeatures, len(ids))
+	for i, id := range ids {
+		for _, f := range c.state.AudioFeatures {
+			if f.ID == id {
+				features[i] = &f
+				break
+			}
+		}
+	}
+	return features, nil
+}
+
+func (c *Client) GetAlbumTracks(ctx context.Context, id spotify.ID, opts ...spotify.RequestOption) (*spotify.SimpleTrackPage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.albums[id]; !ok {
+		return nil, apiError(http.StatusNotFound, "Resource not found")
+	}
+	tracks := c.albumTracks(id)
+	start, end, limit, err := window(opts, len(tracks), 20, 50)
+	if err != nil {
+		return nil, err
+	}
+	page := &spotify.SimpleTrackPage{}
+	page.Total, page.Offset, page.Limit = spotify.Numeric(len(tracks)), spotify.Numeric(start), spotify.Numeric(limit)
+	page.Tracks = slices.Clone(tracks[start:end])
+	return page, nil
+}
+
+func (c *Client) CurrentUsersFollowedArtists(ctx context.Context, opts ...spotify.RequestOption) (*spotify.FullArtistCursorPage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	followed := c.state.FollowedArtists
+	start := 0
+	query, _ := cache.Query(opts)
+	if after := spotify.ID(query.Get("after")); after != "" {
+		start = slices.Index(followed, after) + 1
+	}
+	_, end, limit, err := window(opts, len(followed)-start, 20, 50)
+	if err != nil {
+		return nil, err
+	}
+	page := &spotify.FullArtistCursorPage{}
+	page.Total, page.Limit = spotify.Numeric(len(followed)), spotify.Numeric(limit)
+	for _, id := range followed[start : start+end] {
+		page.Artists = append(page.Artists, c.artists[id])
+	}
+	if start+end < len(followed) {
+		page.Cursor.After = string(followed[start+end-1])
+	}
+	return page, nil
+}
+
+func (c *Client) FollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 50); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, ok := c.artists[id]; !ok {
+			return apiError(http.StatusBadRequest, "Invalid artist id %s", id)
+		}
+		if !slices.Contains(c.state.FollowedArtists, id) {
+			c.state.FollowedArtists = append(c.state.FollowedArtists, id)
+		}
+	}
+	return nil
+}
+
+func (c *Client) UnfollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 50); err != nil {
+		return err
+	}
+	c.state.FollowedArtists = slices.DeleteFunc(c.state.FollowedArtists, func(id spotify.ID) bool { return slices.Contains(ids, id) })
+	return nil
+}
+
+func (c *Client) GetPlaylistsForUser(ctx context.Context, userID string, opts ...spotify.RequestOption) (*spotify.SimplePlaylistPage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if userID != c.state.User.ID {
+		return nil, apiError(http.StatusNotFound, "Resource not found")
+	}
+	start, end, limit, err := window(opts, len(c.state.Playlists), 20, 50)
+	if err != nil {
+		return nil, err
+	}
+	page := &spotify.SimplePlaylistPage{}
+	page.Total, page.Offset, page.Limit = spotify.Numeric(len(c.state.Playlists)), spotify.Numeric(start), spotify.Numeric(limit)
+	for _, p := range c.state.Playlists[start:end] {
+		page.Playlists = append(page.Playlists, c.simplePlaylist(p))
+	}
+	return page, nil
+}
+
+// simplePlaylist converts a playlist to Spotify's form. c.mu must be held.
+func (c *Client) simplePlaylist(p Playlist) spotify.SimplePlaylist {
+	sp := spotify.SimplePlaylist{
+		ID:            p.ID,
+		Name:          p.Name,
+		Description:   p.Description,
+		IsPublic:      p.Public,
+		Collaborative: p.Collaborative,
+		SnapshotID:    fmt.Sprintf("%s-%d", p.ID, c.snapshots[p.ID]),
+		URI:           spotify.URI("spotify:playlist:" + string(p.ID)),
+	}
+	sp.Owner.ID = p.Owner
+	sp.Tracks.Total = spotify.Numeric(len(p.Tracks))
+	return sp
+}
+
+func (c *Client) GetPlaylist(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.FullPlaylist, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.playlist(playlistID)
+	if err != nil {
+		return nil, err
+	}
+	p := c.state.Playlists[i]
+	playlist := &spotify.FullPlaylist{SimplePlaylist: c.simplePlaylist(p)}
+	playlist.Tracks = c.playlistTracks(p, 0, min(100, len(p.Tracks)), 100)
+	return playlist, nil
+}
+
+func (c *Client) GetPlaylistTracks(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.PlaylistTrackPage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.playlist(playlistID)
+	if err != nil {
+		return nil, err
+	}
+	p := c.state.Playlists[i]
+	start, end, limit, err := window(opts, len(p.Tracks), 100, 100)
+	if err != nil {
+		return nil, err
+	}
+	page := c.playlistTracks(p, start, end, limit)
+	return &page, nil
+}
+
+// playlistTracks returns a page of a playlist's tracks. c.mu must be held.
+func (c *Client) playlistTracks(p Playlist, start, end, limit int) spotify.PlaylistTrackPage {
+	page := spotify.PlaylistTrackPage{}
+	page.Total, page.Offset, page.Limit = spotify.Numeric(len(p.Tracks)), spotify.Numeric(start), spotify.Numeric(limit)
+	for _, id := range p.Tracks[start:end] {
+		item := spotify.PlaylistTrack{Track: c.tracks[id]}
+		item.AddedBy.ID = p.Owner
+		page.Tracks = append(page.Tracks, item)
+	}
+	return page
+}
+
+func (c *Client) CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if userID != c.state.User.ID {
+		return nil, apiError(http.StatusForbidden, "You cannot create a playlist for another user")
+	}
+	c.created++
+	p := Playlist{
+		ID:            spotify.ID("simulated-" + strconv.Itoa(c.created)),
+		Name:          playlistName,
+		Description:   description,
+		Owner:         userID,
+		Public:        public,
+		Collaborative: collaborative,
+	}
+	// Spotify lists the newest playlists first.
+	c.state.Playlists = slices.Insert(c.state.Playlists, 0, p)
+	return &spotify.FullPlaylist{SimplePlaylist: c.simplePlaylist(p)}, nil
+}
+
+func (c *Client) UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.playlist(playlistID)
+	if err != nil {
+		return err
+	}
+	c.state.Playlists = slices.Delete(c.state.Playlists, i, i+1)
+	return nil
+}
+
+func (c *Client) ChangePlaylistName(ctx context.Context, playlistID spotify.ID, newName string) error {
+	return c.editPlaylist(playlistID, func(p *Playlist) { p.Name = newName })
+}
+
+func (c *Client) ChangePlaylistDescription(ctx context.Context, playlistID spotify.ID, newDescription string) error {
+	return c.editPlaylist(playlistID, func(p *Playlist) { p.Description = newDescription })
+}
+
+func (c *Client) ChangePlaylistAccess(ctx context.Context, playlistID spotify.ID, public bool) error {
+	return c.editPlaylist(playlistID, func(p *Playlist) { p.Public = public })
+}
+
+func (c *Client) SetPlaylistImage(ctx context.Context, playlistID spotify.ID, img io.Reader) error {
+	if _, err := io.Copy(io.Discard, img); err != nil {
+		return err
+	}
+	return c.editPlaylist(playlistID, func(p *Playlist) { p.CoverUploads++ })
+}
+
+// editPlaylist changes a playlist's details, which doesn't change its snapshot.
+func (c *Client) editPlaylist(playlistID spotify.ID, edit func(*Playlist)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, err := c.playlist(playlistID)
+	if err != nil {
+		return err
+	}
+	edit(&c.state.Playlists[i])
+	return nil
+}
+
+// writeTracks changes a playlist's tracks and returns its new snapshot.
+func (c *Client) writeTracks(playlistID spotify.ID, ids []spotify.ID, write func(tracks []spotify.ID) []spotify.ID) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkBatch(len(ids), 100); err != nil {
+		return "", err
+	}
+	if err := c.knownTracks(ids); err != nil {
+		return "", err
+	}
+	i, err := c.playlist(playlistID)
+	if err != nil {
+		return "", err
+	}
+	p := &c.state.Playlists[i]
+	p.Tracks = write(p.Tracks)
+	c.snapshots[playlistID]++
+	return c.simplePlaylist(*p).SnapshotID, nil
+}
+
+func (c *Client) AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	return c.writeTracks(playlistID, trackIDs, func(tracks []spotify.ID) []spotify.ID {
+		return append(tracks, trackIDs...)
+	})
+}
+
+func (c *Client) ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error) {
+	ids := make([]spotify.ID, len(items))
+	for i, uri := range items {
+		ids[i] = spotify.ID(strings.TrimPrefix(string(uri), "spotify:track:"))
+	}
+	return c.writeTracks(playlistID, ids, func([]spotify.ID) []spotify.ID {
+		return slices.Clone(ids)
+	})
+}
+
+func (c *Client) RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	return c.writeTracks(playlistID, trackIDs, func(tracks []spotify.ID) []spotify.ID {
+		// Like Spotify, every occurrence of a track is removed.
+		return slices.DeleteFunc(tracks, func(id spotify.ID) bool { return slices.Contains(trackIDs, id) })
+	})
+}
+
+func (c *Client) PlayerDevices(ctx context.Context) ([]spotify.PlayerDevice, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.state.Devices), nil
+}
+
+func (c *Client) TransferPlayback(ctx context.Context, deviceID spotify.ID, play bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.IndexFunc(c.state.Devices, func(d spotify.PlayerDevice) bool { return d.ID == deviceID })
+	if i < 0 {
+		return apiError(http.StatusNotFound, "Device not found")
+	}
+	for j := range c.state.Devices {
+		c.state.Devices[j].Active = j == i
+	}
+	return nil
+}
+
+func (c *Client) PlayOpt(ctx context.Context, opt *spotify.PlayOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.IndexFunc(c.state.Devices, func(d spotify.PlayerDevice) bool {
+		if opt != nil && opt.DeviceID != nil {
+			return d.ID == *opt.DeviceID
+		}
+		return d.Active
+	})
+	if i < 0 {
+		return apiError(http.StatusNotFound, "Player command failed: No active device found")
+	}
+	if opt != nil && opt.PlaybackContext != nil {
+		c.state.Playing = *opt.PlaybackContext
+	}
+	return nil
+}
+
+func (c *Client) QueueSongOpt(ctx context.Context, trackID spotify.ID, opt *spotify.PlayOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !slices.ContainsFunc(c.state.Devices, func(d spotify.PlayerDevice) bool {
+		if opt != nil && opt.DeviceID != nil {
+			return d.ID == *opt.DeviceID
+		}
+		return d.Active
+	}) {
+		return apiError(http.StatusNotFound, "Player command failed: No active device found")
+	}
+	if _, ok := c.tracks[trackID]; !ok {
+		return apiError(http.StatusBadRequest, "Invalid track id %s", trackID)
+	}
+	c.state.Queue = append(c.state.Queue, trackID)
+	return nil
+}
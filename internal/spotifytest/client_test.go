@@ -0,0 +1,85 @@
+package spotifytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func fixture() Fixture {
+	return Fixture{
+		User: spotify.PrivateUser{User: spotify.User{ID: "me"}},
+		Tracks: []spotify.FullTrack{
+			{SimpleTrack: spotify.SimpleTrack{ID: "t1", Name: "One"}},
+			{SimpleTrack: spotify.SimpleTrack{ID: "t2", Name: "Two"}},
+			{SimpleTrack: spotify.SimpleTrack{ID: "t3", Name: "Three"}},
+		},
+		Liked: []Saved{{ID: "t1"}, {ID: "t2"}, {ID: "t3"}},
+	}
+}
+
+func TestNewValidatesFixture(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*Fixture)
+		want string
+	}{
+		{"no user", func(f *Fixture) { f.User.ID = "" }, "no user id"},
+		{"unknown liked track", func(f *Fixture) { f.Liked = append(f.Liked, Saved{ID: "t9"}) }, "liked track t9"},
+		{"unknown playlist track", func(f *Fixture) { f.Playlists = []Playlist{{ID: "p1", Name: "Mix", Tracks: []spotify.ID{"t9"}}} }, "track t9 of playlist 'Mix'"},
+		{"duplicate playlist", func(f *Fixture) { f.Playlists = []Playlist{{ID: "p1", Name: "A"}, {ID: "p1", Name: "B"}} }, "playlist 'B' needs a unique id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fixture()
+			tt.edit(&f)
+			_, err := New(f)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLikedPages(t *testing.T) {
+	c, err := New(fixture())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	page, err := c.CurrentUsersTracks(ctx, spotify.Limit(2), spotify.Offset(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || len(page.Tracks) != 2 || page.Tracks[0].ID != "t2" {
+		t.Errorf("page at offset 1 = %d of %d starting at %v, want t2 and t3 of 3", len(page.Tracks), page.Total, page.Tracks)
+	}
+	var apiErr spotify.Error
+	if _, err := c.CurrentUsersTracks(ctx, spotify.Limit(51)); !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		t.Errorf("a page of 51 liked songs error = %v, want a 400 like Spotify's", err)
+	}
+}
+
+func TestChangesStayInMemory(t *testing.T) {
+	f := fixture()
+	c, err := New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveTracksFromLibrary(context.Background(), "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Fixture().Liked; len(got) != 2 || got[1].ID != "t3" {
+		t.Errorf("liked songs after removing t2 = %v", got)
+	}
+	if len(f.Liked) != 3 {
+		t.Error("removing a liked song changed the fixture the client was made from")
+	}
+	if err := c.AddTracksToLibrary(context.Background(), "t9"); err == nil {
+		t.Error("liking a song that isn't in the catalog didn't fail")
+	}
+}
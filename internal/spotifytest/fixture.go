@@ -0,0 +1,114 @@
+// Package spotifytest provides a SpotifyClient backed by an in-memory library, for
+// trying processors out without touching a real account and for testing them.
+package spotifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Fixture is the starting state of a simulated account. Tracks, artists and albums make
+// up the catalog, in Spotify's own JSON format; the library and playlists refer to it by
+// ID.
+type Fixture struct {
+	User          spotify.PrivateUser     `json:"user"`
+	Tracks        []spotify.FullTrack     `json:"tracks"`
+	Artists       []spotify.FullArtist    `json:"artists"`
+	Albums        []spotify.FullAlbum     `json:"albums"`
+	AudioFeatures []spotify.AudioFeatures `json:"audio_features"`
+	// Liked and SavedAlbums are newest first, as Spotify lists them.
+	Liked           []Saved      `json:"liked"`
+	SavedAlbums     []Saved      `json:"saved_albums"`
+	FollowedArtists []spotify.ID `json:"followed_artists"`
+	Playlists       []Playlist   `json:"playlists"`
+	// Devices are the user's Spotify Connect devices, Playing the context last started on
+	// one of them and Queue the tracks queued after it.
+	Devices []spotify.PlayerDevice `json:"devices"`
+	Playing spotify.URI            `json:"playing,omitempty"`
+	Queue   []spotify.ID           `json:"queue,omitempty"`
+}
+
+// Saved is an item of the library and when it was added.
+type Saved struct {
+	ID      spotify.ID `json:"id"`
+	AddedAt string     `json:"added_at"`
+}
+
+// Playlist is a playlist of the simulated account. Owner defaults to the user.
+type Playlist struct {
+	ID            spotify.ID   `json:"id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	Owner         string       `json:"owner"`
+	Public        bool         `json:"public"`
+	Collaborative bool         `json:"collaborative"`
+	Tracks        []spotify.ID `json:"tracks"`
+	// CoverUploads counts the covers uploaded during the simulation.
+	CoverUploads int `json:"cover_uploads,omitempty"`
+}
+
+// Load reads a fixture from a JSON file and returns a client over it.
+func Load(path string) (*Client, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read fixture: %w", err)
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("could not parse fixture %s: %w", path, err)
+	}
+	return New(f)
+}
+
+// validate checks that everything the fixture refers to is in its catalog.
+func (f Fixture) validate() error {
+	if f.User.ID == "" {
+		return fmt.Errorf("fixture has no user id")
+	}
+	tracks := make(map[spotify.ID]bool)
+	for _, t := range f.Tracks {
+		tracks[t.ID] = true
+	}
+	albums := make(map[spotify.ID]bool)
+	for _, a := range f.Albums {
+		albums[a.ID] = true
+	}
+	for _, t := range f.Tracks {
+		albums[t.Album.ID] = true
+	}
+	artists := make(map[spotify.ID]bool)
+	for _, a := range f.Artists {
+		artists[a.ID] = true
+	}
+	for _, s := range f.Liked {
+		if !tracks[s.ID] {
+			return fmt.Errorf("liked track %s is not in the fixture's tracks", s.ID)
+		}
+	}
+	for _, s := range f.SavedAlbums {
+		if !albums[s.ID] {
+			return fmt.Errorf("saved album %s is not in the fixture's albums", s.ID)
+		}
+	}
+	for _, id := range f.FollowedArtists {
+		if !artists[id] {
+			return fmt.Errorf("followed artist %s is not in the fixture's artists", id)
+		}
+	}
+	seen := make(map[spotify.ID]bool)
+	for _, p := range f.Playlists {
+		if p.ID == "" || seen[p.ID] {
+			return fmt.Errorf("playlist '%s' needs a unique id", p.Name)
+		}
+		seen[p.ID] = true
+		for _, id := range p.Tracks {
+			if !tracks[id] {
+				return fmt.Errorf("track %s of playlist '%s' is not in the fixture's tracks", id, p.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package spotifytest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Search finds catalog items whose details contain every term of query. Terms may be
+// limited to a field with Spotify's filters (track:, artist:, album:, year:, isrc:) and
+// quoted to span words.
+func (c *Client) Search(ctx context.Context, query string, t spotify.SearchType, opts ...spotify.RequestOption) (*spotify.SearchResult, error) {
+	terms := parseQuery(query)
+	if len(terms) == 0 {
+		return nil, apiError(http.StatusBadRequest, "No search query")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &spotify.SearchResult{}
+	if t&spotify.SearchTypeTrack != 0 {
+		var matches []spotify.FullTrack
+		for _, track := range c.state.Tracks {
+			if matchesAll(terms, trackFields(track)) {
+				matches = append(matches, track)
+			}
+		}
+		start, end, limit, err := window(opts, len(matches), 20, 50)
+		if err != nil {
+			return nil, err
+		}
+		result.Tracks = &spotify.FullTrackPage{Tracks: matches[start:end]}
+		result.Tracks.Total, result.Tracks.Offset, result.Tracks.Limit = spotify.Numeric(len(matches)), spotify.Numeric(start), spotify.Numeric(limit)
+	}
+	if t&spotify.SearchTypeArtist != 0 {
+		var matches []spotify.FullArtist
+		for _, artist := range c.state.Artists {
+			if matchesAll(terms, map[string][]string{"artist": {artist.Name}}) {
+				matches = append(matches, artist)
+			}
+		}
+		start, end, limit, err := window(opts, len(matches), 20, 50)
+		if err != nil {
+			return nil, err
+		}
+		result.Artists = &spotify.FullArtistPage{Artists: matches[start:end]}
+		result.Artists.Total, result.Artists.Offset, result.Artists.Limit = spotify.Numeric(len(matches)), spotify.Numeric(start), spotify.Numeric(limit)
+	}
+	if t&spotify.SearchTypeAlbum != 0 {
+		var matches []spotify.SimpleAlbum
+		for _, album := range c.albumList() {
+			if matchesAll(terms, albumFields(album)) {
+				matches = append(matches, album)
+			}
+		}
+		start, end, limit, err := window(opts, len(matches), 20, 50)
+		if err != nil {
+			return nil, err
+		}
+		result.Albums = &spotify.SimpleAlbumPage{Albums: matches[start:end]}
+		result.Albums.Total, result.Albums.Offset, result.Albums.Limit = spotify.Numeric(len(matches)), spotify.Numeric(start), spotify.Numeric(limit)
+	}
+	return result, nil
+}
+
+// albumList returns the catalog's albums: the fixture's own, then those only known from
+// their tracks. c.mu must be held.
+func (c *Client) albumList() []spotify.SimpleAlbum {
+	var albums []spotify.SimpleAlbum
+	seen := make(map[spotify.ID]bool)
+	for _, a := range c.state.Albums {
+		albums = append(albums, a.SimpleAlbum)
+		seen[a.ID] = true
+	}
+	for _, t := range c.state.Tracks {
+		if !seen[t.Album.ID] {
+			albums = append(albums, t.Album)
+			seen[t.Album.ID] = true
+		}
+	}
+	return albums
+}
+
+// term is a search term, limited to field if it's non-empty.
+type term struct {
+	field, value string
+}
+
+// parseQuery splits a query into lower-case terms.
+func parseQuery(query string) []term {
+	var terms []term
+	for query = strings.TrimSpace(query); query != ""; query = strings.TrimSpace(query) {
+		var field string
+		if i := strings.IndexAny(query, ": \""); i > 0 && query[i] == ':' {
+			field, query = strings.ToLower(query[:i]), query[i+1:]
+		}
+		var value string
+		if rest, ok := strings.CutPrefix(query, `"`); ok {
+			value, query, _ = strings.Cut(rest, `"`)
+		} else {
+			value, query, _ = strings.Cut(query, " ")
+		}
+		if value != "" {
+			terms = append(terms, term{field: field, value: strings.ToLower(value)})
+		}
+	}
+	return terms
+}
+
+// matchesAll reports whether every term is found in fields, which map a filter name to
+// the values it searches.
+func matchesAll(terms []term, fields map[string][]string) bool {
+	for _, t := range terms {
+		found := false
+		for field, values := range fields {
+			if t.field != "" && t.field != field {
+				continue
+			}
+			for _, v := range values {
+				if field == "isrc" && strings.EqualFold(v, t.value) ||
+					field != "isrc" && strings.Contains(strings.ToLower(v), t.value) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func trackFields(t spotify.FullTrack) map[string][]string {
+	fields := albumFields(t.Album)
+	fields["track"] = []string{t.Name}
+	fields["isrc"] = []string{t.ExternalIDs["isrc"]}
+	for _, a := range t.Artists {
+		fields["artist"] = append(fields["artist"], a.Name)
+	}
+	return fields
+}
+
+func albumFields(a spotify.SimpleAlbum) map[string][]string {
+	fields := map[string][]string{"album": {a.Name}}
+	if len(a.ReleaseDate) >= 4 {
+		fields["year"] = []string{a.ReleaseDate[:4]}
+	}
+	for _, artist := range a.Artists {
+		fields["artist"] = append(fields["artist"], artist.Name)
+	}
+	return fields
+}
@@ -0,0 +1,106 @@
+package spotifytest
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// WriteSummary prints how the account differs from the fixture it started as.
+func (c *Client) WriteSummary(out io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintln(out, "\n🧪 Simulation: the real account was not touched. The run would:")
+	var lines []string
+	add := func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+
+	if added, removed := diff(savedIDs(c.initial.Liked), savedIDs(c.state.Liked)); added+removed > 0 {
+		add("like %d and unlike %d songs (%d liked after)", added, removed, len(c.state.Liked))
+	}
+	if added, removed := diff(savedIDs(c.initial.SavedAlbums), savedIDs(c.state.SavedAlbums)); added+removed > 0 {
+		add("save %d and unsave %d albums", added, removed)
+	}
+	if added, removed := diff(c.initial.FollowedArtists, c.state.FollowedArtists); added+removed > 0 {
+		add("follow %d and unfollow %d artists", added, removed)
+	}
+	if c.state.Playing != c.initial.Playing {
+		add("start playing %s", c.state.Playing)
+	}
+	if queued := len(c.state.Queue) - len(c.initial.Queue); queued > 0 {
+		add("queue %d tracks", queued)
+	}
+	for _, p := range c.initial.Playlists {
+		if !slices.ContainsFunc(c.state.Playlists, func(q Playlist) bool { return q.ID == p.ID }) {
+			add("delete '%s'", p.Name)
+		}
+	}
+	for _, p := range c.state.Playlists {
+		i := slices.IndexFunc(c.initial.Playlists, func(q Playlist) bool { return q.ID == p.ID })
+		if i < 0 {
+			add("create '%s' with %d tracks", p.Name, len(p.Tracks))
+			continue
+		}
+		if what := describeChange(c.initial.Playlists[i], p); what != "" {
+			add("%s", what)
+		}
+	}
+
+	if len(lines) == 0 {
+		fmt.Fprintln(out, "  change nothing.")
+		return
+	}
+	for _, line := range lines {
+		fmt.Fprintf(out, "  - %s\n", line)
+	}
+}
+
+// describeChange summarizes how a playlist changed, or returns "" if it didn't.
+func describeChange(before, after Playlist) string {
+	var what string
+	if added, removed := diff(before.Tracks, after.Tracks); added+removed > 0 {
+		what = fmt.Sprintf("change '%s' from %d to %d tracks (%d added, %d removed)", before.Name, len(before.Tracks), len(after.Tracks), added, removed)
+	} else if !slices.Equal(before.Tracks, after.Tracks) {
+		what = fmt.Sprintf("reorder '%s'", before.Name)
+	}
+	extra := func(s string) {
+		if what == "" {
+			what = fmt.Sprintf("update '%s'", before.Name)
+		}
+		what += ", " + s
+	}
+	if after.Name != before.Name {
+		extra(fmt.Sprintf("renaming it to '%s'", after.Name))
+	}
+	if after.Description != before.Description {
+		extra("updating its description")
+	}
+	if after.CoverUploads > before.CoverUploads {
+		extra("uploading a cover")
+	}
+	return what
+}
+
+func savedIDs(saved []Saved) []spotify.ID {
+	ids := make([]spotify.ID, len(saved))
+	for i, s := range saved {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+// diff counts the IDs of after not in before and the IDs of before not in after.
+func diff(before, after []spotify.ID) (added, removed int) {
+	for _, id := range after {
+		if !slices.Contains(before, id) {
+			added++
+		}
+	}
+	for _, id := range before {
+		if !slices.Contains(after, id) {
+			removed++
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,36 @@
+package store
+
+import "github.com/zmb3/spotify/v2"
+
+// Annotation holds facts about a track that Spotify doesn't provide, either set by the
+// user or looked up once and remembered.
+type Annotation struct {
+	// Language is the user's override of the language the track is sung in (ISO 639-1).
+	Language string `json:"language,omitempty"`
+	// LyricsLanguage caches the answer of the lyrics language hook; "und" means the hook
+	// didn't know.
+	LyricsLanguage string `json:"lyrics_language,omitempty"`
+}
+
+// Annotation returns the annotation of a track, which is empty if there is none.
+func (s *Store) Annotation(trackID spotify.ID) Annotation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Annotations[trackID]
+}
+
+// Annotate updates the annotation of a track with fn. Annotations left empty are removed.
+func (s *Store) Annotate(trackID spotify.ID, fn func(a *Annotation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.data.Annotations[trackID]
+	fn(&a)
+	if a == (Annotation{}) {
+		delete(s.data.Annotations, trackID)
+		return
+	}
+	if s.data.Annotations == nil {
+		s.data.Annotations = make(map[spotify.ID]Annotation)
+	}
+	s.data.Annotations[trackID] = a
+}
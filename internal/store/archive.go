@@ -0,0 +1,55 @@
+package store
+
+import (
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// ArchivedTrack is a liked song the sorter unliked after writing it to its playlist, so
+// Liked Songs stays small while the playlist keeps it.
+type ArchivedTrack struct {
+	// Track is the song as it was liked, with its like date.
+	Track      spotify.SavedTrack `json:"track"`
+	ArchivedAt time.Time          `json:"archived_at"`
+	// RestoredAt is when the song was liked again by unarchive. Spotify dates the like
+	// then, so the sorter keeps using the original date.
+	RestoredAt time.Time `json:"restored_at,omitzero"`
+}
+
+// Archived returns the archived songs by track ID.
+func (s *Store) Archived() map[spotify.ID]ArchivedTrack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archived := make(map[spotify.ID]ArchivedTrack, len(s.data.Archived))
+	for id, a := range s.data.Archived {
+		archived[id] = a
+	}
+	return archived
+}
+
+// Archive records tracks as unliked at at.
+func (s *Store) Archive(tracks []spotify.SavedTrack, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Archived == nil {
+		s.data.Archived = make(map[spotify.ID]ArchivedTrack)
+	}
+	for _, t := range tracks {
+		// Markets make up most of a track's JSON and aren't needed to sort it.
+		t.AvailableMarkets, t.Album.AvailableMarkets = nil, nil
+		s.data.Archived[t.ID] = ArchivedTrack{Track: t, ArchivedAt: at}
+	}
+}
+
+// Restore records archived songs as liked again at at.
+func (s *Store) Restore(ids []spotify.ID, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if a, ok := s.data.Archived[id]; ok {
+			a.RestoredAt = at
+			s.data.Archived[id] = a
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Checkpoint records how far a long run got, so an interrupted run can resume instead of
+// starting over.
+type Checkpoint struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	// Liked holds the liked tracks of the selected periods fetched so far, LikedIDs every
+	// liked song fetched so far, and Offset the position pagination continues from.
+	// FetchDone is set once the whole library has been fetched.
+	Liked     []spotify.SavedTrack `json:"liked,omitempty"`
+	LikedIDs  []spotify.ID         `json:"liked_ids,omitempty"`
+	Offset    int                  `json:"offset"`
+	FetchDone bool                 `json:"fetch_done,omitempty"`
+	// Since is the oldest year fetched when the run was limited to recent years, and 0
+	// when the whole library was fetched.
+	Since int `json:"since,omitempty"`
+	// Completed lists the playlists, by name, that were fully written.
+	Completed []string `json:"completed,omitempty"`
+	// Partial records playlists whose rewrite stopped part-way, keyed by playlist ID.
+	Partial map[spotify.ID]PartialWrite `json:"partial,omitempty"`
+}
+
+// PartialWrite is the state of a playlist rewrite after its last successful batch.
+type PartialWrite struct {
+	Total      int    `json:"total"`
+	Written    int    `json:"written"`
+	SnapshotID string `json:"snapshot_id"`
+}
+
+// Checkpoint returns the checkpoint saved under name, if any.
+func (s *Store) Checkpoint(name string) (Checkpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.data.Checkpoints[name]
+	return cp, ok
+}
+
+// SetCheckpoint saves cp under name, stamping it with the current time.
+func (s *Store) SetCheckpoint(name string, cp Checkpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Checkpoints == nil {
+		s.data.Checkpoints = make(map[string]Checkpoint)
+	}
+	cp.UpdatedAt = time.Now()
+	s.data.Checkpoints[name] = cp
+}
+
+// ClearCheckpoint removes the checkpoint saved under name.
+func (s *Store) ClearCheckpoint(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.Checkpoints, name)
+}
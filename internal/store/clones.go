@@ -0,0 +1,21 @@
+package store
+
+import "github.com/zmb3/spotify/v2"
+
+// Clone returns the playlist a source playlist was last copied into.
+func (s *Store) Clone(source spotify.ID) (spotify.ID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.data.Clones[source]
+	return id, ok
+}
+
+// SetClone records that source is copied into target.
+func (s *Store) SetClone(source, target spotify.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Clones == nil {
+		s.data.Clones = make(map[spotify.ID]spotify.ID)
+	}
+	s.data.Clones[source] = target
+}
@@ -0,0 +1,41 @@
+package store
+
+import "github.com/zmb3/spotify/v2"
+
+// CoverHashes returns the perceptual hash of every generated cover, keyed by playlist name.
+func (s *Store) CoverHashes() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hashes := make(map[string]uint64, len(s.data.CoverHashes))
+	for name, hash := range s.data.CoverHashes {
+		hashes[name] = hash
+	}
+	return hashes
+}
+
+// SetCoverHash records the perceptual hash of the cover generated for a playlist.
+func (s *Store) SetCoverHash(name string, hash uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.CoverHashes == nil {
+		s.data.CoverHashes = make(map[string]uint64)
+	}
+	s.data.CoverHashes[name] = hash
+}
+
+// UploadedCover returns the checksum of the cover last uploaded to a playlist, or "".
+func (s *Store) UploadedCover(playlistID spotify.ID) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.UploadedCovers[playlistID]
+}
+
+// SetUploadedCover records the checksum of the cover uploaded to a playlist.
+func (s *Store) SetUploadedCover(playlistID spotify.ID, checksum string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.UploadedCovers == nil {
+		s.data.UploadedCovers = make(map[spotify.ID]string)
+	}
+	s.data.UploadedCovers[playlistID] = checksum
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// encryptedMagic starts the file of an encrypted store, followed by the salt, the nonce
+// and the AES-256-GCM sealed JSON.
+var encryptedMagic = []byte("spotify-manager/encrypted-store/v1\n")
+
+const (
+	saltSize = 16
+	// keyIterations is the PBKDF2-SHA256 work factor, as recommended by OWASP.
+	keyIterations = 600_000
+)
+
+// ErrEncrypted is returned when opening an encrypted store without a passphrase.
+var ErrEncrypted = errors.New("the store is encrypted; a passphrase is needed")
+
+// encryption seals the store with a key derived from a passphrase.
+type encryption struct {
+	salt []byte
+	aead cipher.AEAD
+}
+
+// newEncryption derives the key of passphrase with salt, or with a new salt if nil.
+func newEncryption(passphrase string, salt []byte) (*encryption, error) {
+	if salt == nil {
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, keyIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &encryption{salt: salt, aead: aead}, nil
+}
+
+// isEncrypted reports whether raw is the content of an encrypted store.
+func isEncrypted(raw []byte) bool {
+	return bytes.HasPrefix(raw, encryptedMagic)
+}
+
+// openEncrypted returns the JSON of an encrypted store and the encryption to save it
+// with again.
+func openEncrypted(raw []byte, passphrase string) ([]byte, *encryption, error) {
+	raw = raw[len(encryptedMagic):]
+	if len(raw) < saltSize {
+		return nil, nil, errors.New("the encrypted store is truncated")
+	}
+	enc, err := newEncryption(passphrase, raw[:saltSize])
+	if err != nil {
+		return nil, nil, err
+	}
+	raw = raw[saltSize:]
+	nonceSize := enc.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return nil, nil, errors.New("the encrypted store is truncated")
+	}
+	plain, err := enc.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], encryptedMagic)
+	if err != nil {
+		return nil, nil, errors.New("wrong passphrase, or the store is damaged")
+	}
+	return plain, enc, nil
+}
+
+// seal encrypts the JSON of the store with a new nonce.
+func (e *encryption) seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(bytes.Clone(encryptedMagic), e.salt...)
+	out = append(out, nonce...)
+	return e.aead.Seal(out, nonce, plain, encryptedMagic), nil
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sealedStore saves a store holding one tag under passphrase and returns its path.
+func sealedStore(t *testing.T, passphrase string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "store.json")
+	s, err := OpenEncrypted(path, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTags("track1", []string{"secret-tag"})
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return path
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	path := sealedStore(t, "correct horse")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(raw) || bytes.Contains(raw, []byte("secret-tag")) {
+		t.Fatalf("store on disk isn't encrypted: %q", raw)
+	}
+
+	s, err := OpenEncrypted(path, "correct horse")
+	if err != nil {
+		t.Fatalf("OpenEncrypted: %v", err)
+	}
+	if got := s.Tags("track1"); !slices.Equal(got, []string{"secret-tag"}) {
+		t.Errorf("tags after reopening = %v, want [secret-tag]", got)
+	}
+
+	// Saving again keeps the store sealed with the same passphrase.
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := OpenEncrypted(path, "correct horse"); err != nil {
+		t.Errorf("reopening after a second save: %v", err)
+	}
+}
+
+func TestEncryptionWrongPassphrase(t *testing.T) {
+	path := sealedStore(t, "correct horse")
+	_, err := OpenEncrypted(path, "battery staple")
+	if err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("error = %v, want a wrong passphrase", err)
+	}
+	if _, err := Open(path); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Open without a passphrase error = %v, want ErrEncrypted", err)
+	}
+}
+
+func TestEncryptionTampering(t *testing.T) {
+	path := sealedStore(t, "correct horse")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := len(encryptedMagic)
+	tests := []struct {
+		name string
+		raw  []byte
+		want string
+	}{
+		{"flipped ciphertext byte", flip(raw, len(raw)-1), "wrong passphrase, or the store is damaged"},
+		{"flipped salt byte", flip(raw, salt), "wrong passphrase, or the store is damaged"},
+		{"flipped nonce byte", flip(raw, salt+saltSize), "wrong passphrase, or the store is damaged"},
+		{"truncated salt", raw[:salt+saltSize-1], "truncated"},
+		{"truncated nonce", raw[:salt+saltSize+1], "truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tt.raw, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := OpenEncrypted(path, "correct horse")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptionOfPlainStore(t *testing.T) {
+	path := sealedStore(t, "")
+	s, err := OpenEncrypted(path, "correct horse")
+	if err != nil {
+		t.Fatalf("opening a plain store with a passphrase: %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	s, err = OpenEncrypted(path, "correct horse")
+	if err != nil {
+		t.Fatalf("OpenEncrypted: %v", err)
+	}
+	if got := s.Tags("track1"); !slices.Equal(got, []string{"secret-tag"}) {
+		t.Errorf("tags after encrypting = %v, want [secret-tag]", got)
+	}
+}
+
+// flip returns a copy of raw with the byte at i inverted.
+func flip(raw []byte, i int) []byte {
+	out := bytes.Clone(raw)
+	out[i] ^= 0xff
+	return out
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Play is a single listening event, typically imported from a Spotify data export.
+// TrackID is empty for plays from exports without IDs until they're resolved.
+type Play struct {
+	PlayedAt   time.Time  `json:"played_at"`
+	MsPlayed   int64      `json:"ms_played"`
+	TrackID    spotify.ID `json:"track_id"`
+	TrackName  string     `json:"track_name"`
+	ArtistName string     `json:"artist_name"`
+	AlbumName  string     `json:"album_name,omitempty"`
+	Skipped    bool       `json:"skipped,omitempty"`
+}
+
+// key identifies a play so that importing overlapping exports doesn't double count.
+func (p Play) key() string {
+	return p.PlayedAt.UTC().Format(time.RFC3339) + "|" + string(p.TrackID) + "|" + p.TrackName
+}
+
+// looseKey identifies a play across export formats, since the basic export has minute
+// precision and no track IDs.
+func (p Play) looseKey() string {
+	return p.PlayedAt.UTC().Truncate(time.Minute).Format(time.RFC3339) + "|" + strings.ToLower(p.TrackName)
+}
+
+// AddPlays merges plays into the store, skipping ones already present, and returns
+// the number of new plays. A play already imported from an export without IDs is
+// replaced by the same play from one with IDs.
+func (s *Store) AddPlays(plays []Play) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]struct{}, len(s.data.Plays))
+	loose := make(map[string]int, len(s.data.Plays))
+	for i, p := range s.data.Plays {
+		seen[p.key()] = struct{}{}
+		loose[p.looseKey()] = i
+	}
+
+	added := 0
+	for _, p := range plays {
+		k := p.key()
+		if _, dup := seen[k]; dup {
+			continue
+		}
+		seen[k] = struct{}{}
+		if i, dup := loose[p.looseKey()]; dup {
+			if p.TrackID != "" && s.data.Plays[i].TrackID == "" {
+				s.data.Plays[i] = p
+			}
+			continue
+		}
+		loose[p.looseKey()] = len(s.data.Plays)
+		s.data.Plays = append(s.data.Plays, p)
+		added++
+	}
+	return added
+}
+
+// ResolvePlays sets the track ID of the plays without one whose track and artist names
+// are exactly trackName and artistName, and returns how many were updated.
+func (s *Store) ResolvePlays(trackName, artistName string, trackID spotify.ID) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resolved := 0
+	for i, p := range s.data.Plays {
+		if p.TrackID == "" && p.TrackName == trackName && p.ArtistName == artistName {
+			s.data.Plays[i].TrackID = trackID
+			resolved++
+		}
+	}
+	return resolved
+}
+
+// Plays returns a copy of every stored play.
+func (s *Store) Plays() []Play {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Play(nil), s.data.Plays...)
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// CachedResponse is an API response kept for reuse by later runs.
+type CachedResponse struct {
+	Expires time.Time       `json:"expires"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// CachedResponse returns the response saved under key, unless it has expired.
+func (s *Store) CachedResponse(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.data.Responses[key]
+	if !ok || time.Now().After(r.Expires) {
+		return nil, false
+	}
+	return r.Body, true
+}
+
+// SetCachedResponse saves body under key for ttl.
+func (s *Store) SetCachedResponse(key string, body []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Responses == nil {
+		s.data.Responses = make(map[string]CachedResponse)
+	}
+	s.data.Responses[key] = CachedResponse{Expires: time.Now().Add(ttl), Body: body}
+}
+
+// CachedResponses returns the unexpired responses whose key starts with prefix, by key.
+func (s *Store) CachedResponses(prefix string) map[string][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	found := make(map[string][]byte)
+	for key, r := range s.data.Responses {
+		if strings.HasPrefix(key, prefix) && !now.After(r.Expires) {
+			found[key] = r.Body
+		}
+	}
+	return found
+}
+
+// DropCachedResponses removes the responses whose key starts with prefix.
+func (s *Store) DropCachedResponses(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.data.Responses {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.data.Responses, key)
+		}
+	}
+}
+
+// PruneCachedResponses removes expired responses, so the store doesn't keep growing.
+func (s *Store) PruneCachedResponses() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, r := range s.data.Responses {
+		if now.After(r.Expires) {
+			delete(s.data.Responses, key)
+		}
+	}
+}
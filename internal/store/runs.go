@@ -0,0 +1,21 @@
+package store
+
+import "time"
+
+// LastRun returns when the named command last completed successfully.
+func (s *Store) LastRun(name string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.data.LastRuns[name]
+	return t, ok
+}
+
+// SetLastRun records that the named command completed successfully at t.
+func (s *Store) SetLastRun(name string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.LastRuns == nil {
+		s.data.LastRuns = make(map[string]time.Time)
+	}
+	s.data.LastRuns[name] = t
+}
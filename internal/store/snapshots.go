@@ -0,0 +1,23 @@
+package store
+
+// Snapshot returns the fingerprint of the library and settings the named command last
+// completed with, or "".
+func (s *Store) Snapshot(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Snapshots[name]
+}
+
+// SetSnapshot records the fingerprint the named command completed with; "" forgets it.
+func (s *Store) SetSnapshot(name, fingerprint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fingerprint == "" {
+		delete(s.data.Snapshots, name)
+		return
+	}
+	if s.data.Snapshots == nil {
+		s.data.Snapshots = make(map[string]string)
+	}
+	s.data.Snapshots[name] = fingerprint
+}
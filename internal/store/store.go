@@ -0,0 +1,130 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Store is a small JSON-file backed database for state that outlives a single run.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	// saving serializes Save, so concurrent saves can't write an older state last.
+	saving   sync.Mutex
+	readOnly bool
+	data     data
+	// encryption, if set, seals the file with a passphrase.
+	encryption *encryption
+}
+
+// data is the on-disk layout of the store. Each feature owns one section.
+type data struct {
+	Plays       []Play            `json:"plays,omitempty"`
+	CoverHashes map[string]uint64 `json:"cover_hashes,omitempty"`
+	// UploadedCovers holds the SHA-256 of the cover last uploaded to each playlist.
+	UploadedCovers map[spotify.ID]string     `json:"uploaded_covers,omitempty"`
+	Annotations    map[spotify.ID]Annotation `json:"annotations,omitempty"`
+	Checkpoints    map[string]Checkpoint     `json:"checkpoints,omitempty"`
+	LastRuns       map[string]time.Time      `json:"last_runs,omitempty"`
+	// Snapshots fingerprints the library each command last completed with, to skip runs
+	// with nothing to do.
+	Snapshots map[string]string `json:"snapshots,omitempty"`
+	// Tags holds the user's labels of each track, sorted.
+	Tags map[spotify.ID][]string `json:"tags,omitempty"`
+	// Clones maps each cloned playlist to the user's copy of it.
+	Clones map[spotify.ID]spotify.ID `json:"clones,omitempty"`
+	// Responses holds API responses reused across runs, by request.
+	Responses map[string]CachedResponse `json:"responses,omitempty"`
+	// Written holds the tracks last written to each sorted playlist, to detect edits
+	// made by hand in between runs.
+	Written map[spotify.ID][]spotify.ID `json:"written,omitempty"`
+	// Sorted maps each liked song to the sorted playlist it was last written to.
+	Sorted map[spotify.ID]spotify.ID `json:"sorted,omitempty"`
+	// SortedPlaylists maps each period the sorter writes, e.g. "2024" or "release/1994",
+	// to its playlist, so it's found again after a change of name template.
+	SortedPlaylists map[string]spotify.ID `json:"sorted_playlists,omitempty"`
+	// Archived holds the liked songs the sorter's archive mode unliked.
+	Archived map[spotify.ID]ArchivedTrack `json:"archived,omitempty"`
+}
+
+// Open loads the store at path. A missing file yields an empty store that will be
+// created on the first Save.
+func Open(path string) (*Store, error) {
+	return OpenEncrypted(path, "")
+}
+
+// OpenEncrypted loads the store at path, encrypted at rest with a key derived from
+// passphrase. An empty passphrase only opens unencrypted stores; an unencrypted store
+// opened with a passphrase is encrypted by the next Save.
+func OpenEncrypted(path, passphrase string) (*Store, error) {
+	s := &Store{path: path}
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read store '%s': %w", path, err)
+	}
+	switch {
+	case isEncrypted(raw) && passphrase == "":
+		return nil, fmt.Errorf("could not open store '%s': %w", path, ErrEncrypted)
+	case isEncrypted(raw):
+		if raw, s.encryption, err = openEncrypted(raw, passphrase); err != nil {
+			return nil, fmt.Errorf("could not decrypt store '%s': %w", path, err)
+		}
+	case passphrase != "":
+		if s.encryption, err = newEncryption(passphrase, nil); err != nil {
+			return nil, fmt.Errorf("could not set up store encryption: %w", err)
+		}
+	}
+	if len(raw) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("could not decode store '%s': %w", path, err)
+	}
+	return s, nil
+}
+
+// ReadOnly makes Save a no-op, for dry runs whose changes must not outlive the process.
+func (s *Store) ReadOnly() {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	s.readOnly = true
+}
+
+// Save atomically writes the store back to disk.
+func (s *Store) Save() error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	if s.readOnly {
+		return nil
+	}
+	s.mu.Lock()
+	raw, err := json.Marshal(s.data)
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("could not encode store: %w", err)
+	}
+	if s.encryption != nil {
+		if raw, err = s.encryption.seal(raw); err != nil {
+			return fmt.Errorf("could not encrypt store: %w", err)
+		}
+	}
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("could not create store directory: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("could not write store: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"slices"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Tags returns the tags of a track, sorted.
+func (s *Store) Tags(trackID spotify.ID) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.data.Tags[trackID])
+}
+
+// SetTags replaces the tags of a track. Duplicates are dropped and no tags removes the
+// track's entry.
+func (s *Store) SetTags(trackID spotify.ID, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	if len(tags) == 0 {
+		delete(s.data.Tags, trackID)
+		return
+	}
+	if s.data.Tags == nil {
+		s.data.Tags = make(map[spotify.ID][]string)
+	}
+	s.data.Tags[trackID] = tags
+}
+
+// AllTags returns a copy of the tags of every tagged track.
+func (s *Store) AllTags() map[spotify.ID][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make(map[spotify.ID][]string, len(s.data.Tags))
+	for id, tags := range s.data.Tags {
+		all[id] = slices.Clone(tags)
+	}
+	return all
+}
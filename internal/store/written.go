@@ -0,0 +1,77 @@
+package store
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// WrittenTracks returns the tracks last written to a generated playlist, to tell the
+// user's own edits apart from the tool's.
+func (s *Store) WrittenTracks(playlistID spotify.ID) ([]spotify.ID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, ok := s.data.Written[playlistID]
+	return slices.Clone(ids), ok
+}
+
+// SetWrittenTracks records the tracks just written to a generated playlist.
+func (s *Store) SetWrittenTracks(playlistID spotify.ID, ids []spotify.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Written == nil {
+		s.data.Written = make(map[spotify.ID][]spotify.ID)
+	}
+	s.data.Written[playlistID] = slices.Clone(ids)
+}
+
+// SortedInto returns the sorted playlist a liked song was last written to.
+func (s *Store) SortedInto(trackID spotify.ID) (spotify.ID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.data.Sorted[trackID]
+	return id, ok
+}
+
+// SetSortedInto records that a liked song was written to a sorted playlist.
+func (s *Store) SetSortedInto(trackID, playlistID spotify.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Sorted == nil {
+		s.data.Sorted = make(map[spotify.ID]spotify.ID)
+	}
+	s.data.Sorted[trackID] = playlistID
+}
+
+// SortedPlaylist returns the playlist the sorter last wrote the period key to.
+func (s *Store) SortedPlaylist(key string) (spotify.ID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.data.SortedPlaylists[key]
+	return id, ok
+}
+
+// SetSortedPlaylist records the playlist the sorter wrote the period key to.
+func (s *Store) SetSortedPlaylist(key string, playlistID spotify.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.SortedPlaylists == nil {
+		s.data.SortedPlaylists = make(map[string]spotify.ID)
+	}
+	s.data.SortedPlaylists[key] = playlistID
+}
+
+// SortedPlaylists returns the playlist of every period the sorter wrote, by period key.
+func (s *Store) SortedPlaylists() map[string]spotify.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.data.SortedPlaylists)
+}
+
+// ForgetSortedPlaylist drops the playlist of the period key.
+func (s *Store) ForgetSortedPlaylist(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.SortedPlaylists, key)
+}
@@ -0,0 +1,183 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"spotify/internal/processor"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Entry is one recorded mutating API call. Fields irrelevant to a call are omitted.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Call   string    `json:"call"`
+	Params Params    `json:"params"`
+	Result Result    `json:"result,omitzero"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Params holds the arguments of a recorded call.
+type Params struct {
+	PlaylistID    spotify.ID    `json:"playlist_id,omitempty"`
+	UserID        string        `json:"user_id,omitempty"`
+	Name          string        `json:"name,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	Public        bool          `json:"public,omitempty"`
+	Collaborative bool          `json:"collaborative,omitempty"`
+	TrackIDs      []spotify.ID  `json:"track_ids,omitempty"`
+	AlbumIDs      []spotify.ID  `json:"album_ids,omitempty"`
+	ArtistIDs     []spotify.ID  `json:"artist_ids,omitempty"`
+	URIs          []spotify.URI `json:"uris,omitempty"`
+	Image         *ImageInfo    `json:"image,omitempty"`
+}
+
+// ImageInfo describes an uploaded image without storing its contents.
+type ImageInfo struct {
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// Result holds the values returned by a recorded call.
+type Result struct {
+	PlaylistID spotify.ID `json:"playlist_id,omitempty"`
+	SnapshotID string     `json:"snapshot_id,omitempty"`
+}
+
+// Recorder is a SpotifyClient that forwards every call to the wrapped client and writes
+// each mutating call, with its parameters and outcome, as a JSON line to a transcript.
+// Read-only calls pass straight through.
+type Recorder struct {
+	processor.SpotifyClient
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder wraps client, writing the transcript to w.
+func NewRecorder(client processor.SpotifyClient, w io.Writer) *Recorder {
+	return &Recorder{
+		SpotifyClient: client,
+		enc:           json.NewEncoder(w),
+	}
+}
+
+func (r *Recorder) record(call string, params Params, result Result, err error) {
+	entry := Entry{Time: time.Now().UTC(), Call: call, Params: params, Result: result}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A broken transcript must never break the run it is observing.
+	_ = r.enc.Encode(entry)
+}
+
+func (r *Recorder) RemoveTracksFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	err := r.SpotifyClient.RemoveTracksFromLibrary(ctx, ids...)
+	r.record("RemoveTracksFromLibrary", Params{TrackIDs: ids}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) AddTracksToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	err := r.SpotifyClient.AddTracksToLibrary(ctx, ids...)
+	r.record("AddTracksToLibrary", Params{TrackIDs: ids}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) FollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	err := r.SpotifyClient.FollowArtist(ctx, ids...)
+	r.record("FollowArtist", Params{ArtistIDs: ids}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) UnfollowArtist(ctx context.Context, ids ...spotify.ID) error {
+	err := r.SpotifyClient.UnfollowArtist(ctx, ids...)
+	r.record("UnfollowArtist", Params{ArtistIDs: ids}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) AddAlbumsToLibrary(ctx context.Context, ids ...spotify.ID) error {
+	err := r.SpotifyClient.AddAlbumsToLibrary(ctx, ids...)
+	r.record("AddAlbumsToLibrary", Params{AlbumIDs: ids}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) RemoveAlbumsFromLibrary(ctx context.Context, ids ...spotify.ID) error {
+	err := r.SpotifyClient.RemoveAlbumsFromLibrary(ctx, ids...)
+	r.record("RemoveAlbumsFromLibrary", Params{AlbumIDs: ids}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error {
+	err := r.SpotifyClient.UnfollowPlaylist(ctx, playlistID)
+	r.record("UnfollowPlaylist", Params{PlaylistID: playlistID}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) ChangePlaylistName(ctx context.Context, playlistID spotify.ID, newName string) error {
+	err := r.SpotifyClient.ChangePlaylistName(ctx, playlistID, newName)
+	r.record("ChangePlaylistName", Params{PlaylistID: playlistID, Name: newName}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) ChangePlaylistDescription(ctx context.Context, playlistID spotify.ID, newDescription string) error {
+	err := r.SpotifyClient.ChangePlaylistDescription(ctx, playlistID, newDescription)
+	r.record("ChangePlaylistDescription", Params{PlaylistID: playlistID, Description: newDescription}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) ChangePlaylistAccess(ctx context.Context, playlistID spotify.ID, public bool) error {
+	err := r.SpotifyClient.ChangePlaylistAccess(ctx, playlistID, public)
+	r.record("ChangePlaylistAccess", Params{PlaylistID: playlistID, Public: public}, Result{}, err)
+	return err
+}
+
+func (r *Recorder) CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error) {
+	playlist, err := r.SpotifyClient.CreatePlaylistForUser(ctx, userID, playlistName, description, public, collaborative)
+	var result Result
+	if playlist != nil {
+		result = Result{PlaylistID: playlist.ID, SnapshotID: playlist.SnapshotID}
+	}
+	params := Params{UserID: userID, Name: playlistName, Description: description, Public: public, Collaborative: collaborative}
+	r.record("CreatePlaylistForUser", params, result, err)
+	return playlist, err
+}
+
+func (r *Recorder) AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	snapshotID, err := r.SpotifyClient.AddTracksToPlaylist(ctx, playlistID, trackIDs...)
+	r.record("AddTracksToPlaylist", Params{PlaylistID: playlistID, TrackIDs: trackIDs}, Result{SnapshotID: snapshotID}, err)
+	return snapshotID, err
+}
+
+func (r *Recorder) ReplacePlaylistItems(ctx context.Context, playlistID spotify.ID, items ...spotify.URI) (string, error) {
+	snapshotID, err := r.SpotifyClient.ReplacePlaylistItems(ctx, playlistID, items...)
+	r.record("ReplacePlaylistItems", Params{PlaylistID: playlistID, URIs: items}, Result{SnapshotID: snapshotID}, err)
+	return snapshotID, err
+}
+
+func (r *Recorder) RemoveTracksFromPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	snapshotID, err := r.SpotifyClient.RemoveTracksFromPlaylist(ctx, playlistID, trackIDs...)
+	r.record("RemoveTracksFromPlaylist", Params{PlaylistID: playlistID, TrackIDs: trackIDs}, Result{SnapshotID: snapshotID}, err)
+	return snapshotID, err
+}
+
+// SetPlaylistImage records only the size and hash of the image, never its contents.
+func (r *Recorder) SetPlaylistImage(ctx context.Context, playlistID spotify.ID, img io.Reader) error {
+	data, err := io.ReadAll(img)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	info := &ImageInfo{Bytes: len(data), SHA256: hex.EncodeToString(sum[:])}
+
+	err = r.SpotifyClient.SetPlaylistImage(ctx, playlistID, bytes.NewReader(data))
+	r.record("SetPlaylistImage", Params{PlaylistID: playlistID, Image: info}, Result{}, err)
+	return err
+}
@@ -0,0 +1,115 @@
+package transcript
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"spotify/internal/processor"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Replay re-issues the calls of a transcript against client, in order. Calls that
+// originally failed are skipped, playlists created during the recording are mapped to
+// the ones created during the replay, and image uploads are skipped because transcripts
+// don't store image contents. Pair it with a fake client to reproduce a run offline.
+func Replay(ctx context.Context, client processor.SpotifyClient, r io.Reader, logger *log.Logger) error {
+	playlistIDs := make(map[spotify.ID]spotify.ID)
+	mapID := func(id spotify.ID) spotify.ID {
+		if mapped, ok := playlistIDs[id]; ok {
+			return mapped
+		}
+		return id
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("invalid transcript entry on line %d: %w", line, err)
+		}
+		if e.Error != "" {
+			logger.Printf("Skipping %s from line %d: it failed when recorded (%s).", e.Call, line, e.Error)
+			continue
+		}
+
+		p := e.Params
+		var err error
+		switch e.Call {
+		case "RemoveTracksFromLibrary":
+			err = client.RemoveTracksFromLibrary(ctx, p.TrackIDs...)
+		case "AddTracksToLibrary":
+			err = client.AddTracksToLibrary(ctx, p.TrackIDs...)
+		case "AddAlbumsToLibrary":
+			err = client.AddAlbumsToLibrary(ctx, p.AlbumIDs...)
+		case "FollowArtist":
+			err = client.FollowArtist(ctx, p.ArtistIDs...)
+		case "UnfollowArtist":
+			err = client.UnfollowArtist(ctx, p.ArtistIDs...)
+		case "RemoveAlbumsFromLibrary":
+			err = client.RemoveAlbumsFromLibrary(ctx, p.AlbumIDs...)
+		case "UnfollowPlaylist":
+			err = client.UnfollowPlaylist(ctx, mapID(p.PlaylistID))
+		case "ChangePlaylistName":
+			err = client.ChangePlaylistName(ctx, mapID(p.PlaylistID), p.Name)
+		case "ChangePlaylistDescription":
+			err = client.ChangePlaylistDescription(ctx, mapID(p.PlaylistID), p.Description)
+		case "ChangePlaylistAccess":
+			err = client.ChangePlaylistAccess(ctx, mapID(p.PlaylistID), p.Public)
+		case "CreatePlaylistForUser":
+			var playlist *spotify.FullPlaylist
+			playlist, err = client.CreatePlaylistForUser(ctx, p.UserID, p.Name, p.Description, p.Public, p.Collaborative)
+			if err == nil {
+				playlistIDs[e.Result.PlaylistID] = playlist.ID
+			}
+		case "AddTracksToPlaylist":
+			_, err = client.AddTracksToPlaylist(ctx, mapID(p.PlaylistID), p.TrackIDs...)
+		case "ReplacePlaylistItems":
+			_, err = client.ReplacePlaylistItems(ctx, mapID(p.PlaylistID), p.URIs...)
+		case "RemoveTracksFromPlaylist":
+			_, err = client.RemoveTracksFromPlaylist(ctx, mapID(p.PlaylistID), p.TrackIDs...)
+		case "SetPlaylistImage":
+			logger.Printf("Skipping SetPlaylistImage from line %d: image contents are not recorded.", line)
+			continue
+		default:
+			return fmt.Errorf("unknown call '%s' on line %d", e.Call, line)
+		}
+		if err != nil {
+			return fmt.Errorf("replaying %s from line %d: %w", e.Call, line, err)
+		}
+		logger.Printf("Replayed %s (line %d).", e.Call, line)
+	}
+	return scanner.Err()
+}
+
+type replayer struct {
+	client processor.SpotifyClient
+	path   string
+	logger *log.Logger
+}
+
+// NewReplayer returns a Processor that replays the transcript file at path against client.
+func NewReplayer(client processor.SpotifyClient, path string, logger *log.Logger) processor.Processor {
+	return &replayer{
+		client: client,
+		path:   path,
+		logger: logger,
+	}
+}
+
+// Run opens the transcript and replays it.
+func (p *replayer) Run(ctx context.Context) error {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return fmt.Errorf("could not open transcript: %w", err)
+	}
+	defer f.Close()
+	return Replay(ctx, p.client, f, p.logger)
+}
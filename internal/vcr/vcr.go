@@ -0,0 +1,200 @@
+// Package vcr records the Spotify API's HTTP responses to a cassette file and plays them
+// back later, so processors can be developed and debugged against a real library without
+// network access or API calls.
+package vcr
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Interaction is one recorded request and its response, a JSON line of a cassette.
+// Request headers, and with them the access token, are never recorded; request bodies
+// are kept only as a hash, so uploaded images aren't stored.
+type Interaction struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	BodySHA256  string `json:"body_sha256,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Recorder writes the responses of the requests it passes on to a cassette.
+type Recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// Create starts a new cassette at path, replacing any existing one.
+func Create(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cassette: %w", err)
+	}
+	return &Recorder{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Close closes the cassette.
+func (r *Recorder) Close() error {
+	return r.f.Close()
+}
+
+// Wrap returns a transport that sends requests through next and records them.
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req = req.Clone(req.Context())
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		// Rate-limited responses are retried outside the recorder; only the final answer matters.
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return resp, nil
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// A broken cassette must never break the run it is recording.
+		_ = r.enc.Encode(Interaction{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			BodySHA256:  hash(body),
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        string(respBody),
+		})
+		return resp, nil
+	})
+}
+
+// Player answers requests from a cassette without touching the network.
+type Player struct {
+	mu sync.Mutex
+	// byBody and byURL queue the interactions of each request, matched with and without
+	// the request body.
+	byBody map[string][]Interaction
+	byURL  map[string][]Interaction
+}
+
+// Load reads the cassette at path.
+func Load(path string) (*Player, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open cassette: %w", err)
+	}
+	defer f.Close()
+	p := &Player{byBody: make(map[string][]Interaction), byURL: make(map[string][]Interaction)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("invalid cassette entry on line %d: %w", line, err)
+		}
+		key := i.Method + " " + i.URL
+		p.byBody[key+" "+i.BodySHA256] = append(p.byBody[key+" "+i.BodySHA256], i)
+		p.byURL[key] = append(p.byURL[key], i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read cassette: %w", err)
+	}
+	return p, nil
+}
+
+// Client returns an HTTP client that plays the cassette back.
+func (p *Player) Client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(p.roundTrip)}
+}
+
+// roundTrip answers a request with the next recorded response to the same method, URL
+// and body. A request whose body differs from the recording (e.g. a description
+// containing today's date) falls back to the same method and URL. Repeated requests get
+// the recorded responses in order, then the last one again.
+func (p *Player) roundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := req.Method + " " + req.URL.String()
+	p.mu.Lock()
+	i, ok := next(p.byBody, key+" "+hash(body))
+	if !ok {
+		i, ok = next(p.byURL, key)
+	}
+	p.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no recorded response for %s", key)
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewBufferString(i.Body)),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}
+	if i.ContentType != "" {
+		resp.Header.Set("Content-Type", i.ContentType)
+	}
+	return resp, nil
+}
+
+// next pops the first interaction queued under key, keeping the last one for repeats.
+func next(queues map[string][]Interaction, key string) (Interaction, bool) {
+	queue := queues[key]
+	if len(queue) == 0 {
+		return Interaction{}, false
+	}
+	if len(queue) > 1 {
+		queues[key] = queue[1:]
+	}
+	return queue[0], true
+}
+
+// readBody reads and closes a request's body.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
+
+func hash(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }